@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"net/url"
 	"os"
 	"strconv"
 
@@ -11,14 +12,20 @@ import (
 type Config struct {
 	Port       string
 	JWTSecret  string
+	DBDriver   string // "sqlite" (padrão) ou "postgres"
+	DBPath     string // Arquivo do banco SQLite
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+	DBSSLMode  string
 	RateLimit  int
 	LogDir     string
 	StaticDir  string
+	// Emitente dos arquivos do SNGPC: CNPJ da farmácia e CPF do responsável técnico
+	SNGPCCNPJ           string
+	SNGPCCPFTransmissor string
 }
 
 func LoadConfig() *Config {
@@ -29,15 +36,48 @@ func LoadConfig() *Config {
 	return &Config{
 		Port:       os.Getenv("PORT"),
 		JWTSecret:  os.Getenv("JWT_SECRET"),
+		DBDriver:   getEnv("DB_DRIVER", "sqlite"),
+		DBPath:     getEnv("DB_PATH", "./data/medicontrol.db"),
 		DBHost:     os.Getenv("DB_HOST"),
 		DBPort:     os.Getenv("DB_PORT"),
 		DBUser:     os.Getenv("DB_USER"),
 		DBPassword: os.Getenv("DB_PASSWORD"),
 		DBName:     os.Getenv("DB_NAME"),
+		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 		RateLimit:  getEnvInt("RATE_LIMIT", 100),
 		LogDir:     os.Getenv("LOG_DIR"),
 		StaticDir:  os.Getenv("STATIC_DIR"),
+
+		SNGPCCNPJ:           os.Getenv("SNGPC_CNPJ"),
+		SNGPCCPFTransmissor: os.Getenv("SNGPC_CPF_TRANSMISSOR"),
+	}
+}
+
+// DSNPostgres monta a URL de conexão com o PostgreSQL a partir das variáveis DB_*.
+// Retorna "" se DB_HOST ou DB_NAME não estiverem definidos.
+func (c *Config) DSNPostgres() string {
+	if c.DBHost == "" || c.DBName == "" {
+		return ""
+	}
+	host := c.DBHost
+	if c.DBPort != "" {
+		host += ":" + c.DBPort
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     host,
+		Path:     "/" + c.DBName,
+		RawQuery: url.Values{"sslmode": {c.DBSSLMode}}.Encode(),
 	}
+	return dsn.String()
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
 }
 
 func getEnvInt(key string, defaultValue int) int {
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"medicontrol/config"
+	"medicontrol/models"
+	"medicontrol/services"
+)
+
+// ConsultaAnvisa busca os dados de um registro na ANVISA. É satisfeita por *services.ClienteAnvisa;
+// os testes podem usar uma implementação própria para não depender da rede.
+type ConsultaAnvisa interface {
+	BuscarDadosAnvisa(codigoRegistro string) (*services.DadosAnvisa, error)
+}
+
+// Aplicacao reúne as dependências dos handlers: o banco de dados (com as suas queries e o relógio),
+// o cliente da ANVISA e a configuração. Os handlers do gin são métodos dela, então cada instância
+// atende sobre o seu próprio banco, sem estado compartilhado entre instâncias.
+type Aplicacao struct {
+	banco  *models.Banco
+	anvisa ConsultaAnvisa
+	config *config.Config
+}
+
+// NovaAplicacao cria a aplicação com as dependências já abertas. Um config nil equivale a uma
+// configuração vazia.
+func NovaAplicacao(banco *models.Banco, anvisa ConsultaAnvisa, cfg *config.Config) *Aplicacao {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	return &Aplicacao{banco: banco, anvisa: anvisa, config: cfg}
+}
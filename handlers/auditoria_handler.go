@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListarAuditoria retorna a trilha de auditoria, filtrável por entidade, usuário e período.
+// Parâmetros: entidade, entidade_id, usuario_id, de e ate (YYYY-MM-DD, inclusivos) e limite.
+func (a *Aplicacao) ListarAuditoria(c *gin.Context) {
+	filtro := models.FiltroAuditoria{
+		Entidade:   c.Query("entidade"),
+		EntidadeID: c.Query("entidade_id"),
+	}
+
+	if usuarioStr := c.Query("usuario_id"); usuarioStr != "" {
+		usuarioID, err := strconv.Atoi(usuarioStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'usuario_id' inválido"})
+			return
+		}
+		filtro.UsuarioID = usuarioID
+	}
+
+	if deStr := c.Query("de"); deStr != "" {
+		de, err := time.ParseInLocation("2006-01-02", deStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'de' inválido, use YYYY-MM-DD"})
+			return
+		}
+		filtro.De = de
+	}
+
+	if ateStr := c.Query("ate"); ateStr != "" {
+		ate, err := time.ParseInLocation("2006-01-02", ateStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'ate' inválido, use YYYY-MM-DD"})
+			return
+		}
+		// Inclui o dia inteiro informado
+		filtro.Ate = ate.AddDate(0, 0, 1)
+	}
+
+	limite, err := strconv.Atoi(c.DefaultQuery("limite", "200"))
+	if err != nil || limite <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'limite' inválido"})
+		return
+	}
+	filtro.Limite = limite
+
+	registros, err := a.banco.ListarAuditoria(filtro)
+	if err != nil {
+		log.Printf("Erro ao buscar trilha de auditoria: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar trilha de auditoria"})
+		return
+	}
+
+	if registros == nil {
+		registros = []models.RegistroAuditoria{}
+	}
+
+	c.JSON(http.StatusOK, registros)
+}
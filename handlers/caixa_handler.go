@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AbrirCaixaHandler abre uma sessão de caixa para o usuário logado com o fundo de troco informado.
+func (a *Aplicacao) AbrirCaixaHandler(c *gin.Context) {
+	var req struct {
+		ValorAbertura float64 `json:"valor_abertura"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	caixa, err := a.banco.AbrirCaixa(usuarioAtualID(c), req.ValorAbertura)
+	if err != nil {
+		responderErroCaixa(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, caixa)
+}
+
+// GetCaixaAtualHandler retorna o resumo parcial do caixa aberto do usuário logado.
+func (a *Aplicacao) GetCaixaAtualHandler(c *gin.Context) {
+	resumo, err := a.banco.GetCaixaAtual(usuarioAtualID(c))
+	if err != nil {
+		responderErroCaixa(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resumo)
+}
+
+// SangriaCaixaHandler registra uma retirada de dinheiro do caixa aberto.
+func (a *Aplicacao) SangriaCaixaHandler(c *gin.Context) {
+	a.registrarMovimentoCaixa(c, models.MovimentoSangria)
+}
+
+// SuprimentoCaixaHandler registra um reforço de dinheiro no caixa aberto.
+func (a *Aplicacao) SuprimentoCaixaHandler(c *gin.Context) {
+	a.registrarMovimentoCaixa(c, models.MovimentoSuprimento)
+}
+
+func (a *Aplicacao) registrarMovimentoCaixa(c *gin.Context, tipo string) {
+	var req struct {
+		Valor  float64 `json:"valor" binding:"required"`
+		Motivo string  `json:"motivo"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	mov, err := a.banco.RegistrarMovimentoCaixa(usuarioAtualID(c), tipo, req.Valor, req.Motivo)
+	if err != nil {
+		responderErroCaixa(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, mov)
+}
+
+// FecharCaixaHandler fecha o caixa aberto do usuário logado com a contagem por forma de pagamento
+// e devolve o resumo de esperado x contado.
+func (a *Aplicacao) FecharCaixaHandler(c *gin.Context) {
+	var req models.FechamentoCaixaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	resumo, err := a.banco.FecharCaixa(usuarioAtualID(c), req)
+	if err != nil {
+		responderErroCaixa(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resumo)
+}
+
+// ListarCaixasHandler lista as sessões de caixa mais recentes.
+// Parâmetros: usuario_id e limite (padrão 50).
+func (a *Aplicacao) ListarCaixasHandler(c *gin.Context) {
+	usuarioID, _ := strconv.Atoi(c.Query("usuario_id"))
+	limite, err := strconv.Atoi(c.DefaultQuery("limite", "50"))
+	if err != nil || limite <= 0 {
+		limite = 50
+	}
+
+	caixas, err := a.banco.ListarCaixas(usuarioID, limite)
+	if err != nil {
+		log.Printf("Erro ao listar caixas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar caixas"})
+		return
+	}
+	if caixas == nil {
+		caixas = []models.Caixa{}
+	}
+	c.JSON(http.StatusOK, caixas)
+}
+
+// GetCaixaHandler retorna o resumo de uma sessão de caixa, aberta ou fechada.
+func (a *Aplicacao) GetCaixaHandler(c *gin.Context) {
+	caixaID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de caixa inválido"})
+		return
+	}
+
+	resumo, err := a.banco.GetResumoCaixa(caixaID)
+	if err != nil {
+		responderErroCaixa(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resumo)
+}
+
+// responderErroCaixa traduz os erros das sessões de caixa em status HTTP.
+func responderErroCaixa(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrCaixaNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrCaixaFechado), errors.Is(err, models.ErrCaixaJaAberto):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrMovimentoCaixaInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro no caixa: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no caixa: " + err.Error()})
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 // ListarCategorias retorna a lista de todas as categorias de medicamentos.
-func ListarCategorias(c *gin.Context) {
+func (a *Aplicacao) ListarCategorias(c *gin.Context) {
 	log.Println("Recebida requisição para listar categorias")
 
 	// Adicionar headers CORS para permitir acesso de diferentes origens
@@ -23,7 +23,7 @@ func ListarCategorias(c *gin.Context) {
 		return
 	}
 
-	categorias, err := models.GetAllCategorias()
+	categorias, err := a.banco.GetAllCategorias()
 	if err != nil {
 		log.Printf("Erro ao buscar categorias do banco de dados: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar sua solicitação"})
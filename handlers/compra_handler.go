@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListarPedidosCompra lista os pedidos de compra. Parâmetros: status e fornecedor_id.
+func (a *Aplicacao) ListarPedidosCompra(c *gin.Context) {
+	filtro := models.FiltroPedidosCompra{Status: c.Query("status")}
+	if v := c.Query("fornecedor_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fornecedor_id inválido"})
+			return
+		}
+		filtro.FornecedorID = id
+	}
+
+	pedidos, err := a.banco.ListarPedidosCompra(filtro)
+	if err != nil {
+		log.Printf("Erro ao listar pedidos de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar pedidos de compra"})
+		return
+	}
+	if pedidos == nil {
+		pedidos = []models.PedidoCompra{}
+	}
+	c.JSON(http.StatusOK, pedidos)
+}
+
+// ObterPedidoCompra retorna um pedido com itens e recebimentos.
+func (a *Aplicacao) ObterPedidoCompra(c *gin.Context) {
+	id, ok := pedidoCompraID(c)
+	if !ok {
+		return
+	}
+
+	pedido, err := a.banco.GetPedidoCompra(id)
+	if err != nil {
+		responderErroCompra(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pedido)
+}
+
+// CriarPedidoCompra cria um pedido em rascunho.
+func (a *Aplicacao) CriarPedidoCompra(c *gin.Context) {
+	var req models.PedidoCompraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	pedido, err := a.banco.CriarPedidoCompra(req, usuarioAtualID(c))
+	if err != nil {
+		responderErroCompra(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, pedido)
+}
+
+// AtualizarItensPedidoCompra substitui os itens de um pedido em rascunho.
+func (a *Aplicacao) AtualizarItensPedidoCompra(c *gin.Context) {
+	id, ok := pedidoCompraID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PedidoCompraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	pedido, err := a.banco.AtualizarItensPedidoCompra(id, req, usuarioAtualID(c))
+	if err != nil {
+		responderErroCompra(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pedido)
+}
+
+// EnviarPedidoCompra marca o pedido como enviado ao fornecedor.
+func (a *Aplicacao) EnviarPedidoCompra(c *gin.Context) {
+	id, ok := pedidoCompraID(c)
+	if !ok {
+		return
+	}
+
+	pedido, err := a.banco.EnviarPedidoCompra(id, usuarioAtualID(c))
+	if err != nil {
+		responderErroCompra(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pedido)
+}
+
+// ReceberPedidoCompra registra a entrega, total ou parcial, dos itens de um pedido.
+func (a *Aplicacao) ReceberPedidoCompra(c *gin.Context) {
+	id, ok := pedidoCompraID(c)
+	if !ok {
+		return
+	}
+
+	var req models.RecebimentoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	pedido, err := a.banco.ReceberPedidoCompra(id, req, usuarioAtualID(c))
+	if err != nil {
+		responderErroCompra(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pedido)
+}
+
+func pedidoCompraID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de pedido inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// responderErroCompra traduz os erros dos pedidos de compra em status HTTP.
+func responderErroCompra(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrPedidoCompraNaoEncontrado), errors.Is(err, models.ErrFornecedorNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrStatusPedidoCompra):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrPedidoCompraInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro no pedido de compra: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no pedido de compra: " + err.Error()})
+	}
+}
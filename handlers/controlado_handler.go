@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ObterLivroControlados retorna o livro de registro de entradas e saídas dos medicamentos controlados.
+// Parâmetros: de e ate (YYYY-MM-DD, inclusivos; padrão: mês corrente) e medicamento_id (opcional).
+func (a *Aplicacao) ObterLivroControlados(c *gin.Context) {
+	de, ate, ok := a.periodoDaRequisicao(c)
+	if !ok {
+		return
+	}
+
+	livros, err := a.banco.GetLivroControlados(de, ate, c.Query("medicamento_id"))
+	if err != nil {
+		log.Printf("Erro ao gerar livro de controlados: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar livro de registro de controlados"})
+		return
+	}
+
+	if livros == nil {
+		livros = []models.LivroControlado{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"de":     de.Format("2006-01-02"),
+		"ate":    ate.AddDate(0, 0, -1).Format("2006-01-02"),
+		"livros": livros,
+		"listas": models.ListasControle,
+	})
+}
+
+// periodoDaRequisicao lê os parâmetros 'de' e 'ate' (YYYY-MM-DD, inclusivos), usando o mês corrente
+// como padrão. Retorna o intervalo semiaberto [de, ate). Em caso de erro, já responde 400.
+func (a *Aplicacao) periodoDaRequisicao(c *gin.Context) (time.Time, time.Time, bool) {
+	agora := a.banco.Agora()
+	de := time.Date(agora.Year(), agora.Month(), 1, 0, 0, 0, 0, time.Local)
+	ate := de.AddDate(0, 1, 0)
+
+	if deStr := c.Query("de"); deStr != "" {
+		d, err := time.ParseInLocation("2006-01-02", deStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'de' inválido, use YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		de = d
+	}
+	if ateStr := c.Query("ate"); ateStr != "" {
+		d, err := time.ParseInLocation("2006-01-02", ateStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'ate' inválido, use YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+		ate = d.AddDate(0, 0, 1)
+	}
+	if !de.Before(ate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Período inválido: 'de' deve ser anterior a 'ate'"})
+		return time.Time{}, time.Time{}, false
+	}
+	return de, ate, true
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ObterRelatorioMargem retorna receita, custo, lucro e margem das vendas do período.
+// Parâmetros: de e ate (YYYY-MM-DD, padrão mês corrente), agrupar (produto, categoria, dia ou mes),
+// categoria_id e formato=csv para baixar a planilha.
+func (a *Aplicacao) ObterRelatorioMargem(c *gin.Context) {
+	de, ate, ok := a.periodoDaRequisicao(c)
+	if !ok {
+		return
+	}
+
+	relatorio, err := a.banco.GetRelatorioMargem(models.FiltroMargem{
+		De:          de,
+		Ate:         ate,
+		Agrupamento: c.DefaultQuery("agrupar", models.AgruparPorProduto),
+		CategoriaID: c.Query("categoria_id"),
+	})
+	if errors.Is(err, models.ErrAgrupamentoInvalido) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao gerar relatório de margem: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de margem"})
+		return
+	}
+
+	if c.Query("formato") == "csv" {
+		escreverMargemCSV(c, relatorio)
+		return
+	}
+	c.JSON(http.StatusOK, relatorio)
+}
+
+// escreverMargemCSV gera a planilha no formato usado pelo Excel em português:
+// separador ';', vírgula decimal e BOM para manter os acentos.
+func escreverMargemCSV(c *gin.Context, relatorio *models.RelatorioMargem) {
+	nome := fmt.Sprintf("margem_%s_%s_%s.csv", relatorio.Agrupamento, relatorio.De, relatorio.Ate)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+nome)
+	c.Status(http.StatusOK)
+
+	c.Writer.WriteString("\ufeff")
+	w := csv.NewWriter(c.Writer)
+	w.Comma = ';'
+	w.Write([]string{"Chave", "Descrição", "Quantidade", "Receita", "Custo", "Lucro", "Margem %", "Quantidade sem custo"})
+	for _, l := range append(relatorio.Linhas, relatorio.Total) {
+		margem := ""
+		if l.MargemPercentual != nil {
+			margem = decimalCSV(*l.MargemPercentual)
+		}
+		w.Write([]string{
+			l.Chave, l.Descricao, strconv.Itoa(l.Quantidade),
+			decimalCSV(l.Receita), decimalCSV(l.Custo), decimalCSV(l.Lucro), margem,
+			strconv.Itoa(l.QuantidadeSemCusto),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Printf("Erro ao escrever CSV de margem: %v", err)
+	}
+}
+
+func decimalCSV(valor float64) string {
+	return strings.Replace(strconv.FormatFloat(valor, 'f', 2, 64), ".", ",", 1)
+}
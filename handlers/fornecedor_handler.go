@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListarFornecedores retorna os fornecedores ativos, ou todos com ?inativos=true.
+func (a *Aplicacao) ListarFornecedores(c *gin.Context) {
+	fornecedores, err := a.banco.ListarFornecedores(c.Query("inativos") == "true")
+	if err != nil {
+		log.Printf("Erro ao buscar fornecedores: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fornecedores"})
+		return
+	}
+
+	if fornecedores == nil {
+		fornecedores = []models.Fornecedor{}
+	}
+
+	c.JSON(http.StatusOK, fornecedores)
+}
+
+// ObterFornecedor retorna um fornecedor específico
+func (a *Aplicacao) ObterFornecedor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de fornecedor inválido"})
+		return
+	}
+
+	fornecedor, err := a.banco.GetFornecedor(id)
+	if err != nil {
+		log.Printf("Erro ao buscar fornecedor %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fornecedor"})
+		return
+	}
+	if fornecedor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fornecedor não encontrado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, fornecedor)
+}
+
+// CriarFornecedor cadastra um novo fornecedor
+func (a *Aplicacao) CriarFornecedor(c *gin.Context) {
+	var req models.FornecedorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fornecedor, err := a.banco.CriarFornecedor(req, usuarioAtualID(c))
+	if err != nil {
+		responderErroFornecedor(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, fornecedor)
+}
+
+// AtualizarFornecedor altera os dados de um fornecedor existente
+func (a *Aplicacao) AtualizarFornecedor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de fornecedor inválido"})
+		return
+	}
+
+	var req models.FornecedorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fornecedor, err := a.banco.AtualizarFornecedor(id, req, usuarioAtualID(c))
+	if err != nil {
+		responderErroFornecedor(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, fornecedor)
+}
+
+// DesativarFornecedor desativa um fornecedor, preservando o histórico de pedidos
+func (a *Aplicacao) DesativarFornecedor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de fornecedor inválido"})
+		return
+	}
+
+	if err := a.banco.DesativarFornecedor(id, usuarioAtualID(c)); err != nil {
+		responderErroFornecedor(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// responderErroFornecedor traduz os erros do cadastro de fornecedores em status HTTP.
+func responderErroFornecedor(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrFornecedorNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrFornecedorInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro no cadastro de fornecedor: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no cadastro de fornecedor: " + err.Error()})
+	}
+}
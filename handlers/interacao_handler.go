@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tamanhoMaximoTabelaInteracoes limita o arquivo aceito na importação da tabela de interações
+const tamanhoMaximoTabelaInteracoes = 10 << 20
+
+// VerificarInteracoesVendaHandler confere os itens da venda em montagem no PDV, sem registrá-la,
+// e retorna os alertas de interação que o atendente precisará confirmar.
+func (a *Aplicacao) VerificarInteracoesVendaHandler(c *gin.Context) {
+	var vendaReq models.RegistrarVendaRequest
+	if err := c.ShouldBindJSON(&vendaReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	verificacao, err := a.banco.VerificarInteracoes(vendaReq.Itens)
+	if err != nil {
+		log.Printf("Erro ao verificar interações da venda: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar interações: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, verificacao)
+}
+
+// responderErroInteracoes responde à venda recusada pelas interações, com os alertas para o PDV.
+// Retorna false quando o erro não é de interações.
+func responderErroInteracoes(c *gin.Context, err error) bool {
+	var erroInteracoes *models.ErroInteracoes
+	if !errors.As(err, &erroInteracoes) {
+		return false
+	}
+	status := http.StatusConflict
+	if errors.Is(err, models.ErrLiberacaoFarmaceutico) {
+		status = http.StatusForbidden
+	}
+	c.JSON(status, gin.H{"error": err.Error(), "interacoes": erroInteracoes.Verificacao})
+	return true
+}
+
+// ListarInteracoesHandler lista a tabela de interações; o parâmetro substancia filtra pelo nome.
+func (a *Aplicacao) ListarInteracoesHandler(c *gin.Context) {
+	interacoes, err := a.banco.ListarInteracoes(c.Query("substancia"))
+	if err != nil {
+		log.Printf("Erro ao listar interações: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar sua solicitação"})
+		return
+	}
+	c.JSON(http.StatusOK, interacoes)
+}
+
+// ImportarInteracoesHandler carrega a tabela de interações em CSV ou JSON, enviada no corpo ou no
+// campo "arquivo" de um formulário multipart. Com substituir=true, as interações que não estão no
+// arquivo são apagadas; sem ele, o arquivo inclui e atualiza pares.
+func (a *Aplicacao) ImportarInteracoesHandler(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, tamanhoMaximoTabelaInteracoes)
+
+	var leitor io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		arquivo, _, err := c.Request.FormFile("arquivo")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Envie a tabela de interações no campo 'arquivo'"})
+			return
+		}
+		defer arquivo.Close()
+		leitor = arquivo
+	}
+
+	data, err := io.ReadAll(leitor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler a tabela de interações: " + err.Error()})
+		return
+	}
+
+	resumo, err := a.banco.ImportarInteracoes(data, c.Query("substituir") == "true", usuarioAtualID(c))
+	if errors.Is(err, models.ErrInteracoesInvalidas) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao importar tabela de interações: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao importar a tabela de interações"})
+		return
+	}
+	c.JSON(http.StatusCreated, resumo)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"medicontrol/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInteracoesNaVenda(t *testing.T) {
+	t.Parallel()
+	app := novaAplicacaoTeste(t, anvisaTeste{})
+	r := roteadorTeste()
+	r.POST("/medicamentos", app.CriarMedicamento)
+	r.POST("/interacoes/importacao", app.ImportarInteracoesHandler)
+	r.GET("/interacoes", app.ListarInteracoesHandler)
+	r.POST("/vendas", app.CriarVendaHandler)
+	r.POST("/vendas/interacoes", app.VerificarInteracoesVendaHandler)
+
+	// A tabela vai em CSV, como exportada da planilha
+	req := httptest.NewRequest(http.MethodPost, "/interacoes/importacao?substituir=true",
+		bytes.NewBufferString("substancia_a;substancia_b;gravidade;descricao\nVarfarina;Ácido acetilsalicílico;Grave;Risco de sangramento\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+	w = requisicaoJSON(t, r, http.MethodPost, "/interacoes/importacao", []map[string]string{{"substancia_a": "varfarina"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = requisicaoJSON(t, r, http.MethodGet, "/interacoes?substancia=Varfarina", nil)
+	assert.Contains(t, w.Body.String(), `"gravidade":"grave"`)
+
+	// ItemVendaRequest identifica o medicamento por número
+	ids := []int{101, 102}
+	for _, med := range []models.Medicamento{
+		{ID: "101", Nome: "Marevan 5mg", PrincipiosAtivos: []models.PrincipioAtivo{{Nome: "Varfarina sódica", Concentracao: "5 mg"}}},
+		{ID: "102", Nome: "AAS 100mg", PrincipiosAtivos: []models.PrincipioAtivo{{Nome: "Ácido acetilsalicílico", Concentracao: "100 mg"}}},
+	} {
+		med.Fabricante, med.Quantidade, med.Preco = "Teste", 10, 5
+		w := requisicaoJSON(t, r, http.MethodPost, "/medicamentos", med)
+		if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+			return
+		}
+	}
+	if _, err := app.banco.AbrirCaixa(1, 0); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+
+	venda := models.RegistrarVendaRequest{
+		Itens:      []models.ItemVendaRequest{{MedicamentoID: ids[0], Quantidade: 1}, {MedicamentoID: ids[1], Quantidade: 1}},
+		Pagamentos: []models.PagamentoVenda{{Forma: "dinheiro", Valor: 10}},
+	}
+	var verificacao models.VerificacaoInteracoes
+	w = requisicaoJSON(t, r, http.MethodPost, "/vendas/interacoes", venda)
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) && assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &verificacao)) {
+		assert.True(t, verificacao.ExigeFarmaceutico)
+		assert.Len(t, verificacao.Alertas, 1)
+	}
+
+	// A venda recusada traz os alertas para o PDV
+	w = requisicaoJSON(t, r, http.MethodPost, "/vendas", venda)
+	if assert.Equal(t, http.StatusConflict, w.Code, w.Body.String()) {
+		assert.Contains(t, w.Body.String(), `"exige_farmaceutico":true`)
+	}
+	venda.Interacoes = &models.ConfirmacaoInteracoes{Ciente: true}
+	w = requisicaoJSON(t, r, http.MethodPost, "/vendas", venda)
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	venda.Interacoes.Justificativa = "Dose baixa de AAS prescrita pelo cardiologista"
+	w = requisicaoJSON(t, r, http.MethodPost, "/vendas", venda)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AbrirInventario inicia uma contagem física da loja inteira ou, com categoria_id, de uma categoria.
+func (a *Aplicacao) AbrirInventario(c *gin.Context) {
+	var req struct {
+		CategoriaID string `json:"categoria_id"`
+		Observacao  string `json:"observacao"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+			return
+		}
+	}
+
+	inventario, err := a.banco.AbrirInventario(req.CategoriaID, req.Observacao, usuarioAtualID(c))
+	if err != nil {
+		responderErroInventario(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, inventario)
+}
+
+// ListarInventarios lista os inventários, opcionalmente filtrados por status.
+func (a *Aplicacao) ListarInventarios(c *gin.Context) {
+	inventarios, err := a.banco.ListarInventarios(c.Query("status"))
+	if err != nil {
+		responderErroInventario(c, err)
+		return
+	}
+	if inventarios == nil {
+		inventarios = []models.Inventario{}
+	}
+	c.JSON(http.StatusOK, inventarios)
+}
+
+// ObterInventario retorna as diferenças de um inventário aberto ou os ajustes de um aprovado.
+func (a *Aplicacao) ObterInventario(c *gin.Context) {
+	id, ok := inventarioID(c)
+	if !ok {
+		return
+	}
+
+	inventario, err := a.banco.GetInventario(id)
+	if err != nil {
+		responderErroInventario(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, inventario)
+}
+
+// RegistrarContagensInventario grava as quantidades contadas pelo usuário logado.
+func (a *Aplicacao) RegistrarContagensInventario(c *gin.Context) {
+	id, ok := inventarioID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Itens []models.ContagemInventarioRequest `json:"itens" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	contagens, err := a.banco.RegistrarContagensInventario(id, req.Itens, usuarioAtualID(c))
+	if err != nil {
+		responderErroInventario(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, contagens)
+}
+
+// AprovarInventario lança as diferenças como movimentações de ajuste e encerra o inventário.
+func (a *Aplicacao) AprovarInventario(c *gin.Context) {
+	id, ok := inventarioID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Motivo string `json:"motivo" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe o motivo do ajuste"})
+		return
+	}
+
+	inventario, err := a.banco.AprovarInventario(id, req.Motivo, usuarioAtualID(c))
+	if err != nil {
+		responderErroInventario(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, inventario)
+}
+
+// CancelarInventario encerra um inventário sem ajustar o estoque.
+func (a *Aplicacao) CancelarInventario(c *gin.Context) {
+	id, ok := inventarioID(c)
+	if !ok {
+		return
+	}
+
+	inventario, err := a.banco.CancelarInventario(id, usuarioAtualID(c))
+	if err != nil {
+		responderErroInventario(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, inventario)
+}
+
+func inventarioID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de inventário inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// responderErroInventario traduz os erros do inventário em status HTTP.
+func responderErroInventario(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInventarioNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInventarioJaAberto), errors.Is(err, models.ErrStatusInventario):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInventarioInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro no inventário: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no inventário: " + err.Error()})
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"log"
+	"medicontrol/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListarLotesMedicamento retorna os lotes de um medicamento, do vencimento mais próximo ao mais distante.
+func (a *Aplicacao) ListarLotesMedicamento(c *gin.Context) {
+	id := c.Param("id")
+	if a.banco.GetMedicamento(id) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Medicamento não encontrado"})
+		return
+	}
+
+	lotes, err := a.banco.GetLotesPorMedicamento(id)
+	if err != nil {
+		log.Printf("Erro ao buscar lotes do medicamento %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar lotes do medicamento"})
+		return
+	}
+
+	if lotes == nil {
+		lotes = []models.Lote{}
+	}
+
+	c.JSON(http.StatusOK, lotes)
+}
@@ -1,56 +1,137 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"medicontrol/models"
-	"medicontrol/services"
 
 	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ListarMedicamentos retorna a lista de todos os medicamentos
-func ListarMedicamentos(c *gin.Context) {
-	log.Println("Recebida requisição para listar medicamentos")
+// paginaMedicamentosResposta acrescenta à página os links da página anterior e da próxima
+type paginaMedicamentosResposta struct {
+	*models.PaginaMedicamentos
+	Anterior string `json:"anterior,omitempty"`
+	Proxima  string `json:"proxima,omitempty"`
+}
 
+// ListarMedicamentos lista os medicamentos com busca, filtros, ordenação e paginação.
+// Parâmetros: search, categoria_id, fabricante, tipo, estoque_min, estoque_max,
+// validade_de e validade_ate (YYYY-MM-DD, inclusivos), ordenar (relevancia, nome, preco,
+// quantidade ou validade; padrão relevancia com search e nome sem), direcao (asc ou desc),
+// pagina (padrão 1) e por_pagina (padrão 20, máximo 100).
+func (a *Aplicacao) ListarMedicamentos(c *gin.Context) {
 	// Adicionar headers CORS específicos
 	c.Header("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 	c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
-	searchQuery := c.Query("search")
-	var meds []models.Medicamento
-	var err error
+	filtro := models.FiltroMedicamentos{
+		Termo:       c.Query("search"),
+		CategoriaID: c.Query("categoria_id"),
+		Fabricante:  c.Query("fabricante"),
+		Tipo:        c.Query("tipo"),
+		Ordenacao:   c.Query("ordenar"),
+	}
 
-	// A lógica foi unificada dentro de models.BuscarMedicamentos
-	// Se a searchQuery for vazia, ela retornará todos os medicamentos.
-	log.Printf("Buscando medicamentos com o termo: '%s'", searchQuery)
-	meds, err = models.BuscarMedicamentos(searchQuery)
+	if minStr := c.Query("estoque_min"); minStr != "" {
+		estoqueMin, err := strconv.Atoi(minStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'estoque_min' inválido"})
+			return
+		}
+		filtro.QuantidadeMin = &estoqueMin
+	}
+	if maxStr := c.Query("estoque_max"); maxStr != "" {
+		estoqueMax, err := strconv.Atoi(maxStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'estoque_max' inválido"})
+			return
+		}
+		filtro.QuantidadeMax = &estoqueMax
+	}
+
+	if deStr := c.Query("validade_de"); deStr != "" {
+		de, err := time.ParseInLocation("2006-01-02", deStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'validade_de' inválido, use YYYY-MM-DD"})
+			return
+		}
+		filtro.ValidadeDe = de
+	}
+	if ateStr := c.Query("validade_ate"); ateStr != "" {
+		ate, err := time.ParseInLocation("2006-01-02", ateStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'validade_ate' inválido, use YYYY-MM-DD"})
+			return
+		}
+		// Inclui o dia informado
+		filtro.ValidadeAte = ate.AddDate(0, 0, 1)
+	}
+
+	switch c.DefaultQuery("direcao", "asc") {
+	case "asc":
+	case "desc":
+		filtro.Decrescente = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'direcao' inválido, use asc ou desc"})
+		return
+	}
+
+	pagina, err := strconv.Atoi(c.DefaultQuery("pagina", "1"))
+	if err != nil || pagina < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'pagina' inválido"})
+		return
+	}
+	porPagina, err := strconv.Atoi(c.DefaultQuery("por_pagina", "20"))
+	if err != nil || porPagina < 1 || porPagina > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'por_pagina' inválido (1 a 100)"})
+		return
+	}
+	filtro.Pagina = pagina
+	filtro.PorPagina = porPagina
+
+	resultado, err := a.banco.BuscarMedicamentos(filtro)
+	if errors.Is(err, models.ErrOrdenacaoInvalida) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		log.Printf("Erro ao buscar medicamentos do banco de dados: %v", err)
+		log.Printf("Erro ao buscar medicamentos com o termo '%s': %v", filtro.Termo, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar sua solicitação"})
 		return
 	}
 
-	log.Printf("Medicamentos recuperados: %d", len(meds))
+	if resultado.Medicamentos == nil {
+		resultado.Medicamentos = []models.Medicamento{}
+	}
 
-	// Garantir que a resposta seja um array válido mesmo se meds for nil
-	if meds == nil {
-		log.Println("Lista de medicamentos é nil (após tentativa de recuperação), retornando array vazio")
-		meds = []models.Medicamento{}
+	resposta := paginaMedicamentosResposta{PaginaMedicamentos: resultado}
+	if pagina > 1 {
+		resposta.Anterior = linkPagina(c, pagina-1)
+	}
+	if resultado.TemProxima() {
+		resposta.Proxima = linkPagina(c, pagina+1)
 	}
+	c.JSON(http.StatusOK, resposta)
+}
 
-	c.JSON(http.StatusOK, meds)
+// linkPagina repete a URL da requisição, com os mesmos filtros, apontando para outra página
+func linkPagina(c *gin.Context, pagina int) string {
+	params := c.Request.URL.Query()
+	params.Set("pagina", strconv.Itoa(pagina))
+	return c.Request.URL.Path + "?" + params.Encode()
 }
 
 // ObterMedicamento retorna um medicamento específico
-func ObterMedicamento(c *gin.Context) {
+func (a *Aplicacao) ObterMedicamento(c *gin.Context) {
 	id := c.Param("id")
-	med := models.GetMedicamento(id)
+	med := a.banco.GetMedicamento(id)
 	if med == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Medicamento não encontrado"})
 		return
@@ -58,12 +139,30 @@ func ObterMedicamento(c *gin.Context) {
 	c.JSON(http.StatusOK, med)
 }
 
+// BuscarPorCodigoBarras encontra o medicamento pela leitura do leitor de código de barras: um
+// EAN-13 (ou outro GTIN) ou um GS1 DataMatrix, cujo lote e validade voltam junto para preencher
+// a venda ou a entrada. O DataMatrix deve vir codificado na URL, com o separador GS como %1D.
+func (a *Aplicacao) BuscarPorCodigoBarras(c *gin.Context) {
+	resultado, err := a.banco.BuscarPorCodigoBarras(c.Param("gtin"))
+	switch {
+	case errors.Is(err, models.ErrCodigoBarrasInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrMedicamentoNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Nenhum medicamento cadastrado com este código de barras"})
+	case err != nil:
+		log.Printf("Erro ao buscar medicamento pelo código de barras: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar sua solicitação"})
+	default:
+		c.JSON(http.StatusOK, resultado)
+	}
+}
+
 // BuscarDadosAnvisa busca os dados do medicamento na ANVISA
-func BuscarDadosAnvisa(c *gin.Context) {
+func (a *Aplicacao) BuscarDadosAnvisa(c *gin.Context) {
 	codigo := c.Param("codigo")
 
 	// Verificar se o medicamento já existe no sistema
-	med := models.GetMedicamentoByCodigo(codigo)
+	med := a.banco.GetMedicamentoByCodigo(codigo)
 	if med != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"nome":       med.Nome,
@@ -74,7 +173,7 @@ func BuscarDadosAnvisa(c *gin.Context) {
 	}
 
 	// Se não existir, buscar na API da ANVISA
-	dados, err := services.BuscarDadosAnvisa(codigo)
+	dados, err := a.anvisa.BuscarDadosAnvisa(codigo)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -83,12 +182,13 @@ func BuscarDadosAnvisa(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"nome":       dados.Nome,
 		"fabricante": dados.Fabricante,
+		"substancia": dados.Substancia,
 		"exists":     false,
 	})
 }
 
 // CriarMedicamento adiciona um novo medicamento
-func CriarMedicamento(c *gin.Context) {
+func (a *Aplicacao) CriarMedicamento(c *gin.Context) {
 	var med models.Medicamento
 	if err := c.ShouldBindJSON(&med); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -97,16 +197,19 @@ func CriarMedicamento(c *gin.Context) {
 
 	// Buscar dados da ANVISA se não existirem
 	if med.Nome == "" || med.Fabricante == "" {
-		dados, err := services.BuscarDadosAnvisa(med.CodigoANVISA)
+		dados, err := a.anvisa.BuscarDadosAnvisa(med.CodigoANVISA)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao buscar dados na ANVISA: " + err.Error()})
 			return
 		}
 		med.Nome = dados.Nome
 		med.Fabricante = dados.Fabricante
+		if len(med.PrincipiosAtivos) == 0 {
+			med.PrincipiosAtivos = models.PrincipiosDaSubstancia(dados.Substancia)
+		}
 	}
 
-	if err := models.AddMedicamento(&med); err != nil {
+	if err := a.banco.AddMedicamento(&med, usuarioAtualID(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -115,7 +218,7 @@ func CriarMedicamento(c *gin.Context) {
 }
 
 // AtualizarMedicamento atualiza um medicamento existente
-func AtualizarMedicamento(c *gin.Context) {
+func (a *Aplicacao) AtualizarMedicamento(c *gin.Context) {
 	id := c.Param("id")
 	var med models.Medicamento
 	if err := c.ShouldBindJSON(&med); err != nil {
@@ -124,7 +227,7 @@ func AtualizarMedicamento(c *gin.Context) {
 	}
 
 	med.ID = id
-	if err := models.UpdateMedicamento(&med); err != nil {
+	if err := a.banco.UpdateMedicamento(&med, usuarioAtualID(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -133,9 +236,9 @@ func AtualizarMedicamento(c *gin.Context) {
 }
 
 // DeletarMedicamento remove um medicamento
-func DeletarMedicamento(c *gin.Context) {
+func (a *Aplicacao) DeletarMedicamento(c *gin.Context) {
 	id := c.Param("id")
-	if err := models.DeleteMedicamento(id); err != nil {
+	if err := a.banco.DeleteMedicamento(id, usuarioAtualID(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -144,15 +247,16 @@ func DeletarMedicamento(c *gin.Context) {
 }
 
 // RegistrarMovimentacao registra uma entrada ou saída de medicamento
-func RegistrarMovimentacao(c *gin.Context) {
+func (a *Aplicacao) RegistrarMovimentacao(c *gin.Context) {
 	var mov models.Movimentacao
 	if err := c.ShouldBindJSON(&mov); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	mov.Data = time.Now()
-	if err := models.RegistrarMovimentacao(mov); err != nil {
+	mov.Data = a.banco.Agora()
+	mov.UsuarioID = usuarioAtualID(c)
+	if err := a.banco.RegistrarMovimentacao(mov); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -161,8 +265,8 @@ func RegistrarMovimentacao(c *gin.Context) {
 }
 
 // ListarMovimentacoes retorna o histórico de movimentações
-func ListarMovimentacoes(c *gin.Context) {
-	movs, err := models.GetMovimentacoes()
+func (a *Aplicacao) ListarMovimentacoes(c *gin.Context) {
+	movs, err := a.banco.GetMovimentacoes()
 	if err != nil {
 		log.Printf("Erro ao buscar movimentações: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar histórico de movimentações"})
@@ -179,7 +283,8 @@ func ListarMovimentacoes(c *gin.Context) {
 }
 
 // ObterRelatorioBaixoEstoque retorna uma lista de medicamentos com baixo estoque.
-func ObterRelatorioBaixoEstoque(c *gin.Context) {
+// Medicamentos com estoque mínimo cadastrado usam o próprio mínimo; os demais usam o limite geral.
+func (a *Aplicacao) ObterRelatorioBaixoEstoque(c *gin.Context) {
 	// Definir um limite padrão, mas permitir que seja sobrescrito por um query param
 	limiteStr := c.DefaultQuery("limite", "50")
 	limite, err := strconv.Atoi(limiteStr)
@@ -188,7 +293,7 @@ func ObterRelatorioBaixoEstoque(c *gin.Context) {
 		return
 	}
 
-	medicamentos, err := models.GetMedicamentosBaixoEstoque(limite)
+	medicamentos, err := a.banco.GetMedicamentosBaixoEstoque(limite)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de baixo estoque"})
 		return
@@ -203,11 +308,30 @@ func ObterRelatorioBaixoEstoque(c *gin.Context) {
 }
 
 // ObterTotalVendas retorna o total de medicamentos vendidos (unidades).
-func ObterTotalVendas(c *gin.Context) {
-	total, err := models.GetTotalVendas()
+func (a *Aplicacao) ObterTotalVendas(c *gin.Context) {
+	total, err := a.banco.GetTotalVendas()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular total de vendas"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"total_vendas": total})
 }
+
+// ObterRelatorioVencimento retorna os lotes vencidos e os que vencem nos próximos dias.
+func (a *Aplicacao) ObterRelatorioVencimento(c *gin.Context) {
+	diasStr := c.DefaultQuery("dias", "30")
+	dias, err := strconv.Atoi(diasStr)
+	if err != nil || dias < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'dias' inválido"})
+		return
+	}
+
+	relatorio, err := a.banco.GetRelatorioVencimento(dias, a.banco.Agora())
+	if err != nil {
+		log.Printf("Erro ao gerar relatório de vencimento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de vencimento"})
+		return
+	}
+
+	c.JSON(http.StatusOK, relatorio)
+}
@@ -3,72 +3,367 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"medicontrol/models"
+	"medicontrol/services"
+	"medicontrol/sqlutils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCreateMedicamento(t *testing.T) {
-	// Configurar o router de teste
-	r := gin.Default()
-	r.POST("/medicamentos", CreateMedicamento)
+// anvisaTeste responde às consultas à ANVISA sem acessar a rede
+type anvisaTeste map[string]services.DadosAnvisa
 
-	// Criar um medicamento de teste
-	medicamento := Medicamento{
-		Nome:       "Paracetamol",
-		Descricao:  "Analgésico e antitérmico",
-		Fabricante: "EMS",
-		Dosagem:    "500mg",
-		Forma:      "Comprimido",
-		Validade:   "2025-12-31",
-		Quantidade: 100,
+func (a anvisaTeste) BuscarDadosAnvisa(codigo string) (*services.DadosAnvisa, error) {
+	dados, ok := a[codigo]
+	if !ok {
+		return nil, errors.New("medicamento não encontrado na API da ANVISA")
 	}
+	return &dados, nil
+}
 
-	// Converter para JSON
-	medJSON, _ := json.Marshal(medicamento)
+// agoraTeste é o relógio fixo dos bancos de teste
+var agoraTeste = time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)
 
-	// Criar requisição de teste
-	req, _ := http.NewRequest("POST", "/medicamentos", bytes.NewBuffer(medJSON))
-	req.Header.Set("Content-Type", "application/json")
+// novaAplicacaoTeste cria a aplicação sobre um SQLite em memória próprio do teste, já migrado.
+func novaAplicacaoTeste(t *testing.T, anvisa ConsultaAnvisa) *Aplicacao {
+	t.Helper()
+	queries, err := sqlutils.LoadEmbeddedSQL()
+	if err != nil {
+		t.Fatalf("erro ao carregar arquivos SQL: %v", err)
+	}
+	banco, err := models.InitDB(models.ConfigBanco{
+		Driver:  models.DriverSQLite,
+		Caminho: ":memory:",
+		Relogio: func() time.Time { return agoraTeste },
+	}, queries)
+	if err != nil {
+		t.Fatalf("erro ao abrir banco de teste: %v", err)
+	}
+	t.Cleanup(func() { banco.Fechar() })
+	return NovaAplicacao(banco, anvisa, nil)
+}
 
-	// Criar response recorder
-	w := httptest.NewRecorder()
+// roteadorTeste autentica todas as requisições como o administrador inicial.
+func roteadorTeste() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(ContextUserID, 1)
+		c.Set(ContextRole, models.RoleAdmin)
+	})
+	return r
+}
 
-	// Executar a requisição
+func requisicaoJSON(t *testing.T, r http.Handler, metodo, caminho string, corpo interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	if corpo != nil {
+		if err := json.NewEncoder(&body).Encode(corpo); err != nil {
+			t.Fatalf("erro ao codificar o corpo: %v", err)
+		}
+	}
+	req := httptest.NewRequest(metodo, caminho, &body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCriarMedicamento(t *testing.T) {
+	t.Parallel()
+	app := novaAplicacaoTeste(t, anvisaTeste{})
+	r := roteadorTeste()
+	r.POST("/medicamentos", app.CriarMedicamento)
+	r.GET("/medicamentos/:id", app.ObterMedicamento)
+
+	w := requisicaoJSON(t, r, http.MethodPost, "/medicamentos", models.Medicamento{
+		Nome:         "Paracetamol 500mg",
+		Fabricante:   "EMS",
+		Tipo:         "comprimido",
+		CodigoANVISA: "1000000000001",
+		Quantidade:   100,
+		Validade:     "2030-12-31",
+		Preco:        8.9,
+	})
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+	var criado models.Medicamento
+	if !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &criado)) {
+		return
+	}
+	assert.NotEmpty(t, criado.ID)
+	assert.True(t, agoraTeste.Equal(criado.CriadoEm))
+
+	w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos/"+criado.ID, nil)
+	if !assert.Equal(t, http.StatusOK, w.Code) {
+		return
+	}
+	var obtido models.Medicamento
+	if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &obtido)) {
+		assert.Equal(t, "Paracetamol 500mg", obtido.Nome)
+		assert.Equal(t, 100, obtido.Quantidade)
+	}
+}
 
-	// Verificar o status da resposta
-	assert.Equal(t, http.StatusCreated, w.Code)
+func TestCriarMedicamentoComDadosDaAnvisa(t *testing.T) {
+	t.Parallel()
+	app := novaAplicacaoTeste(t, anvisaTeste{
+		"1000000000002": {Nome: "Dipirona Sódica 500mg", Fabricante: "Medley", Registro: "1000000000002", Substancia: "DIPIRONA MONOIDRATADA"},
+	})
+	r := roteadorTeste()
+	r.POST("/medicamentos", app.CriarMedicamento)
 
-	// Verificar se o medicamento foi criado
-	var createdMed Medicamento
-	json.Unmarshal(w.Body.Bytes(), &createdMed)
-	assert.Equal(t, medicamento.Nome, createdMed.Nome)
-	assert.Equal(t, medicamento.Descricao, createdMed.Descricao)
-	assert.Equal(t, medicamento.Fabricante, createdMed.Fabricante)
+	w := requisicaoJSON(t, r, http.MethodPost, "/medicamentos", models.Medicamento{
+		CodigoANVISA: "1000000000002",
+		Quantidade:   10,
+		Validade:     "2030-06-30",
+		Preco:        5.5,
+	})
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+	var criado models.Medicamento
+	if assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &criado)) {
+		assert.Equal(t, "Dipirona Sódica 500mg", criado.Nome)
+		assert.Equal(t, "Medley", criado.Fabricante)
+		if assert.Len(t, criado.PrincipiosAtivos, 1) {
+			assert.Equal(t, "DIPIRONA MONOIDRATADA", criado.PrincipiosAtivos[0].Nome)
+		}
+	}
+
+	// Registro desconhecido na ANVISA
+	w = requisicaoJSON(t, r, http.MethodPost, "/medicamentos", models.Medicamento{CodigoANVISA: "9999999999999"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
-func TestGetMedicamentos(t *testing.T) {
-	// Configurar o router de teste
-	r := gin.Default()
-	r.GET("/medicamentos", GetMedicamentos)
+func TestAtualizarMedicamentoMantemListaControle(t *testing.T) {
+	t.Parallel()
+	app := novaAplicacaoTeste(t, anvisaTeste{})
+	r := roteadorTeste()
+	r.POST("/medicamentos", app.CriarMedicamento)
+	r.PUT("/medicamentos/:id", app.AtualizarMedicamento)
+	r.GET("/medicamentos/:id", app.ObterMedicamento)
 
-	// Criar response recorder
-	w := httptest.NewRecorder()
+	w := requisicaoJSON(t, r, http.MethodPost, "/medicamentos", gin.H{
+		"nome": "Clonazepam 2mg", "fabricante": "Roche", "quantidade": 10, "validade": "2030-06-30", "preco": 20, "lista_controle": "b1",
+	})
+	var criado models.Medicamento
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) || !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &criado)) {
+		return
+	}
+	obter := func() models.Medicamento {
+		var med models.Medicamento
+		w := requisicaoJSON(t, r, http.MethodGet, "/medicamentos/"+criado.ID, nil)
+		if assert.Equal(t, http.StatusOK, w.Code) {
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &med))
+		}
+		return med
+	}
 
-	// Criar requisição de teste
-	req, _ := http.NewRequest("GET", "/medicamentos", nil)
+	// Uma edição sem o campo, como a dos clientes antigos, não tira o medicamento do controle
+	w = requisicaoJSON(t, r, http.MethodPut, "/medicamentos/"+criado.ID, gin.H{
+		"nome": "Clonazepam 2mg", "fabricante": "Roche", "quantidade": 10, "validade": "2030-06-30", "preco": 22,
+	})
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) {
+		med := obter()
+		assert.True(t, med.Controlado)
+		if assert.NotNil(t, med.ListaControle) {
+			assert.Equal(t, "B1", *med.ListaControle)
+		}
+		assert.Equal(t, 22.0, med.Preco)
+	}
 
-	// Executar a requisição
-	r.ServeHTTP(w, req)
+	// Vazio remove a classificação
+	w = requisicaoJSON(t, r, http.MethodPut, "/medicamentos/"+criado.ID, gin.H{
+		"nome": "Clonazepam 2mg", "fabricante": "Roche", "quantidade": 10, "validade": "2030-06-30", "preco": 22, "lista_controle": "",
+	})
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) {
+		assert.False(t, obter().Controlado)
+	}
+	w = requisicaoJSON(t, r, http.MethodPut, "/medicamentos/"+criado.ID, gin.H{"nome": "Clonazepam 2mg", "lista_controle": "Z9"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListarMedicamentosIsoladoPorAplicacao(t *testing.T) {
+	t.Parallel()
+	comEstoque := novaAplicacaoTeste(t, anvisaTeste{})
+	vazia := novaAplicacaoTeste(t, anvisaTeste{})
+
+	r := roteadorTeste()
+	r.POST("/a/medicamentos", comEstoque.CriarMedicamento)
+	r.GET("/a/medicamentos", comEstoque.ListarMedicamentos)
+	r.GET("/b/medicamentos", vazia.ListarMedicamentos)
+
+	w := requisicaoJSON(t, r, http.MethodPost, "/a/medicamentos", models.Medicamento{
+		Nome: "Loratadina 10mg", Fabricante: "EMS", Quantidade: 12, Validade: "2030-01-31", Preco: 14,
+	})
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
 
-	// Verificar o status da resposta
-	assert.Equal(t, http.StatusOK, w.Code)
+	var pagina models.PaginaMedicamentos
+	w = requisicaoJSON(t, r, http.MethodGet, "/a/medicamentos", nil)
+	if assert.Equal(t, http.StatusOK, w.Code) && assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pagina)) {
+		assert.Len(t, pagina.Medicamentos, 1)
+	}
+
+	// A outra aplicação tem o seu próprio banco
+	pagina = models.PaginaMedicamentos{}
+	w = requisicaoJSON(t, r, http.MethodGet, "/b/medicamentos", nil)
+	if assert.Equal(t, http.StatusOK, w.Code) && assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &pagina)) {
+		assert.Empty(t, pagina.Medicamentos)
+		assert.Equal(t, 0, pagina.Total)
+	}
+}
+
+func TestListarMedicamentosPaginado(t *testing.T) {
+	t.Parallel()
+	app := novaAplicacaoTeste(t, anvisaTeste{})
+	r := roteadorTeste()
+	r.POST("/medicamentos", app.CriarMedicamento)
+	r.GET("/medicamentos", app.ListarMedicamentos)
+
+	for i, preco := range []float64{12, 4.5, 30, 8} {
+		w := requisicaoJSON(t, r, http.MethodPost, "/medicamentos", models.Medicamento{
+			Nome: "Medicamento " + string(rune('A'+i)), Fabricante: "EMS", Quantidade: 10 * (i + 1), Validade: "2030-01-31", Preco: preco,
+		})
+		if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+			return
+		}
+	}
+
+	var resposta struct {
+		models.PaginaMedicamentos
+		Anterior string `json:"anterior"`
+		Proxima  string `json:"proxima"`
+	}
+	w := requisicaoJSON(t, r, http.MethodGet, "/medicamentos?ordenar=preco&direcao=desc&por_pagina=3", nil)
+	if !assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) || !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resposta)) {
+		return
+	}
+	assert.Equal(t, 4, resposta.Total)
+	if assert.Len(t, resposta.Medicamentos, 3) {
+		assert.Equal(t, 30.0, resposta.Medicamentos[0].Preco)
+	}
+	assert.Empty(t, resposta.Anterior)
+	assert.Equal(t, "/medicamentos?direcao=desc&ordenar=preco&pagina=2&por_pagina=3", resposta.Proxima)
+
+	// A próxima página mantém os filtros e a ordenação
+	proxima := resposta.Proxima
+	resposta.PaginaMedicamentos, resposta.Anterior, resposta.Proxima = models.PaginaMedicamentos{}, "", ""
+	w = requisicaoJSON(t, r, http.MethodGet, proxima, nil)
+	if assert.Equal(t, http.StatusOK, w.Code) && assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resposta)) {
+		if assert.Len(t, resposta.Medicamentos, 1) {
+			assert.Equal(t, 4.5, resposta.Medicamentos[0].Preco)
+		}
+		assert.Equal(t, "/medicamentos?direcao=desc&ordenar=preco&pagina=1&por_pagina=3", resposta.Anterior)
+		assert.Empty(t, resposta.Proxima)
+	}
+
+	for _, consulta := range []string{"ordenar=fabricante", "direcao=cima", "estoque_min=x", "validade_ate=31/01/2030", "por_pagina=500"} {
+		w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos?"+consulta, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, consulta)
+	}
+}
+
+func TestBuscarPorCodigoBarras(t *testing.T) {
+	t.Parallel()
+	app := novaAplicacaoTeste(t, anvisaTeste{})
+	r := roteadorTeste()
+	r.POST("/medicamentos", app.CriarMedicamento)
+	r.GET("/medicamentos/barcode/:gtin", app.BuscarPorCodigoBarras)
+	r.GET("/medicamentos/:id", app.ObterMedicamento)
+
+	w := requisicaoJSON(t, r, http.MethodPost, "/medicamentos", models.Medicamento{
+		Nome: "Dipirona 500mg", Fabricante: "EMS", Quantidade: 10, Validade: "2030-01-31", Preco: 6.4,
+		CodigosBarras: []string{"7891234567895"},
+	})
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) {
+		return
+	}
+
+	// DataMatrix com o separador GS codificado na URL
+	var resultado models.ResultadoCodigoBarras
+	w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos/barcode/01078912345678951727013110L2401%1D21SN1", nil)
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) && assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resultado)) {
+		assert.Equal(t, "Dipirona 500mg", resultado.Medicamento.Nome)
+		assert.Equal(t, "L2401", resultado.Lote)
+		assert.Equal(t, "2027-01-31", resultado.Validade)
+	}
+
+	w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos/barcode/7891234567894", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos/barcode/7891000000014", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestListarAlternativasMedicamento(t *testing.T) {
+	t.Parallel()
+	app := novaAplicacaoTeste(t, anvisaTeste{})
+	r := roteadorTeste()
+	r.POST("/medicamentos", app.CriarMedicamento)
+	r.GET("/medicamentos/:id/alternativas", app.ListarAlternativasMedicamento)
+	r.GET("/principios-ativos", app.ListarPrincipiosAtivos)
+	r.POST("/principios-ativos", app.CriarPrincipioAtivo)
+
+	w := requisicaoJSON(t, r, http.MethodPost, "/principios-ativos", models.PrincipioAtivo{Nome: "Sinvastatina", Concentracao: "20 mg", Forma: "comprimido"})
+	var sinvastatina models.PrincipioAtivo
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) || !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sinvastatina)) {
+		return
+	}
+	// O mesmo princípio com outra grafia não é duplicado
+	w = requisicaoJSON(t, r, http.MethodPost, "/principios-ativos", models.PrincipioAtivo{Nome: "SINVASTATINA", Concentracao: "20mg", Forma: "Comprimido"})
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) {
+		assert.Contains(t, w.Body.String(), sinvastatina.ID)
+	}
+	w = requisicaoJSON(t, r, http.MethodPost, "/principios-ativos", models.PrincipioAtivo{Concentracao: "20 mg"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = requisicaoJSON(t, r, http.MethodGet, "/principios-ativos?q=sinva", nil)
+	var principios []models.PrincipioAtivo
+	if assert.Equal(t, http.StatusOK, w.Code) && assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &principios)) {
+		assert.Equal(t, []models.PrincipioAtivo{sinvastatina}, principios)
+	}
+
+	ids := map[string]string{}
+	for _, med := range []models.Medicamento{
+		{Nome: "Zocor 20mg", Fabricante: "MSD", CategoriaRegulatoria: "referencia", Preco: 80},
+		{Nome: "Sinvastatina Medley", Fabricante: "Medley", CategoriaRegulatoria: "generico", Preco: 12},
+		{Nome: "Sinvastatina Sem Categoria", Fabricante: "Manipulação", Preco: 8},
+	} {
+		med.Quantidade = 10
+		med.PrincipiosAtivos = []models.PrincipioAtivo{{ID: sinvastatina.ID}}
+		w := requisicaoJSON(t, r, http.MethodPost, "/medicamentos", med)
+		var criado models.Medicamento
+		if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) || !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &criado)) {
+			return
+		}
+		ids[med.Nome] = criado.ID
+	}
+	w = requisicaoJSON(t, r, http.MethodPost, "/medicamentos", models.Medicamento{Nome: "Soro Fisiológico", Fabricante: "JP", Quantidade: 10})
+	var soro models.Medicamento
+	if !assert.Equal(t, http.StatusCreated, w.Code, w.Body.String()) || !assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &soro)) {
+		return
+	}
+
+	var resultado models.AlternativasMedicamento
+	w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos/"+ids["Zocor 20mg"]+"/alternativas", nil)
+	if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) && assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resultado)) {
+		assert.Equal(t, "Zocor 20mg", resultado.Medicamento.Nome)
+		if assert.Len(t, resultado.Alternativas, 1) {
+			assert.Equal(t, ids["Sinvastatina Medley"], resultado.Alternativas[0].ID)
+		}
+	}
 
-	// Verificar se a resposta é um JSON válido
-	var medicamentos []Medicamento
-	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &medicamentos))
+	w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos/"+soro.ID+"/alternativas", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = requisicaoJSON(t, r, http.MethodGet, "/medicamentos/999/alternativas", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
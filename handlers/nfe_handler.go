@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tamanhoMaximoXMLNFe limita o XML aceito na importação (NF-e reais têm poucas centenas de KB)
+const tamanhoMaximoXMLNFe = 5 << 20
+
+// ImportarNFe recebe o XML de uma NF-e de entrada, no corpo da requisição ou no campo "arquivo"
+// de um formulário multipart, e devolve os itens com o medicamento correspondente de cada um.
+func (a *Aplicacao) ImportarNFe(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, tamanhoMaximoXMLNFe)
+
+	var leitor io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		arquivo, _, err := c.Request.FormFile("arquivo")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Envie o XML da NF-e no campo 'arquivo'"})
+			return
+		}
+		defer arquivo.Close()
+		leitor = arquivo
+	}
+
+	data, err := io.ReadAll(leitor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler o XML da NF-e: " + err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "XML da NF-e não informado"})
+		return
+	}
+
+	importacao, err := a.banco.ImportarNFe(data, usuarioAtualID(c))
+	if err != nil {
+		responderErroNFe(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, importacao)
+}
+
+// ObterImportacaoNFe retorna uma importação com os itens e as correspondências atuais.
+func (a *Aplicacao) ObterImportacaoNFe(c *gin.Context) {
+	id, ok := importacaoNFeID(c)
+	if !ok {
+		return
+	}
+
+	importacao, err := a.banco.GetImportacaoNFe(id)
+	if err != nil {
+		responderErroNFe(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, importacao)
+}
+
+// ConfirmarImportacaoNFe dá entrada no estoque dos itens da nota.
+func (a *Aplicacao) ConfirmarImportacaoNFe(c *gin.Context) {
+	id, ok := importacaoNFeID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ConfirmacaoNFeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+			return
+		}
+	}
+
+	importacao, err := a.banco.ConfirmarImportacaoNFe(id, req, usuarioAtualID(c))
+	if err != nil {
+		responderErroNFe(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, importacao)
+}
+
+func importacaoNFeID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de importação inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// responderErroNFe traduz os erros da importação de NF-e em status HTTP.
+func responderErroNFe(c *gin.Context, err error) {
+	var pendentes *models.ErroItensPendentesNFe
+	switch {
+	case errors.As(err, &pendentes):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     "Relacione ou ignore os itens sem medicamento correspondente",
+			"pendentes": pendentes.Itens,
+		})
+	case errors.Is(err, models.ErrImportacaoNFeNaoEncontrada):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNFeJaImportada):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrNFeInvalida):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro na importação de NF-e: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na importação de NF-e: " + err.Error()})
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tamanhoMaximoTabelaCMED limita o arquivo aceito na importação (a tabela completa tem cerca de 15 MB)
+const tamanhoMaximoTabelaCMED = 40 << 20
+
+// AgendarPrecoRequest é o corpo do agendamento de um novo preço de venda
+type AgendarPrecoRequest struct {
+	MedicamentoID string  `json:"medicamento_id" binding:"required"`
+	Preco         float64 `json:"preco" binding:"required"`
+	Vigencia      string  `json:"vigencia" binding:"required"` // YYYY-MM-DD
+}
+
+// ListarHistoricoPrecos retorna as alterações de preço do medicamento, da mais recente para a mais antiga.
+func (a *Aplicacao) ListarHistoricoPrecos(c *gin.Context) {
+	historico, err := a.banco.GetHistoricoPrecos(c.Param("id"))
+	if err != nil {
+		responderErroPreco(c, err)
+		return
+	}
+	if historico == nil {
+		historico = []models.HistoricoPreco{}
+	}
+	c.JSON(http.StatusOK, historico)
+}
+
+// ListarAgendamentosPreco lista os agendamentos de preço. Filtros opcionais: medicamento_id e status.
+func (a *Aplicacao) ListarAgendamentosPreco(c *gin.Context) {
+	agendamentos, err := a.banco.ListarAgendamentosPreco(c.Query("medicamento_id"), c.Query("status"))
+	if err != nil {
+		responderErroPreco(c, err)
+		return
+	}
+	if agendamentos == nil {
+		agendamentos = []models.AgendamentoPreco{}
+	}
+	c.JSON(http.StatusOK, agendamentos)
+}
+
+// ObterAgendamentoPreco retorna um agendamento de preço.
+func (a *Aplicacao) ObterAgendamentoPreco(c *gin.Context) {
+	id, ok := agendamentoPrecoID(c)
+	if !ok {
+		return
+	}
+
+	agendamento, err := a.banco.GetAgendamentoPreco(id)
+	if err != nil {
+		responderErroPreco(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, agendamento)
+}
+
+// AgendarPreco programa um novo preço de venda para uma data futura.
+func (a *Aplicacao) AgendarPreco(c *gin.Context) {
+	var req AgendarPrecoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	agendamento, err := a.banco.AgendarPreco(req.MedicamentoID, req.Preco, req.Vigencia, usuarioAtualID(c))
+	if err != nil {
+		responderErroPreco(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, agendamento)
+}
+
+// CancelarAgendamentoPreco cancela um agendamento que ainda não entrou em vigor.
+func (a *Aplicacao) CancelarAgendamentoPreco(c *gin.Context) {
+	id, ok := agendamentoPrecoID(c)
+	if !ok {
+		return
+	}
+
+	agendamento, err := a.banco.CancelarAgendamentoPreco(id, usuarioAtualID(c))
+	if err != nil {
+		responderErroPreco(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, agendamento)
+}
+
+// ImportarTabelaCMED substitui a tabela de preços máximos pela planilha CMED em CSV, enviada no corpo
+// ou no campo "arquivo" de um formulário multipart. O parâmetro aliquota escolhe a coluna de PMC
+// (padrão 18, para "PMC 18%").
+func (a *Aplicacao) ImportarTabelaCMED(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, tamanhoMaximoTabelaCMED)
+
+	var leitor io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		arquivo, _, err := c.Request.FormFile("arquivo")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Envie a tabela CMED no campo 'arquivo'"})
+			return
+		}
+		defer arquivo.Close()
+		leitor = arquivo
+	}
+
+	data, err := io.ReadAll(leitor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Erro ao ler a tabela CMED: " + err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tabela CMED não informada"})
+		return
+	}
+
+	resumo, err := a.banco.ImportarTabelaCMED(data, c.DefaultQuery("aliquota", models.AliquotaCMEDPadrao), usuarioAtualID(c))
+	if err != nil {
+		responderErroPreco(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resumo)
+}
+
+// ObterRelatorioViolacoesPMC lista os medicamentos com preço de venda acima do PMC da tabela CMED.
+func (a *Aplicacao) ObterRelatorioViolacoesPMC(c *gin.Context) {
+	violacoes, err := a.banco.GetViolacoesPMC()
+	if err != nil {
+		log.Printf("Erro ao gerar relatório de violações de PMC: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de violações de PMC"})
+		return
+	}
+	c.JSON(http.StatusOK, violacoes)
+}
+
+func agendamentoPrecoID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de agendamento inválido"})
+		return 0, false
+	}
+	return id, true
+}
+
+// responderErroPreco traduz os erros de preços e da tabela CMED em status HTTP.
+func responderErroPreco(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrMedicamentoNaoEncontrado), errors.Is(err, models.ErrAgendamentoPrecoNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrStatusAgendamentoPreco):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrPrecoAcimaPMC), errors.Is(err, models.ErrAgendamentoPrecoInvalido),
+		errors.Is(err, models.ErrTabelaCMEDInvalida):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro na gestão de preços: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na gestão de preços: " + err.Error()})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"medicontrol/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListarPrincipiosAtivos retorna os princípios ativos cadastrados, filtrados pelo parâmetro "q"
+func (a *Aplicacao) ListarPrincipiosAtivos(c *gin.Context) {
+	principios, err := a.banco.ListarPrincipiosAtivos(c.Query("q"))
+	if err != nil {
+		log.Printf("Erro ao listar princípios ativos: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar sua solicitação"})
+		return
+	}
+	c.JSON(http.StatusOK, principios)
+}
+
+// CriarPrincipioAtivo cadastra um princípio ativo. Se ele já existe, com outra grafia, retorna o
+// cadastrado com status 200 em vez de 201.
+func (a *Aplicacao) CriarPrincipioAtivo(c *gin.Context) {
+	var p models.PrincipioAtivo
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.ID = ""
+
+	criado, err := a.banco.CriarPrincipioAtivo(&p, usuarioAtualID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if criado {
+		c.JSON(http.StatusCreated, p)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// ListarAlternativasMedicamento retorna os medicamentos intercambiáveis em estoque que podem
+// substituir o informado, do mais barato ao mais caro.
+func (a *Aplicacao) ListarAlternativasMedicamento(c *gin.Context) {
+	alternativas, err := a.banco.BuscarAlternativas(c.Param("id"))
+	switch {
+	case errors.Is(err, models.ErrMedicamentoNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Medicamento não encontrado"})
+	case errors.Is(err, models.ErrSemPrincipioAtivo):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "O medicamento não tem princípio ativo cadastrado; cadastre a composição para buscar alternativas"})
+	case err != nil:
+		log.Printf("Erro ao buscar alternativas do medicamento: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar sua solicitação"})
+	default:
+		c.JSON(http.StatusOK, alternativas)
+	}
+}
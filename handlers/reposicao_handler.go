@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ObterRelatorioReposicao sugere quantidades de compra a partir do consumo recente.
+// Parâmetros: dias (janela de consumo, padrão 30), cobertura (padrão 30), prazo_padrao (padrão 7),
+// fornecedor_id e todos=true para listar também os medicamentos que não precisam de reposição.
+func (a *Aplicacao) ObterRelatorioReposicao(c *gin.Context) {
+	filtro, ok := filtroReposicao(c)
+	if !ok {
+		return
+	}
+	if v := c.Query("fornecedor_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fornecedor_id inválido"})
+			return
+		}
+		filtro.FornecedorID = id
+	}
+	filtro.Todos = c.Query("todos") == "true"
+
+	sugestoes, err := a.banco.SugerirReposicao(filtro)
+	if err != nil {
+		log.Printf("Erro ao gerar relatório de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório de reposição"})
+		return
+	}
+	if sugestoes == nil {
+		sugestoes = []models.SugestaoReposicao{}
+	}
+	c.JSON(http.StatusOK, sugestoes)
+}
+
+// ObterParametrosReposicao retorna o estoque mínimo, máximo e o ponto de pedido do medicamento.
+func (a *Aplicacao) ObterParametrosReposicao(c *gin.Context) {
+	parametros, err := a.banco.GetParametrosReposicao(c.Param("id"))
+	if err != nil {
+		responderErroReposicao(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, parametros)
+}
+
+// SalvarParametrosReposicao define os limites de estoque do medicamento. Campos nulos voltam a ser calculados.
+func (a *Aplicacao) SalvarParametrosReposicao(c *gin.Context) {
+	var req models.ParametrosReposicao
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+	req.MedicamentoID = c.Param("id")
+
+	parametros, err := a.banco.SalvarParametrosReposicao(req, usuarioAtualID(c))
+	if err != nil {
+		responderErroReposicao(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, parametros)
+}
+
+// CriarPedidoSugerido cria um pedido de compra em rascunho com as sugestões de reposição do fornecedor.
+func (a *Aplicacao) CriarPedidoSugerido(c *gin.Context) {
+	var req struct {
+		FornecedorID   int      `json:"fornecedor_id" binding:"required"`
+		Dias           int      `json:"dias"`
+		Cobertura      int      `json:"cobertura"`
+		MedicamentoIDs []string `json:"medicamento_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	filtro := models.FiltroReposicao{JanelaDias: req.Dias, CoberturaDias: req.Cobertura}
+	pedido, err := a.banco.CriarPedidoSugerido(req.FornecedorID, filtro, req.MedicamentoIDs, usuarioAtualID(c))
+	if err != nil {
+		responderErroCompra(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, pedido)
+}
+
+func filtroReposicao(c *gin.Context) (models.FiltroReposicao, bool) {
+	var filtro models.FiltroReposicao
+	for param, destino := range map[string]*int{"dias": &filtro.JanelaDias, "cobertura": &filtro.CoberturaDias, "prazo_padrao": &filtro.PrazoPadrao} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro '" + param + "' inválido"})
+			return filtro, false
+		}
+		*destino = n
+	}
+	return filtro, true
+}
+
+// responderErroReposicao traduz os erros dos parâmetros de reposição em status HTTP.
+func responderErroReposicao(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrMedicamentoNaoEncontrado), errors.Is(err, models.ErrFornecedorNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrParametrosReposicaoInvalidos):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro nos parâmetros de reposição: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro nos parâmetros de reposição: " + err.Error()})
+	}
+}
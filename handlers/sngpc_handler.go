@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"medicontrol/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// emitenteSNGPC lê da configuração o CNPJ da farmácia e o CPF do responsável técnico.
+func (a *Aplicacao) emitenteSNGPC() models.EmitenteSNGPC {
+	return models.EmitenteSNGPC{
+		CNPJ:           a.config.SNGPCCNPJ,
+		CPFTransmissor: a.config.SNGPCCPFTransmissor,
+	}
+}
+
+// ExportarSNGPC gera o arquivo XML do SNGPC com as entradas e vendas de controlados do período.
+// Parâmetros: de e ate (YYYY-MM-DD, inclusivos; padrão: mês corrente).
+func (a *Aplicacao) ExportarSNGPC(c *gin.Context) {
+	de, ate, ok := a.periodoDaRequisicao(c)
+	if !ok {
+		return
+	}
+
+	xmlSNGPC, err := a.banco.GerarSNGPCMovimentacao(a.emitenteSNGPC(), de, ate)
+	if err != nil {
+		responderErroSNGPC(c, err)
+		return
+	}
+
+	nome := fmt.Sprintf("sngpc_%s_%s.xml", de.Format("20060102"), ate.AddDate(0, 0, -1).Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", nome))
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", xmlSNGPC)
+}
+
+// ExportarInventarioSNGPC gera o arquivo XML de inventário dos controlados com o saldo atual por lote.
+func (a *Aplicacao) ExportarInventarioSNGPC(c *gin.Context) {
+	hoje := a.banco.Agora()
+	xmlSNGPC, err := a.banco.GerarSNGPCInventario(a.emitenteSNGPC(), hoje)
+	if err != nil {
+		responderErroSNGPC(c, err)
+		return
+	}
+
+	nome := fmt.Sprintf("sngpc_inventario_%s.xml", hoje.Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", nome))
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", xmlSNGPC)
+}
+
+// responderErroSNGPC devolve 422 com a lista de problemas quando o arquivo não passa na validação.
+func responderErroSNGPC(c *gin.Context, err error) {
+	var erroValidacao *models.ErroValidacaoSNGPC
+	if errors.As(err, &erroValidacao) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     "Dados incompletos para o SNGPC no período",
+			"problemas": erroValidacao.Problemas,
+		})
+		return
+	}
+	log.Printf("Erro ao gerar arquivo SNGPC: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar arquivo SNGPC"})
+}
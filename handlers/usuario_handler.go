@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"log"
+	"medicontrol/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Chaves usadas para guardar no contexto do Gin os dados do usuário autenticado
+const (
+	ContextUserID   = "user_id"
+	ContextUsername = "username"
+	ContextRole     = "role"
+)
+
+// usuarioAtualID retorna o ID do usuário autenticado na requisição.
+func usuarioAtualID(c *gin.Context) int {
+	return c.GetInt(ContextUserID)
+}
+
+// ListarUsuarios retorna todos os usuários cadastrados.
+func (a *Aplicacao) ListarUsuarios(c *gin.Context) {
+	usuarios, err := a.banco.ListarUsuarios()
+	if err != nil {
+		log.Printf("Erro ao buscar usuários: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuários"})
+		return
+	}
+
+	if usuarios == nil {
+		usuarios = []models.Usuario{}
+	}
+
+	c.JSON(http.StatusOK, usuarios)
+}
+
+// ObterUsuario retorna um usuário específico
+func (a *Aplicacao) ObterUsuario(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuário inválido"})
+		return
+	}
+
+	usuario, err := a.banco.GetUsuario(id)
+	if err != nil {
+		log.Printf("Erro ao buscar usuário %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuário"})
+		return
+	}
+	if usuario == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usuario)
+}
+
+// CriarUsuario cadastra um novo usuário
+func (a *Aplicacao) CriarUsuario(c *gin.Context) {
+	var req models.UsuarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	usuario, err := a.banco.CriarUsuario(req, usuarioAtualID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, usuario)
+}
+
+// AtualizarUsuario altera os dados de um usuário existente
+func (a *Aplicacao) AtualizarUsuario(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuário inválido"})
+		return
+	}
+
+	var req models.UsuarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Impede que o administrador retire o próprio acesso
+	if id == usuarioAtualID(c) && ((req.Role != "" && req.Role != models.RoleAdmin) || (req.Ativo != nil && !*req.Ativo)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Não é possível remover o próprio acesso de administrador"})
+		return
+	}
+
+	usuario, err := a.banco.AtualizarUsuario(id, req, usuarioAtualID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, usuario)
+}
+
+// DesativarUsuario desativa um usuário, preservando seu histórico
+func (a *Aplicacao) DesativarUsuario(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuário inválido"})
+		return
+	}
+
+	if id == usuarioAtualID(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Não é possível desativar o próprio usuário"})
+		return
+	}
+
+	if err := a.banco.DesativarUsuario(id, usuarioAtualID(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
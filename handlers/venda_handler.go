@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"medicontrol/models"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // CriarVendaHandler processa a requisição para criar uma nova venda.
 // Esta é a versão correta do handler, que se alinha com a lógica do Gin em main.go
-func CriarVendaHandler(c *gin.Context) {
+func (a *Aplicacao) CriarVendaHandler(c *gin.Context) {
 	var vendaReq models.RegistrarVendaRequest
 	if err := c.ShouldBindJSON(&vendaReq); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
@@ -23,12 +27,194 @@ func CriarVendaHandler(c *gin.Context) {
 	}
 
 	// Registrar a venda usando a lógica de modelo
-	vendaID, err := models.RegistrarVenda(vendaReq)
+	resumo, err := a.banco.RegistrarVenda(vendaReq, usuarioAtualID(c), c.GetString(ContextRole))
 	if err != nil {
+		if responderErroInteracoes(c, err) {
+			return
+		}
+		if errors.Is(err, models.ErrCaixaFechado) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Abra o caixa antes de registrar vendas"})
+			return
+		}
+		if errors.Is(err, models.ErrReceitaObrigatoria) || errors.Is(err, models.ErrPagamentoInvalido) ||
+			errors.Is(err, models.ErrPrecoAcimaPMC) || errors.Is(err, models.ErrQuantidadeInvalida) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, models.ErrDescontoAcimaDoLimite) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		// O erro do modelo pode ser específico (ex: estoque insuficiente)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar venda: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"venda_id": vendaID})
+	c.JSON(http.StatusCreated, resumo)
+}
+
+// CancelarVendaHandler cancela uma venda inteira, devolvendo os itens ao estoque.
+func (a *Aplicacao) CancelarVendaHandler(c *gin.Context) {
+	vendaID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de venda inválido"})
+		return
+	}
+
+	// O corpo é opcional; sem ele o cancelamento fica sem motivo informado
+	var req models.EstornoVendaRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+			return
+		}
+	}
+
+	valorEstornado, err := a.banco.CancelarVenda(vendaID, req.Motivo, usuarioAtualID(c))
+	if err != nil {
+		responderErroEstorno(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"venda_id": vendaID, "status": models.StatusVendaCancelada, "valor_estornado": valorEstornado})
+}
+
+// DevolverItensVendaHandler registra a devolução de parte dos itens de uma venda.
+func (a *Aplicacao) DevolverItensVendaHandler(c *gin.Context) {
+	vendaID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de venda inválido"})
+		return
+	}
+
+	var req models.DevolucaoVendaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requisição inválida: " + err.Error()})
+		return
+	}
+
+	resultado, err := a.banco.DevolverItensVenda(vendaID, req, usuarioAtualID(c))
+	if err != nil {
+		responderErroEstorno(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resultado)
+}
+
+// responderErroEstorno traduz os erros de cancelamento e devolução em status HTTP.
+func responderErroEstorno(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrVendaNaoEncontrada):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrVendaCancelada), errors.Is(err, models.ErrCaixaFechado):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrDevolucaoInvalida):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Erro ao estornar venda: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao estornar venda: " + err.Error()})
+	}
+}
+
+// ListarVendasHandler lista as vendas com filtros e paginação.
+// Parâmetros: de e ate (YYYY-MM-DD, inclusivos), usuario_id, medicamento_id, status,
+// total_min, total_max, pagina (padrão 1) e por_pagina (padrão 20, máximo 100).
+func (a *Aplicacao) ListarVendasHandler(c *gin.Context) {
+	filtro := models.FiltroVendas{
+		MedicamentoID: c.Query("medicamento_id"),
+		Status:        c.Query("status"),
+	}
+
+	if deStr := c.Query("de"); deStr != "" {
+		de, err := time.ParseInLocation("2006-01-02", deStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'de' inválido, use YYYY-MM-DD"})
+			return
+		}
+		filtro.De = de
+	}
+	if ateStr := c.Query("ate"); ateStr != "" {
+		ate, err := time.ParseInLocation("2006-01-02", ateStr, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'ate' inválido, use YYYY-MM-DD"})
+			return
+		}
+		// Inclui o dia inteiro informado
+		filtro.Ate = ate.AddDate(0, 0, 1)
+	}
+
+	if usuarioStr := c.Query("usuario_id"); usuarioStr != "" {
+		usuarioID, err := strconv.Atoi(usuarioStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'usuario_id' inválido"})
+			return
+		}
+		filtro.UsuarioID = usuarioID
+	}
+
+	if minStr := c.Query("total_min"); minStr != "" {
+		totalMin, err := strconv.ParseFloat(minStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'total_min' inválido"})
+			return
+		}
+		filtro.TotalMin = &totalMin
+	}
+	if maxStr := c.Query("total_max"); maxStr != "" {
+		totalMax, err := strconv.ParseFloat(maxStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'total_max' inválido"})
+			return
+		}
+		filtro.TotalMax = &totalMax
+	}
+
+	pagina, err := strconv.Atoi(c.DefaultQuery("pagina", "1"))
+	if err != nil || pagina < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'pagina' inválido"})
+		return
+	}
+	porPagina, err := strconv.Atoi(c.DefaultQuery("por_pagina", "20"))
+	if err != nil || porPagina < 1 || porPagina > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parâmetro 'por_pagina' inválido (1 a 100)"})
+		return
+	}
+	filtro.Pagina = pagina
+	filtro.PorPagina = porPagina
+
+	resultado, err := a.banco.BuscarVendas(filtro)
+	if err != nil {
+		log.Printf("Erro ao listar vendas: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar vendas"})
+		return
+	}
+
+	if resultado.Vendas == nil {
+		resultado.Vendas = []models.VendaInfo{}
+	}
+
+	c.JSON(http.StatusOK, resultado)
+}
+
+// ObterVendaHandler retorna uma venda com seus itens.
+func (a *Aplicacao) ObterVendaHandler(c *gin.Context) {
+	vendaID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de venda inválido"})
+		return
+	}
+
+	venda, err := a.banco.GetVenda(vendaID)
+	if err != nil {
+		log.Printf("Erro ao buscar venda %d: %v", vendaID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar venda"})
+		return
+	}
+	if venda == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Venda não encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, venda)
 }
@@ -1,52 +1,51 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
+	"medicontrol/config"
 	"medicontrol/handlers"
 	"medicontrol/models"
+	"medicontrol/services"
 	"medicontrol/sqlutils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// Chave secreta para assinar os tokens JWT
-var jwtSecret = []byte("seu_segredo_super_secreto")
+// Chave secreta para assinar os tokens JWT, lida de JWT_SECRET na inicialização
+var jwtSecret []byte
 
-// User representa a estrutura de um usuário
-type User struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-}
+// jwtSecretExemplo é o valor de exemplo da documentação, que não pode ser usado de verdade
+const jwtSecretExemplo = "seu_segredo_super_secreto"
 
 // Claims representa as claims do JWT
 type Claims struct {
+	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// Credenciais do admin (em produção, isso estaria em um banco de dados)
-var adminUser = User{
-	Username: "admin",
-	// Senha: senha123 (hash gerado com bcrypt)
-	Password: "$2a$10$YourHashedPasswordHere", // Será substituído na inicialização
-}
-
 // LoginRequest representa a estrutura do corpo da requisição de login
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
-// authMiddleware verifica se o token JWT é válido
-func authMiddleware() gin.HandlerFunc {
+// authMiddleware verifica se o token JWT é válido e se o usuário dele continua ativo. O papel vem do
+// cadastro atual, e não do token, para que desativações e trocas de papel valham na hora.
+func authMiddleware(banco *models.Banco) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -55,12 +54,12 @@ func authMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := authHeader[7:] // Remove "Bearer "
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		claims := &Claims{}
 		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 			return jwtSecret, nil
-		})
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -68,44 +67,101 @@ func authMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		usuario, err := banco.UsuarioDaSessao(claims.UserID)
+		if err != nil {
+			if !errors.Is(err, models.ErrSessaoInvalida) {
+				log.Printf("Erro ao conferir o usuário %d do token: %v", claims.UserID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify session"})
+				c.Abort()
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		// Disponibilizar o usuário autenticado para os handlers
+		c.Set(handlers.ContextUserID, usuario.ID)
+		c.Set(handlers.ContextUsername, usuario.Username)
+		c.Set(handlers.ContextRole, usuario.Role)
+
 		c.Next()
 	}
 }
 
+// requireRole permite o acesso apenas aos usuários com um dos papéis informados
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(handlers.ContextRole)
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+
+		log.Printf("Acesso negado para o usuário '%s' (papel '%s') em %s", c.GetString(handlers.ContextUsername), role, c.FullPath())
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permissão insuficiente"})
+		c.Abort()
+	}
+}
+
+// configBanco escolhe o banco de dados a partir das variáveis DB_*
+func configBanco(cfg *config.Config) models.ConfigBanco {
+	return models.ConfigBanco{Driver: cfg.DBDriver, Caminho: cfg.DBPath, DSN: cfg.DSNPostgres()}
+}
+
 func main() {
+	// Subcomando de migrações do esquema: medicontrol migrate [up|rollback|status]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(executarMigrate(os.Args[2:]))
+	}
+
 	log.Println("Attempting to start MediControl server...")
 	log.Println("Iniciando o servidor MediControl...")
 
 	// Carregar arquivos SQL
-	if err := sqlutils.LoadSQLFiles("sql"); err != nil {
+	queries, err := sqlutils.LoadEmbeddedSQL()
+	if err != nil {
 		log.Fatalf("Erro ao carregar arquivos SQL: %v", err)
 	}
 	log.Println("Arquivos SQL carregados com sucesso.")
 
 	// Inicializar o banco de dados
-	if err := models.InitDB(); err != nil {
+	cfg := config.LoadConfig()
+	if cfg.JWTSecret == "" || cfg.JWTSecret == jwtSecretExemplo {
+		log.Fatal("Defina em JWT_SECRET uma chave secreta própria para assinar os tokens (veja docs/setup.md)")
+	}
+	jwtSecret = []byte(cfg.JWTSecret)
+	banco, err := models.InitDB(configBanco(cfg), queries)
+	if err != nil {
+		if errors.Is(err, models.ErrMigracao) {
+			log.Fatalf("Erro ao atualizar o esquema do banco de dados: %v (consulte 'medicontrol migrate status')", err)
+		}
 		log.Fatal("Erro ao inicializar banco de dados:", err)
 	}
 
 	// Corrigir preços de medicamentos existentes (migração de dados)
-	if err := models.CorrigirPrecosMedicamentos(); err != nil {
+	if err := banco.CorrigirPrecosMedicamentos(); err != nil {
 		log.Printf("Aviso: Ocorreu um erro durante a correção de preços: %v", err)
 	}
 
 	// Importar medicamentos do arquivo JSON
-	if err := models.ImportarMedicamentos(); err != nil {
+	if err := banco.ImportarMedicamentos(); err != nil {
 		log.Printf("Erro ao importar medicamentos: %v", err)
 	} else {
 		log.Println("Medicamentos importados com sucesso")
 	}
 
-	// Gerar hash da senha do admin na inicialização
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("senha123"), bcrypt.DefaultCost)
-	if err != nil {
-		log.Fatal("Erro ao gerar hash da senha:", err)
-	}
-	adminUser.Password = string(hashedPassword)
-	log.Println("Hash da senha do admin gerado com sucesso")
+	// Encerrar o agendador e o servidor ao receber Ctrl+C ou SIGTERM
+	ctx, parar := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer parar()
+
+	// Aplicar os preços agendados cuja vigência já começou e conferir novamente a cada hora
+	banco.IniciarAgendadorPrecos(ctx, time.Hour)
+
+	// Handlers das rotas, sobre o banco aberto acima
+	app := handlers.NovaAplicacao(banco, services.NovoClienteAnvisa(), cfg)
 
 	r := gin.Default()
 
@@ -170,22 +226,22 @@ func main() {
 			}
 
 			// Verificar credenciais
-			if loginReq.Username != adminUser.Username {
-				log.Printf("Tentativa de login com usuário inválido: %s", loginReq.Username)
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-				return
-			}
-
-			// Verificar senha
-			if err := bcrypt.CompareHashAndPassword([]byte(adminUser.Password), []byte(loginReq.Password)); err != nil {
-				log.Printf("Senha incorreta para o usuário: %s", loginReq.Username)
+			usuario, err := banco.AutenticarUsuario(loginReq.Username, loginReq.Password)
+			if err != nil {
+				log.Printf("Falha no login do usuário '%s': %v", loginReq.Username, err)
+				if errors.Is(err, models.ErrUsuarioBloqueado) {
+					c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, try again later"})
+					return
+				}
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 				return
 			}
 
 			// Criar claims para o token
 			claims := &Claims{
-				Username: loginReq.Username,
+				UserID:   usuario.ID,
+				Username: usuario.Username,
+				Role:     usuario.Role,
 				RegisteredClaims: jwt.RegisteredClaims{
 					ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 				},
@@ -202,37 +258,49 @@ func main() {
 
 			log.Printf("Login bem-sucedido para o usuário: %s", loginReq.Username)
 			c.JSON(http.StatusOK, gin.H{
-				"token": tokenString,
+				"token":   tokenString,
+				"usuario": usuario,
 			})
 		})
 
 		// Rotas protegidas
 		protected := api.Group("")
-		protected.Use(authMiddleware())
+		protected.Use(authMiddleware(banco))
 		{
 			// Rotas de medicamentos
-			protected.GET("/medicamentos", handlers.ListarMedicamentos)
-			protected.GET("/medicamentos/:id", handlers.ObterMedicamento)
-			protected.POST("/medicamentos", handlers.CriarMedicamento)
-			protected.PUT("/medicamentos/:id", handlers.AtualizarMedicamento)
-			protected.DELETE("/medicamentos/:id", handlers.DeletarMedicamento)
+			protected.GET("/medicamentos", app.ListarMedicamentos)
+			protected.GET("/medicamentos/barcode/:gtin", app.BuscarPorCodigoBarras)
+			protected.GET("/medicamentos/:id", app.ObterMedicamento)
+			protected.GET("/medicamentos/:id/lotes", app.ListarLotesMedicamento)
+			protected.GET("/medicamentos/:id/alternativas", app.ListarAlternativasMedicamento)
+
+			// Princípios ativos que compõem os medicamentos
+			protected.GET("/principios-ativos", app.ListarPrincipiosAtivos)
 
 			// Rotas de categorias
-			protected.GET("/categorias", handlers.ListarCategorias)
+			protected.GET("/categorias", app.ListarCategorias)
 
 			// Nova rota para buscar dados da ANVISA
-			protected.GET("/anvisa/:codigo", handlers.BuscarDadosAnvisa)
+			protected.GET("/anvisa/:codigo", app.BuscarDadosAnvisa)
 
-			// Rotas de movimentação
-			protected.POST("/movimentacoes", handlers.RegistrarMovimentacao)
-			protected.GET("/movimentacoes", handlers.ListarMovimentacoes)
+			// Rota para Vendas
+			protected.GET("/vendas", app.ListarVendasHandler)
+			protected.GET("/vendas/:id", app.ObterVendaHandler)
+			protected.POST("/vendas", app.CriarVendaHandler)
+			protected.POST("/vendas/interacoes", app.VerificarInteracoesVendaHandler)
 
-			// Rotas de relatórios
-			protected.GET("/relatorios/vendas", handlers.ObterTotalVendas)
-			protected.GET("/relatorios/baixo-estoque", handlers.ObterRelatorioBaixoEstoque)
+			// Tabela de interações medicamentosas
+			protected.GET("/interacoes", app.ListarInteracoesHandler)
 
-			// Rota para Vendas
-			protected.POST("/vendas", handlers.CriarVendaHandler)
+			// Sessão de caixa do usuário logado
+			protected.POST("/caixa/abertura", app.AbrirCaixaHandler)
+			protected.GET("/caixa/atual", app.GetCaixaAtualHandler)
+			protected.POST("/caixa/sangria", app.SangriaCaixaHandler)
+			protected.POST("/caixa/suprimento", app.SuprimentoCaixaHandler)
+			protected.POST("/caixa/fechamento", app.FecharCaixaHandler)
+
+			// Contagem de inventário (qualquer usuário pode contar)
+			protected.POST("/inventarios/:id/contagens", app.RegistrarContagensInventario)
 
 			// Rota protegida de teste
 			protected.GET("/protected", func(c *gin.Context) {
@@ -242,11 +310,109 @@ func main() {
 				})
 			})
 		}
+
+		// Rotas de gestão de estoque (farmacêutico e administrador)
+		gestao := protected.Group("")
+		gestao.Use(requireRole(models.RoleAdmin, models.RoleFarmaceutico))
+		{
+			gestao.POST("/medicamentos", app.CriarMedicamento)
+			gestao.PUT("/medicamentos/:id", app.AtualizarMedicamento)
+			gestao.DELETE("/medicamentos/:id", app.DeletarMedicamento)
+			gestao.GET("/medicamentos/:id/reposicao", app.ObterParametrosReposicao)
+			gestao.PUT("/medicamentos/:id/reposicao", app.SalvarParametrosReposicao)
+			gestao.GET("/medicamentos/:id/precos", app.ListarHistoricoPrecos)
+			gestao.POST("/principios-ativos", app.CriarPrincipioAtivo)
+
+			// Agendamento de preços e tabela CMED de preços máximos
+			gestao.GET("/precos/agendamentos", app.ListarAgendamentosPreco)
+			gestao.GET("/precos/agendamentos/:id", app.ObterAgendamentoPreco)
+			gestao.POST("/precos/agendamentos", app.AgendarPreco)
+			gestao.POST("/precos/agendamentos/:id/cancelamento", app.CancelarAgendamentoPreco)
+			gestao.POST("/precos/cmed", app.ImportarTabelaCMED)
+
+			// Carga da tabela de interações medicamentosas
+			gestao.POST("/interacoes/importacao", app.ImportarInteracoesHandler)
+
+			// Rotas de movimentação
+			gestao.POST("/movimentacoes", app.RegistrarMovimentacao)
+			gestao.GET("/movimentacoes", app.ListarMovimentacoes)
+
+			// Inventário físico do estoque
+			gestao.GET("/inventarios", app.ListarInventarios)
+			gestao.GET("/inventarios/:id", app.ObterInventario)
+			gestao.POST("/inventarios", app.AbrirInventario)
+			gestao.POST("/inventarios/:id/aprovacao", app.AprovarInventario)
+			gestao.POST("/inventarios/:id/cancelamento", app.CancelarInventario)
+
+			// Estorno de vendas
+			gestao.POST("/vendas/:id/cancelamento", app.CancelarVendaHandler)
+			gestao.POST("/vendas/:id/devolucoes", app.DevolverItensVendaHandler)
+
+			// Fornecedores e pedidos de compra
+			gestao.GET("/fornecedores", app.ListarFornecedores)
+			gestao.GET("/fornecedores/:id", app.ObterFornecedor)
+			gestao.POST("/fornecedores", app.CriarFornecedor)
+			gestao.PUT("/fornecedores/:id", app.AtualizarFornecedor)
+			gestao.DELETE("/fornecedores/:id", app.DesativarFornecedor)
+			gestao.GET("/pedidos-compra", app.ListarPedidosCompra)
+			gestao.GET("/pedidos-compra/:id", app.ObterPedidoCompra)
+			gestao.POST("/pedidos-compra", app.CriarPedidoCompra)
+			gestao.PUT("/pedidos-compra/:id/itens", app.AtualizarItensPedidoCompra)
+			gestao.POST("/pedidos-compra/:id/envio", app.EnviarPedidoCompra)
+			gestao.POST("/pedidos-compra/:id/recebimentos", app.ReceberPedidoCompra)
+			gestao.POST("/pedidos-compra/sugestao", app.CriarPedidoSugerido)
+
+			// Importação de NF-e de entrada
+			gestao.POST("/nfe/importacoes", app.ImportarNFe)
+			gestao.GET("/nfe/importacoes/:id", app.ObterImportacaoNFe)
+			gestao.POST("/nfe/importacoes/:id/confirmacao", app.ConfirmarImportacaoNFe)
+
+			// Conferência das sessões de caixa
+			gestao.GET("/caixas", app.ListarCaixasHandler)
+			gestao.GET("/caixas/:id", app.GetCaixaHandler)
+
+			// Rotas de relatórios
+			gestao.GET("/relatorios/vendas", app.ObterTotalVendas)
+			gestao.GET("/relatorios/baixo-estoque", app.ObterRelatorioBaixoEstoque)
+			gestao.GET("/relatorios/reposicao", app.ObterRelatorioReposicao)
+			gestao.GET("/relatorios/margem", app.ObterRelatorioMargem)
+			gestao.GET("/relatorios/pmc", app.ObterRelatorioViolacoesPMC)
+			gestao.GET("/relatorios/vencimento", app.ObterRelatorioVencimento)
+			gestao.GET("/relatorios/controlados", app.ObterLivroControlados)
+
+			// Arquivos do SNGPC (ANVISA)
+			gestao.GET("/sngpc/movimentacao", app.ExportarSNGPC)
+			gestao.GET("/sngpc/inventario", app.ExportarInventarioSNGPC)
+		}
+
+		// Rotas de administração (somente administrador)
+		admin := protected.Group("")
+		admin.Use(requireRole(models.RoleAdmin))
+		{
+			admin.GET("/usuarios", app.ListarUsuarios)
+			admin.GET("/usuarios/:id", app.ObterUsuario)
+			admin.POST("/usuarios", app.CriarUsuario)
+			admin.PUT("/usuarios/:id", app.AtualizarUsuario)
+			admin.DELETE("/usuarios/:id", app.DesativarUsuario)
+
+			// Trilha de auditoria
+			admin.GET("/auditoria", app.ListarAuditoria)
+		}
 	}
 
 	// Iniciar o servidor na porta 8080
 	log.Println("Servidor iniciado na porta 8080 - Acesse http://localhost:8080")
-	if err := r.Run(":8080"); err != nil {
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		<-ctx.Done()
+		log.Println("Encerrando o servidor...")
+		ctxEncerrar, cancelar := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelar()
+		if err := srv.Shutdown(ctxEncerrar); err != nil {
+			log.Printf("Erro ao encerrar o servidor: %v", err)
+		}
+	}()
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal("Erro ao iniciar o servidor:", err)
 	}
 
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"medicontrol/config"
+	"medicontrol/models"
+	"medicontrol/sqlutils"
+)
+
+const usoMigrate = `Uso: medicontrol migrate <comando> [opções]
+
+Comandos:
+  up [-to N]           aplica as migrações pendentes (até a versão N, se informada)
+  rollback [-steps N]  reverte as últimas N migrações aplicadas (padrão 1)
+  status               lista as migrações e indica quais já foram aplicadas
+`
+
+// executarMigrate trata o subcomando "migrate" e retorna o código de saída do processo.
+func executarMigrate(args []string) int {
+	comando := "up"
+	if len(args) > 0 {
+		comando, args = args[0], args[1:]
+	}
+	switch comando {
+	case "up", "rollback", "down", "status":
+	default:
+		fmt.Fprint(os.Stderr, usoMigrate)
+		return 2
+	}
+
+	queries, err := sqlutils.LoadEmbeddedSQL()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao carregar arquivos SQL: %v\n", err)
+		return 1
+	}
+	banco, err := models.AbrirBanco(configBanco(config.LoadConfig()), queries)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro ao abrir o banco de dados: %v\n", err)
+		return 1
+	}
+	defer banco.Fechar()
+
+	switch comando {
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+		alvo := flags.Int("to", 0, "versão final (0 aplica todas)")
+		if flags.Parse(args) != nil {
+			return 2
+		}
+		var aplicadas []models.StatusMigracao
+		aplicadas, err = banco.Migrar(*alvo)
+		for _, m := range aplicadas {
+			fmt.Printf("Aplicada %04d_%s\n", m.Versao, m.Nome)
+		}
+		if err == nil && len(aplicadas) == 0 {
+			fmt.Println("Nenhuma migração pendente.")
+		}
+	case "rollback", "down":
+		flags := flag.NewFlagSet("migrate rollback", flag.ContinueOnError)
+		passos := flags.Int("steps", 1, "quantidade de migrações a reverter")
+		if flags.Parse(args) != nil {
+			return 2
+		}
+		var revertidas []models.StatusMigracao
+		revertidas, err = banco.ReverterMigracoes(*passos)
+		for _, m := range revertidas {
+			fmt.Printf("Revertida %04d_%s\n", m.Versao, m.Nome)
+		}
+	case "status":
+		var status []models.StatusMigracao
+		status, err = banco.GetStatusMigracoes()
+		if err == nil {
+			imprimirStatusMigracoes(os.Stdout, status)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Erro: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func imprimirStatusMigracoes(w io.Writer, status []models.StatusMigracao) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSÃO\tNOME\tSITUAÇÃO\tAPLICADA EM")
+	for _, m := range status {
+		situacao := "pendente"
+		aplicadaEm := "-"
+		if m.Aplicada {
+			situacao = "aplicada"
+			aplicadaEm = m.AplicadaEm.Format("2006-01-02 15:04:05")
+		}
+		if m.Alterada {
+			situacao += " (alterada depois de aplicada)"
+		}
+		if m.Ausente {
+			situacao += " (sem arquivo nesta versão)"
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", m.Versao, m.Nome, situacao, aplicadaEm)
+	}
+	tw.Flush()
+}
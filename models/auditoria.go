@@ -0,0 +1,137 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Ações registradas na trilha de auditoria
+const (
+	AcaoCriar     = "criar"
+	AcaoAtualizar = "atualizar"
+	AcaoExcluir   = "excluir"
+)
+
+// RegistroAuditoria representa uma alteração registrada na trilha de auditoria
+type RegistroAuditoria struct {
+	ID         int64           `json:"id"`
+	UsuarioID  int             `json:"usuario_id"`
+	Username   string          `json:"username"`
+	Data       time.Time       `json:"data"`
+	Acao       string          `json:"acao"`
+	Entidade   string          `json:"entidade"`
+	EntidadeID string          `json:"entidade_id"`
+	Antes      json.RawMessage `json:"antes"`
+	Depois     json.RawMessage `json:"depois"`
+}
+
+// FiltroAuditoria define os critérios de busca na trilha de auditoria
+type FiltroAuditoria struct {
+	Entidade   string
+	EntidadeID string
+	UsuarioID  int
+	De         time.Time
+	Ate        time.Time
+	Limite     int
+}
+
+// registrarAuditoria grava uma alteração na trilha de auditoria. Deve ser chamada na mesma
+// transação da alteração, para que uma não exista sem a outra.
+func (b *Banco) registrarAuditoria(tx execer, usuarioID int, acao, entidade, entidadeID string, antes, depois interface{}) error {
+	query := b.queries.GetQuery(qInserirAuditoria)
+	if query == "" {
+		return errors.New("query 'inserir_auditoria' não encontrada")
+	}
+
+	antesJSON, err := snapshotJSON(antes)
+	if err != nil {
+		return err
+	}
+	depoisJSON, err := snapshotJSON(depois)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(query, usuarioID, b.Agora(), acao, entidade, entidadeID, antesJSON, depoisJSON)
+	if err != nil {
+		return fmt.Errorf("erro ao registrar auditoria de %s %s: %w", entidade, entidadeID, err)
+	}
+	return nil
+}
+
+// snapshotJSON serializa o estado de uma entidade; nil vira NULL no banco.
+func snapshotJSON(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar snapshot de auditoria: %w", err)
+	}
+	return string(data), nil
+}
+
+// ListarAuditoria busca registros da trilha de auditoria, do mais recente para o mais antigo.
+func (b *Banco) ListarAuditoria(filtro FiltroAuditoria) ([]RegistroAuditoria, error) {
+	query := `
+		SELECT a.id, a.usuario_id, COALESCE(u.username, ''), a.data, a.acao, a.entidade, a.entidade_id, a.antes, a.depois
+		FROM auditoria a
+		LEFT JOIN usuarios u ON u.id = a.usuario_id
+		WHERE 1 = 1`
+
+	var args []interface{}
+	if filtro.Entidade != "" {
+		query += " AND a.entidade = ?"
+		args = append(args, filtro.Entidade)
+	}
+	if filtro.EntidadeID != "" {
+		query += " AND a.entidade_id = ?"
+		args = append(args, filtro.EntidadeID)
+	}
+	if filtro.UsuarioID != 0 {
+		query += " AND a.usuario_id = ?"
+		args = append(args, filtro.UsuarioID)
+	}
+	if !filtro.De.IsZero() {
+		query += " AND a.data >= ?"
+		args = append(args, filtro.De)
+	}
+	if !filtro.Ate.IsZero() {
+		query += " AND a.data < ?"
+		args = append(args, filtro.Ate)
+	}
+	query += " ORDER BY a.data DESC, a.id DESC"
+	if filtro.Limite > 0 {
+		query += " LIMIT ?"
+		args = append(args, filtro.Limite)
+	}
+
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Erro ao buscar registros de auditoria: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var registros []RegistroAuditoria
+	for rows.Next() {
+		var r RegistroAuditoria
+		var antes, depois sql.NullString
+		if err := rows.Scan(&r.ID, &r.UsuarioID, &r.Username, &r.Data, &r.Acao, &r.Entidade, &r.EntidadeID, &antes, &depois); err != nil {
+			return nil, fmt.Errorf("erro ao escanear registro de auditoria: %w", err)
+		}
+		if antes.Valid {
+			r.Antes = json.RawMessage(antes.String)
+		}
+		if depois.Valid {
+			r.Depois = json.RawMessage(depois.String)
+		}
+		registros = append(registros, r)
+	}
+
+	return registros, rows.Err()
+}
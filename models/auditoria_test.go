@@ -0,0 +1,79 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrilhaDeAuditoria(t *testing.T) {
+	t.Parallel()
+	agora := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	b := abrirBancoTesteCom(t, ConfigBanco{Driver: DriverSQLite, Caminho: ":memory:", Relogio: func() time.Time { return agora }})
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	gerente, err := b.CriarUsuario(UsuarioRequest{Username: "gerente", Nome: "Gerente", Password: "Gerente@2025", Role: RoleAdmin}, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	med := &Medicamento{ID: "1", Nome: "Dipirona 500mg", Preco: 10}
+	if !assert.NoError(t, b.AddMedicamento(med, gerente.ID)) {
+		return
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "2", Nome: "Cadastro errado", Preco: 1}, gerente.ID)) {
+		return
+	}
+
+	agora = agora.AddDate(0, 0, 1)
+	med.Preco = 12.5
+	if !assert.NoError(t, b.UpdateMedicamento(med, gerente.ID)) {
+		return
+	}
+	if !assert.NoError(t, b.DeleteMedicamento("2", gerente.ID)) {
+		return
+	}
+	// Uma operação recusada não deixa rastro
+	assert.Error(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoSaida, Quantidade: 1, UsuarioID: gerente.ID}))
+
+	registros, err := b.ListarAuditoria(FiltroAuditoria{Entidade: "medicamento", EntidadeID: "1"})
+	if !assert.NoError(t, err) || !assert.Len(t, registros, 2) {
+		return
+	}
+	// Do mais recente para o mais antigo, com o estado antes e depois
+	atualizacao, criacao := registros[0], registros[1]
+	assert.Equal(t, AcaoAtualizar, atualizacao.Acao)
+	assert.Equal(t, "gerente", atualizacao.Username)
+	assert.True(t, agora.Equal(atualizacao.Data))
+	var antes, depois Medicamento
+	if assert.NoError(t, json.Unmarshal(atualizacao.Antes, &antes)) && assert.NoError(t, json.Unmarshal(atualizacao.Depois, &depois)) {
+		assert.Equal(t, 10.0, antes.Preco)
+		assert.Equal(t, 12.5, depois.Preco)
+	}
+	assert.Equal(t, AcaoCriar, criacao.Acao)
+	assert.Nil(t, criacao.Antes)
+
+	excluido, err := b.ListarAuditoria(FiltroAuditoria{EntidadeID: "2"})
+	if assert.NoError(t, err) && assert.Len(t, excluido, 2) {
+		assert.Equal(t, AcaoExcluir, excluido[0].Acao)
+		assert.Nil(t, excluido[0].Depois)
+	}
+
+	// Filtros por usuário, período e limite
+	doUsuario, err := b.ListarAuditoria(FiltroAuditoria{UsuarioID: gerente.ID})
+	if assert.NoError(t, err) {
+		assert.Len(t, doUsuario, 4)
+	}
+	dia := time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)
+	doDia, err := b.ListarAuditoria(FiltroAuditoria{De: dia, Ate: dia.AddDate(0, 0, 1)})
+	if assert.NoError(t, err) {
+		assert.Len(t, doDia, 2)
+	}
+	limitados, err := b.ListarAuditoria(FiltroAuditoria{Limite: 1})
+	if assert.NoError(t, err) {
+		assert.Len(t, limitados, 1)
+	}
+}
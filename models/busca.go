@@ -0,0 +1,451 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// A busca textual de medicamentos usa um índice próprio de cada banco (FTS5 no SQLite, tsvector no
+// PostgreSQL) sobre nome, fabricante, princípio ativo, código ANVISA e códigos de barras. O índice é
+// derivado do cadastro: fica fora das migrações, é criado depois delas e reconstruído quando não
+// acompanha a tabela de medicamentos. Os repositórios o atualizam a cada inclusão, alteração e exclusão.
+
+// maxAlternativasBusca limita as palavras do índice aceitas no lugar de uma palavra digitada com erro
+const maxAlternativasBusca = 10
+
+// documentoBusca é o texto indexado de um medicamento, já normalizado por normalizarBusca
+type documentoBusca struct {
+	MedicamentoID  string
+	Nome           string
+	Fabricante     string
+	PrincipioAtivo string
+	CodigoANVISA   string
+	CodigosBarras  string
+}
+
+// alternativaBusca é uma palavra aceita num grupo da busca: a digitada, como prefixo, ou uma palavra
+// do índice próxima a ela, quando a digitada não existe no índice
+type alternativaBusca struct {
+	Palavra string
+	Prefixo bool
+}
+
+// indiceBusca é o índice de busca textual de cada banco
+type indiceBusca interface {
+	// criar cria as tabelas do índice, se ainda não existirem
+	criar(db Executor) error
+	// descartar remove as tabelas do índice
+	descartar(db Executor) error
+	gravar(db Executor, doc documentoBusca) error
+	// palavras lista o vocabulário do índice, usado para tolerar erros de digitação
+	palavras(db Executor) ([]string, error)
+	// subconsulta monta um SELECT de (medicamento_id, relevancia) com os medicamentos que têm, em algum
+	// campo, uma das alternativas de cada grupo. Quanto maior a relevância, melhor o resultado.
+	subconsulta(grupos [][]alternativaBusca) (string, []interface{})
+}
+
+// palavrasBusca separa o texto em palavras sem acentos e em minúsculas, do mesmo jeito para o que é
+// indexado e para o que é buscado. Pontuação separa palavras: "1.0573.0001" vira "1 0573 0001".
+func palavrasBusca(texto string) []string {
+	semAcentos, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), texto)
+	if err != nil {
+		semAcentos = texto
+	}
+	return strings.FieldsFunc(strings.ToLower(semAcentos), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// normalizarBusca devolve as palavras do texto separadas por um espaço
+func normalizarBusca(texto string) string {
+	return strings.Join(palavrasBusca(texto), " ")
+}
+
+// errosTolerados é quantas letras podem estar erradas numa palavra buscada; palavras curtas não
+// toleram erros, para não trazer resultados demais
+func errosTolerados(palavra string) int {
+	switch n := utf8.RuneCountInString(palavra); {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// expandirBusca monta os grupos da busca, um por palavra digitada. A palavra vale como prefixo; se
+// nenhuma palavra do índice começa com ela, as mais próximas entram como alternativas.
+func expandirBusca(palavras, vocabulario []string) [][]alternativaBusca {
+	grupos := make([][]alternativaBusca, 0, len(palavras))
+	for _, palavra := range palavras {
+		grupo := []alternativaBusca{{Palavra: palavra, Prefixo: true}}
+		if erros := errosTolerados(palavra); erros > 0 && !algumPrefixo(vocabulario, palavra) {
+			grupo = append(grupo, palavrasProximas(palavra, vocabulario, erros)...)
+		}
+		grupos = append(grupos, grupo)
+	}
+	return grupos
+}
+
+func algumPrefixo(vocabulario []string, prefixo string) bool {
+	for _, termo := range vocabulario {
+		if strings.HasPrefix(termo, prefixo) {
+			return true
+		}
+	}
+	return false
+}
+
+// palavrasProximas retorna as palavras do vocabulário a no máximo `erros` edições da palavra
+// digitada, ou de um começo delas, das mais próximas para as mais distantes
+func palavrasProximas(palavra string, vocabulario []string, erros int) []alternativaBusca {
+	type candidata struct {
+		termo     string
+		distancia int
+	}
+	var candidatas []candidata
+	for _, termo := range vocabulario {
+		if d := distanciaPrefixo(palavra, termo, erros); d <= erros {
+			candidatas = append(candidatas, candidata{termo, d})
+		}
+	}
+	sort.Slice(candidatas, func(i, j int) bool {
+		if candidatas[i].distancia != candidatas[j].distancia {
+			return candidatas[i].distancia < candidatas[j].distancia
+		}
+		return candidatas[i].termo < candidatas[j].termo
+	})
+	if len(candidatas) > maxAlternativasBusca {
+		candidatas = candidatas[:maxAlternativasBusca]
+	}
+	alternativas := make([]alternativaBusca, len(candidatas))
+	for i, c := range candidatas {
+		alternativas[i] = alternativaBusca{Palavra: c.termo}
+	}
+	return alternativas
+}
+
+// distanciaPrefixo é a menor distância de edição entre a palavra e os começos do termo com tamanho
+// próximo ao dela, para que "amoxicil" e "amoxcilina" encontrem "amoxicilina" enquanto se digita
+func distanciaPrefixo(palavra, termo string, erros int) int {
+	p, t := []rune(palavra), []rune(termo)
+	menor := erros + 1
+	for n := len(p) - erros; n <= len(p)+erros && n <= len(t); n++ {
+		if n < 1 {
+			continue
+		}
+		if d := distanciaEdicao(p, t[:n]); d < menor {
+			menor = d
+		}
+	}
+	return menor
+}
+
+// distanciaEdicao conta inserções, remoções, trocas e inversões de letras vizinhas entre a e b
+// (distância de Damerau-Levenshtein restrita)
+func distanciaEdicao(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			custo := 1
+			if a[i-1] == b[j-1] {
+				custo = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+custo)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// documentosBusca lê o texto indexado dos medicamentos; com id vazio, de todos
+func documentosBusca(db Executor, id string) ([]documentoBusca, error) {
+	query := "SELECT ID, Nome, COALESCE(Fabricante, ''), COALESCE(CodigoANVISA, '') FROM medicamentos"
+	var args []interface{}
+	if id != "" {
+		query += " WHERE ID = ?"
+		args = append(args, id)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler medicamentos para o índice de busca: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []documentoBusca
+	for rows.Next() {
+		var doc documentoBusca
+		if err := rows.Scan(&doc.MedicamentoID, &doc.Nome, &doc.Fabricante, &doc.CodigoANVISA); err != nil {
+			return nil, err
+		}
+		doc.Nome = normalizarBusca(doc.Nome)
+		doc.Fabricante = normalizarBusca(doc.Fabricante)
+		doc.CodigoANVISA = normalizarBusca(doc.CodigoANVISA)
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Os GTINs entram inteiros, para que a busca pelo começo do código encontre o medicamento
+	query = "SELECT medicamento_id, gtin FROM medicamento_codigos_barras"
+	if id != "" {
+		query += " WHERE medicamento_id = ?"
+	}
+	rows, err = db.Query(query+" ORDER BY gtin", args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler códigos de barras para o índice de busca: %w", err)
+	}
+	defer rows.Close()
+	codigos := map[string][]string{}
+	for rows.Next() {
+		var medicamentoID, gtin string
+		if err := rows.Scan(&medicamentoID, &gtin); err != nil {
+			return nil, err
+		}
+		codigos[medicamentoID] = append(codigos[medicamentoID], gtin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	query = `SELECT mp.medicamento_id, p.nome FROM medicamento_principios mp
+		JOIN principios_ativos p ON p.id = mp.principio_id`
+	if id != "" {
+		query += " WHERE mp.medicamento_id = ?"
+	}
+	rows, err = db.Query(query+" ORDER BY p.chave", args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler princípios ativos para o índice de busca: %w", err)
+	}
+	defer rows.Close()
+	principios := map[string][]string{}
+	for rows.Next() {
+		var medicamentoID, nome string
+		if err := rows.Scan(&medicamentoID, &nome); err != nil {
+			return nil, err
+		}
+		principios[medicamentoID] = append(principios[medicamentoID], normalizarBusca(nome))
+	}
+	for i := range docs {
+		docs[i].CodigosBarras = strings.Join(codigos[docs[i].MedicamentoID], " ")
+		docs[i].PrincipioAtivo = strings.Join(principios[docs[i].MedicamentoID], " ")
+	}
+	return docs, rows.Err()
+}
+
+// indexarMedicamento regrava o medicamento no índice de busca, ou o retira se ele não existe mais
+func indexarMedicamento(db Executor, busca indiceBusca, id string) error {
+	if busca == nil {
+		return nil
+	}
+	if _, err := db.Exec("DELETE FROM medicamentos_busca WHERE medicamento_id = ?", id); err != nil {
+		return fmt.Errorf("erro ao atualizar o índice de busca: %w", err)
+	}
+	docs, err := documentosBusca(db, id)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := busca.gravar(db, doc); err != nil {
+			return fmt.Errorf("erro ao atualizar o índice de busca: %w", err)
+		}
+	}
+	return nil
+}
+
+// prepararIndiceBusca acompanha o esquema: cria o índice de busca quando as tabelas que ele lê
+// existem e o reconstrói se ele não tem um documento por medicamento; sem elas, o descarta.
+func (b *Banco) prepararIndiceBusca() error {
+	busca := b.dialeto.indiceBusca()
+	if busca == nil {
+		return nil
+	}
+	for _, tabela := range []string{"medicamentos", "medicamento_codigos_barras", "principios_ativos", "medicamento_principios"} {
+		existe, err := b.tabelaExiste(tabela)
+		if err != nil {
+			return err
+		}
+		if !existe {
+			return busca.descartar(b.db)
+		}
+	}
+	if err := busca.criar(b.db); err != nil {
+		return fmt.Errorf("erro ao criar o índice de busca: %w", err)
+	}
+
+	var indexados, cadastrados int
+	if err := b.db.QueryRow("SELECT COUNT(*) FROM medicamentos_busca").Scan(&indexados); err != nil {
+		return err
+	}
+	if err := b.db.QueryRow("SELECT COUNT(*) FROM medicamentos").Scan(&cadastrados); err != nil {
+		return err
+	}
+	if indexados == cadastrados {
+		return nil
+	}
+	return b.reconstruirIndiceBusca(busca)
+}
+
+// reconstruirIndiceBusca regrava todos os medicamentos no índice de busca numa transação
+func (b *Banco) reconstruirIndiceBusca(busca indiceBusca) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM medicamentos_busca"); err != nil {
+		return err
+	}
+	docs, err := documentosBusca(tx, "")
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := busca.gravar(tx, doc); err != nil {
+			return fmt.Errorf("erro ao reconstruir o índice de busca: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("Índice de busca reconstruído com %d medicamentos.", len(docs))
+	return nil
+}
+
+// buscaFTS5 usa uma tabela FTS5 do SQLite, com o vocabulário lido de uma tabela fts5vocab
+type buscaFTS5 struct{}
+
+func (buscaFTS5) criar(db Executor) error {
+	if _, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS medicamentos_busca USING fts5(
+		medicamento_id UNINDEXED, nome, fabricante, principio_ativo, codigo_anvisa, codigo_barras,
+		tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3')`); err != nil {
+		return err
+	}
+	_, err := db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS medicamentos_busca_palavras USING fts5vocab(medicamentos_busca, 'row')")
+	return err
+}
+
+func (buscaFTS5) descartar(db Executor) error {
+	if _, err := db.Exec("DROP TABLE IF EXISTS medicamentos_busca_palavras"); err != nil {
+		return err
+	}
+	_, err := db.Exec("DROP TABLE IF EXISTS medicamentos_busca")
+	return err
+}
+
+func (buscaFTS5) gravar(db Executor, doc documentoBusca) error {
+	_, err := db.Exec(`INSERT INTO medicamentos_busca (medicamento_id, nome, fabricante, principio_ativo, codigo_anvisa, codigo_barras)
+		VALUES (?, ?, ?, ?, ?, ?)`, doc.MedicamentoID, doc.Nome, doc.Fabricante, doc.PrincipioAtivo, doc.CodigoANVISA, doc.CodigosBarras)
+	return err
+}
+
+func (buscaFTS5) palavras(db Executor) ([]string, error) {
+	return listarPalavras(db, "SELECT term FROM medicamentos_busca_palavras")
+}
+
+// subconsulta usa o bm25 com o nome pesando mais que os códigos e o princípio ativo, e estes mais
+// que o fabricante. O bm25 é menor para os melhores resultados, por isso o sinal invertido.
+func (buscaFTS5) subconsulta(grupos [][]alternativaBusca) (string, []interface{}) {
+	e := make([]string, len(grupos))
+	for i, grupo := range grupos {
+		alternativas := make([]string, len(grupo))
+		for j, a := range grupo {
+			alternativas[j] = `"` + a.Palavra + `"`
+			if a.Prefixo {
+				alternativas[j] += "*"
+			}
+		}
+		e[i] = "(" + strings.Join(alternativas, " OR ") + ")"
+	}
+	return `SELECT medicamento_id, -bm25(medicamentos_busca, 0, 10, 2, 5, 5, 5) AS relevancia
+		FROM medicamentos_busca WHERE medicamentos_busca MATCH ?`, []interface{}{strings.Join(e, " AND ")}
+}
+
+// buscaPostgres usa um tsvector com a configuração simple, já que os textos chegam normalizados,
+// e pesos A (nome), B (princípio ativo e códigos) e C (fabricante)
+type buscaPostgres struct{}
+
+func (buscaPostgres) criar(db Executor) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS medicamentos_busca (
+		medicamento_id TEXT PRIMARY KEY,
+		documento TSVECTOR NOT NULL
+	)`); err != nil {
+		return err
+	}
+	_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_medicamentos_busca_documento ON medicamentos_busca USING GIN (documento)")
+	return err
+}
+
+func (buscaPostgres) descartar(db Executor) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS medicamentos_busca")
+	return err
+}
+
+func (buscaPostgres) gravar(db Executor, doc documentoBusca) error {
+	_, err := db.Exec(`INSERT INTO medicamentos_busca (medicamento_id, documento) VALUES (?,
+		setweight(to_tsvector('simple', ?), 'A') || setweight(to_tsvector('simple', ?), 'B') ||
+		setweight(to_tsvector('simple', ?), 'B') || setweight(to_tsvector('simple', ?), 'C'))`,
+		doc.MedicamentoID, doc.Nome, doc.PrincipioAtivo, doc.CodigoANVISA+" "+doc.CodigosBarras, doc.Fabricante)
+	return err
+}
+
+func (buscaPostgres) palavras(db Executor) ([]string, error) {
+	return listarPalavras(db, "SELECT word FROM ts_stat('SELECT documento FROM medicamentos_busca')")
+}
+
+func (buscaPostgres) subconsulta(grupos [][]alternativaBusca) (string, []interface{}) {
+	e := make([]string, len(grupos))
+	for i, grupo := range grupos {
+		alternativas := make([]string, len(grupo))
+		for j, a := range grupo {
+			alternativas[j] = a.Palavra
+			if a.Prefixo {
+				alternativas[j] += ":*"
+			}
+		}
+		e[i] = "(" + strings.Join(alternativas, " | ") + ")"
+	}
+	consulta := strings.Join(e, " & ")
+	return `SELECT medicamento_id, ts_rank(documento, to_tsquery('simple', ?)) AS relevancia
+		FROM medicamentos_busca WHERE documento @@ to_tsquery('simple', ?)`, []interface{}{consulta, consulta}
+}
+
+func listarPalavras(db Executor, query string) ([]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler as palavras do índice de busca: %w", err)
+	}
+	defer rows.Close()
+
+	var palavras []string
+	for rows.Next() {
+		var palavra string
+		if err := rows.Scan(&palavra); err != nil {
+			return nil, err
+		}
+		palavras = append(palavras, palavra)
+	}
+	return palavras, rows.Err()
+}
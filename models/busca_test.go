@@ -0,0 +1,131 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPalavrasBusca(t *testing.T) {
+	assert.Equal(t, []string{"dipirona", "sodica", "500mg"}, palavrasBusca("Dipirona Sódica 500mg"))
+	assert.Equal(t, []string{"acao", "pediatrica"}, palavrasBusca("  AÇÃO, pediátrica! "))
+	assert.Equal(t, []string{"1", "0573", "0001"}, palavrasBusca("1.0573.0001"))
+	assert.Empty(t, palavrasBusca(" - / "))
+}
+
+func TestDistanciaEdicao(t *testing.T) {
+	assert.Equal(t, 0, distanciaEdicao([]rune("dipirona"), []rune("dipirona")))
+	assert.Equal(t, 1, distanciaEdicao([]rune("dipirna"), []rune("dipirona")))  // letra faltando
+	assert.Equal(t, 1, distanciaEdicao([]rune("dipriona"), []rune("dipirona"))) // letras invertidas
+	assert.Equal(t, 2, distanciaEdicao([]rune("dypyrona"), []rune("dipirona")))
+
+	// Digitando, com erro no começo da palavra
+	assert.Equal(t, 1, distanciaPrefixo("amoxcil", "amoxicilina", 2))
+	assert.Equal(t, 3, distanciaPrefixo("paracetamol", "dipirona", 2))
+}
+
+func TestExpandirBusca(t *testing.T) {
+	vocabulario := []string{"dipirona", "sodica", "dipropionato", "500mg", "ems"}
+
+	// Palavras que começam alguma do índice não recebem alternativas
+	assert.Equal(t, [][]alternativaBusca{
+		{{Palavra: "dipi", Prefixo: true}},
+		{{Palavra: "sod", Prefixo: true}},
+	}, expandirBusca([]string{"dipi", "sod"}, vocabulario))
+
+	assert.Equal(t, [][]alternativaBusca{
+		{{Palavra: "dipirna", Prefixo: true}, {Palavra: "dipirona"}},
+		{{Palavra: "sodca", Prefixo: true}, {Palavra: "sodica"}},
+	}, expandirBusca([]string{"dipirna", "sodca"}, vocabulario))
+
+	// Palavras curtas não toleram erros
+	assert.Equal(t, [][]alternativaBusca{{{Palavra: "emx", Prefixo: true}}}, expandirBusca([]string{"emx"}, vocabulario))
+}
+
+func TestBuscaTextualMedicamentos(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if b.dialeto.indiceBusca() == nil {
+		t.Skip("SQLite compilado sem FTS5; rode com -tags sqlite_fts5")
+	}
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+
+	cadastrar := func(id, nome, fabricante, codigo string) *Medicamento {
+		med := &Medicamento{ID: id, Nome: nome, Fabricante: fabricante, CodigoANVISA: codigo, Quantidade: 10, CriadoEm: time.Now()}
+		if err := b.repos.Medicamentos.Inserir(b.db, med); err != nil {
+			t.Fatalf("erro ao inserir medicamento %s: %v", nome, err)
+		}
+		return med
+	}
+	cadastrar("1", "Dipirona Sódica 500mg", "Medley", "1.0573.0001")
+	cadastrar("2", "Novalgina", "Sanofi", "1.8326.0002")
+	amoxicilina := cadastrar("3", "Amoxicilina 500mg", "EMS", "1.0235.0100")
+	cadastrar("4", "Loratadina 10mg", "Dipirona Genéricos", "1.0497.0030")
+
+	buscar := func(termo string) []string {
+		t.Helper()
+		pagina, err := b.BuscarMedicamentos(FiltroMedicamentos{Termo: termo})
+		if !assert.NoError(t, err, termo) {
+			return nil
+		}
+		var ids []string
+		for _, med := range pagina.Medicamentos {
+			ids = append(ids, med.ID)
+		}
+		return ids
+	}
+
+	// Sem acentos e sem maiúsculas; o nome pesa mais que o fabricante
+	assert.Equal(t, []string{"1", "4"}, buscar("DIPIRONA"))
+	assert.Equal(t, []string{"1"}, buscar("dipirona sodica"))
+	// Começo das palavras, inclusive dos códigos
+	assert.Equal(t, []string{"3"}, buscar("amox 500"))
+	assert.Equal(t, []string{"2"}, buscar("1.8326"))
+	// Erros de digitação
+	assert.Equal(t, []string{"1"}, buscar("dipirna sodca"))
+	assert.Equal(t, []string{"3"}, buscar("amoxcilina"))
+	assert.Empty(t, buscar("ibuprofeno"))
+
+	// O índice acompanha alterações e exclusões
+	amoxicilina.Nome = "Amoxicilina + Clavulanato 875mg"
+	amoxicilina.CodigosBarras = []string{"7891234567895"}
+	if !assert.NoError(t, b.repos.Medicamentos.Atualizar(b.db, amoxicilina)) {
+		return
+	}
+	assert.Equal(t, []string{"3"}, buscar("clavulanato"))
+	assert.Empty(t, buscar("amoxicilina 500"))
+	assert.Equal(t, []string{"3"}, buscar("7891234"))
+	// O princípio ativo encontra o medicamento pelo nome da substância
+	dipirona := b.GetMedicamento("1")
+	dipirona.PrincipiosAtivos = []PrincipioAtivo{{Nome: "Metamizol Sódico", Concentracao: "500 mg", Forma: "comprimido"}}
+	if !assert.NoError(t, b.UpdateMedicamento(dipirona, 0)) {
+		return
+	}
+	assert.Equal(t, []string{"1"}, buscar("metamizol"))
+	if !assert.NoError(t, b.repos.Medicamentos.Excluir(b.db, "2")) {
+		return
+	}
+	assert.Empty(t, buscar("novalgina"))
+
+	// Medicamentos gravados sem passar pelo repositório entram na reconstrução do índice
+	if _, err := b.db.Exec(`INSERT INTO medicamentos (ID, Nome, Quantidade, CriadoEm) VALUES ('5', 'Cetoprofeno 100mg', 10, ?)`, time.Now()); err != nil {
+		t.Fatalf("erro ao inserir medicamento: %v", err)
+	}
+	assert.Empty(t, buscar("cetoprofeno"))
+	if !assert.NoError(t, b.prepararIndiceBusca()) {
+		return
+	}
+	assert.Equal(t, []string{"5"}, buscar("cetoprofeno"))
+
+	// Sem a tabela de medicamentos, o índice é descartado
+	if _, err := b.ReverterMigracoes(len(b.queries.GetMigrations())); !assert.NoError(t, err) {
+		return
+	}
+	var tabelas int
+	if assert.NoError(t, b.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name LIKE 'medicamentos_busca%'").Scan(&tabelas)) {
+		assert.Equal(t, 0, tabelas)
+	}
+}
@@ -0,0 +1,515 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Status da sessão de caixa
+const (
+	StatusCaixaAberto  = "aberto"
+	StatusCaixaFechado = "fechado"
+)
+
+// Tipos de movimento de caixa fora das vendas
+const (
+	MovimentoSangria    = "sangria"    // retirada de dinheiro
+	MovimentoSuprimento = "suprimento" // reforço de dinheiro
+	MovimentoDevolucao  = "devolucao"  // valor devolvido ao cliente em cancelamentos e devoluções, por forma de pagamento
+)
+
+var (
+	// ErrCaixaFechado indica que o usuário não tem caixa aberto
+	ErrCaixaFechado = errors.New("nenhum caixa aberto para o usuário")
+	// ErrCaixaJaAberto indica que o usuário já tem um caixa aberto
+	ErrCaixaJaAberto = errors.New("o usuário já possui um caixa aberto")
+	// ErrCaixaNaoEncontrado indica que a sessão de caixa não existe
+	ErrCaixaNaoEncontrado = errors.New("caixa não encontrado")
+	// ErrMovimentoCaixaInvalido indica valores inválidos em abertura, sangria ou suprimento
+	ErrMovimentoCaixaInvalido = errors.New("movimento de caixa inválido")
+)
+
+// Caixa é uma sessão de caixa de um usuário, da abertura ao fechamento
+type Caixa struct {
+	ID            int64      `json:"id"`
+	UsuarioID     int        `json:"usuario_id"`
+	Username      string     `json:"username"`
+	Status        string     `json:"status"`
+	AbertoEm      time.Time  `json:"aberto_em"`
+	ValorAbertura float64    `json:"valor_abertura"`
+	FechadoEm     *time.Time `json:"fechado_em,omitempty"`
+	Observacao    string     `json:"observacao,omitempty"`
+}
+
+// MovimentoCaixa é uma sangria, suprimento ou devolução ao cliente
+type MovimentoCaixa struct {
+	ID        int64     `json:"id"`
+	CaixaID   int64     `json:"caixa_id"`
+	Tipo      string    `json:"tipo"`
+	Forma     string    `json:"forma"` // Dinheiro nas sangrias e suprimentos; nas devoluções, a forma em que a venda foi paga
+	Valor     float64   `json:"valor"`
+	Motivo    string    `json:"motivo,omitempty"`
+	VendaID   int64     `json:"venda_id,omitempty"`
+	UsuarioID int       `json:"usuario_id"`
+	Data      time.Time `json:"data"`
+}
+
+// ResumoFormaCaixa compara, para uma forma de pagamento, o valor esperado com o contado no fechamento
+type ResumoFormaCaixa struct {
+	Forma     string   `json:"forma"`
+	Esperado  float64  `json:"esperado"`
+	Contado   *float64 `json:"contado,omitempty"`
+	Diferenca *float64 `json:"diferenca,omitempty"`
+}
+
+// ResumoCaixa consolida as vendas e movimentos de uma sessão de caixa
+type ResumoCaixa struct {
+	Caixa
+	QuantidadeVendas int                `json:"quantidade_vendas"`
+	TotalVendas      float64            `json:"total_vendas"`
+	Troco            float64            `json:"troco"`
+	Suprimentos      float64            `json:"suprimentos"`
+	Sangrias         float64            `json:"sangrias"`
+	Devolucoes       float64            `json:"devolucoes"` // Todas as formas; o esperado de cada forma desconta a sua parte
+	Formas           []ResumoFormaCaixa `json:"formas"`
+	Movimentos       []MovimentoCaixa   `json:"movimentos"`
+}
+
+// FechamentoCaixaRequest traz o valor contado em cada forma de pagamento
+type FechamentoCaixaRequest struct {
+	Contagem   map[string]float64 `json:"contagem"`
+	Observacao string             `json:"observacao"`
+}
+
+// formasCaixa é a ordem em que as formas de pagamento aparecem no resumo
+var formasCaixa = []string{FormaDinheiro, FormaCartaoDebito, FormaCartaoCredito, FormaPix, FormaConvenio}
+
+// AbrirCaixa abre uma sessão de caixa para o usuário com o valor inicial em dinheiro (fundo de troco).
+func (b *Banco) AbrirCaixa(usuarioID int, valorAbertura float64) (*Caixa, error) {
+	if valorAbertura < 0 {
+		return nil, fmt.Errorf("%w: o valor de abertura não pode ser negativo", ErrMovimentoCaixaInvalido)
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := b.caixaAbertoID(tx, usuarioID); err == nil {
+		return nil, ErrCaixaJaAberto
+	} else if !errors.Is(err, ErrCaixaFechado) {
+		return nil, err
+	}
+
+	query := b.queries.GetQuery(qInserirCaixa)
+	if query == "" {
+		return nil, errors.New("query 'inserir_caixa' não encontrada")
+	}
+	caixa := &Caixa{UsuarioID: usuarioID, Status: StatusCaixaAberto, AbertoEm: b.Agora(), ValorAbertura: arredondar(valorAbertura)}
+	caixa.ID, err = b.inserirComID(tx, query, caixa.UsuarioID, caixa.Status, caixa.AbertoEm, caixa.ValorAbertura)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir caixa: %w", err)
+	}
+
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "caixa", strconv.FormatInt(caixa.ID, 10), nil, caixa); err != nil {
+		return nil, err
+	}
+	return caixa, tx.Commit()
+}
+
+// RegistrarMovimentoCaixa lança uma sangria ou suprimento no caixa aberto do usuário.
+// A sangria não pode ser maior que o dinheiro esperado no caixa.
+func (b *Banco) RegistrarMovimentoCaixa(usuarioID int, tipo string, valor float64, motivo string) (*MovimentoCaixa, error) {
+	if tipo != MovimentoSangria && tipo != MovimentoSuprimento {
+		return nil, fmt.Errorf("%w: tipo '%s'", ErrMovimentoCaixaInvalido, tipo)
+	}
+	valor = arredondar(valor)
+	if valor <= 0 {
+		return nil, fmt.Errorf("%w: o valor deve ser maior que zero", ErrMovimentoCaixaInvalido)
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	caixaID, err := b.caixaAbertoID(tx, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+
+	if tipo == MovimentoSangria {
+		resumo, err := b.resumoCaixa(tx, caixaID)
+		if err != nil {
+			return nil, err
+		}
+		if dinheiro := resumo.Formas[0].Esperado; valor > dinheiro+0.001 {
+			return nil, fmt.Errorf("%w: sangria de R$ %.2f maior que o dinheiro em caixa (R$ %.2f)", ErrMovimentoCaixaInvalido, valor, dinheiro)
+		}
+	}
+
+	mov := &MovimentoCaixa{CaixaID: caixaID, Tipo: tipo, Forma: FormaDinheiro, Valor: valor, Motivo: motivo, UsuarioID: usuarioID}
+	if err := b.inserirMovimentoCaixa(tx, mov); err != nil {
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "caixa_movimento", strconv.FormatInt(mov.ID, 10), nil, mov); err != nil {
+		return nil, err
+	}
+	return mov, tx.Commit()
+}
+
+// FecharCaixa encerra o caixa aberto do usuário, gravando o valor contado em cada forma de pagamento,
+// e retorna o resumo com o esperado, o contado e a diferença.
+func (b *Banco) FecharCaixa(usuarioID int, req FechamentoCaixaRequest) (*ResumoCaixa, error) {
+	for forma, valor := range req.Contagem {
+		if !FormaPagamentoValida(forma) {
+			return nil, fmt.Errorf("%w: forma de pagamento '%s' não aceita", ErrMovimentoCaixaInvalido, forma)
+		}
+		if valor < 0 {
+			return nil, fmt.Errorf("%w: valor contado em %s não pode ser negativo", ErrMovimentoCaixaInvalido, forma)
+		}
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	caixaID, err := b.caixaAbertoID(tx, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	resumo, err := b.resumoCaixa(tx, caixaID)
+	if err != nil {
+		return nil, err
+	}
+
+	queryFechamento := b.queries.GetQuery(qInserirCaixaFechamento)
+	if queryFechamento == "" {
+		return nil, errors.New("query 'inserir_caixa_fechamento' não encontrada")
+	}
+	for i := range resumo.Formas {
+		f := &resumo.Formas[i]
+		contado := arredondar(req.Contagem[f.Forma])
+		diferenca := arredondar(contado - f.Esperado)
+		f.Contado, f.Diferenca = &contado, &diferenca
+		if _, err := tx.Exec(queryFechamento, caixaID, f.Forma, f.Esperado, contado, diferenca); err != nil {
+			return nil, fmt.Errorf("erro ao gravar fechamento em %s: %w", f.Forma, err)
+		}
+	}
+
+	query := b.queries.GetQuery(qFecharCaixa)
+	if query == "" {
+		return nil, errors.New("query 'fechar_caixa' não encontrada")
+	}
+	agora := b.Agora()
+	if _, err := tx.Exec(query, StatusCaixaFechado, agora, usuarioID, req.Observacao, caixaID); err != nil {
+		return nil, fmt.Errorf("erro ao fechar caixa: %w", err)
+	}
+	antes := map[string]interface{}{"status": StatusCaixaAberto}
+	depois := map[string]interface{}{"status": StatusCaixaFechado, "formas": resumo.Formas, "observacao": req.Observacao}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "caixa", strconv.FormatInt(caixaID, 10), antes, depois); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	resumo.Status = StatusCaixaFechado
+	resumo.FechadoEm = &agora
+	resumo.Observacao = req.Observacao
+	return resumo, nil
+}
+
+// GetCaixaAtual retorna o resumo parcial do caixa aberto do usuário.
+func (b *Banco) GetCaixaAtual(usuarioID int) (*ResumoCaixa, error) {
+	caixaID, err := b.caixaAbertoID(b.db, usuarioID)
+	if err != nil {
+		return nil, err
+	}
+	return b.resumoCaixa(b.db, caixaID)
+}
+
+// GetResumoCaixa retorna o resumo de uma sessão de caixa, com o contado no fechamento se já estiver fechada.
+func (b *Banco) GetResumoCaixa(caixaID int64) (*ResumoCaixa, error) {
+	return b.resumoCaixa(b.db, caixaID)
+}
+
+// ListarCaixas retorna as sessões de caixa mais recentes, opcionalmente de um usuário.
+func (b *Banco) ListarCaixas(usuarioID, limite int) ([]Caixa, error) {
+	query := `
+		SELECT c.id, c.usuario_id, COALESCE(u.username, ''), c.status, c.aberto_em, c.valor_abertura, c.fechado_em, c.observacao
+		FROM caixas c
+		LEFT JOIN usuarios u ON u.id = c.usuario_id
+		WHERE 1 = 1`
+	var args []interface{}
+	if usuarioID != 0 {
+		query += " AND c.usuario_id = ?"
+		args = append(args, usuarioID)
+	}
+	query += " ORDER BY c.aberto_em DESC, c.id DESC"
+	if limite > 0 {
+		query += " LIMIT ?"
+		args = append(args, limite)
+	}
+
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var caixas []Caixa
+	for rows.Next() {
+		c, err := scanCaixa(rows)
+		if err != nil {
+			return nil, err
+		}
+		caixas = append(caixas, *c)
+	}
+	return caixas, rows.Err()
+}
+
+// caixaAbertoID retorna o caixa aberto do usuário ou ErrCaixaFechado.
+func (b *Banco) caixaAbertoID(db execer, usuarioID int) (int64, error) {
+	query := b.queries.GetQuery(qSelecionarCaixaAberto)
+	if query == "" {
+		return 0, errors.New("query 'selecionar_caixa_aberto' não encontrada")
+	}
+	var id int64
+	err := db.QueryRow(query, usuarioID, StatusCaixaAberto).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrCaixaFechado
+	}
+	return id, err
+}
+
+// inserirMovimentoCaixa grava um movimento de caixa.
+func (b *Banco) inserirMovimentoCaixa(tx *sql.Tx, mov *MovimentoCaixa) error {
+	query := b.queries.GetQuery(qInserirCaixaMovimento)
+	if query == "" {
+		return errors.New("query 'inserir_caixa_movimento' não encontrada")
+	}
+	mov.Data = b.Agora()
+	id, err := b.inserirComID(tx, query, mov.CaixaID, mov.Tipo, mov.Valor, mov.Motivo, sql.NullInt64{Int64: mov.VendaID, Valid: mov.VendaID != 0}, mov.UsuarioID, mov.Data, mov.Forma)
+	if err != nil {
+		return fmt.Errorf("erro ao registrar %s no caixa: %w", mov.Tipo, err)
+	}
+	mov.ID = id
+	return nil
+}
+
+// registrarDevolucaoCaixa lança o valor estornado ao cliente, rateado entre as formas em que a venda foi
+// paga (o dinheiro já sem o troco), no caixa da venda ou, se ele já foi fechado, no caixa aberto de quem
+// faz o estorno. Sem nenhum dos dois retorna ErrCaixaFechado: o estorno não pode sair sem caixa.
+func (b *Banco) registrarDevolucaoCaixa(tx *sql.Tx, usuarioID int, vendaID int64, valor float64, motivo string) error {
+	if valor <= 0 {
+		return nil
+	}
+	query, err := b.queryObrigatoria(qSelecionarCaixaVenda)
+	if err != nil {
+		return err
+	}
+	var caixaVenda sql.NullInt64
+	var statusCaixa sql.NullString
+	var troco float64
+	if err := tx.QueryRow(query, vendaID).Scan(&caixaVenda, &statusCaixa, &troco); err != nil {
+		return fmt.Errorf("erro ao buscar o caixa da venda %d: %w", vendaID, err)
+	}
+	caixaID := caixaVenda.Int64
+	if !caixaVenda.Valid || statusCaixa.String != StatusCaixaAberto {
+		if caixaID, err = b.caixaAbertoID(tx, usuarioID); err != nil {
+			if errors.Is(err, ErrCaixaFechado) {
+				return fmt.Errorf("%w: o caixa da venda %d já foi fechado; abra um caixa para estornar R$ %.2f", err, vendaID, valor)
+			}
+			return err
+		}
+	}
+
+	pagamentos, err := b.pagamentosVenda(tx, vendaID)
+	if err != nil {
+		return err
+	}
+	pago := make(map[string]float64)
+	for _, p := range pagamentos {
+		pago[p.Forma] += p.Valor
+	}
+	pago[FormaDinheiro] -= troco
+	var formas []string
+	var valores []float64
+	for _, forma := range formasCaixa {
+		if pago[forma] > 0.001 {
+			formas = append(formas, forma)
+			valores = append(valores, pago[forma])
+		}
+	}
+	// Vendas sem pagamentos registrados foram recebidas em dinheiro
+	if len(formas) == 0 {
+		formas, valores = []string{FormaDinheiro}, []float64{valor}
+	}
+
+	for i, parte := range ratear(valores, arredondar(valor)) {
+		if parte <= 0 {
+			continue
+		}
+		mov := &MovimentoCaixa{CaixaID: caixaID, Tipo: MovimentoDevolucao, Forma: formas[i], Valor: parte, Motivo: motivo, VendaID: vendaID, UsuarioID: usuarioID}
+		if err := b.inserirMovimentoCaixa(tx, mov); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanCaixa(row scanner) (*Caixa, error) {
+	var c Caixa
+	var fechadoEm sql.NullTime
+	var observacao sql.NullString
+	if err := row.Scan(&c.ID, &c.UsuarioID, &c.Username, &c.Status, &c.AbertoEm, &c.ValorAbertura, &fechadoEm, &observacao); err != nil {
+		return nil, err
+	}
+	if fechadoEm.Valid {
+		c.FechadoEm = &fechadoEm.Time
+	}
+	c.Observacao = observacao.String
+	return &c, nil
+}
+
+// resumoCaixa calcula o esperado por forma de pagamento: os pagamentos recebidos menos as devoluções
+// naquela forma e, no dinheiro, mais a abertura e os suprimentos, menos o troco e as sangrias.
+func (b *Banco) resumoCaixa(db execer, caixaID int64) (*ResumoCaixa, error) {
+	query := b.queries.GetQuery(qSelecionarCaixaPorId)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_caixa_por_id' não encontrada")
+	}
+	caixa, err := scanCaixa(db.QueryRow(query, caixaID))
+	if err == sql.ErrNoRows {
+		return nil, ErrCaixaNaoEncontrado
+	}
+	if err != nil {
+		return nil, err
+	}
+	resumo := &ResumoCaixa{Caixa: *caixa, Movimentos: []MovimentoCaixa{}}
+
+	// Vendas da sessão
+	queryVendas := b.queries.GetQuery(qResumirVendasCaixa)
+	if queryVendas == "" {
+		return nil, errors.New("query 'resumir_vendas_caixa' não encontrada")
+	}
+	if err := db.QueryRow(queryVendas, caixaID).Scan(&resumo.QuantidadeVendas, &resumo.TotalVendas, &resumo.Troco); err != nil {
+		return nil, fmt.Errorf("erro ao resumir vendas do caixa: %w", err)
+	}
+
+	// Pagamentos recebidos por forma
+	queryPagamentos := b.queries.GetQuery(qSomarPagamentosCaixa)
+	if queryPagamentos == "" {
+		return nil, errors.New("query 'somar_pagamentos_caixa' não encontrada")
+	}
+	rows, err := db.Query(queryPagamentos, caixaID)
+	if err != nil {
+		return nil, err
+	}
+	recebido := make(map[string]float64)
+	for rows.Next() {
+		var forma string
+		var valor float64
+		if err := rows.Scan(&forma, &valor); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		recebido[forma] = valor
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sangrias, suprimentos e devoluções
+	queryMovimentos := b.queries.GetQuery(qSelecionarMovimentosCaixa)
+	if queryMovimentos == "" {
+		return nil, errors.New("query 'selecionar_movimentos_caixa' não encontrada")
+	}
+	rows, err = db.Query(queryMovimentos, caixaID)
+	if err != nil {
+		return nil, err
+	}
+	devolvido := make(map[string]float64)
+	for rows.Next() {
+		var m MovimentoCaixa
+		var motivo, forma sql.NullString
+		var vendaID sql.NullInt64
+		if err := rows.Scan(&m.ID, &m.CaixaID, &m.Tipo, &m.Valor, &motivo, &vendaID, &m.UsuarioID, &m.Data, &forma); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("erro ao escanear movimento de caixa: %w", err)
+		}
+		m.Motivo = motivo.String
+		m.VendaID = vendaID.Int64
+		m.Forma = forma.String
+		if m.Forma == "" {
+			m.Forma = FormaDinheiro
+		}
+		switch m.Tipo {
+		case MovimentoSangria:
+			resumo.Sangrias += m.Valor
+		case MovimentoSuprimento:
+			resumo.Suprimentos += m.Valor
+		case MovimentoDevolucao:
+			resumo.Devolucoes += m.Valor
+			devolvido[m.Forma] += m.Valor
+		}
+		resumo.Movimentos = append(resumo.Movimentos, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Valores contados no fechamento, se houver
+	contado := make(map[string]float64)
+	if resumo.Status == StatusCaixaFechado {
+		queryFechamento := b.queries.GetQuery(qSelecionarCaixaFechamentos)
+		if queryFechamento == "" {
+			return nil, errors.New("query 'selecionar_caixa_fechamentos' não encontrada")
+		}
+		rows, err = db.Query(queryFechamento, caixaID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var forma string
+			var valor float64
+			if err := rows.Scan(&forma, &valor); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			contado[forma] = valor
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, forma := range formasCaixa {
+		f := ResumoFormaCaixa{Forma: forma, Esperado: recebido[forma] - devolvido[forma]}
+		if forma == FormaDinheiro {
+			f.Esperado += resumo.ValorAbertura + resumo.Suprimentos - resumo.Sangrias - resumo.Troco
+		}
+		f.Esperado = arredondar(f.Esperado)
+		if valor, ok := contado[forma]; ok {
+			diferenca := arredondar(valor - f.Esperado)
+			f.Contado, f.Diferenca = &valor, &diferenca
+		}
+		resumo.Formas = append(resumo.Formas, f)
+	}
+	resumo.TotalVendas = arredondar(resumo.TotalVendas)
+	resumo.Sangrias = arredondar(resumo.Sangrias)
+	resumo.Suprimentos = arredondar(resumo.Suprimentos)
+	resumo.Devolucoes = arredondar(resumo.Devolucoes)
+	return resumo, nil
+}
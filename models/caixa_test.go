@@ -0,0 +1,182 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// esperadoCaixa retorna o esperado da forma no resumo do caixa.
+func esperadoCaixa(resumo *ResumoCaixa, forma string) float64 {
+	for _, f := range resumo.Formas {
+		if f.Forma == forma {
+			return f.Esperado
+		}
+	}
+	return 0
+}
+
+func TestAbrirEFecharCaixa(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Dipirona 500mg", Quantidade: 10, Preco: 10}, 0)) {
+		return
+	}
+
+	_, err := b.AbrirCaixa(1, -10)
+	assert.ErrorIs(t, err, ErrMovimentoCaixaInvalido)
+	caixa, err := b.AbrirCaixa(1, 100)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = b.AbrirCaixa(1, 50)
+	assert.Error(t, err, "um caixa aberto por usuário")
+
+	if _, err := b.RegistrarMovimentoCaixa(1, MovimentoSuprimento, 20, "moedas"); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := b.RegistrarMovimentoCaixa(1, MovimentoSangria, 50, "depósito"); !assert.NoError(t, err) {
+		return
+	}
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{
+		Itens:      []ItemVendaRequest{{MedicamentoID: 1, Quantidade: 3}},
+		Pagamentos: []PagamentoVenda{{Forma: FormaDinheiro, Valor: 50}},
+	}, 1, RoleAtendente)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{
+		Itens:      []ItemVendaRequest{{MedicamentoID: 1, Quantidade: 2}},
+		Pagamentos: []PagamentoVenda{{Forma: FormaCartaoDebito, Valor: 20}},
+	}, 1, RoleAtendente)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	atual, err := b.GetCaixaAtual(1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, caixa.ID, atual.ID)
+	assert.Equal(t, 2, atual.QuantidadeVendas)
+	assert.Equal(t, 50.0, atual.TotalVendas)
+	assert.Equal(t, 20.0, atual.Troco)
+	assert.Len(t, atual.Movimentos, 2)
+	// 100 de abertura + 20 de suprimento - 50 de sangria + 50 recebidos - 20 de troco
+	assert.Equal(t, 100.0, esperadoCaixa(atual, FormaDinheiro))
+	assert.Equal(t, 20.0, esperadoCaixa(atual, FormaCartaoDebito))
+
+	_, err = b.FecharCaixa(1, FechamentoCaixaRequest{Contagem: map[string]float64{"cheque": 10}})
+	assert.ErrorIs(t, err, ErrMovimentoCaixaInvalido)
+	fechado, err := b.FecharCaixa(1, FechamentoCaixaRequest{
+		Contagem:   map[string]float64{FormaDinheiro: 95, FormaCartaoDebito: 20},
+		Observacao: "faltaram 5 reais",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, StatusCaixaFechado, fechado.Status)
+	for _, f := range fechado.Formas {
+		switch f.Forma {
+		case FormaDinheiro:
+			assert.Equal(t, -5.0, *f.Diferenca)
+		case FormaCartaoDebito:
+			assert.Equal(t, 0.0, *f.Diferenca)
+		}
+	}
+
+	// Depois de fechado, o resumo traz o contado no fechamento e o caixa não recebe mais movimentos
+	resumo, err := b.GetResumoCaixa(caixa.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatusCaixaFechado, resumo.Status)
+		assert.Equal(t, 100.0, esperadoCaixa(resumo, FormaDinheiro))
+	}
+	_, err = b.GetCaixaAtual(1)
+	assert.ErrorIs(t, err, ErrCaixaFechado)
+	_, err = b.RegistrarMovimentoCaixa(1, MovimentoSangria, 10, "")
+	assert.ErrorIs(t, err, ErrCaixaFechado)
+}
+
+func TestDevolucaoNoCaixaPorForma(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Dipirona 500mg", Quantidade: 20, Preco: 10}, 0)) {
+		return
+	}
+	if _, err := b.AbrirCaixa(1, 100); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+	if _, err := b.AbrirCaixa(2, 50); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+	vender := func(quantidade int, pagamentos ...PagamentoVenda) int64 {
+		resumo, err := b.RegistrarVenda(RegistrarVendaRequest{
+			Itens:      []ItemVendaRequest{{MedicamentoID: 1, Quantidade: quantidade}},
+			Pagamentos: pagamentos,
+		}, 1, RoleAtendente)
+		if err != nil {
+			t.Fatalf("erro ao registrar venda: %v", err)
+		}
+		return resumo.VendaID
+	}
+
+	// Venda de 30,00 paga com 10,00 em PIX e 50,00 em dinheiro (30,00 de troco): o estorno devolve
+	// 10,00 pelo PIX e 20,00 em dinheiro, no caixa da venda, mesmo cancelada por outro usuário
+	pix := vender(3, PagamentoVenda{Forma: FormaPix, Valor: 10}, PagamentoVenda{Forma: FormaDinheiro, Valor: 50})
+	valor, err := b.CancelarVenda(pix, "desistência", 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 30.0, valor)
+	}
+	resumo, err := b.GetCaixaAtual(1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 30.0, resumo.Devolucoes)
+	assert.Equal(t, 100.0, esperadoCaixa(resumo, FormaDinheiro))
+	assert.Equal(t, 0.0, esperadoCaixa(resumo, FormaPix))
+	outro, err := b.GetCaixaAtual(2)
+	if assert.NoError(t, err) {
+		assert.Empty(t, outro.Movimentos)
+	}
+
+	// Venda no cartão não gera falta de dinheiro ao ser devolvida
+	cartao := vender(2, PagamentoVenda{Forma: FormaCartaoCredito, Valor: 20})
+	if _, err := b.CancelarVenda(cartao, "", 1); !assert.NoError(t, err) {
+		return
+	}
+	resumo, err = b.GetCaixaAtual(1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 100.0, esperadoCaixa(resumo, FormaDinheiro))
+		assert.Equal(t, 0.0, esperadoCaixa(resumo, FormaCartaoCredito))
+	}
+
+	// Com o caixa da venda fechado, a devolução vai para o caixa aberto de quem cancela
+	dinheiro := vender(1, PagamentoVenda{Forma: FormaDinheiro, Valor: 10})
+	semCaixa := vender(1, PagamentoVenda{Forma: FormaDinheiro, Valor: 10})
+	if _, err := b.FecharCaixa(1, FechamentoCaixaRequest{}); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := b.CancelarVenda(dinheiro, "", 2); !assert.NoError(t, err) {
+		return
+	}
+	outro, err = b.GetCaixaAtual(2)
+	if assert.NoError(t, err) && assert.Len(t, outro.Movimentos, 1) {
+		assert.Equal(t, dinheiro, outro.Movimentos[0].VendaID)
+		assert.Equal(t, FormaDinheiro, outro.Movimentos[0].Forma)
+		assert.Equal(t, 40.0, esperadoCaixa(outro, FormaDinheiro))
+	}
+
+	// Sem nenhum caixa aberto o estorno é recusado e nada muda
+	_, err = b.CancelarVenda(semCaixa, "", 1)
+	assert.ErrorIs(t, err, ErrCaixaFechado)
+	venda, err := b.GetVenda(semCaixa)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, StatusVendaCancelada, venda.Status)
+	}
+}
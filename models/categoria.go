@@ -1,12 +1,8 @@
 package models
 
 import (
-	"database/sql"
-	"errors"
 	"log"
 
-	"medicontrol/sqlutils"
-
 	"github.com/google/uuid"
 )
 
@@ -16,32 +12,13 @@ type Categoria struct {
 	Nome string `json:"nome"`
 }
 
-// criarTabelaCategorias cria a tabela de categorias no banco de dados se ela não existir.
-func criarTabelaCategorias() error {
-	query := sqlutils.GetQuery("criar_tabela_categorias")
-	if query == "" {
-		log.Fatal("Query 'criar_tabela_categorias' não encontrada.")
-	}
-
-	_, err := sqlDB.Exec(query)
-	if err != nil {
-		log.Printf("Erro ao criar tabela 'categorias': %v", err)
-		return err
-	}
-	log.Println("Tabela 'categorias' verificada/criada com sucesso.")
-	return nil
-}
-
 // AddCategoria adiciona uma nova categoria e retorna seu ID.
-func AddCategoria(nome string) (string, error) {
+func (b *Banco) AddCategoria(nome string) (string, error) {
 	// Verificar se a categoria já existe
-	categoriaExistente, err := GetCategoriaByNome(nome)
+	categoriaExistente, err := b.GetCategoriaByNome(nome)
 	if err != nil {
-		// Se o erro não for 'não encontrado', temos um problema real
-		if err != sql.ErrNoRows {
-			log.Printf("Erro ao verificar existência da categoria '%s': %v", nome, err)
-			return "", err
-		}
+		log.Printf("Erro ao verificar existência da categoria '%s': %v", nome, err)
+		return "", err
 	}
 	if categoriaExistente != nil {
 		log.Printf("Categoria '%s' já existe com ID: %s. Não será adicionada novamente.", nome, categoriaExistente.ID)
@@ -53,13 +30,7 @@ func AddCategoria(nome string) (string, error) {
 		Nome: nome,
 	}
 
-	query := sqlutils.GetQuery("inserir_categoria")
-	if query == "" {
-		return "", errors.New("query 'inserir_categoria' não encontrada")
-	}
-
-	_, err = sqlDB.Exec(query, categoria.ID, categoria.Nome)
-	if err != nil {
+	if err := b.repos.Categorias.Inserir(b.db, &categoria); err != nil {
 		log.Printf("Erro ao inserir categoria '%s': %v", nome, err)
 		return "", err
 	}
@@ -68,47 +39,17 @@ func AddCategoria(nome string) (string, error) {
 	return categoria.ID, nil
 }
 
-// GetCategoriaByNome busca uma categoria pelo nome.
-func GetCategoriaByNome(nome string) (*Categoria, error) {
-	query := sqlutils.GetQuery("selecionar_categoria_por_nome")
-	if query == "" {
-		return nil, errors.New("query 'selecionar_categoria_por_nome' não encontrada")
-	}
-
-	var cat Categoria
-	err := sqlDB.QueryRow(query, nome).Scan(&cat.ID, &cat.Nome)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Não é um erro, apenas não encontrou
-		}
-		return nil, err
-	}
-	return &cat, nil
+// GetCategoriaByNome busca uma categoria pelo nome. Retorna nil, sem erro, se ela não existir.
+func (b *Banco) GetCategoriaByNome(nome string) (*Categoria, error) {
+	return b.repos.Categorias.BuscarPorNome(b.db, nome)
 }
 
 // GetAllCategorias retorna todas as categorias do banco de dados.
-func GetAllCategorias() ([]Categoria, error) {
-	query := sqlutils.GetQuery("selecionar_todas_categorias")
-	if query == "" {
-		return nil, errors.New("query 'selecionar_todas_categorias' não encontrada")
-	}
-
-	rows, err := sqlDB.Query(query)
+func (b *Banco) GetAllCategorias() ([]Categoria, error) {
+	categorias, err := b.repos.Categorias.Listar(b.db)
 	if err != nil {
 		log.Printf("Erro ao buscar categorias: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	var categorias []Categoria
-	for rows.Next() {
-		var cat Categoria
-		if err := rows.Scan(&cat.ID, &cat.Nome); err != nil {
-			log.Printf("Erro ao escanear linha da categoria: %v", err)
-			continue
-		}
-		categorias = append(categorias, cat)
-	}
-
 	return categorias, nil
 }
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AliquotaCMEDPadrao é a alíquota de ICMS usada quando a importação não informa outra.
+// A tabela CMED publica um PMC para cada alíquota; a farmácia usa a do seu estado.
+const AliquotaCMEDPadrao = "18"
+
+// ErrTabelaCMEDInvalida indica um arquivo que não pôde ser lido como tabela CMED
+var ErrTabelaCMEDInvalida = errors.New("tabela CMED inválida")
+
+// PrecoCMED é uma apresentação da tabela CMED com o PMC da alíquota escolhida
+type PrecoCMED struct {
+	Registro     string  `json:"registro"`
+	EAN          string  `json:"ean"`
+	Produto      string  `json:"produto"`
+	Apresentacao string  `json:"apresentacao"`
+	Laboratorio  string  `json:"laboratorio"`
+	PMC          float64 `json:"pmc"`
+}
+
+// ImportacaoCMED resume a carga de uma tabela CMED
+type ImportacaoCMED struct {
+	Aliquota    string    `json:"aliquota"`
+	Importados  int       `json:"importados"`
+	Ignorados   int       `json:"ignorados"` // Linhas sem registro ou sem PMC na alíquota
+	Violacoes   int       `json:"violacoes"` // Medicamentos cadastrados acima do novo PMC
+	ImportadoEm time.Time `json:"importado_em"`
+}
+
+// ViolacaoPMC é um medicamento cadastrado com preço de venda acima do PMC
+type ViolacaoPMC struct {
+	MedicamentoID   string  `json:"medicamento_id"`
+	MedicamentoNome string  `json:"medicamento_nome"`
+	CodigoANVISA    string  `json:"codigo_anvisa"`
+	Preco           float64 `json:"preco"`
+	PMC             float64 `json:"pmc"`
+	Excesso         float64 `json:"excesso"`
+	ProdutoCMED     string  `json:"produto_cmed"`
+	Apresentacao    string  `json:"apresentacao"`
+}
+
+// ParseCMED lê a tabela CMED exportada em CSV (separador ';', vírgula decimal), em UTF-8 ou Latin-1.
+// As linhas antes do cabeçalho (título e data de publicação) são ignoradas. aliquota escolhe a
+// coluna "PMC <aliquota>%"; ignorados conta as linhas sem registro ou sem PMC nessa coluna.
+func ParseCMED(data []byte, aliquota string) (precos []PrecoCMED, ignorados int, err error) {
+	if !utf8.Valid(data) {
+		data = latin1ParaUTF8(data)
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = ';'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	colunaPMC := "PMC" + strings.ReplaceAll(strings.TrimSuffix(strings.TrimSpace(aliquota), "%"), ".", ",") + "%"
+	colunas := map[string]int{}
+	for {
+		registro, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrTabelaCMEDInvalida, err)
+		}
+
+		if len(colunas) == 0 {
+			cabecalho := map[string]int{}
+			for i, nome := range registro {
+				cabecalho[normalizarColunaCMED(nome)] = i
+			}
+			if _, ok := cabecalho["REGISTRO"]; !ok {
+				continue
+			}
+			if _, ok := cabecalho[colunaPMC]; !ok {
+				return nil, 0, fmt.Errorf("%w: coluna 'PMC %s%%' não encontrada", ErrTabelaCMEDInvalida, aliquota)
+			}
+			colunas = cabecalho
+			continue
+		}
+
+		campo := func(nome string) string {
+			i, ok := colunas[nome]
+			if !ok || i >= len(registro) {
+				return ""
+			}
+			return strings.TrimSpace(registro[i])
+		}
+		pmc, ok := valorCMED(campo(colunaPMC))
+		reg := somenteDigitos(campo("REGISTRO"))
+		if reg == "" || !ok {
+			ignorados++
+			continue
+		}
+		precos = append(precos, PrecoCMED{
+			Registro:     reg,
+			EAN:          somenteDigitos(campo("EAN1")),
+			Produto:      campo("PRODUTO"),
+			Apresentacao: campo("APRESENTACAO"),
+			Laboratorio:  campo("LABORATORIO"),
+			PMC:          pmc,
+		})
+	}
+
+	if len(colunas) == 0 {
+		return nil, 0, fmt.Errorf("%w: cabeçalho com a coluna 'REGISTRO' não encontrado", ErrTabelaCMEDInvalida)
+	}
+	if len(precos) == 0 {
+		return nil, ignorados, fmt.Errorf("%w: nenhum produto com PMC %s%%", ErrTabelaCMEDInvalida, aliquota)
+	}
+	return precos, ignorados, nil
+}
+
+// ImportarTabelaCMED substitui a tabela CMED pelo conteúdo do arquivo. A partir da carga, novos preços
+// de venda acima do PMC são recusados; os já cadastrados acima dele aparecem no relatório de violações.
+func (b *Banco) ImportarTabelaCMED(data []byte, aliquota string, usuarioID int) (*ImportacaoCMED, error) {
+	if aliquota == "" {
+		aliquota = AliquotaCMEDPadrao
+	}
+	precos, ignorados, err := ParseCMED(data, aliquota)
+	if err != nil {
+		return nil, err
+	}
+
+	queryExcluir := b.queries.GetQuery(qExcluirCmedPrecos)
+	queryInserir := b.queries.GetQuery(qInserirCmedPreco)
+	if queryExcluir == "" || queryInserir == "" {
+		return nil, errors.New("queries da tabela CMED não encontradas")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(queryExcluir); err != nil {
+		return nil, err
+	}
+	stmt, err := tx.Prepare(queryInserir)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	resumo := &ImportacaoCMED{Aliquota: aliquota, Importados: len(precos), Ignorados: ignorados, ImportadoEm: b.Agora()}
+	for _, p := range precos {
+		if _, err := stmt.Exec(p.Registro, p.EAN, p.Produto, p.Apresentacao, p.Laboratorio, p.PMC, aliquota, resumo.ImportadoEm); err != nil {
+			return nil, fmt.Errorf("erro ao gravar o registro %s da tabela CMED: %w", p.Registro, err)
+		}
+	}
+
+	violacoes, err := b.violacoesPMC(tx)
+	if err != nil {
+		return nil, err
+	}
+	resumo.Violacoes = len(violacoes)
+
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "tabela_cmed", "", nil, resumo); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("Tabela CMED importada: %d produtos (PMC %s%%), %d linhas ignoradas, %d medicamentos acima do PMC",
+		resumo.Importados, aliquota, ignorados, resumo.Violacoes)
+	return resumo, nil
+}
+
+// GetViolacoesPMC lista os medicamentos com preço de venda acima do PMC, do maior excesso para o menor.
+func (b *Banco) GetViolacoesPMC() ([]ViolacaoPMC, error) {
+	return b.violacoesPMC(b.db)
+}
+
+func (b *Banco) violacoesPMC(db execer) ([]ViolacaoPMC, error) {
+	query := b.queries.GetQuery(qSelecionarViolacoesPmc)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_violacoes_pmc' não encontrada")
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	violacoes := []ViolacaoPMC{}
+	for rows.Next() {
+		var v ViolacaoPMC
+		if err := rows.Scan(&v.MedicamentoID, &v.MedicamentoNome, &v.CodigoANVISA, &v.Preco, &v.PMC, &v.ProdutoCMED, &v.Apresentacao); err != nil {
+			return nil, err
+		}
+		v.Excesso = arredondar(v.Preco - v.PMC)
+		violacoes = append(violacoes, v)
+	}
+	return violacoes, rows.Err()
+}
+
+var acentosCMED = strings.NewReplacer("Á", "A", "Â", "A", "Ã", "A", "À", "A", "É", "E", "Ê", "E", "Í", "I",
+	"Ó", "O", "Ô", "O", "Õ", "O", "Ú", "U", "Ç", "C", " ", "", "\u00a0", "")
+
+// normalizarColunaCMED deixa o nome da coluna em maiúsculas, sem acentos e sem espaços,
+// já que a grafia do cabeçalho varia entre as publicações ("PMC 17,5 %", "Laboratório").
+func normalizarColunaCMED(nome string) string {
+	return acentosCMED.Replace(strings.ToUpper(strings.TrimSpace(nome)))
+}
+
+// valorCMED converte um preço no formato "1.234,56" (ou "1234.56", quando a planilha foi exportada
+// sem formatação regional). Células vazias ou com "-" não têm PMC.
+func valorCMED(s string) (float64, bool) {
+	if strings.Contains(s, ",") {
+		s = strings.ReplaceAll(strings.ReplaceAll(s, ".", ""), ",", ".")
+	}
+	valor, err := strconv.ParseFloat(s, 64)
+	if err != nil || valor <= 0 {
+		return 0, false
+	}
+	return arredondar(valor), true
+}
+
+func latin1ParaUTF8(data []byte) []byte {
+	runas := make([]rune, len(data))
+	for i, b := range data {
+		runas[i] = rune(b)
+	}
+	return []byte(string(runas))
+}
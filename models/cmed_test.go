@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCMEDAliquotaPadrao(t *testing.T) {
+	precos, ignorados, err := ParseCMED(lerNFeTeste(t, "cmed_exemplo.csv"), "18")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// A linha sem PMC e a sem registro ficam de fora
+	assert.Equal(t, 2, ignorados)
+	assert.Len(t, precos, 3)
+	assert.Equal(t, PrecoCMED{
+		Registro:     "1058300140017",
+		EAN:          "7896004700014",
+		Produto:      "DIPIRONA",
+		Apresentacao: "500 MG COM CT BL AL PLAS INC X 10",
+		Laboratorio:  "EMS S/A",
+		PMC:          4.33,
+	}, precos[0])
+	assert.Equal(t, 1401.22, precos[2].PMC)
+}
+
+func TestParseCMEDOutraAliquotaELatin1(t *testing.T) {
+	// Mesmo conteúdo em Latin-1, como a planilha costuma ser exportada no Windows
+	utf8 := string(lerNFeTeste(t, "cmed_exemplo.csv"))
+	latin1 := make([]byte, 0, len(utf8))
+	for _, r := range utf8 {
+		latin1 = append(latin1, byte(r))
+	}
+
+	precos, _, err := ParseCMED(latin1, "17.5")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 4.31, precos[0].PMC)
+	assert.Equal(t, "500 MG COM CT BL AL PLAS INC X 10", precos[0].Apresentacao)
+}
+
+func TestParseCMEDInvalida(t *testing.T) {
+	_, _, err := ParseCMED(lerNFeTeste(t, "cmed_exemplo.csv"), "12")
+	assert.True(t, errors.Is(err, ErrTabelaCMEDInvalida), "coluna de alíquota ausente")
+
+	_, _, err = ParseCMED([]byte(strings.Repeat("a;b;c\n", 3)), "18")
+	assert.True(t, errors.Is(err, ErrTabelaCMEDInvalida), "sem cabeçalho")
+}
@@ -0,0 +1,323 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrCodigoBarrasInvalido indica um GTIN com tamanho, caracteres ou dígito verificador inválidos
+var ErrCodigoBarrasInvalido = errors.New("código de barras inválido")
+
+// ErrCodigoBarrasEmUso indica um GTIN já cadastrado em outro medicamento
+var ErrCodigoBarrasEmUso = errors.New("código de barras já cadastrado em outro medicamento")
+
+// separadorGS1 (GS, ASCII 29) encerra os campos de tamanho variável de um código GS1
+const separadorGS1 = "\x1d"
+
+// LeituraCodigoBarras é o que foi lido de um código de barras: só o GTIN, num EAN-13, ou também
+// o lote, a validade, o número de série e o registro ANVISA, num GS1 DataMatrix do SNCM.
+type LeituraCodigoBarras struct {
+	GTIN           string `json:"gtin"`
+	Lote           string `json:"lote,omitempty"`
+	Validade       string `json:"validade,omitempty"` // Formato: YYYY-MM-DD
+	Serie          string `json:"serie,omitempty"`
+	RegistroANVISA string `json:"registro_anvisa,omitempty"`
+}
+
+// ResultadoCodigoBarras é o medicamento encontrado por uma leitura de código de barras
+type ResultadoCodigoBarras struct {
+	LeituraCodigoBarras
+	Medicamento *Medicamento `json:"medicamento"`
+	// Saldo do lote lido; zero quando a leitura não tem lote ou ele não está cadastrado
+	SaldoLote int `json:"saldo_lote"`
+}
+
+// normalizarGTIN confere um GTIN-8, GTIN-12 (UPC-A), GTIN-13 (EAN-13) ou GTIN-14 e o retorna
+// com 13 dígitos, ou com 14 quando o indicador de embalagem não é zero. Assim o mesmo produto
+// tem o mesmo código lido do EAN-13 da caixa ou do GTIN-14 do DataMatrix.
+func normalizarGTIN(codigo string) (string, error) {
+	codigo = strings.TrimSpace(codigo)
+	switch len(codigo) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("%w: '%s' deve ter 8, 12, 13 ou 14 dígitos", ErrCodigoBarrasInvalido, codigo)
+	}
+	for _, r := range codigo {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: '%s' deve ter apenas dígitos", ErrCodigoBarrasInvalido, codigo)
+		}
+	}
+	if digitoVerificadorGTIN(codigo[:len(codigo)-1]) != codigo[len(codigo)-1] {
+		return "", fmt.Errorf("%w: dígito verificador de '%s' não confere", ErrCodigoBarrasInvalido, codigo)
+	}
+
+	codigo = strings.Repeat("0", 14-len(codigo)) + codigo
+	if codigo[0] == '0' {
+		codigo = codigo[1:]
+	}
+	return codigo, nil
+}
+
+// digitoVerificadorGTIN calcula o dígito verificador GS1 (módulo 10) dos dígitos informados:
+// da direita para a esquerda, os dígitos têm peso 3 e 1 alternadamente.
+func digitoVerificadorGTIN(digitos string) byte {
+	soma := 0
+	for i := 0; i < len(digitos); i++ {
+		d := int(digitos[len(digitos)-1-i] - '0')
+		if i%2 == 0 {
+			d *= 3
+		}
+		soma += d
+	}
+	return byte('0' + (10-soma%10)%10)
+}
+
+// normalizarCodigosBarras confere e normaliza os GTINs do medicamento, sem repetições.
+// Nil continua nil, para que a atualização mantenha os códigos já cadastrados.
+func normalizarCodigosBarras(med *Medicamento) error {
+	if med.CodigosBarras == nil {
+		return nil
+	}
+	codigos := []string{}
+	vistos := map[string]bool{}
+	for _, codigo := range med.CodigosBarras {
+		gtin, err := normalizarGTIN(codigo)
+		if err != nil {
+			return err
+		}
+		if !vistos[gtin] {
+			vistos[gtin] = true
+			codigos = append(codigos, gtin)
+		}
+	}
+	med.CodigosBarras = codigos
+	return nil
+}
+
+// aiGS1 descreve um identificador de aplicação (AI) do GS1: os de tamanho variável terminam
+// no separador GS ou no fim do código.
+type aiGS1 struct {
+	tamanho  int // Tamanho fixo dos dados; zero nos de tamanho variável
+	maximo   int // Tamanho máximo dos dados, nos de tamanho variável
+	numerico bool
+}
+
+// aisGS1 lista os AIs aceitos na leitura: os usados nas embalagens de medicamentos pelo SNCM
+// (GTIN, registro ANVISA, série, validade e lote) e as demais datas de tamanho fixo.
+var aisGS1 = map[string]aiGS1{
+	"01":  {tamanho: 14, numerico: true}, // GTIN
+	"02":  {tamanho: 14, numerico: true}, // GTIN dos itens contidos
+	"10":  {maximo: 20},                  // Lote
+	"11":  {tamanho: 6, numerico: true},  // Data de fabricação
+	"13":  {tamanho: 6, numerico: true},  // Data de embalagem
+	"15":  {tamanho: 6, numerico: true},  // Consumir preferencialmente até
+	"16":  {tamanho: 6, numerico: true},  // Vender até
+	"17":  {tamanho: 6, numerico: true},  // Validade
+	"21":  {maximo: 20},                  // Número de série
+	"713": {maximo: 20, numerico: true},  // Registro do medicamento na ANVISA
+}
+
+// LerCodigoBarras interpreta a leitura de um leitor de código de barras: um GTIN (EAN-13, EAN-8,
+// UPC-A ou GTIN-14) ou um código GS1, como o DataMatrix das embalagens de medicamentos, com os
+// AIs separados por GS ou escritos entre parênteses, como no texto impresso abaixo do código.
+// O identificador de simbologia que alguns leitores enviam antes do código (como "]d2") é ignorado.
+func LerCodigoBarras(leitura string) (*LeituraCodigoBarras, error) {
+	leitura = strings.TrimSpace(leitura)
+	if len(leitura) > 3 && leitura[0] == ']' {
+		leitura = leitura[3:]
+	}
+	// Alguns leitores enviam o FNC1 inicial do DataMatrix como GS
+	leitura = strings.TrimPrefix(leitura, separadorGS1)
+	if leitura == "" {
+		return nil, fmt.Errorf("%w: leitura vazia", ErrCodigoBarrasInvalido)
+	}
+
+	var campos map[string]string
+	var err error
+	switch {
+	case strings.HasPrefix(leitura, "("):
+		campos, err = camposGS1Parenteses(leitura)
+	case len(leitura) > 14 || strings.Contains(leitura, separadorGS1):
+		campos, err = camposGS1(leitura)
+	default:
+		gtin, err := normalizarGTIN(leitura)
+		if err != nil {
+			return nil, err
+		}
+		return &LeituraCodigoBarras{GTIN: gtin}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gtinLido, ok := campos["01"]
+	if !ok {
+		return nil, fmt.Errorf("%w: o código GS1 não tem o GTIN (01)", ErrCodigoBarrasInvalido)
+	}
+	gtin, err := normalizarGTIN(gtinLido)
+	if err != nil {
+		return nil, err
+	}
+	resultado := &LeituraCodigoBarras{
+		GTIN:           gtin,
+		Lote:           campos["10"],
+		Serie:          campos["21"],
+		RegistroANVISA: campos["713"],
+	}
+	if validade, ok := campos["17"]; ok {
+		if resultado.Validade, err = dataGS1(validade); err != nil {
+			return nil, err
+		}
+	}
+	return resultado, nil
+}
+
+// camposGS1 separa os AIs de um código GS1 em sequência, com os de tamanho variável terminados por GS.
+func camposGS1(codigo string) (map[string]string, error) {
+	campos := map[string]string{}
+	for codigo != "" {
+		ai, def, ok := aiGS1Inicial(codigo)
+		if !ok {
+			return nil, fmt.Errorf("%w: identificador GS1 desconhecido em '%s'", ErrCodigoBarrasInvalido, codigo)
+		}
+		codigo = codigo[len(ai):]
+
+		var valor string
+		if def.tamanho > 0 {
+			if len(codigo) < def.tamanho {
+				return nil, fmt.Errorf("%w: o campo GS1 (%s) deve ter %d caracteres", ErrCodigoBarrasInvalido, ai, def.tamanho)
+			}
+			valor, codigo = codigo[:def.tamanho], codigo[def.tamanho:]
+		} else if fim := strings.Index(codigo, separadorGS1); fim >= 0 {
+			valor, codigo = codigo[:fim], codigo[fim:]
+		} else {
+			valor, codigo = codigo, ""
+		}
+		// Os campos de tamanho fixo também podem vir seguidos do separador
+		codigo = strings.TrimPrefix(codigo, separadorGS1)
+
+		if err := validarCampoGS1(ai, def, valor); err != nil {
+			return nil, err
+		}
+		campos[ai] = valor
+	}
+	return campos, nil
+}
+
+// camposGS1Parenteses separa os AIs de um código GS1 escrito como "(01)07891234567895(17)271231(10)L123".
+func camposGS1Parenteses(codigo string) (map[string]string, error) {
+	campos := map[string]string{}
+	for codigo != "" {
+		fim := strings.Index(codigo, ")")
+		if !strings.HasPrefix(codigo, "(") || fim < 0 {
+			return nil, fmt.Errorf("%w: código GS1 mal formado em '%s'", ErrCodigoBarrasInvalido, codigo)
+		}
+		ai := codigo[1:fim]
+		def, ok := aisGS1[ai]
+		if !ok {
+			return nil, fmt.Errorf("%w: identificador GS1 (%s) desconhecido", ErrCodigoBarrasInvalido, ai)
+		}
+		codigo = codigo[fim+1:]
+
+		proximo := strings.Index(codigo, "(")
+		if proximo < 0 {
+			proximo = len(codigo)
+		}
+		valor := strings.TrimSuffix(codigo[:proximo], separadorGS1)
+		codigo = codigo[proximo:]
+
+		if def.tamanho > 0 && len(valor) != def.tamanho {
+			return nil, fmt.Errorf("%w: o campo GS1 (%s) deve ter %d caracteres", ErrCodigoBarrasInvalido, ai, def.tamanho)
+		}
+		if err := validarCampoGS1(ai, def, valor); err != nil {
+			return nil, err
+		}
+		campos[ai] = valor
+	}
+	return campos, nil
+}
+
+// aiGS1Inicial reconhece o AI no começo do código; os AIs conhecidos têm 2 ou 3 dígitos.
+func aiGS1Inicial(codigo string) (string, aiGS1, bool) {
+	for _, tamanho := range []int{2, 3} {
+		if len(codigo) < tamanho {
+			break
+		}
+		if def, ok := aisGS1[codigo[:tamanho]]; ok {
+			return codigo[:tamanho], def, true
+		}
+	}
+	return "", aiGS1{}, false
+}
+
+func validarCampoGS1(ai string, def aiGS1, valor string) error {
+	if valor == "" || (def.maximo > 0 && len(valor) > def.maximo) {
+		return fmt.Errorf("%w: tamanho inválido no campo GS1 (%s)", ErrCodigoBarrasInvalido, ai)
+	}
+	if def.numerico {
+		for _, r := range valor {
+			if r < '0' || r > '9' {
+				return fmt.Errorf("%w: o campo GS1 (%s) deve ter apenas dígitos", ErrCodigoBarrasInvalido, ai)
+			}
+		}
+	}
+	return nil
+}
+
+// dataGS1 converte uma data GS1 (YYMMDD) para YYYY-MM-DD. Dia 00 indica o último dia do mês,
+// como nas validades impressas só com mês e ano.
+func dataGS1(valor string) (string, error) {
+	data, err := time.Parse("060102", valor[:4]+"01")
+	if err != nil {
+		return "", fmt.Errorf("%w: data GS1 '%s' inválida", ErrCodigoBarrasInvalido, valor)
+	}
+	// Medicamentos vencem neste século, mesmo nos anos que o Go leria como 19YY
+	data = data.AddDate(2000+data.Year()%100-data.Year(), 0, 0)
+	if valor[4:] == "00" {
+		return data.AddDate(0, 1, -1).Format("2006-01-02"), nil
+	}
+	dia, err := time.Parse("2006-01-02", data.Format("2006-01-")+valor[4:])
+	if err != nil {
+		return "", fmt.Errorf("%w: data GS1 '%s' inválida", ErrCodigoBarrasInvalido, valor)
+	}
+	return dia.Format("2006-01-02"), nil
+}
+
+// BuscarPorCodigoBarras encontra o medicamento de uma leitura do leitor de código de barras.
+// Um GTIN sem cadastro ainda encontra o medicamento pelo registro ANVISA, quando o DataMatrix o traz.
+// Retorna ErrMedicamentoNaoEncontrado quando nenhum medicamento corresponde à leitura.
+func (b *Banco) BuscarPorCodigoBarras(leitura string) (*ResultadoCodigoBarras, error) {
+	lido, err := LerCodigoBarras(leitura)
+	if err != nil {
+		return nil, err
+	}
+
+	med, err := b.repos.Medicamentos.BuscarPorCodigoBarras(b.db, lido.GTIN)
+	if err != nil {
+		return nil, err
+	}
+	if med == nil && lido.RegistroANVISA != "" {
+		if med, err = b.repos.Medicamentos.BuscarPorCodigoANVISA(b.db, lido.RegistroANVISA); err != nil {
+			return nil, err
+		}
+	}
+	if med == nil {
+		return nil, fmt.Errorf("%w: código de barras %s", ErrMedicamentoNaoEncontrado, lido.GTIN)
+	}
+
+	resultado := &ResultadoCodigoBarras{LeituraCodigoBarras: *lido, Medicamento: med}
+	if lido.Lote != "" {
+		lotes, err := b.lotesPorMedicamento(b.db, med.ID, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range lotes {
+			if l.NumeroLote == lido.Lote {
+				resultado.SaldoLote = l.Quantidade
+			}
+		}
+	}
+	return resultado, nil
+}
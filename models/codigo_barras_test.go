@@ -0,0 +1,185 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizarGTIN(t *testing.T) {
+	for codigo, esperado := range map[string]string{
+		"7891234567895":  "7891234567895",
+		"07891234567895": "7891234567895", // GTIN-14 do DataMatrix, o mesmo produto do EAN-13
+		"17891234567892": "17891234567892",
+		"036000291452":   "0036000291452", // UPC-A
+		"03614143":       "0000003614143", // EAN-8
+		" 7891000000014": "7891000000014",
+	} {
+		gtin, err := normalizarGTIN(codigo)
+		if assert.NoError(t, err, codigo) {
+			assert.Equal(t, esperado, gtin, codigo)
+		}
+	}
+
+	for _, codigo := range []string{"7891234567894", "789123456789", "78912345678a5", "", "123456789012345"} {
+		_, err := normalizarGTIN(codigo)
+		assert.ErrorIs(t, err, ErrCodigoBarrasInvalido, codigo)
+	}
+}
+
+func TestLerCodigoBarras(t *testing.T) {
+	// EAN-13 da caixa
+	lido, err := LerCodigoBarras("7891234567895\r\n")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &LeituraCodigoBarras{GTIN: "7891234567895"}, lido)
+	}
+
+	// DataMatrix do SNCM: registro ANVISA, GTIN, série, validade e lote, com os variáveis terminados por GS
+	esperado := &LeituraCodigoBarras{
+		GTIN:           "7891234567895",
+		Lote:           "L2407A",
+		Validade:       "2027-03-31",
+		Serie:          "SN000123",
+		RegistroANVISA: "1057300010011",
+	}
+	lido, err = LerCodigoBarras("]d27131057300010011\x1d0107891234567895" + "21SN000123\x1d17270300" + "10L2407A")
+	if assert.NoError(t, err) {
+		assert.Equal(t, esperado, lido)
+	}
+
+	// O mesmo código em outra ordem e com o texto impresso, entre parênteses
+	lido, err = LerCodigoBarras("(01)07891234567895(17)270300(10)L2407A(21)SN000123(713)1057300010011")
+	if assert.NoError(t, err) {
+		assert.Equal(t, esperado, lido)
+	}
+
+	lido, err = LerCodigoBarras("\x1d010789123456789517261115")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "2026-11-15", lido.Validade)
+		assert.Empty(t, lido.Lote)
+	}
+
+	for _, leitura := range []string{
+		"",
+		"0107891234567894",             // dígito verificador
+		"10L2407A",                     // sem GTIN
+		"01078912345678951726133110L1", // validade no mês 13
+		"010789123456789517260231",     // 31 de fevereiro
+		"0107891234567895991234",       // AI desconhecido
+		"(01)0789123456789(10)L1",      // GTIN curto
+	} {
+		_, err := LerCodigoBarras(leitura)
+		assert.ErrorIs(t, err, ErrCodigoBarrasInvalido, leitura)
+	}
+}
+
+func TestCodigosBarrasMedicamento(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+
+	dipirona := &Medicamento{
+		ID: "1", Nome: "Dipirona 500mg", Fabricante: "EMS", CodigoANVISA: "1057300010011", Quantidade: 10,
+		Validade: "2027-03-31", Preco: 6.4, CodigosBarras: []string{"07891234567895", "7891234567895", "17891234567892"},
+	}
+	if !assert.NoError(t, b.AddMedicamento(dipirona, 0)) {
+		return
+	}
+	assert.Equal(t, []string{"7891234567895", "17891234567892"}, dipirona.CodigosBarras)
+	assert.Equal(t, []string{"17891234567892", "7891234567895"}, b.GetMedicamento("1").CodigosBarras)
+
+	// Um GTIN identifica um só medicamento
+	assert.ErrorIs(t, b.AddMedicamento(&Medicamento{ID: "2", Nome: "Outro", CodigosBarras: []string{"7891234567895"}}, 0), ErrCodigoBarrasEmUso)
+	assert.ErrorIs(t, b.AddMedicamento(&Medicamento{ID: "2", Nome: "Outro", CodigosBarras: []string{"7891234567894"}}, 0), ErrCodigoBarrasInvalido)
+
+	// Lote e validade vêm do DataMatrix; o lote que não está em estoque tem saldo zero
+	if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: "entrada", Quantidade: 5, Lote: "L2407A", Validade: "2027-03-31"})) {
+		return
+	}
+	resultado, err := b.BuscarPorCodigoBarras("01078912345678951727030010L2407A")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", resultado.Medicamento.ID)
+		assert.Equal(t, "L2407A", resultado.Lote)
+		assert.Equal(t, "2027-03-31", resultado.Validade)
+		assert.Equal(t, 5, resultado.SaldoLote)
+	}
+	resultado, err = b.BuscarPorCodigoBarras("(01)17891234567892(10)OUTRO")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", resultado.Medicamento.ID)
+		assert.Equal(t, 0, resultado.SaldoLote)
+	}
+
+	// Sem o GTIN cadastrado, o registro ANVISA do DataMatrix encontra o medicamento
+	resultado, err = b.BuscarPorCodigoBarras("(01)07891000000014(713)1057300010011")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", resultado.Medicamento.ID)
+	}
+	_, err = b.BuscarPorCodigoBarras("7891000000021")
+	assert.ErrorIs(t, err, ErrMedicamentoNaoEncontrado)
+
+	// Na atualização, sem a lista os códigos ficam; com a lista, são substituídos
+	dipirona.CodigosBarras = nil
+	dipirona.Nome = "Dipirona Sódica 500mg"
+	if !assert.NoError(t, b.UpdateMedicamento(dipirona, 0)) {
+		return
+	}
+	assert.Len(t, b.GetMedicamento("1").CodigosBarras, 2)
+	dipirona.CodigosBarras = []string{"7891000000014"}
+	if !assert.NoError(t, b.UpdateMedicamento(dipirona, 0)) {
+		return
+	}
+	pagina, err := b.BuscarMedicamentos(FiltroMedicamentos{Pagina: 1, PorPagina: 10})
+	if assert.NoError(t, err) && assert.Len(t, pagina.Medicamentos, 1) {
+		assert.Equal(t, []string{"7891000000014"}, pagina.Medicamentos[0].CodigosBarras)
+	}
+	_, err = b.BuscarPorCodigoBarras("7891234567895")
+	assert.ErrorIs(t, err, ErrMedicamentoNaoEncontrado)
+
+	// Com o medicamento excluído, o código pode ir para outro
+	if !assert.NoError(t, b.DeleteMedicamento("1", 0)) {
+		return
+	}
+	assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "2", Nome: "Outro", CodigosBarras: []string{"7891000000014"}}, 0))
+}
+
+func TestVendaDoLoteLido(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Dipirona 500mg", Quantidade: 10, Validade: "2026-12-31", Preco: 5}, 0)) {
+		return
+	}
+	if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: "entrada", Quantidade: 10, Lote: "L2", Validade: "2027-06-30"})) {
+		return
+	}
+	if _, err := b.AbrirCaixa(1, 0); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+
+	// O FEFO baixaria o lote inicial, que vence primeiro
+	_, err := b.RegistrarVenda(RegistrarVendaRequest{
+		Itens:      []ItemVendaRequest{{MedicamentoID: 1, Quantidade: 3, Lote: "L2"}},
+		Pagamentos: []PagamentoVenda{{Forma: "dinheiro", Valor: 15}},
+	}, 1, RoleAdmin)
+	if !assert.NoError(t, err) {
+		return
+	}
+	saldos := map[string]int{}
+	lotes, err := b.GetLotesPorMedicamento("1")
+	if assert.NoError(t, err) {
+		for _, l := range lotes {
+			saldos[l.NumeroLote] = l.Quantidade
+		}
+	}
+	assert.Equal(t, map[string]int{"LOTE-INICIAL": 10, "L2": 7}, saldos)
+
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{
+		Itens:      []ItemVendaRequest{{MedicamentoID: 1, Quantidade: 8, Lote: "L2"}},
+		Pagamentos: []PagamentoVenda{{Forma: "dinheiro", Valor: 40}},
+	}, 1, RoleAdmin)
+	assert.ErrorContains(t, err, "saldo do lote 'L2'")
+}
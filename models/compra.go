@@ -0,0 +1,534 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status de um pedido de compra
+const (
+	StatusPedidoRascunho             = "rascunho"
+	StatusPedidoEnviado              = "enviado"
+	StatusPedidoParcialmenteRecebido = "parcialmente recebido"
+	StatusPedidoRecebido             = "recebido"
+)
+
+var (
+	// ErrPedidoCompraNaoEncontrado indica que o pedido de compra informado não existe
+	ErrPedidoCompraNaoEncontrado = errors.New("pedido de compra não encontrado")
+	// ErrPedidoCompraInvalido indica itens, quantidades ou custos inválidos no pedido ou no recebimento
+	ErrPedidoCompraInvalido = errors.New("pedido de compra inválido")
+	// ErrStatusPedidoCompra indica uma operação que o status atual do pedido não permite
+	ErrStatusPedidoCompra = errors.New("operação não permitida no status atual do pedido")
+)
+
+// PedidoCompra é um pedido de reposição de estoque feito a um fornecedor
+type PedidoCompra struct {
+	ID              int64                   `json:"id"`
+	FornecedorID    int                     `json:"fornecedor_id"`
+	FornecedorNome  string                  `json:"fornecedor_nome"`
+	FornecedorCNPJ  string                  `json:"fornecedor_cnpj"`
+	Status          string                  `json:"status"`
+	UsuarioID       int                     `json:"usuario_id"`
+	CriadoEm        time.Time               `json:"criado_em"`
+	EnviadoEm       *time.Time              `json:"enviado_em,omitempty"`
+	PrevisaoEntrega string                  `json:"previsao_entrega,omitempty"` // YYYY-MM-DD, pelo prazo do fornecedor
+	RecebidoEm      *time.Time              `json:"recebido_em,omitempty"`
+	Observacao      string                  `json:"observacao,omitempty"`
+	ValorTotal      float64                 `json:"valor_total"`
+	Itens           []ItemPedidoCompra      `json:"itens"`
+	Recebimentos    []RecebimentoPedidoItem `json:"recebimentos,omitempty"`
+}
+
+// ItemPedidoCompra é uma linha do pedido de compra
+type ItemPedidoCompra struct {
+	ID                 int64   `json:"id"`
+	MedicamentoID      string  `json:"medicamento_id"`
+	Nome               string  `json:"nome"`
+	Quantidade         int     `json:"quantidade"`
+	QuantidadeRecebida int     `json:"quantidade_recebida"`
+	CustoUnitario      float64 `json:"custo_unitario"`
+}
+
+// RecebimentoPedidoItem registra a entrega de parte de um item, com a movimentação de entrada gerada
+type RecebimentoPedidoItem struct {
+	ID             int64     `json:"id"`
+	ItemID         int64     `json:"item_id"`
+	MovimentacaoID string    `json:"movimentacao_id"`
+	Quantidade     int       `json:"quantidade"`
+	CustoUnitario  float64   `json:"custo_unitario"`
+	Lote           string    `json:"lote"`
+	Validade       string    `json:"validade"`
+	NotaFiscal     string    `json:"nota_fiscal"`
+	UsuarioID      int       `json:"usuario_id"`
+	Data           time.Time `json:"data"`
+}
+
+// PedidoCompraRequest é o que a API recebe para criar um pedido ou substituir seus itens
+type PedidoCompraRequest struct {
+	FornecedorID int                       `json:"fornecedor_id"`
+	Observacao   string                    `json:"observacao"`
+	Itens        []ItemPedidoCompraRequest `json:"itens"`
+}
+
+// ItemPedidoCompraRequest é uma linha do pedido na requisição
+type ItemPedidoCompraRequest struct {
+	MedicamentoID string  `json:"medicamento_id"`
+	Quantidade    int     `json:"quantidade"`
+	CustoUnitario float64 `json:"custo_unitario"`
+}
+
+// RecebimentoRequest é o que a API recebe ao conferir uma entrega do fornecedor
+type RecebimentoRequest struct {
+	NotaFiscal string                   `json:"nota_fiscal"`
+	Itens      []ItemRecebimentoRequest `json:"itens"`
+}
+
+// ItemRecebimentoRequest é a quantidade entregue de um item do pedido, com o lote recebido.
+// Sem custo informado, vale o custo do pedido.
+type ItemRecebimentoRequest struct {
+	ItemID        int64    `json:"item_id"`
+	Quantidade    int      `json:"quantidade"`
+	Lote          string   `json:"lote"`
+	Validade      string   `json:"validade"`
+	CustoUnitario *float64 `json:"custo_unitario"`
+}
+
+// FiltroPedidosCompra restringe a listagem de pedidos de compra
+type FiltroPedidosCompra struct {
+	Status       string
+	FornecedorID int
+}
+
+func (i ItemPedidoCompra) pendente() int {
+	return i.Quantidade - i.QuantidadeRecebida
+}
+
+// CriarPedidoCompra cadastra um pedido em rascunho para um fornecedor ativo.
+func (b *Banco) CriarPedidoCompra(req PedidoCompraRequest, usuarioID int) (*PedidoCompra, error) {
+	fornecedor, err := b.GetFornecedor(req.FornecedorID)
+	if err != nil {
+		return nil, err
+	}
+	if fornecedor == nil {
+		return nil, ErrFornecedorNaoEncontrado
+	}
+	if !fornecedor.Ativo {
+		return nil, fmt.Errorf("%w: o fornecedor '%s' está inativo", ErrPedidoCompraInvalido, fornecedor.Nome)
+	}
+
+	query := b.queries.GetQuery(qInserirPedidoCompra)
+	if query == "" {
+		return nil, errors.New("query 'inserir_pedido_compra' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pedidoID, err := b.inserirComID(tx, query, req.FornecedorID, StatusPedidoRascunho, usuarioID, b.Agora(), req.Observacao)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar pedido de compra: %w", err)
+	}
+	if err := b.inserirItensPedidoCompra(tx, pedidoID, req.Itens); err != nil {
+		return nil, err
+	}
+
+	pedido, err := b.pedidoCompra(tx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "pedido_compra", strconv.FormatInt(pedidoID, 10), nil, pedido); err != nil {
+		return nil, err
+	}
+	return pedido, tx.Commit()
+}
+
+// AtualizarItensPedidoCompra substitui os itens de um pedido que ainda está em rascunho.
+func (b *Banco) AtualizarItensPedidoCompra(pedidoID int64, req PedidoCompraRequest, usuarioID int) (*PedidoCompra, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	anterior, err := b.pedidoCompra(tx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if anterior.Status != StatusPedidoRascunho {
+		return nil, fmt.Errorf("%w: só é possível alterar os itens de pedidos em rascunho (status '%s')", ErrStatusPedidoCompra, anterior.Status)
+	}
+
+	query := b.queries.GetQuery(qExcluirItensPedidoCompra)
+	if query == "" {
+		return nil, errors.New("query 'excluir_itens_pedido_compra' não encontrada")
+	}
+	if _, err := tx.Exec(query, pedidoID); err != nil {
+		return nil, err
+	}
+	if err := b.inserirItensPedidoCompra(tx, pedidoID, req.Itens); err != nil {
+		return nil, err
+	}
+
+	pedido, err := b.pedidoCompra(tx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "pedido_compra", strconv.FormatInt(pedidoID, 10), anterior.Itens, pedido.Itens); err != nil {
+		return nil, err
+	}
+	return pedido, tx.Commit()
+}
+
+// inserirItensPedidoCompra valida e grava as linhas do pedido. Cada medicamento aparece uma única vez.
+func (b *Banco) inserirItensPedidoCompra(tx *sql.Tx, pedidoID int64, itens []ItemPedidoCompraRequest) error {
+	if len(itens) == 0 {
+		return fmt.Errorf("%w: informe ao menos um item", ErrPedidoCompraInvalido)
+	}
+	query := b.queries.GetQuery(qInserirItemPedidoCompra)
+	if query == "" {
+		return errors.New("query 'inserir_item_pedido_compra' não encontrada")
+	}
+
+	vistos := make(map[string]bool, len(itens))
+	for _, item := range itens {
+		if item.Quantidade <= 0 {
+			return fmt.Errorf("%w: a quantidade do medicamento '%s' deve ser maior que zero", ErrPedidoCompraInvalido, item.MedicamentoID)
+		}
+		if item.CustoUnitario < 0 {
+			return fmt.Errorf("%w: o custo do medicamento '%s' não pode ser negativo", ErrPedidoCompraInvalido, item.MedicamentoID)
+		}
+		if vistos[item.MedicamentoID] {
+			return fmt.Errorf("%w: o medicamento '%s' aparece mais de uma vez", ErrPedidoCompraInvalido, item.MedicamentoID)
+		}
+		vistos[item.MedicamentoID] = true
+		if b.buscarMedicamento(tx, item.MedicamentoID) == nil {
+			return fmt.Errorf("%w: medicamento '%s' não encontrado", ErrPedidoCompraInvalido, item.MedicamentoID)
+		}
+
+		if _, err := tx.Exec(query, pedidoID, item.MedicamentoID, item.Quantidade, arredondar(item.CustoUnitario)); err != nil {
+			return fmt.Errorf("erro ao inserir item do pedido de compra: %w", err)
+		}
+	}
+	return nil
+}
+
+// EnviarPedidoCompra marca o pedido como enviado ao fornecedor e calcula a previsão de entrega
+// a partir do prazo cadastrado no fornecedor.
+func (b *Banco) EnviarPedidoCompra(pedidoID int64, usuarioID int) (*PedidoCompra, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pedido, err := b.pedidoCompra(tx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if pedido.Status != StatusPedidoRascunho {
+		return nil, fmt.Errorf("%w: o pedido já foi enviado (status '%s')", ErrStatusPedidoCompra, pedido.Status)
+	}
+	fornecedor, err := b.selecionarFornecedor(tx, qSelecionarFornecedorPorId, pedido.FornecedorID)
+	if err != nil {
+		return nil, err
+	}
+	if fornecedor == nil || !fornecedor.Ativo {
+		return nil, fmt.Errorf("%w: o fornecedor do pedido está inativo", ErrPedidoCompraInvalido)
+	}
+
+	agora := b.Agora()
+	pedido.Status = StatusPedidoEnviado
+	pedido.EnviadoEm = &agora
+	pedido.PrevisaoEntrega = agora.AddDate(0, 0, fornecedor.PrazoEntregaDias).Format("2006-01-02")
+	if err := b.atualizarStatusPedidoCompra(tx, pedido); err != nil {
+		return nil, err
+	}
+
+	antes := map[string]interface{}{"status": StatusPedidoRascunho}
+	depois := map[string]interface{}{"status": pedido.Status, "previsao_entrega": pedido.PrevisaoEntrega}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "pedido_compra", strconv.FormatInt(pedidoID, 10), antes, depois); err != nil {
+		return nil, err
+	}
+	return pedido, tx.Commit()
+}
+
+// ReceberPedidoCompra confere uma entrega do fornecedor: cada item recebido gera uma movimentação de entrada
+// no lote informado, com o custo unitário, a nota fiscal e o CNPJ do fornecedor. O pedido passa a
+// parcialmente recebido ou, quando não restar nada pendente, a recebido.
+func (b *Banco) ReceberPedidoCompra(pedidoID int64, req RecebimentoRequest, usuarioID int) (*PedidoCompra, error) {
+	if len(req.Itens) == 0 {
+		return nil, fmt.Errorf("%w: informe ao menos um item recebido", ErrPedidoCompraInvalido)
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pedido, err := b.pedidoCompra(tx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if pedido.Status != StatusPedidoEnviado && pedido.Status != StatusPedidoParcialmenteRecebido {
+		return nil, fmt.Errorf("%w: só é possível receber pedidos enviados (status '%s')", ErrStatusPedidoCompra, pedido.Status)
+	}
+	statusAnterior := pedido.Status
+
+	porID := make(map[int64]*ItemPedidoCompra, len(pedido.Itens))
+	for i := range pedido.Itens {
+		porID[pedido.Itens[i].ID] = &pedido.Itens[i]
+	}
+
+	queryItem := b.queries.GetQuery(qAtualizarRecebimentoItemPedidoCompra)
+	if queryItem == "" {
+		return nil, errors.New("query 'atualizar_recebimento_item_pedido_compra' não encontrada")
+	}
+	queryRecebimento := b.queries.GetQuery(qInserirRecebimentoPedidoCompra)
+	if queryRecebimento == "" {
+		return nil, errors.New("query 'inserir_recebimento_pedido_compra' não encontrada")
+	}
+
+	observacao := "Recebimento do pedido de compra " + strconv.FormatInt(pedidoID, 10)
+	var recebidos []RecebimentoPedidoItem
+	for _, itemReq := range req.Itens {
+		item, ok := porID[itemReq.ItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: item %d não pertence ao pedido %d", ErrPedidoCompraInvalido, itemReq.ItemID, pedidoID)
+		}
+		if itemReq.Quantidade <= 0 {
+			return nil, fmt.Errorf("%w: a quantidade recebida do item %d deve ser maior que zero", ErrPedidoCompraInvalido, item.ID)
+		}
+		if itemReq.Quantidade > item.pendente() {
+			return nil, fmt.Errorf("%w: item %d ('%s') tem apenas %d unidades pendentes", ErrPedidoCompraInvalido, item.ID, item.Nome, item.pendente())
+		}
+		custo := item.CustoUnitario
+		if itemReq.CustoUnitario != nil {
+			if *itemReq.CustoUnitario < 0 {
+				return nil, fmt.Errorf("%w: o custo do item %d não pode ser negativo", ErrPedidoCompraInvalido, item.ID)
+			}
+			custo = arredondar(*itemReq.CustoUnitario)
+		}
+		if itemReq.Validade != "" {
+			if _, err := parseData(itemReq.Validade); err != nil {
+				return nil, fmt.Errorf("%w: validade '%s' do item %d", ErrPedidoCompraInvalido, itemReq.Validade, item.ID)
+			}
+		}
+
+		mov := Movimentacao{
+			MedicamentoID:  item.MedicamentoID,
+			Tipo:           "entrada",
+			Quantidade:     itemReq.Quantidade,
+			Observacao:     observacao,
+			UsuarioID:      usuarioID,
+			Lote:           strings.TrimSpace(itemReq.Lote),
+			Validade:       itemReq.Validade,
+			Fornecedor:     pedido.FornecedorNome,
+			NotaFiscal:     req.NotaFiscal,
+			CNPJFornecedor: pedido.FornecedorCNPJ,
+			CustoUnitario:  custo,
+		}
+		if err := b.registrarMovimentacaoTx(tx, &mov); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(queryItem, itemReq.Quantidade, item.ID); err != nil {
+			return nil, err
+		}
+		item.QuantidadeRecebida += itemReq.Quantidade
+
+		recebimento := RecebimentoPedidoItem{
+			ItemID:         item.ID,
+			MovimentacaoID: mov.ID,
+			Quantidade:     itemReq.Quantidade,
+			CustoUnitario:  custo,
+			Lote:           mov.Lote,
+			Validade:       mov.Validade,
+			NotaFiscal:     req.NotaFiscal,
+			UsuarioID:      usuarioID,
+			Data:           mov.Data,
+		}
+		recebimento.ID, err = b.inserirComID(tx, queryRecebimento, pedidoID, recebimento.ItemID, recebimento.MovimentacaoID, recebimento.Quantidade,
+			recebimento.CustoUnitario, recebimento.Lote, recebimento.Validade, recebimento.NotaFiscal, usuarioID, recebimento.Data)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao registrar recebimento do item %d: %w", item.ID, err)
+		}
+		recebidos = append(recebidos, recebimento)
+	}
+
+	pedido.Status = StatusPedidoRecebido
+	for _, item := range pedido.Itens {
+		if item.pendente() > 0 {
+			pedido.Status = StatusPedidoParcialmenteRecebido
+			break
+		}
+	}
+	if pedido.Status == StatusPedidoRecebido {
+		agora := b.Agora()
+		pedido.RecebidoEm = &agora
+	}
+	if err := b.atualizarStatusPedidoCompra(tx, pedido); err != nil {
+		return nil, err
+	}
+
+	antes := map[string]interface{}{"status": statusAnterior}
+	depois := map[string]interface{}{"status": pedido.Status, "nota_fiscal": req.NotaFiscal, "recebimentos": recebidos}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "pedido_compra", strconv.FormatInt(pedidoID, 10), antes, depois); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return b.GetPedidoCompra(pedidoID)
+}
+
+// atualizarStatusPedidoCompra grava o status e as datas de envio e recebimento do pedido.
+func (b *Banco) atualizarStatusPedidoCompra(tx *sql.Tx, p *PedidoCompra) error {
+	query := b.queries.GetQuery(qAtualizarStatusPedidoCompra)
+	if query == "" {
+		return errors.New("query 'atualizar_status_pedido_compra' não encontrada")
+	}
+	var enviadoEm, recebidoEm sql.NullTime
+	if p.EnviadoEm != nil {
+		enviadoEm = sql.NullTime{Time: *p.EnviadoEm, Valid: true}
+	}
+	if p.RecebidoEm != nil {
+		recebidoEm = sql.NullTime{Time: *p.RecebidoEm, Valid: true}
+	}
+	_, err := tx.Exec(query, p.Status, enviadoEm, sql.NullString{String: p.PrevisaoEntrega, Valid: p.PrevisaoEntrega != ""}, recebidoEm, p.ID)
+	return err
+}
+
+// GetPedidoCompra retorna o pedido com seus itens e recebimentos, ou ErrPedidoCompraNaoEncontrado.
+func (b *Banco) GetPedidoCompra(pedidoID int64) (*PedidoCompra, error) {
+	pedido, err := b.pedidoCompra(b.db, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := b.queries.GetQuery(qSelecionarRecebimentosPedidoCompra)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_recebimentos_pedido_compra' não encontrada")
+	}
+	rows, err := b.db.Query(query, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r RecebimentoPedidoItem
+		if err := rows.Scan(&r.ID, &r.ItemID, &r.MovimentacaoID, &r.Quantidade, &r.CustoUnitario, &r.Lote, &r.Validade,
+			&r.NotaFiscal, &r.UsuarioID, &r.Data); err != nil {
+			return nil, fmt.Errorf("erro ao escanear recebimento: %w", err)
+		}
+		pedido.Recebimentos = append(pedido.Recebimentos, r)
+	}
+	return pedido, rows.Err()
+}
+
+// pedidoCompra carrega o cabeçalho e os itens do pedido.
+func (b *Banco) pedidoCompra(db execer, pedidoID int64) (*PedidoCompra, error) {
+	query := b.queries.GetQuery(qSelecionarPedidoCompraPorId)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_pedido_compra_por_id' não encontrada")
+	}
+	pedido, err := scanPedidoCompra(db.QueryRow(query, pedidoID))
+	if err == sql.ErrNoRows {
+		return nil, ErrPedidoCompraNaoEncontrado
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	queryItens := b.queries.GetQuery(qSelecionarItensPedidoCompra)
+	if queryItens == "" {
+		return nil, errors.New("query 'selecionar_itens_pedido_compra' não encontrada")
+	}
+	rows, err := db.Query(queryItens, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pedido.Itens = []ItemPedidoCompra{}
+	for rows.Next() {
+		var item ItemPedidoCompra
+		if err := rows.Scan(&item.ID, &item.MedicamentoID, &item.Nome, &item.Quantidade, &item.QuantidadeRecebida, &item.CustoUnitario); err != nil {
+			return nil, fmt.Errorf("erro ao escanear item do pedido de compra: %w", err)
+		}
+		pedido.ValorTotal += float64(item.Quantidade) * item.CustoUnitario
+		pedido.Itens = append(pedido.Itens, item)
+	}
+	pedido.ValorTotal = arredondar(pedido.ValorTotal)
+	return pedido, rows.Err()
+}
+
+// scanPedidoCompra lê o cabeçalho do pedido; extras recebe as colunas adicionais da consulta, se houver.
+func scanPedidoCompra(row scanner, extras ...interface{}) (*PedidoCompra, error) {
+	var p PedidoCompra
+	var enviadoEm, recebidoEm sql.NullTime
+	var previsao, observacao sql.NullString
+	dest := []interface{}{&p.ID, &p.FornecedorID, &p.FornecedorNome, &p.FornecedorCNPJ, &p.Status, &p.UsuarioID, &p.CriadoEm,
+		&enviadoEm, &previsao, &recebidoEm, &observacao}
+	err := row.Scan(append(dest, extras...)...)
+	if err != nil {
+		return nil, err
+	}
+	if enviadoEm.Valid {
+		p.EnviadoEm = &enviadoEm.Time
+	}
+	if recebidoEm.Valid {
+		p.RecebidoEm = &recebidoEm.Time
+	}
+	p.PrevisaoEntrega = previsao.String
+	p.Observacao = observacao.String
+	return &p, nil
+}
+
+// ListarPedidosCompra retorna os pedidos de compra mais recentes primeiro, sem os itens.
+func (b *Banco) ListarPedidosCompra(filtro FiltroPedidosCompra) ([]PedidoCompra, error) {
+	query := `
+		SELECT p.id, p.fornecedor_id, f.nome, f.cnpj, p.status, p.usuario_id, p.criado_em, p.enviado_em,
+		       p.previsao_entrega, p.recebido_em, p.observacao,
+		       COALESCE((SELECT SUM(i.quantidade * i.custo_unitario) FROM pedido_compra_itens i WHERE i.pedido_id = p.id), 0)
+		FROM pedidos_compra p
+		JOIN fornecedores f ON f.id = p.fornecedor_id
+		WHERE 1 = 1`
+	var args []interface{}
+	if filtro.Status != "" {
+		query += " AND p.status = ?"
+		args = append(args, filtro.Status)
+	}
+	if filtro.FornecedorID != 0 {
+		query += " AND p.fornecedor_id = ?"
+		args = append(args, filtro.FornecedorID)
+	}
+	query += " ORDER BY p.criado_em DESC, p.id DESC"
+
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pedidos []PedidoCompra
+	for rows.Next() {
+		var valorTotal float64
+		p, err := scanPedidoCompra(rows, &valorTotal)
+		if err != nil {
+			log.Printf("Erro ao escanear pedido de compra: %v", err)
+			return nil, err
+		}
+		p.ValorTotal = arredondar(valorTotal)
+		pedidos = append(pedidos, *p)
+	}
+	return pedidos, rows.Err()
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizarCNPJ(t *testing.T) {
+	cnpj, err := normalizarCNPJ("11.222.333/0001-81")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "11222333000181", cnpj)
+	}
+	for _, invalido := range []string{"11.222.333/0001-82", "1122233300018", "00000000000000"} {
+		_, err := normalizarCNPJ(invalido)
+		assert.ErrorIs(t, err, ErrFornecedorInvalido, invalido)
+	}
+}
+
+func TestCicloPedidoCompra(t *testing.T) {
+	t.Parallel()
+	agora := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	b := abrirBancoTesteCom(t, ConfigBanco{Driver: DriverSQLite, Caminho: ":memory:", Relogio: func() time.Time { return agora }})
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	for _, med := range []*Medicamento{{ID: "1", Nome: "Dipirona 500mg", Preco: 10}, {ID: "2", Nome: "Losartana 50mg", Preco: 25}} {
+		if !assert.NoError(t, b.AddMedicamento(med, 0)) {
+			return
+		}
+	}
+	prazo := 5
+	fornecedor, err := b.CriarFornecedor(FornecedorRequest{Nome: "Distribuidora Sul", CNPJ: "11.222.333/0001-81", PrazoEntregaDias: &prazo}, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, invalido := range [][]ItemPedidoCompraRequest{
+		nil,
+		{{MedicamentoID: "1", Quantidade: 0, CustoUnitario: 4}},
+		{{MedicamentoID: "1", Quantidade: 10, CustoUnitario: -1}},
+		{{MedicamentoID: "1", Quantidade: 10}, {MedicamentoID: "1", Quantidade: 5}},
+		{{MedicamentoID: "99", Quantidade: 10}},
+	} {
+		_, err := b.CriarPedidoCompra(PedidoCompraRequest{FornecedorID: fornecedor.ID, Itens: invalido}, 1)
+		assert.ErrorIs(t, err, ErrPedidoCompraInvalido, invalido)
+	}
+
+	pedido, err := b.CriarPedidoCompra(PedidoCompraRequest{FornecedorID: fornecedor.ID, Itens: []ItemPedidoCompraRequest{
+		{MedicamentoID: "1", Quantidade: 10, CustoUnitario: 4},
+		{MedicamentoID: "2", Quantidade: 6, CustoUnitario: 12.5},
+	}}, 1)
+	if !assert.NoError(t, err) || !assert.Len(t, pedido.Itens, 2) {
+		return
+	}
+	assert.Equal(t, StatusPedidoRascunho, pedido.Status)
+	assert.Equal(t, 115.0, pedido.ValorTotal)
+	_, err = b.ReceberPedidoCompra(pedido.ID, RecebimentoRequest{Itens: []ItemRecebimentoRequest{{ItemID: pedido.Itens[0].ID, Quantidade: 1}}}, 1)
+	assert.ErrorIs(t, err, ErrStatusPedidoCompra, "rascunho não é recebido")
+
+	enviado, err := b.EnviarPedidoCompra(pedido.ID, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, StatusPedidoEnviado, enviado.Status)
+	assert.Equal(t, "2025-03-15", enviado.PrevisaoEntrega)
+	_, err = b.AtualizarItensPedidoCompra(pedido.ID, PedidoCompraRequest{Itens: []ItemPedidoCompraRequest{{MedicamentoID: "1", Quantidade: 1}}}, 1)
+	assert.ErrorIs(t, err, ErrStatusPedidoCompra, "itens de pedido enviado não mudam")
+
+	// Primeira entrega: todo o item 1, com custo diferente do pedido, e parte do item 2
+	custoNegociado := 3.8
+	recebido, err := b.ReceberPedidoCompra(pedido.ID, RecebimentoRequest{NotaFiscal: "4521", Itens: []ItemRecebimentoRequest{
+		{ItemID: pedido.Itens[0].ID, Quantidade: 10, Lote: "D1", Validade: "2030-01", CustoUnitario: &custoNegociado},
+		{ItemID: pedido.Itens[1].ID, Quantidade: 4, Lote: "L1", Validade: "2030-06-30"},
+	}}, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, StatusPedidoParcialmenteRecebido, recebido.Status)
+	assert.Nil(t, recebido.RecebidoEm)
+	assert.Len(t, recebido.Recebimentos, 2)
+	assert.Equal(t, 10, b.GetMedicamento("1").Quantidade)
+	assert.Equal(t, 3.8, b.GetMedicamento("1").CustoMedio)
+	assert.Equal(t, 4, b.GetMedicamento("2").Quantidade)
+
+	_, err = b.ReceberPedidoCompra(pedido.ID, RecebimentoRequest{Itens: []ItemRecebimentoRequest{{ItemID: pedido.Itens[1].ID, Quantidade: 3}}}, 1)
+	assert.ErrorIs(t, err, ErrPedidoCompraInvalido, "mais que o pendente")
+	_, err = b.ReceberPedidoCompra(pedido.ID, RecebimentoRequest{Itens: []ItemRecebimentoRequest{{ItemID: pedido.Itens[1].ID, Quantidade: 2, Validade: "31/31/2030"}}}, 1)
+	assert.ErrorIs(t, err, ErrPedidoCompraInvalido, "validade inválida")
+
+	// Segunda entrega fecha o pedido
+	agora = agora.AddDate(0, 0, 6)
+	recebido, err = b.ReceberPedidoCompra(pedido.ID, RecebimentoRequest{NotaFiscal: "4533", Itens: []ItemRecebimentoRequest{
+		{ItemID: pedido.Itens[1].ID, Quantidade: 2, Lote: "L2", Validade: "2030-09-30"},
+	}}, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, StatusPedidoRecebido, recebido.Status)
+	if assert.NotNil(t, recebido.RecebidoEm) {
+		assert.True(t, agora.Equal(*recebido.RecebidoEm))
+	}
+	assert.Equal(t, 6, b.GetMedicamento("2").Quantidade)
+	assert.Equal(t, 12.5, b.GetMedicamento("2").CustoMedio)
+
+	lotes, err := b.GetLotesPorMedicamento("2")
+	if assert.NoError(t, err) && assert.Len(t, lotes, 2) {
+		assert.Equal(t, "L1", lotes[0].NumeroLote)
+		assert.Equal(t, "Distribuidora Sul", lotes[0].Fornecedor)
+	}
+	recebidos, err := b.ListarPedidosCompra(FiltroPedidosCompra{Status: StatusPedidoRecebido})
+	if assert.NoError(t, err) {
+		assert.Len(t, recebidos, 1)
+	}
+}
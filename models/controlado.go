@@ -0,0 +1,280 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ListasControle descreve as listas da Portaria SVS/MS 344/98 aceitas no cadastro de medicamentos
+var ListasControle = map[string]string{
+	"A1": "Entorpecentes",
+	"A2": "Entorpecentes de uso permitido em concentrações especiais",
+	"A3": "Psicotrópicos",
+	"B1": "Psicotrópicos",
+	"B2": "Psicotrópicos anorexígenos",
+	"C1": "Outras substâncias sujeitas a controle especial",
+	"C2": "Retinoides de uso sistêmico",
+	"C3": "Imunossupressores",
+	"C4": "Antirretrovirais",
+	"C5": "Anabolizantes",
+}
+
+// validadeReceitaDias é o prazo, a partir da emissão, em que a receita pode ser dispensada
+const validadeReceitaDias = 30
+
+// Receita representa a receita retida na venda de um medicamento controlado
+type Receita struct {
+	ID                string    `json:"id"`
+	VendaID           int64     `json:"venda_id"`
+	VendaItemID       int64     `json:"venda_item_id"`
+	MedicamentoID     string    `json:"medicamento_id"`
+	NumeroReceita     string    `json:"numero_receita"`
+	DataReceita       string    `json:"data_receita"` // Formato: YYYY-MM-DD
+	PrescritorNome    string    `json:"prescritor_nome"`
+	PrescritorCRM     string    `json:"prescritor_crm"`
+	PrescritorUF      string    `json:"prescritor_uf"`
+	PacienteNome      string    `json:"paciente_nome"`
+	PacienteDocumento string    `json:"paciente_documento"`
+	CriadoEm          time.Time `json:"criado_em"`
+}
+
+// RegistroLivro é um lançamento do livro de registro de um medicamento controlado
+type RegistroLivro struct {
+	Data          time.Time `json:"data"`
+	Tipo          string    `json:"tipo"`   // Tipo da movimentação; as vendas são "saida"
+	Origem        string    `json:"origem"` // "movimentacao" ou "venda"
+	Referencia    string    `json:"referencia"`
+	Quantidade    int       `json:"quantidade"`
+	Saldo         int       `json:"saldo"`
+	NumeroReceita string    `json:"numero_receita,omitempty"`
+	PrescritorCRM string    `json:"prescritor_crm,omitempty"`
+	Observacao    string    `json:"observacao,omitempty"`
+}
+
+// LivroControlado é o livro de registro de entradas e saídas de um medicamento controlado no período
+type LivroControlado struct {
+	MedicamentoID string          `json:"medicamento_id"`
+	Nome          string          `json:"nome"`
+	ListaControle string          `json:"lista_controle"`
+	SaldoInicial  int             `json:"saldo_inicial"`
+	TotalEntradas int             `json:"total_entradas"`
+	TotalSaidas   int             `json:"total_saidas"`
+	SaldoFinal    int             `json:"saldo_final"`
+	Lancamentos   []RegistroLivro `json:"lancamentos"`
+}
+
+// normalizarListaControle padroniza e valida a lista de controle informada no medicamento.
+// Sem lista informada, nada muda.
+func normalizarListaControle(med *Medicamento) error {
+	if med.ListaControle == nil {
+		return nil
+	}
+	lista := strings.ToUpper(strings.TrimSpace(*med.ListaControle))
+	if lista != "" {
+		if _, ok := ListasControle[lista]; !ok {
+			return fmt.Errorf("lista de controle '%s' inválida", lista)
+		}
+	}
+	med.ListaControle = &lista
+	med.Controlado = lista != ""
+	return nil
+}
+
+// listaControle retorna a lista da Portaria 344/98 do medicamento, vazia se ele não é controlado.
+func (med *Medicamento) listaControle() string {
+	if med.ListaControle == nil {
+		return ""
+	}
+	return *med.ListaControle
+}
+
+// validarReceita confere se a receita tem os dados exigidos para a dispensação de um controlado.
+// São os mesmos que a exportação do SNGPC exige, para que uma venda aceita não trave o arquivo do período.
+func validarReceita(r *Receita, hoje time.Time) error {
+	if r == nil {
+		return errors.New("receita obrigatória")
+	}
+	r.PrescritorUF = strings.ToUpper(strings.TrimSpace(r.PrescritorUF))
+
+	var faltando []string
+	if strings.TrimSpace(r.NumeroReceita) == "" {
+		faltando = append(faltando, "numero_receita")
+	}
+	if strings.TrimSpace(r.DataReceita) == "" {
+		faltando = append(faltando, "data_receita")
+	}
+	if strings.TrimSpace(r.PrescritorNome) == "" {
+		faltando = append(faltando, "prescritor_nome")
+	}
+	if strings.TrimSpace(r.PrescritorCRM) == "" {
+		faltando = append(faltando, "prescritor_crm")
+	}
+	if r.PrescritorUF == "" {
+		faltando = append(faltando, "prescritor_uf")
+	}
+	if somenteDigitos(r.PacienteDocumento) == "" {
+		faltando = append(faltando, "paciente_documento")
+	}
+	if len(faltando) > 0 {
+		return fmt.Errorf("receita incompleta, faltam: %s", strings.Join(faltando, ", "))
+	}
+	if !reUF.MatchString(r.PrescritorUF) {
+		return fmt.Errorf("UF do conselho do prescritor '%s' inválida: use a sigla do estado", r.PrescritorUF)
+	}
+
+	data, err := parseData(r.DataReceita)
+	if err != nil {
+		return fmt.Errorf("data da receita inválida: %w", err)
+	}
+	if data.After(hoje) {
+		return errors.New("data da receita no futuro")
+	}
+	if hoje.Sub(data) > validadeReceitaDias*24*time.Hour {
+		return fmt.Errorf("receita emitida há mais de %d dias", validadeReceitaDias)
+	}
+	return nil
+}
+
+// inserirReceita grava a receita vinculada ao item de venda.
+func (b *Banco) inserirReceita(tx execer, r *Receita) error {
+	query := b.queries.GetQuery(qInserirReceita)
+	if query == "" {
+		return errors.New("query 'inserir_receita' não encontrada")
+	}
+	r.ID = uuid.New().String()
+	r.CriadoEm = b.Agora()
+	_, err := tx.Exec(query, r.ID, r.VendaID, r.VendaItemID, r.MedicamentoID, r.NumeroReceita, r.DataReceita,
+		r.PrescritorNome, r.PrescritorCRM, r.PrescritorUF, r.PacienteNome, r.PacienteDocumento, r.CriadoEm)
+	if err != nil {
+		return fmt.Errorf("erro ao registrar receita %s: %w", r.NumeroReceita, err)
+	}
+	return nil
+}
+
+// GetLivroControlados monta o livro de registro dos medicamentos controlados no período [de, ate).
+// Se medicamentoID for informado, apenas aquele medicamento é considerado.
+func (b *Banco) GetLivroControlados(de, ate time.Time, medicamentoID string) ([]LivroControlado, error) {
+	query := b.queries.GetQuery(qSelecionarMedicamentosControlados)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_medicamentos_controlados' não encontrada")
+	}
+
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	var livros []LivroControlado
+	saldoAtual := make(map[string]int)
+	for rows.Next() {
+		var l LivroControlado
+		var quantidade int
+		if err := rows.Scan(&l.MedicamentoID, &l.Nome, &l.ListaControle, &quantidade); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if medicamentoID != "" && l.MedicamentoID != medicamentoID {
+			continue
+		}
+		saldoAtual[l.MedicamentoID] = quantidade
+		livros = append(livros, l)
+	}
+	rows.Close()
+
+	for i := range livros {
+		livro := &livros[i]
+		lancamentos, err := b.lancamentosControlado(livro.MedicamentoID)
+		if err != nil {
+			return nil, err
+		}
+
+		// O saldo inicial é o saldo atual desfeito de tudo o que aconteceu a partir do início do período
+		saldo := saldoAtual[livro.MedicamentoID]
+		for _, l := range lancamentos {
+			if l.Data.Before(de) {
+				continue
+			}
+			if movimentacaoEntrada(l.Tipo) {
+				saldo -= l.Quantidade
+			} else {
+				saldo += l.Quantidade
+			}
+		}
+		livro.SaldoInicial = saldo
+
+		livro.Lancamentos = []RegistroLivro{}
+		for _, l := range lancamentos {
+			if l.Data.Before(de) || !l.Data.Before(ate) {
+				continue
+			}
+			if movimentacaoEntrada(l.Tipo) {
+				saldo += l.Quantidade
+				livro.TotalEntradas += l.Quantidade
+			} else {
+				saldo -= l.Quantidade
+				livro.TotalSaidas += l.Quantidade
+			}
+			l.Saldo = saldo
+			livro.Lancamentos = append(livro.Lancamentos, l)
+		}
+		livro.SaldoFinal = saldo
+	}
+
+	return livros, nil
+}
+
+// lancamentosControlado reúne as movimentações e vendas de um medicamento em ordem cronológica.
+func (b *Banco) lancamentosControlado(medicamentoID string) ([]RegistroLivro, error) {
+	var lancamentos []RegistroLivro
+
+	queryMov := b.queries.GetQuery(qSelecionarMovimentacoesPorMedicamento)
+	if queryMov == "" {
+		return nil, errors.New("query 'selecionar_movimentacoes_por_medicamento' não encontrada")
+	}
+	rows, err := b.db.Query(queryMov, medicamentoID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		l := RegistroLivro{Origem: "movimentacao"}
+		var observacao sql.NullString
+		if err := rows.Scan(&l.Referencia, &l.Tipo, &l.Quantidade, &l.Data, &observacao); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("erro ao escanear movimentação do controlado: %w", err)
+		}
+		l.Observacao = observacao.String
+		lancamentos = append(lancamentos, l)
+	}
+	rows.Close()
+
+	queryVendas := b.queries.GetQuery(qSelecionarVendasControlado)
+	if queryVendas == "" {
+		return nil, errors.New("query 'selecionar_vendas_controlado' não encontrada")
+	}
+	rows, err = b.db.Query(queryVendas, medicamentoID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		l := RegistroLivro{Origem: "venda", Tipo: "saida"}
+		var vendaID int64
+		var numeroReceita, crm sql.NullString
+		if err := rows.Scan(&vendaID, &l.Data, &l.Quantidade, &numeroReceita, &crm); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("erro ao escanear venda do controlado: %w", err)
+		}
+		l.Referencia = fmt.Sprintf("venda %d", vendaID)
+		l.NumeroReceita = numeroReceita.String
+		l.PrescritorCRM = crm.String
+		lancamentos = append(lancamentos, l)
+	}
+	rows.Close()
+
+	sort.SliceStable(lancamentos, func(i, j int) bool { return lancamentos[i].Data.Before(lancamentos[j].Data) })
+	return lancamentos, nil
+}
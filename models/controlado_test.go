@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidarReceita(t *testing.T) {
+	hoje := time.Date(2025, 3, 10, 15, 0, 0, 0, time.Local)
+	receita := func() *Receita {
+		return &Receita{
+			NumeroReceita:     "B1-123456",
+			DataReceita:       "2025-03-01",
+			PrescritorNome:    "Dra. Marta Lima",
+			PrescritorCRM:     "12345",
+			PrescritorUF:      " sp ",
+			PacienteNome:      "João Souza",
+			PacienteDocumento: "123.456.789-09",
+		}
+	}
+
+	r := receita()
+	if assert.NoError(t, validarReceita(r, hoje)) {
+		assert.Equal(t, "SP", r.PrescritorUF)
+	}
+	assert.ErrorContains(t, validarReceita(nil, hoje), "obrigatória")
+
+	// Os dados que o SNGPC exige são conferidos já na venda
+	for campo, alterar := range map[string]func(*Receita){
+		"numero_receita":     func(r *Receita) { r.NumeroReceita = " " },
+		"prescritor_nome":    func(r *Receita) { r.PrescritorNome = "" },
+		"prescritor_crm":     func(r *Receita) { r.PrescritorCRM = "" },
+		"prescritor_uf":      func(r *Receita) { r.PrescritorUF = "" },
+		"paciente_documento": func(r *Receita) { r.PacienteDocumento = "não informado" },
+		"UF do conselho":     func(r *Receita) { r.PrescritorUF = "São Paulo" },
+		"inválida":           func(r *Receita) { r.DataReceita = "01/13/2025" },
+		"no futuro":          func(r *Receita) { r.DataReceita = "2025-03-11" },
+		"mais de 30 dias":    func(r *Receita) { r.DataReceita = "2025-02-01" },
+	} {
+		r := receita()
+		alterar(r)
+		assert.ErrorContains(t, validarReceita(r, hoje), campo)
+	}
+}
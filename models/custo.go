@@ -0,0 +1,176 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Agrupamentos do relatório de margem
+const (
+	AgruparPorProduto   = "produto"
+	AgruparPorCategoria = "categoria"
+	AgruparPorDia       = "dia"
+	AgruparPorMes       = "mes"
+)
+
+// ErrAgrupamentoInvalido indica um agrupamento desconhecido no relatório de margem
+var ErrAgrupamentoInvalido = errors.New("agrupamento inválido: use produto, categoria, dia ou mes")
+
+// FiltroMargem define o período e o agrupamento do relatório de margem
+type FiltroMargem struct {
+	De          time.Time // Inclusivo
+	Ate         time.Time // Exclusivo
+	Agrupamento string
+	CategoriaID string
+}
+
+// LinhaMargem é o resultado de um produto, categoria ou período. O lucro e a margem consideram só
+// as unidades vendidas com custo registrado; as demais aparecem em QuantidadeSemCusto.
+type LinhaMargem struct {
+	Chave              string   `json:"chave"`
+	Descricao          string   `json:"descricao"`
+	Quantidade         int      `json:"quantidade"`
+	Receita            float64  `json:"receita"`
+	Custo              float64  `json:"custo"`
+	Lucro              float64  `json:"lucro"`
+	MargemPercentual   *float64 `json:"margem_percentual"` // Lucro sobre a receita; nulo sem custo registrado
+	QuantidadeSemCusto int      `json:"quantidade_sem_custo"`
+	receitaComCusto    float64
+}
+
+// RelatorioMargem reúne as linhas do relatório e o total do período
+type RelatorioMargem struct {
+	De          string        `json:"de"`
+	Ate         string        `json:"ate"`
+	Agrupamento string        `json:"agrupamento"`
+	Linhas      []LinhaMargem `json:"linhas"`
+	Total       LinhaMargem   `json:"total"`
+}
+
+// arredondarCusto arredonda custos unitários em quatro casas, para que o custo médio não perca
+// precisão a cada entrada.
+func arredondarCusto(valor float64) float64 {
+	return math.Round(valor*10000) / 10000
+}
+
+// custoMedioPonderado recalcula o custo médio após uma entrada. Entradas sem custo informado não
+// alteram o custo médio; sem estoque ou sem custo anterior, vale o custo da entrada.
+func custoMedioPonderado(quantidadeAtual int, custoAtual float64, quantidadeEntrada int, custoEntrada float64) float64 {
+	if custoEntrada <= 0 || quantidadeEntrada <= 0 {
+		return custoAtual
+	}
+	if quantidadeAtual <= 0 || custoAtual <= 0 {
+		return arredondarCusto(custoEntrada)
+	}
+	total := float64(quantidadeAtual)*custoAtual + float64(quantidadeEntrada)*custoEntrada
+	return arredondarCusto(total / float64(quantidadeAtual+quantidadeEntrada))
+}
+
+// inicializarCustoMedio preenche o custo médio dos medicamentos que ainda não têm um,
+// a partir do custo dos lotes com saldo.
+func (b *Banco) inicializarCustoMedio() error {
+	query := b.queries.GetQuery(qInicializarCustoMedio)
+	if query == "" {
+		return errors.New("query 'inicializar_custo_medio' não encontrada")
+	}
+	if _, err := b.db.Exec(query); err != nil {
+		log.Printf("Erro ao inicializar o custo médio dos medicamentos: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetRelatorioMargem calcula receita, custo e lucro das vendas não canceladas do período, descontando
+// as unidades devolvidas. A receita já tem os descontos da venda e o custo é o custo médio congelado
+// em cada item no momento da venda.
+func (b *Banco) GetRelatorioMargem(filtro FiltroMargem) (*RelatorioMargem, error) {
+	if filtro.Agrupamento == "" {
+		filtro.Agrupamento = AgruparPorProduto
+	}
+	var chave, descricao, ordem string
+	switch filtro.Agrupamento {
+	case AgruparPorProduto:
+		chave, descricao, ordem = "vi.medicamento_id", "COALESCE(m.Nome, vi.medicamento_id)", "lucro DESC, descricao"
+	case AgruparPorCategoria:
+		chave, descricao, ordem = "COALESCE(m.CategoriaID, '')", "COALESCE(c.Nome, 'Sem categoria')", "lucro DESC, descricao"
+	case AgruparPorDia:
+		dia := b.dialeto.prefixoData("v.data", 10)
+		chave, descricao, ordem = dia, dia, "chave"
+	case AgruparPorMes:
+		mes := b.dialeto.prefixoData("v.data", 7)
+		chave, descricao, ordem = mes, mes, "chave"
+	default:
+		return nil, ErrAgrupamentoInvalido
+	}
+
+	// Receita líquida do item proporcional às unidades que não foram devolvidas
+	receita := "COALESCE(vi.valor_total, vi.quantidade * vi.preco_unitario) * (vi.quantidade - vi.quantidade_devolvida) / vi.quantidade"
+	query := `
+		SELECT ` + chave + ` AS chave, ` + descricao + ` AS descricao,
+		       SUM(vi.quantidade - vi.quantidade_devolvida),
+		       SUM(` + receita + `),
+		       SUM(CASE WHEN vi.custo_unitario IS NOT NULL THEN ` + receita + ` ELSE 0 END),
+		       SUM(COALESCE(vi.custo_unitario, 0) * (vi.quantidade - vi.quantidade_devolvida)),
+		       SUM(CASE WHEN vi.custo_unitario IS NULL THEN vi.quantidade - vi.quantidade_devolvida ELSE 0 END),
+		       SUM(CASE WHEN vi.custo_unitario IS NOT NULL THEN ` + receita + ` ELSE 0 END)
+		         - SUM(COALESCE(vi.custo_unitario, 0) * (vi.quantidade - vi.quantidade_devolvida)) AS lucro
+		FROM venda_items vi
+		JOIN vendas v ON v.id = vi.venda_id
+		LEFT JOIN medicamentos m ON m.ID = vi.medicamento_id
+		LEFT JOIN categorias c ON c.ID = m.CategoriaID
+		WHERE v.status <> 'cancelada' AND vi.quantidade > vi.quantidade_devolvida
+		  AND v.data >= ? AND v.data < ?`
+	args := []interface{}{filtro.De, filtro.Ate}
+	if filtro.CategoriaID != "" {
+		query += " AND m.CategoriaID = ?"
+		args = append(args, filtro.CategoriaID)
+	}
+	query += " GROUP BY 1, 2 ORDER BY " + ordem
+
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao calcular margem: %w", err)
+	}
+	defer rows.Close()
+
+	relatorio := &RelatorioMargem{
+		De:          filtro.De.Format("2006-01-02"),
+		Ate:         filtro.Ate.AddDate(0, 0, -1).Format("2006-01-02"),
+		Agrupamento: filtro.Agrupamento,
+		Linhas:      []LinhaMargem{},
+		Total:       LinhaMargem{Chave: "total", Descricao: "Total"},
+	}
+	for rows.Next() {
+		var l LinhaMargem
+		var lucro float64
+		if err := rows.Scan(&l.Chave, &l.Descricao, &l.Quantidade, &l.Receita, &l.receitaComCusto, &l.Custo, &l.QuantidadeSemCusto, &lucro); err != nil {
+			return nil, fmt.Errorf("erro ao ler linha do relatório de margem: %w", err)
+		}
+		relatorio.Total.Quantidade += l.Quantidade
+		relatorio.Total.Receita += l.Receita
+		relatorio.Total.receitaComCusto += l.receitaComCusto
+		relatorio.Total.Custo += l.Custo
+		relatorio.Total.QuantidadeSemCusto += l.QuantidadeSemCusto
+		relatorio.Linhas = append(relatorio.Linhas, l.calcularLucro())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	relatorio.Total = relatorio.Total.calcularLucro()
+	return relatorio, nil
+}
+
+// calcularLucro arredonda os valores da linha e calcula o lucro e a margem sobre a receita com custo.
+func (l LinhaMargem) calcularLucro() LinhaMargem {
+	l.Receita = arredondar(l.Receita)
+	l.Custo = arredondar(l.Custo)
+	l.Lucro = arredondar(l.receitaComCusto - l.Custo)
+	if l.receitaComCusto > 0 {
+		margem := math.Round(l.Lucro/l.receitaComCusto*10000) / 100
+		l.MargemPercentual = &margem
+	}
+	return l
+}
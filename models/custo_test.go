@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustoMedioPonderado(t *testing.T) {
+	// 10 unidades a 2,00 + 30 unidades a 3,00 = 110,00 / 40
+	assert.Equal(t, 2.75, custoMedioPonderado(10, 2, 30, 3))
+
+	// Sem estoque ou sem custo anterior, vale o custo da entrada
+	assert.Equal(t, 3.0, custoMedioPonderado(0, 2, 30, 3))
+	assert.Equal(t, 3.0, custoMedioPonderado(10, 0, 30, 3))
+
+	// Entrada sem custo não altera o custo médio
+	assert.Equal(t, 2.0, custoMedioPonderado(10, 2, 30, 0))
+
+	// Quatro casas decimais
+	assert.Equal(t, 1.3333, custoMedioPonderado(2, 1, 1, 2))
+}
+
+func TestLinhaMargemCalcularLucro(t *testing.T) {
+	// 100,00 de receita, dos quais 80,00 em itens com custo de 50,00
+	l := LinhaMargem{Receita: 100, Custo: 50, receitaComCusto: 80, QuantidadeSemCusto: 2}.calcularLucro()
+	assert.Equal(t, 30.0, l.Lucro)
+	if assert.NotNil(t, l.MargemPercentual) {
+		assert.Equal(t, 37.5, *l.MargemPercentual)
+	}
+
+	// Sem nenhum custo registrado não há margem
+	l = LinhaMargem{Receita: 100}.calcularLucro()
+	assert.Nil(t, l.MargemPercentual)
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Status possíveis de uma venda
+const (
+	StatusVendaAtiva                 = "ativa"
+	StatusVendaCancelada             = "cancelada"
+	StatusVendaParcialmenteDevolvida = "parcialmente devolvida"
+)
+
+var (
+	// ErrVendaNaoEncontrada indica que a venda informada não existe
+	ErrVendaNaoEncontrada = errors.New("venda não encontrada")
+	// ErrVendaCancelada indica uma tentativa de estornar uma venda já cancelada
+	ErrVendaCancelada = errors.New("venda já cancelada")
+	// ErrDevolucaoInvalida indica itens ou quantidades inválidos em uma devolução
+	ErrDevolucaoInvalida = errors.New("devolução inválida")
+)
+
+// EstornoVendaRequest é o que a API recebe para cancelar uma venda
+type EstornoVendaRequest struct {
+	Motivo string `json:"motivo"`
+}
+
+// DevolucaoVendaRequest é o que a API recebe para devolver parte dos itens de uma venda
+type DevolucaoVendaRequest struct {
+	Motivo string                 `json:"motivo"`
+	Itens  []ItemDevolucaoRequest `json:"itens"`
+}
+
+// ItemDevolucaoRequest é a quantidade devolvida de um item de venda
+type ItemDevolucaoRequest struct {
+	VendaItemID int64 `json:"venda_item_id"`
+	Quantidade  int   `json:"quantidade"`
+}
+
+// itemVendaEstorno é um item de venda com o que ainda pode ser devolvido
+type itemVendaEstorno struct {
+	ID                  int64
+	MedicamentoID       string
+	Nome                string
+	Quantidade          int
+	QuantidadeDevolvida int
+	ValorTotal          float64 // valor pago pelo item, já com descontos
+	CustoUnitario       float64 // custo médio congelado na venda; as unidades devolvidas voltam com esse custo
+}
+
+func (i itemVendaEstorno) pendente() int {
+	return i.Quantidade - i.QuantidadeDevolvida
+}
+
+// valorDevolvido é o valor pago proporcional às unidades devolvidas
+func (i itemVendaEstorno) valorDevolvido(quantidade int) float64 {
+	if i.Quantidade == 0 {
+		return 0
+	}
+	return arredondar(i.ValorTotal * float64(quantidade) / float64(i.Quantidade))
+}
+
+// CancelarVenda cancela a venda inteira: devolve ao estoque tudo o que ainda não foi devolvido,
+// registra as entradas de estorno e marca a venda como cancelada, sem apagá-la.
+// Retorna o valor estornado ao cliente, lançado como devolução no caixa da venda (ver registrarDevolucaoCaixa).
+func (b *Banco) CancelarVenda(vendaID int64, motivo string, usuarioID int) (float64, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	statusAnterior, err := b.statusVenda(tx, vendaID)
+	if err != nil {
+		return 0, err
+	}
+	itens, err := b.itensVendaEstorno(tx, vendaID)
+	if err != nil {
+		return 0, err
+	}
+
+	observacao := "Cancelamento da venda " + strconv.FormatInt(vendaID, 10)
+	var estornos []Movimentacao
+	var valorEstornado float64
+	for _, item := range itens {
+		if item.pendente() <= 0 {
+			continue
+		}
+		movs, err := b.devolverItemVenda(tx, vendaID, item, item.pendente(), observacao, usuarioID)
+		if err != nil {
+			return 0, err
+		}
+		valorEstornado += item.valorDevolvido(item.pendente())
+		estornos = append(estornos, movs...)
+	}
+	valorEstornado = arredondar(valorEstornado)
+
+	if err := b.atualizarStatusVenda(tx, vendaID, StatusVendaCancelada, motivo); err != nil {
+		return 0, err
+	}
+	if err := b.registrarDevolucaoCaixa(tx, usuarioID, vendaID, valorEstornado, observacao); err != nil {
+		return 0, err
+	}
+
+	antes := map[string]interface{}{"status": statusAnterior}
+	depois := map[string]interface{}{"status": StatusVendaCancelada, "motivo": motivo, "valor_estornado": valorEstornado, "estornos": estornos}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "venda", strconv.FormatInt(vendaID, 10), antes, depois); err != nil {
+		return 0, err
+	}
+	return valorEstornado, tx.Commit()
+}
+
+// ResultadoDevolucao informa a situação da venda após a devolução e o valor estornado ao cliente
+type ResultadoDevolucao struct {
+	VendaID        int64   `json:"venda_id"`
+	Status         string  `json:"status"`
+	ValorEstornado float64 `json:"valor_estornado"`
+}
+
+// DevolverItensVenda devolve parte dos itens de uma venda, restaurando o estoque nos lotes de origem.
+// Quando todos os itens são devolvidos a venda passa a cancelada; caso contrário, a parcialmente devolvida.
+// O valor estornado é lançado como devolução no caixa da venda (ver registrarDevolucaoCaixa).
+func (b *Banco) DevolverItensVenda(vendaID int64, req DevolucaoVendaRequest, usuarioID int) (*ResultadoDevolucao, error) {
+	if len(req.Itens) == 0 {
+		return nil, fmt.Errorf("%w: informe ao menos um item", ErrDevolucaoInvalida)
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	statusAnterior, err := b.statusVenda(tx, vendaID)
+	if err != nil {
+		return nil, err
+	}
+	itens, err := b.itensVendaEstorno(tx, vendaID)
+	if err != nil {
+		return nil, err
+	}
+	porID := make(map[int64]*itemVendaEstorno, len(itens))
+	for i := range itens {
+		porID[itens[i].ID] = &itens[i]
+	}
+
+	observacao := "Devolução da venda " + strconv.FormatInt(vendaID, 10)
+	resultado := &ResultadoDevolucao{VendaID: vendaID}
+	var estornos []Movimentacao
+	for _, itemReq := range req.Itens {
+		item, ok := porID[itemReq.VendaItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: item %d não pertence à venda %d", ErrDevolucaoInvalida, itemReq.VendaItemID, vendaID)
+		}
+		if itemReq.Quantidade <= 0 {
+			return nil, fmt.Errorf("%w: a quantidade devolvida do item %d deve ser maior que zero", ErrDevolucaoInvalida, item.ID)
+		}
+		if itemReq.Quantidade > item.pendente() {
+			return nil, fmt.Errorf("%w: item %d ('%s') tem apenas %d unidades a devolver", ErrDevolucaoInvalida, item.ID, item.Nome, item.pendente())
+		}
+
+		movs, err := b.devolverItemVenda(tx, vendaID, *item, itemReq.Quantidade, observacao, usuarioID)
+		if err != nil {
+			return nil, err
+		}
+		resultado.ValorEstornado += item.valorDevolvido(itemReq.Quantidade)
+		item.QuantidadeDevolvida += itemReq.Quantidade
+		estornos = append(estornos, movs...)
+	}
+	resultado.ValorEstornado = arredondar(resultado.ValorEstornado)
+
+	status := StatusVendaCancelada
+	for _, item := range itens {
+		if item.pendente() > 0 {
+			status = StatusVendaParcialmenteDevolvida
+			break
+		}
+	}
+	resultado.Status = status
+	if err := b.atualizarStatusVenda(tx, vendaID, status, req.Motivo); err != nil {
+		return nil, err
+	}
+	if err := b.registrarDevolucaoCaixa(tx, usuarioID, vendaID, resultado.ValorEstornado, observacao); err != nil {
+		return nil, err
+	}
+
+	antes := map[string]interface{}{"status": statusAnterior}
+	depois := map[string]interface{}{"status": status, "motivo": req.Motivo, "itens": req.Itens, "valor_estornado": resultado.ValorEstornado, "estornos": estornos}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "venda", strconv.FormatInt(vendaID, 10), antes, depois); err != nil {
+		return nil, err
+	}
+	return resultado, tx.Commit()
+}
+
+// statusVenda retorna o status atual da venda, recusando vendas inexistentes ou já canceladas.
+func (b *Banco) statusVenda(tx *sql.Tx, vendaID int64) (string, error) {
+	status, err := b.repos.Vendas.Status(tx, vendaID)
+	if err != nil {
+		return "", err
+	}
+	if status == StatusVendaCancelada {
+		return "", ErrVendaCancelada
+	}
+	return status, nil
+}
+
+// itensVendaEstorno lista os itens da venda com as quantidades já devolvidas.
+func (b *Banco) itensVendaEstorno(tx *sql.Tx, vendaID int64) ([]itemVendaEstorno, error) {
+	query := b.queries.GetQuery(qSelecionarItensVenda)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_itens_venda' não encontrada")
+	}
+	rows, err := tx.Query(query, vendaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var itens []itemVendaEstorno
+	for rows.Next() {
+		var item itemVendaEstorno
+		var nome sql.NullString
+		if err := rows.Scan(&item.ID, &item.MedicamentoID, &nome, &item.Quantidade, &item.QuantidadeDevolvida, &item.ValorTotal, &item.CustoUnitario); err != nil {
+			return nil, fmt.Errorf("erro ao escanear item da venda: %w", err)
+		}
+		item.Nome = nome.String
+		itens = append(itens, item)
+	}
+	return itens, rows.Err()
+}
+
+// devolverItemVenda devolve a quantidade ao estoque, recolocando-a nos lotes de onde o item saiu.
+// Cada lote gera uma entrada de estorno vinculada à venda; o que foi vendido sem lote associado
+// volta em um lote novo, como uma entrada comum.
+func (b *Banco) devolverItemVenda(tx *sql.Tx, vendaID int64, item itemVendaEstorno, quantidade int, observacao string, usuarioID int) ([]Movimentacao, error) {
+	query := b.queries.GetQuery(qSelecionarLotesVendaItem)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_lotes_venda_item' não encontrada")
+	}
+	rows, err := tx.Query(query, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	type loteVendido struct {
+		LoteConsumido
+		devolvida int
+	}
+	var lotes []loteVendido
+	for rows.Next() {
+		var l loteVendido
+		var validade sql.NullString
+		if err := rows.Scan(&l.LoteID, &l.NumeroLote, &validade, &l.Quantidade, &l.devolvida); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("erro ao escanear lote do item de venda: %w", err)
+		}
+		l.Validade = validade.String
+		lotes = append(lotes, l)
+	}
+	rows.Close()
+
+	queryLote := b.queries.GetQuery(qAtualizarDevolucaoVendaItemLote)
+	if queryLote == "" {
+		return nil, errors.New("query 'atualizar_devolucao_venda_item_lote' não encontrada")
+	}
+
+	var movs []Movimentacao
+	restante := quantidade
+	for _, l := range lotes {
+		if restante == 0 {
+			break
+		}
+		devolver := l.Quantidade - l.devolvida
+		if devolver <= 0 {
+			continue
+		}
+		if devolver > restante {
+			devolver = restante
+		}
+		mov := Movimentacao{
+			MedicamentoID: item.MedicamentoID,
+			Tipo:          "entrada",
+			Quantidade:    devolver,
+			Observacao:    observacao,
+			UsuarioID:     usuarioID,
+			Lote:          l.NumeroLote,
+			Validade:      l.Validade,
+			VendaID:       vendaID,
+			CustoUnitario: item.CustoUnitario,
+		}
+		if err := b.registrarMovimentacaoTx(tx, &mov); err != nil {
+			return nil, fmt.Errorf("erro ao estornar lote %s do item %d: %w", l.NumeroLote, item.ID, err)
+		}
+		if _, err := tx.Exec(queryLote, devolver, item.ID, l.LoteID); err != nil {
+			return nil, err
+		}
+		movs = append(movs, mov)
+		restante -= devolver
+	}
+
+	if restante > 0 {
+		mov := Movimentacao{
+			MedicamentoID: item.MedicamentoID,
+			Tipo:          "entrada",
+			Quantidade:    restante,
+			Observacao:    observacao,
+			UsuarioID:     usuarioID,
+			VendaID:       vendaID,
+			CustoUnitario: item.CustoUnitario,
+		}
+		if err := b.registrarMovimentacaoTx(tx, &mov); err != nil {
+			return nil, fmt.Errorf("erro ao estornar item %d: %w", item.ID, err)
+		}
+		movs = append(movs, mov)
+	}
+
+	queryItem := b.queries.GetQuery(qAtualizarDevolucaoVendaItem)
+	if queryItem == "" {
+		return nil, errors.New("query 'atualizar_devolucao_venda_item' não encontrada")
+	}
+	if _, err := tx.Exec(queryItem, quantidade, item.ID); err != nil {
+		return nil, fmt.Errorf("erro ao registrar devolução do item %d: %w", item.ID, err)
+	}
+	return movs, nil
+}
+
+// atualizarStatusVenda grava o novo status da venda e o motivo do estorno.
+func (b *Banco) atualizarStatusVenda(tx *sql.Tx, vendaID int64, status, motivo string) error {
+	return b.repos.Vendas.AtualizarStatus(tx, vendaID, status, motivo)
+}
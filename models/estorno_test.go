@@ -0,0 +1,102 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevolverECancelarVenda(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Dipirona 500mg", Preco: 10}, 0)) {
+		return
+	}
+	for _, mov := range []Movimentacao{
+		{MedicamentoID: "1", Tipo: TipoEntrada, Quantidade: 3, Lote: "A", Validade: "2030-03-31"},
+		{MedicamentoID: "1", Tipo: TipoEntrada, Quantidade: 5, Lote: "B", Validade: "2030-09-30"},
+	} {
+		if !assert.NoError(t, b.RegistrarMovimentacao(mov)) {
+			return
+		}
+	}
+	saldos := func() map[string]int {
+		lotes, err := b.GetLotesPorMedicamento("1")
+		assert.NoError(t, err)
+		s := map[string]int{}
+		for _, l := range lotes {
+			s[l.NumeroLote] = l.Quantidade
+		}
+		return s
+	}
+	if _, err := b.AbrirCaixa(1, 0); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+
+	// 5 unidades com 10% de desconto: 3 do lote A, que vence antes, e 2 do B
+	resumo, err := b.RegistrarVenda(RegistrarVendaRequest{
+		Itens:      []ItemVendaRequest{{MedicamentoID: 1, Quantidade: 5, DescontoPercentual: 10}},
+		Pagamentos: []PagamentoVenda{{Forma: FormaDinheiro, Valor: 45}},
+	}, 1, RoleFarmaceutico)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]int{"A": 0, "B": 3}, saldos())
+	venda, err := b.GetVenda(resumo.VendaID)
+	if !assert.NoError(t, err) || !assert.Len(t, venda.Itens, 1) {
+		return
+	}
+	itemID := venda.Itens[0].ID
+
+	for _, invalida := range []DevolucaoVendaRequest{
+		{},
+		{Itens: []ItemDevolucaoRequest{{VendaItemID: itemID, Quantidade: 0}}},
+		{Itens: []ItemDevolucaoRequest{{VendaItemID: itemID, Quantidade: 6}}},
+		{Itens: []ItemDevolucaoRequest{{VendaItemID: itemID + 100, Quantidade: 1}}},
+	} {
+		_, err := b.DevolverItensVenda(resumo.VendaID, invalida, 1)
+		assert.ErrorIs(t, err, ErrDevolucaoInvalida, invalida)
+	}
+
+	// Devolução de 2 unidades: voltam ao lote A, pelo valor pago com desconto
+	devolucao, err := b.DevolverItensVenda(resumo.VendaID, DevolucaoVendaRequest{
+		Motivo: "Cliente comprou a mais",
+		Itens:  []ItemDevolucaoRequest{{VendaItemID: itemID, Quantidade: 2}},
+	}, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, StatusVendaParcialmenteDevolvida, devolucao.Status)
+	assert.Equal(t, 18.0, devolucao.ValorEstornado)
+	assert.Equal(t, map[string]int{"A": 2, "B": 3}, saldos())
+	assert.Equal(t, 5, b.GetMedicamento("1").Quantidade)
+
+	// O cancelamento devolve só o que restou com o cliente
+	valor, err := b.CancelarVenda(resumo.VendaID, "Desistência", 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 27.0, valor)
+	assert.Equal(t, map[string]int{"A": 3, "B": 5}, saldos())
+	assert.Equal(t, 8, b.GetMedicamento("1").Quantidade)
+
+	venda, err = b.GetVenda(resumo.VendaID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatusVendaCancelada, venda.Status)
+		assert.Equal(t, "Desistência", venda.MotivoEstorno)
+	}
+	_, err = b.CancelarVenda(resumo.VendaID, "", 1)
+	assert.ErrorIs(t, err, ErrVendaCancelada)
+	_, err = b.CancelarVenda(999, "", 1)
+	assert.ErrorIs(t, err, ErrVendaNaoEncontrada)
+
+	// O caixa recebeu 45,00 e devolveu tudo em dinheiro
+	caixa, err := b.GetCaixaAtual(1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 45.0, caixa.Devolucoes)
+		assert.Equal(t, 0.0, esperadoCaixa(caixa, FormaDinheiro))
+	}
+}
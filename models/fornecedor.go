@@ -0,0 +1,269 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFornecedorNaoEncontrado indica que o fornecedor informado não existe
+	ErrFornecedorNaoEncontrado = errors.New("fornecedor não encontrado")
+	// ErrFornecedorInvalido indica dados obrigatórios ausentes ou inválidos no cadastro do fornecedor
+	ErrFornecedorInvalido = errors.New("fornecedor inválido")
+)
+
+// Fornecedor é um distribuidor ou laboratório de quem a farmácia compra
+type Fornecedor struct {
+	ID               int       `json:"id"`
+	Nome             string    `json:"nome"`
+	CNPJ             string    `json:"cnpj"` // Somente os 14 dígitos
+	Contato          string    `json:"contato"`
+	Telefone         string    `json:"telefone"`
+	Email            string    `json:"email"`
+	PrazoEntregaDias int       `json:"prazo_entrega_dias"` // Prazo médio entre o envio do pedido e a entrega
+	Ativo            bool      `json:"ativo"`
+	CriadoEm         time.Time `json:"criado_em"`
+}
+
+// FornecedorRequest é o que a API recebe para criar ou atualizar um fornecedor.
+// Na atualização, campos vazios mantêm o valor atual.
+type FornecedorRequest struct {
+	Nome             string `json:"nome"`
+	CNPJ             string `json:"cnpj"`
+	Contato          string `json:"contato"`
+	Telefone         string `json:"telefone"`
+	Email            string `json:"email"`
+	PrazoEntregaDias *int   `json:"prazo_entrega_dias"`
+	Ativo            *bool  `json:"ativo"`
+}
+
+// normalizarCNPJ remove a pontuação e confere os dígitos verificadores do CNPJ.
+func normalizarCNPJ(cnpj string) (string, error) {
+	digitos := somenteDigitos(cnpj)
+	if len(digitos) != 14 || strings.Count(digitos, digitos[:1]) == 14 {
+		return "", fmt.Errorf("%w: CNPJ '%s' deve ter 14 dígitos", ErrFornecedorInvalido, cnpj)
+	}
+
+	digito := func(base string) byte {
+		pesos := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}[13-len(base):]
+		soma := 0
+		for i := range base {
+			soma += int(base[i]-'0') * pesos[i]
+		}
+		resto := soma % 11
+		if resto < 2 {
+			return '0'
+		}
+		return byte('0' + 11 - resto)
+	}
+	if digito(digitos[:12]) != digitos[12] || digito(digitos[:13]) != digitos[13] {
+		return "", fmt.Errorf("%w: CNPJ '%s' com dígito verificador inválido", ErrFornecedorInvalido, cnpj)
+	}
+	return digitos, nil
+}
+
+// CriarFornecedor valida os dados e cadastra um novo fornecedor em nome de autorID.
+func (b *Banco) CriarFornecedor(req FornecedorRequest, autorID int) (*Fornecedor, error) {
+	fornecedor := &Fornecedor{Ativo: true}
+	if err := aplicarFornecedorRequest(fornecedor, req); err != nil {
+		return nil, err
+	}
+	if fornecedor.Nome == "" || fornecedor.CNPJ == "" {
+		return nil, fmt.Errorf("%w: nome e CNPJ são obrigatórios", ErrFornecedorInvalido)
+	}
+	if err := b.verificarCNPJDisponivel(fornecedor.CNPJ, 0); err != nil {
+		return nil, err
+	}
+
+	query := b.queries.GetQuery(qInserirFornecedor)
+	if query == "" {
+		return nil, errors.New("query 'inserir_fornecedor' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	fornecedor.CriadoEm = b.Agora()
+	id, err := b.inserirComID(tx, query, fornecedor.Nome, fornecedor.CNPJ, fornecedor.Contato, fornecedor.Telefone, fornecedor.Email,
+		fornecedor.PrazoEntregaDias, fornecedor.Ativo, fornecedor.CriadoEm)
+	if err != nil {
+		log.Printf("Erro ao inserir fornecedor '%s': %v", fornecedor.Nome, err)
+		return nil, err
+	}
+	fornecedor.ID = int(id)
+
+	if err := b.registrarAuditoria(tx, autorID, AcaoCriar, "fornecedor", strconv.Itoa(fornecedor.ID), nil, fornecedor); err != nil {
+		return nil, err
+	}
+	return fornecedor, tx.Commit()
+}
+
+// AtualizarFornecedor altera os dados de um fornecedor existente.
+func (b *Banco) AtualizarFornecedor(id int, req FornecedorRequest, autorID int) (*Fornecedor, error) {
+	fornecedor, err := b.GetFornecedor(id)
+	if err != nil {
+		return nil, err
+	}
+	if fornecedor == nil {
+		return nil, ErrFornecedorNaoEncontrado
+	}
+	anterior := *fornecedor
+
+	if err := aplicarFornecedorRequest(fornecedor, req); err != nil {
+		return nil, err
+	}
+	if fornecedor.CNPJ != anterior.CNPJ {
+		if err := b.verificarCNPJDisponivel(fornecedor.CNPJ, id); err != nil {
+			return nil, err
+		}
+	}
+
+	query := b.queries.GetQuery(qAtualizarFornecedor)
+	if query == "" {
+		return nil, errors.New("query 'atualizar_fornecedor' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(query, fornecedor.Nome, fornecedor.CNPJ, fornecedor.Contato, fornecedor.Telefone, fornecedor.Email,
+		fornecedor.PrazoEntregaDias, fornecedor.Ativo, fornecedor.ID)
+	if err != nil {
+		log.Printf("Erro ao atualizar fornecedor %d: %v", id, err)
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, autorID, AcaoAtualizar, "fornecedor", strconv.Itoa(id), anterior, fornecedor); err != nil {
+		return nil, err
+	}
+	return fornecedor, tx.Commit()
+}
+
+// DesativarFornecedor impede novos pedidos ao fornecedor sem apagar o histórico de compras.
+func (b *Banco) DesativarFornecedor(id int, autorID int) error {
+	ativo := false
+	_, err := b.AtualizarFornecedor(id, FornecedorRequest{Ativo: &ativo}, autorID)
+	return err
+}
+
+// aplicarFornecedorRequest copia para o fornecedor os campos preenchidos na requisição.
+func aplicarFornecedorRequest(f *Fornecedor, req FornecedorRequest) error {
+	if nome := strings.TrimSpace(req.Nome); nome != "" {
+		f.Nome = nome
+	}
+	if req.CNPJ != "" {
+		cnpj, err := normalizarCNPJ(req.CNPJ)
+		if err != nil {
+			return err
+		}
+		f.CNPJ = cnpj
+	}
+	if req.Contato != "" {
+		f.Contato = req.Contato
+	}
+	if req.Telefone != "" {
+		f.Telefone = req.Telefone
+	}
+	if req.Email != "" {
+		f.Email = req.Email
+	}
+	if req.PrazoEntregaDias != nil {
+		if *req.PrazoEntregaDias < 0 {
+			return fmt.Errorf("%w: o prazo de entrega não pode ser negativo", ErrFornecedorInvalido)
+		}
+		f.PrazoEntregaDias = *req.PrazoEntregaDias
+	}
+	if req.Ativo != nil {
+		f.Ativo = *req.Ativo
+	}
+	return nil
+}
+
+// verificarCNPJDisponivel recusa um CNPJ já cadastrado para outro fornecedor.
+func (b *Banco) verificarCNPJDisponivel(cnpj string, id int) error {
+	existente, err := b.GetFornecedorByCNPJ(cnpj)
+	if err != nil {
+		return err
+	}
+	if existente != nil && existente.ID != id {
+		return fmt.Errorf("%w: CNPJ já cadastrado para '%s'", ErrFornecedorInvalido, existente.Nome)
+	}
+	return nil
+}
+
+// GetFornecedor retorna um fornecedor pelo ID, ou nil se não existir.
+func (b *Banco) GetFornecedor(id int) (*Fornecedor, error) {
+	return b.selecionarFornecedor(b.db, qSelecionarFornecedorPorId, id)
+}
+
+// GetFornecedorByCNPJ retorna um fornecedor pelo CNPJ (com ou sem pontuação), ou nil se não existir.
+func (b *Banco) GetFornecedorByCNPJ(cnpj string) (*Fornecedor, error) {
+	return b.selecionarFornecedor(b.db, qSelecionarFornecedorPorCnpj, somenteDigitos(cnpj))
+}
+
+func (b *Banco) selecionarFornecedor(db execer, nomeQuery string, arg interface{}) (*Fornecedor, error) {
+	query := b.queries.GetQuery(nomeQuery)
+	if query == "" {
+		return nil, fmt.Errorf("query '%s' não encontrada", nomeQuery)
+	}
+
+	f, err := scanFornecedor(db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil // Não é um erro, apenas não encontrou
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func scanFornecedor(row scanner) (*Fornecedor, error) {
+	var f Fornecedor
+	var contato, telefone, email sql.NullString
+	err := row.Scan(&f.ID, &f.Nome, &f.CNPJ, &contato, &telefone, &email, &f.PrazoEntregaDias, &f.Ativo, &f.CriadoEm)
+	if err != nil {
+		return nil, err
+	}
+	f.Contato = contato.String
+	f.Telefone = telefone.String
+	f.Email = email.String
+	return &f, nil
+}
+
+// ListarFornecedores retorna os fornecedores em ordem alfabética, opcionalmente incluindo os inativos.
+func (b *Banco) ListarFornecedores(incluirInativos bool) ([]Fornecedor, error) {
+	query := b.queries.GetQuery(qSelecionarTodosFornecedores)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_todos_fornecedores' não encontrada")
+	}
+
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fornecedores []Fornecedor
+	for rows.Next() {
+		f, err := scanFornecedor(rows)
+		if err != nil {
+			log.Printf("Erro ao escanear linha do fornecedor: %v", err)
+			continue
+		}
+		if !f.Ativo && !incluirInativos {
+			continue
+		}
+		fornecedores = append(fornecedores, *f)
+	}
+	return fornecedores, rows.Err()
+}
@@ -2,11 +2,11 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"time"
 
 	"github.com/google/uuid"
 )
@@ -18,6 +18,7 @@ type MedicamentoImportado struct {
 	CodigoAnvisa      string  `json:"codigo_anvisa"`
 	QuantidadeEstoque int     `json:"quantidade_estoque"`
 	PrecoVenda        float64 `json:"preco_venda"`
+	PrecoCusto        float64 `json:"preco_custo"` // Opcional; vira o custo médio inicial
 	Fabricante        string  `json:"fabricante"`
 	DataValidade      string  `json:"data_validade"`
 	DataEntrada       string  `json:"data_entrada"`
@@ -25,9 +26,9 @@ type MedicamentoImportado struct {
 }
 
 // ImportarMedicamentos carrega medicamentos do arquivo JSON e os adiciona ao banco de dados
-func ImportarMedicamentos() error {
+func (b *Banco) ImportarMedicamentos() error {
 	// Verificar se já existem medicamentos no banco para evitar duplicação
-	medicamentosExistentes, err := GetMedicamentos()
+	medicamentosExistentes, err := b.GetMedicamentos()
 	if err == nil && len(medicamentosExistentes) > 0 {
 		log.Println("O banco de dados já contém medicamentos. A importação será ignorada.")
 		return nil // Não retorna erro, simplesmente não faz nada
@@ -61,12 +62,13 @@ func ImportarMedicamentos() error {
 			CodigoANVISA: medData.CodigoAnvisa,
 			Quantidade:   medData.QuantidadeEstoque,
 			Preco:        medData.PrecoVenda,
+			CustoMedio:   medData.PrecoCusto,
 			Validade:     medData.DataValidade,
-			CriadoEm:     time.Now(),
+			CriadoEm:     b.Agora(),
 		}
 
 		// Adicionar ao banco de dados
-		if err := AddMedicamento(med); err != nil {
+		if err := b.AddMedicamento(med, 0); err != nil {
 			log.Printf("Erro ao adicionar medicamento %s: %v", med.Nome, err)
 			continue
 		}
@@ -77,7 +79,7 @@ func ImportarMedicamentos() error {
 }
 
 // CorrigirPrecosMedicamentos atualiza os preços dos medicamentos existentes no banco com base no arquivo JSON.
-func CorrigirPrecosMedicamentos() error {
+func (b *Banco) CorrigirPrecosMedicamentos() error {
 	log.Println("Iniciando a correção de preços dos medicamentos existentes...")
 
 	// 1. Carregar os dados do JSON
@@ -102,20 +104,18 @@ func CorrigirPrecosMedicamentos() error {
 	}
 
 	// 2. Buscar todos os medicamentos do banco de dados
-	medicamentosDB, err := GetMedicamentos()
+	medicamentosDB, err := b.GetMedicamentos()
 	if err != nil {
 		return fmt.Errorf("erro ao buscar medicamentos do banco para correção: %v", err)
 	}
 
-	// 3. Atualizar cada medicamento no banco de dados
-	queryUpdatePreco := "UPDATE medicamentos SET preco = ? WHERE CodigoANVISA = ?"
+	// 3. Atualizar cada medicamento sem preço, registrando a alteração no histórico
 	for _, medDB := range medicamentosDB {
 		if preco, ok := precosPorCodigo[medDB.CodigoANVISA]; ok {
 			// Apenas atualiza se o preço atual for 0 ou nulo, para segurança
-			if medDB.Preco == 0 {
-				_, err := sqlDB.Exec(queryUpdatePreco, preco, medDB.CodigoANVISA)
-				if err != nil {
-					log.Printf("Erro ao atualizar o preço para o medicamento com código ANVISA %s: %v", medDB.CodigoANVISA, err)
+			if medDB.Preco == 0 && preco > 0 {
+				if err := b.corrigirPrecoMedicamento(medDB, preco); err != nil {
+					log.Printf("Preço do medicamento %s (ANVISA: %s) não corrigido: %v", medDB.Nome, medDB.CodigoANVISA, err)
 					// Continua para o próximo mesmo se um falhar
 				} else {
 					log.Printf("Preço do medicamento %s (ANVISA: %s) corrigido para R$ %.2f", medDB.Nome, medDB.CodigoANVISA, preco)
@@ -127,3 +127,33 @@ func CorrigirPrecosMedicamentos() error {
 	log.Println("Correção de preços concluída.")
 	return nil
 }
+
+// corrigirPrecoMedicamento grava o preço do arquivo de importação, respeitando o PMC da tabela CMED.
+func (b *Banco) corrigirPrecoMedicamento(med Medicamento, preco float64) error {
+	query := b.queries.GetQuery(qAtualizarPrecoMedicamento)
+	if query == "" {
+		return errors.New("query 'atualizar_preco_medicamento' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := b.validarPrecoPMC(tx, med.CodigoANVISA, preco); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(query, preco, med.ID); err != nil {
+		return err
+	}
+	if err := b.registrarHistoricoPreco(tx, med.ID, med.Preco, preco, OrigemPrecoImportacao, 0, 0); err != nil {
+		return err
+	}
+	antes := map[string]interface{}{"preco": med.Preco}
+	depois := map[string]interface{}{"preco": preco, "origem": OrigemPrecoImportacao}
+	if err := b.registrarAuditoria(tx, 0, AcaoAtualizar, "medicamento", med.ID, antes, depois); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,526 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Gravidades das interações medicamentosas, da menor para a maior. As graves e as contraindicadas
+// só são vendidas juntas com a liberação de um farmacêutico.
+const (
+	GravidadeLeve           = "leve"
+	GravidadeModerada       = "moderada"
+	GravidadeGrave          = "grave"
+	GravidadeContraindicada = "contraindicada"
+)
+
+// nivelGravidade ordena as gravidades; as de nível grave ou acima exigem o farmacêutico
+var nivelGravidade = map[string]int{
+	GravidadeLeve:           1,
+	GravidadeModerada:       2,
+	GravidadeGrave:          3,
+	GravidadeContraindicada: 4,
+}
+
+var (
+	// ErrInteracoesInvalidas indica uma planilha de interações que não pôde ser lida
+	ErrInteracoesInvalidas = errors.New("tabela de interações inválida")
+	// ErrInteracaoNaoConfirmada indica uma venda com interações sem a ciência do atendente
+	ErrInteracaoNaoConfirmada = errors.New("a venda tem interações medicamentosas que precisam ser confirmadas")
+	// ErrLiberacaoFarmaceutico indica uma interação grave sem a liberação de um farmacêutico
+	ErrLiberacaoFarmaceutico = errors.New("interação grave: a venda precisa da liberação de um farmacêutico")
+)
+
+// InteracaoMedicamentosa é uma interação entre duas substâncias, gravadas normalizadas e em ordem
+type InteracaoMedicamentosa struct {
+	ID          int64  `json:"id"`
+	SubstanciaA string `json:"substancia_a"`
+	SubstanciaB string `json:"substancia_b"`
+	Gravidade   string `json:"gravidade"`
+	Descricao   string `json:"descricao"`
+	Conduta     string `json:"conduta"`
+}
+
+// AlertaInteracao é uma interação encontrada entre dois medicamentos da venda
+type AlertaInteracao struct {
+	MedicamentoAID    string `json:"medicamento_a_id"`
+	MedicamentoANome  string `json:"medicamento_a_nome"`
+	MedicamentoBID    string `json:"medicamento_b_id"`
+	MedicamentoBNome  string `json:"medicamento_b_nome"`
+	SubstanciaA       string `json:"substancia_a"`
+	SubstanciaB       string `json:"substancia_b"`
+	Gravidade         string `json:"gravidade"`
+	Descricao         string `json:"descricao"`
+	Conduta           string `json:"conduta"`
+	ExigeFarmaceutico bool   `json:"exige_farmaceutico"`
+}
+
+// VerificacaoInteracoes é o resultado da conferência dos itens de uma venda, do alerta mais grave
+// para o mais leve. ExigeCiente e ExigeFarmaceutico dizem o que a venda precisa para ser registrada.
+type VerificacaoInteracoes struct {
+	Alertas           []AlertaInteracao `json:"alertas"`
+	ExigeCiente       bool              `json:"exige_ciente"`
+	ExigeFarmaceutico bool              `json:"exige_farmaceutico"`
+}
+
+// ConfirmacaoInteracoes é a resposta do PDV aos alertas de interação da venda
+type ConfirmacaoInteracoes struct {
+	// Ciente confirma que o atendente viu os alertas e orientou o cliente
+	Ciente bool `json:"ciente"`
+	// Justificativa da liberação; obrigatória nas interações graves
+	Justificativa string `json:"justificativa,omitempty"`
+	// Liberacao são as credenciais do farmacêutico que libera uma interação grave na venda de um
+	// atendente. Quando o próprio vendedor é farmacêutico ou administrador, não é preciso informá-las.
+	Liberacao *CredenciaisLiberacao `json:"liberacao,omitempty"`
+}
+
+// CredenciaisLiberacao identifica o farmacêutico que libera a venda no balcão
+type CredenciaisLiberacao struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ErroInteracoes recusa uma venda por causa das interações, levando os alertas para que o PDV os mostre
+type ErroInteracoes struct {
+	Motivo      error
+	Detalhe     string
+	Verificacao *VerificacaoInteracoes
+}
+
+func (e *ErroInteracoes) Error() string {
+	if e.Detalhe == "" {
+		return e.Motivo.Error()
+	}
+	return e.Motivo.Error() + ": " + e.Detalhe
+}
+
+func (e *ErroInteracoes) Unwrap() error {
+	return e.Motivo
+}
+
+// InteracaoVenda é um alerta de interação gravado na venda, com quem confirmou e quem liberou
+type InteracaoVenda struct {
+	MedicamentoAID      string    `json:"medicamento_a_id"`
+	MedicamentoANome    string    `json:"medicamento_a_nome"`
+	MedicamentoBID      string    `json:"medicamento_b_id"`
+	MedicamentoBNome    string    `json:"medicamento_b_nome"`
+	SubstanciaA         string    `json:"substancia_a"`
+	SubstanciaB         string    `json:"substancia_b"`
+	Gravidade           string    `json:"gravidade"`
+	CienteUserID        int       `json:"ciente_user_id"`
+	CienteUsername      string    `json:"ciente_username"`
+	LiberadoPorUserID   *int      `json:"liberado_por_user_id,omitempty"`
+	LiberadoPorUsername string    `json:"liberado_por_username,omitempty"`
+	Justificativa       string    `json:"justificativa,omitempty"`
+	CriadoEm            time.Time `json:"criado_em"`
+}
+
+// ImportacaoInteracoes resume a carga de uma tabela de interações
+type ImportacaoInteracoes struct {
+	Importadas  int       `json:"importadas"`
+	Substituida bool      `json:"substituida"` // A tabela anterior foi apagada antes da carga
+	Total       int       `json:"total"`       // Interações cadastradas depois da carga
+	ImportadoEm time.Time `json:"importado_em"`
+}
+
+// normalizarGravidade aceita a gravidade com acentos, maiúsculas e espaços, como "Contra-indicada"
+func normalizarGravidade(gravidade string) (string, bool) {
+	g := strings.ReplaceAll(normalizarBusca(gravidade), " ", "")
+	if g == "contraindicado" {
+		g = GravidadeContraindicada
+	}
+	_, ok := nivelGravidade[g]
+	return g, ok
+}
+
+// normalizarInteracao deixa as substâncias normalizadas e em ordem, para que cada par tenha uma só linha
+func normalizarInteracao(i *InteracaoMedicamentosa) error {
+	a, b := normalizarBusca(i.SubstanciaA), normalizarBusca(i.SubstanciaB)
+	if a == "" || b == "" {
+		return errors.New("informe as duas substâncias")
+	}
+	if a == b {
+		return fmt.Errorf("a substância '%s' não interage com ela mesma", i.SubstanciaA)
+	}
+	if a > b {
+		a, b = b, a
+	}
+	gravidade, ok := normalizarGravidade(i.Gravidade)
+	if !ok {
+		return fmt.Errorf("gravidade '%s' inválida: use leve, moderada, grave ou contraindicada", i.Gravidade)
+	}
+	i.SubstanciaA, i.SubstanciaB, i.Gravidade = a, b, gravidade
+	i.Descricao = strings.TrimSpace(i.Descricao)
+	i.Conduta = strings.TrimSpace(i.Conduta)
+	return nil
+}
+
+// colunasInteracoes são as colunas da planilha, com o nome normalizado por normalizarBusca
+var colunasInteracoes = []string{"substancia a", "substancia b", "gravidade", "descricao", "conduta"}
+
+// ParseInteracoes lê a tabela de interações em JSON (uma lista de objetos com os campos de
+// InteracaoMedicamentosa) ou em CSV com cabeçalho substancia_a, substancia_b, gravidade, descricao e
+// conduta, separado por ';' ou ','. O CSV pode estar em UTF-8 ou Latin-1.
+func ParseInteracoes(data []byte) ([]InteracaoMedicamentosa, error) {
+	if !utf8.Valid(data) {
+		data = latin1ParaUTF8(data)
+	}
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: arquivo vazio", ErrInteracoesInvalidas)
+	}
+
+	var interacoes []InteracaoMedicamentosa
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &interacoes); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInteracoesInvalidas, err)
+		}
+		for n := range interacoes {
+			if err := normalizarInteracao(&interacoes[n]); err != nil {
+				return nil, fmt.Errorf("%w: item %d: %v", ErrInteracoesInvalidas, n+1, err)
+			}
+		}
+	} else {
+		var err error
+		if interacoes, err = parseInteracoesCSV(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(interacoes) == 0 {
+		return nil, fmt.Errorf("%w: nenhuma interação encontrada", ErrInteracoesInvalidas)
+	}
+	return interacoes, nil
+}
+
+func parseInteracoesCSV(data []byte) ([]InteracaoMedicamentosa, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	// O separador é o que aparece no cabeçalho: ';' nas planilhas em português, ',' nas demais
+	cabecalho, _, _ := bytes.Cut(data, []byte("\n"))
+	r.Comma = ','
+	if bytes.Count(cabecalho, []byte(";")) > bytes.Count(cabecalho, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	registro, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInteracoesInvalidas, err)
+	}
+	colunas := map[string]int{}
+	for i, nome := range registro {
+		colunas[normalizarBusca(nome)] = i
+	}
+	for _, nome := range colunasInteracoes[:3] {
+		if _, ok := colunas[nome]; !ok {
+			return nil, fmt.Errorf("%w: coluna '%s' não encontrada", ErrInteracoesInvalidas, strings.ReplaceAll(nome, " ", "_"))
+		}
+	}
+
+	var interacoes []InteracaoMedicamentosa
+	for linha := 2; ; linha++ {
+		registro, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInteracoesInvalidas, err)
+		}
+		campo := func(nome string) string {
+			i, ok := colunas[nome]
+			if !ok || i >= len(registro) {
+				return ""
+			}
+			return registro[i]
+		}
+		if strings.TrimSpace(strings.Join(registro, "")) == "" {
+			continue
+		}
+		interacao := InteracaoMedicamentosa{
+			SubstanciaA: campo("substancia a"),
+			SubstanciaB: campo("substancia b"),
+			Gravidade:   campo("gravidade"),
+			Descricao:   campo("descricao"),
+			Conduta:     campo("conduta"),
+		}
+		if err := normalizarInteracao(&interacao); err != nil {
+			return nil, fmt.Errorf("%w: linha %d: %v", ErrInteracoesInvalidas, linha, err)
+		}
+		interacoes = append(interacoes, interacao)
+	}
+	return interacoes, nil
+}
+
+// ImportarInteracoes grava as interações do arquivo. Um par já cadastrado é atualizado; com
+// substituir, as interações que não estão no arquivo são apagadas.
+func (b *Banco) ImportarInteracoes(data []byte, substituir bool, usuarioID int) (*ImportacaoInteracoes, error) {
+	interacoes, err := ParseInteracoes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	queryExcluir := b.queries.GetQuery(qExcluirInteracoesMedicamentosas)
+	queryInserir := b.queries.GetQuery(qInserirInteracaoMedicamentosa)
+	queryContar := b.queries.GetQuery(qContarInteracoes)
+	if queryExcluir == "" || queryInserir == "" || queryContar == "" {
+		return nil, errors.New("queries da tabela de interações não encontradas")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if substituir {
+		if _, err := tx.Exec(queryExcluir); err != nil {
+			return nil, err
+		}
+	}
+	stmt, err := tx.Prepare(queryInserir)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	for _, i := range interacoes {
+		if _, err := stmt.Exec(i.SubstanciaA, i.SubstanciaB, i.Gravidade, i.Descricao, i.Conduta); err != nil {
+			return nil, fmt.Errorf("erro ao gravar a interação %s + %s: %w", i.SubstanciaA, i.SubstanciaB, err)
+		}
+	}
+
+	resumo := &ImportacaoInteracoes{Importadas: len(interacoes), Substituida: substituir, ImportadoEm: b.Agora()}
+	if err := tx.QueryRow(queryContar).Scan(&resumo.Total); err != nil {
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "tabela_interacoes", "", nil, resumo); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("Tabela de interações importada: %d interações (%d cadastradas)", resumo.Importadas, resumo.Total)
+	return resumo, nil
+}
+
+// ListarInteracoes lista as interações cadastradas; com substância, só as que a mencionam.
+func (b *Banco) ListarInteracoes(substancia string) ([]InteracaoMedicamentosa, error) {
+	interacoes, err := b.interacoesCadastradas(b.db)
+	if err != nil {
+		return nil, err
+	}
+	termo := normalizarBusca(substancia)
+	filtradas := []InteracaoMedicamentosa{}
+	for _, i := range interacoes {
+		if strings.Contains(i.SubstanciaA, termo) || strings.Contains(i.SubstanciaB, termo) {
+			filtradas = append(filtradas, i)
+		}
+	}
+	return filtradas, nil
+}
+
+func (b *Banco) interacoesCadastradas(db execer) ([]InteracaoMedicamentosa, error) {
+	query := b.queries.GetQuery(qSelecionarInteracoesMedicamentosas)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_interacoes_medicamentosas' não encontrada")
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interacoes []InteracaoMedicamentosa
+	for rows.Next() {
+		var i InteracaoMedicamentosa
+		if err := rows.Scan(&i.ID, &i.SubstanciaA, &i.SubstanciaB, &i.Gravidade, &i.Descricao, &i.Conduta); err != nil {
+			return nil, err
+		}
+		interacoes = append(interacoes, i)
+	}
+	return interacoes, rows.Err()
+}
+
+// contemSubstancia informa se o princípio ativo, já normalizado, é a substância ou um sal dela:
+// "cloridrato de sertralina" e "sertralina" contêm "sertralina".
+func contemSubstancia(principio, substancia string) bool {
+	return strings.Contains(" "+principio+" ", " "+substancia+" ")
+}
+
+// VerificarInteracoes confere as combinações dos medicamentos da venda com a tabela de interações,
+// pelos princípios ativos de cada um. Medicamentos não cadastrados são ignorados aqui e recusados
+// no registro da venda.
+func (b *Banco) VerificarInteracoes(itens []ItemVendaRequest) (*VerificacaoInteracoes, error) {
+	verificacao := &VerificacaoInteracoes{Alertas: []AlertaInteracao{}}
+
+	var medicamentos []*Medicamento
+	vistos := map[int]bool{}
+	for _, item := range itens {
+		if vistos[item.MedicamentoID] {
+			continue
+		}
+		vistos[item.MedicamentoID] = true
+		med, err := b.repos.Medicamentos.BuscarPorID(b.db, strconv.Itoa(item.MedicamentoID))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar o medicamento com ID %d: %w", item.MedicamentoID, err)
+		}
+		if med != nil && len(med.PrincipiosAtivos) > 0 {
+			medicamentos = append(medicamentos, med)
+		}
+	}
+	if len(medicamentos) < 2 {
+		return verificacao, nil
+	}
+
+	interacoes, err := b.interacoesCadastradas(b.db)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(medicamentos); i++ {
+		for j := i + 1; j < len(medicamentos); j++ {
+			verificacao.Alertas = append(verificacao.Alertas, alertasEntre(medicamentos[i], medicamentos[j], interacoes)...)
+		}
+	}
+
+	sort.SliceStable(verificacao.Alertas, func(i, j int) bool {
+		return nivelGravidade[verificacao.Alertas[i].Gravidade] > nivelGravidade[verificacao.Alertas[j].Gravidade]
+	})
+	for _, alerta := range verificacao.Alertas {
+		verificacao.ExigeCiente = true
+		verificacao.ExigeFarmaceutico = verificacao.ExigeFarmaceutico || alerta.ExigeFarmaceutico
+	}
+	return verificacao, nil
+}
+
+// alertasEntre lista as interações entre os princípios ativos de dois medicamentos, uma vez cada
+func alertasEntre(a, b *Medicamento, interacoes []InteracaoMedicamentosa) []AlertaInteracao {
+	var alertas []AlertaInteracao
+	encontradas := map[int64]bool{}
+	for _, pa := range a.PrincipiosAtivos {
+		for _, pb := range b.PrincipiosAtivos {
+			nomeA, nomeB := normalizarBusca(pa.Nome), normalizarBusca(pb.Nome)
+			for _, i := range interacoes {
+				if encontradas[i.ID] {
+					continue
+				}
+				direta := contemSubstancia(nomeA, i.SubstanciaA) && contemSubstancia(nomeB, i.SubstanciaB)
+				inversa := contemSubstancia(nomeA, i.SubstanciaB) && contemSubstancia(nomeB, i.SubstanciaA)
+				if !direta && !inversa {
+					continue
+				}
+				encontradas[i.ID] = true
+				alerta := AlertaInteracao{
+					MedicamentoAID: a.ID, MedicamentoANome: a.Nome,
+					MedicamentoBID: b.ID, MedicamentoBNome: b.Nome,
+					SubstanciaA: i.SubstanciaA, SubstanciaB: i.SubstanciaB,
+					Gravidade: i.Gravidade, Descricao: i.Descricao, Conduta: i.Conduta,
+					ExigeFarmaceutico: nivelGravidade[i.Gravidade] >= nivelGravidade[GravidadeGrave],
+				}
+				if !direta {
+					// O medicamento A é o da primeira substância
+					alerta.MedicamentoAID, alerta.MedicamentoBID = b.ID, a.ID
+					alerta.MedicamentoANome, alerta.MedicamentoBNome = b.Nome, a.Nome
+				}
+				alertas = append(alertas, alerta)
+			}
+		}
+	}
+	return alertas
+}
+
+// confirmarInteracoes confere a resposta do PDV aos alertas e retorna o ID do farmacêutico que
+// liberou as interações graves, ou nil quando não houve liberação.
+func (b *Banco) confirmarInteracoes(verificacao *VerificacaoInteracoes, confirmacao *ConfirmacaoInteracoes, userID int, role string) (*int, error) {
+	if !verificacao.ExigeCiente {
+		return nil, nil
+	}
+	if confirmacao == nil || !confirmacao.Ciente {
+		return nil, &ErroInteracoes{Motivo: ErrInteracaoNaoConfirmada, Verificacao: verificacao}
+	}
+	if !verificacao.ExigeFarmaceutico {
+		return nil, nil
+	}
+
+	recusar := func(detalhe string) (*int, error) {
+		return nil, &ErroInteracoes{Motivo: ErrLiberacaoFarmaceutico, Detalhe: detalhe, Verificacao: verificacao}
+	}
+	if strings.TrimSpace(confirmacao.Justificativa) == "" {
+		return recusar("informe a justificativa da liberação")
+	}
+	if role == RoleFarmaceutico || role == RoleAdmin {
+		return &userID, nil
+	}
+	if confirmacao.Liberacao == nil {
+		return recusar("informe o usuário e a senha do farmacêutico")
+	}
+	farmaceutico, err := b.AutenticarUsuario(confirmacao.Liberacao.Username, confirmacao.Liberacao.Password)
+	if errors.Is(err, ErrCredenciaisInvalidas) || errors.Is(err, ErrUsuarioBloqueado) {
+		return recusar(err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if farmaceutico.Role != RoleFarmaceutico && farmaceutico.Role != RoleAdmin {
+		return recusar(fmt.Sprintf("'%s' não é farmacêutico", farmaceutico.Username))
+	}
+	return &farmaceutico.ID, nil
+}
+
+// registrarInteracoesVenda grava na venda os alertas confirmados
+func (b *Banco) registrarInteracoesVenda(tx execer, vendaID int64, alertas []AlertaInteracao, userID int, liberadoPor *int, justificativa string) error {
+	if len(alertas) == 0 {
+		return nil
+	}
+	query := b.queries.GetQuery(qInserirVendaInteracao)
+	if query == "" {
+		return errors.New("query 'inserir_venda_interacao' não encontrada")
+	}
+	agora := b.Agora()
+	for _, a := range alertas {
+		if _, err := tx.Exec(query, vendaID, a.MedicamentoAID, a.MedicamentoBID, a.SubstanciaA, a.SubstanciaB, a.Gravidade,
+			userID, liberadoPor, strings.TrimSpace(justificativa), agora); err != nil {
+			return fmt.Errorf("erro ao registrar a interação %s + %s na venda: %w", a.SubstanciaA, a.SubstanciaB, err)
+		}
+	}
+	return nil
+}
+
+// interacoesVenda lista os alertas de interação gravados na venda
+func (b *Banco) interacoesVenda(vendaID int64) ([]InteracaoVenda, error) {
+	query := b.queries.GetQuery(qSelecionarInteracoesVenda)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_interacoes_venda' não encontrada")
+	}
+	rows, err := b.db.Query(query, vendaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interacoes []InteracaoVenda
+	for rows.Next() {
+		var i InteracaoVenda
+		var liberadoPor sql.NullInt64
+		if err := rows.Scan(&i.MedicamentoAID, &i.MedicamentoANome, &i.MedicamentoBID, &i.MedicamentoBNome, &i.SubstanciaA, &i.SubstanciaB,
+			&i.Gravidade, &i.CienteUserID, &i.CienteUsername, &liberadoPor, &i.LiberadoPorUsername, &i.Justificativa, &i.CriadoEm); err != nil {
+			return nil, err
+		}
+		if liberadoPor.Valid {
+			id := int(liberadoPor.Int64)
+			i.LiberadoPorUserID = &id
+		}
+		interacoes = append(interacoes, i)
+	}
+	return interacoes, rows.Err()
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInteracoes(t *testing.T) {
+	data, err := os.ReadFile("testdata/interacoes_exemplo.csv")
+	if err != nil {
+		t.Fatalf("erro ao ler tabela de exemplo: %v", err)
+	}
+	interacoes, err := ParseInteracoes(data)
+	if !assert.NoError(t, err) || !assert.Len(t, interacoes, 6) {
+		return
+	}
+	assert.Equal(t, InteracaoMedicamentosa{
+		SubstanciaA: "selegilina",
+		SubstanciaB: "sertralina",
+		Gravidade:   GravidadeContraindicada,
+		Descricao:   "Risco de síndrome serotoninérgica com IMAO",
+		Conduta:     "Não dispensar juntos; aguardar 14 dias após suspender o IMAO",
+	}, interacoes[0])
+	assert.Equal(t, "acido acetilsalicilico", interacoes[2].SubstanciaA)
+	assert.Equal(t, "varfarina", interacoes[2].SubstanciaB)
+
+	interacoes, err = ParseInteracoes([]byte(`[{"substancia_a": "Fluoxetina", "substancia_b": "Moclobemida", "gravidade": "Contra-indicada"}]`))
+	if assert.NoError(t, err) && assert.Len(t, interacoes, 1) {
+		assert.Equal(t, "fluoxetina", interacoes[0].SubstanciaA)
+		assert.Equal(t, GravidadeContraindicada, interacoes[0].Gravidade)
+	}
+	interacoes, err = ParseInteracoes([]byte("Substância A,Substância B,Gravidade\nlítio,ibuprofeno,grave\n"))
+	if assert.NoError(t, err) && assert.Len(t, interacoes, 1) {
+		assert.Equal(t, "ibuprofeno", interacoes[0].SubstanciaA)
+		assert.Equal(t, "litio", interacoes[0].SubstanciaB)
+	}
+
+	for _, invalida := range []string{
+		"",
+		"substancia_a;gravidade\nsertralina;grave",
+		"substancia_a;substancia_b;gravidade\nsertralina;selegilina;perigosa",
+		"substancia_a;substancia_b;gravidade\nsertralina;Sertralina;grave",
+		`[{"substancia_a": "sertralina", "gravidade": "grave"}]`,
+		"substancia_a;substancia_b;gravidade\n",
+	} {
+		_, err := ParseInteracoes([]byte(invalida))
+		assert.ErrorIs(t, err, ErrInteracoesInvalidas, invalida)
+	}
+	_, err = ParseInteracoes([]byte("substancia_a;substancia_b;gravidade\nsertralina;selegilina;grave\nsertralina;;grave"))
+	assert.ErrorContains(t, err, "linha 3")
+}
+
+func TestVendaComInteracoes(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	data, err := os.ReadFile("testdata/interacoes_exemplo.csv")
+	if err != nil {
+		t.Fatalf("erro ao ler tabela de exemplo: %v", err)
+	}
+	if _, err := b.ImportarInteracoes(data, true, 0); err != nil {
+		t.Fatalf("erro ao importar interações: %v", err)
+	}
+
+	for _, med := range []*Medicamento{
+		{ID: "1", Nome: "Sertralina 50mg", PrincipiosAtivos: []PrincipioAtivo{{Nome: "Cloridrato de sertralina", Concentracao: "50 mg"}}},
+		{ID: "2", Nome: "Jumexil 5mg", PrincipiosAtivos: []PrincipioAtivo{{Nome: "Cloridrato de selegilina", Concentracao: "5 mg"}}},
+		{ID: "3", Nome: "Ibuprofeno 400mg", PrincipiosAtivos: []PrincipioAtivo{{Nome: "Ibuprofeno", Concentracao: "400 mg"}}},
+		{ID: "4", Nome: "Losartana 50mg", PrincipiosAtivos: []PrincipioAtivo{{Nome: "Losartana potássica", Concentracao: "50 mg"}}},
+	} {
+		med.Quantidade, med.Preco = 20, 10
+		if !assert.NoError(t, b.AddMedicamento(med, 0)) {
+			return
+		}
+	}
+	atendente, err := b.CriarUsuario(UsuarioRequest{Username: "ana", Nome: "Ana", Password: "Balcao@2026", Role: RoleAtendente}, 0)
+	if err != nil {
+		t.Fatalf("erro ao criar atendente: %v", err)
+	}
+	farmaceutico, err := b.CriarUsuario(UsuarioRequest{Username: "carlos", Nome: "Carlos", Password: "Farmacia@2026", Role: RoleFarmaceutico}, 0)
+	if err != nil {
+		t.Fatalf("erro ao criar farmacêutico: %v", err)
+	}
+	for _, id := range []int{atendente.ID, farmaceutico.ID} {
+		if _, err := b.AbrirCaixa(id, 0); err != nil {
+			t.Fatalf("erro ao abrir caixa: %v", err)
+		}
+	}
+	vender := func(userID int, role string, confirmacao *ConfirmacaoInteracoes, ids ...int) (*ResumoVenda, error) {
+		req := RegistrarVendaRequest{Interacoes: confirmacao}
+		for _, id := range ids {
+			req.Itens = append(req.Itens, ItemVendaRequest{MedicamentoID: id, Quantidade: 1})
+		}
+		req.Pagamentos = []PagamentoVenda{{Forma: "dinheiro", Valor: float64(10 * len(ids))}}
+		return b.RegistrarVenda(req, userID, role)
+	}
+
+	// Sem interação entre os itens, a venda não pede confirmação
+	verificacao, err := b.VerificarInteracoes([]ItemVendaRequest{{MedicamentoID: 1}, {MedicamentoID: 4}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, &VerificacaoInteracoes{Alertas: []AlertaInteracao{}}, verificacao)
+	}
+	_, err = vender(atendente.ID, RoleAtendente, nil, 1, 4)
+	assert.NoError(t, err)
+
+	// Interação moderada: basta a ciência do atendente
+	verificacao, err = b.VerificarInteracoes([]ItemVendaRequest{{MedicamentoID: 4}, {MedicamentoID: 3}})
+	if assert.NoError(t, err) && assert.Len(t, verificacao.Alertas, 1) {
+		assert.True(t, verificacao.ExigeCiente)
+		assert.False(t, verificacao.ExigeFarmaceutico)
+		alerta := verificacao.Alertas[0]
+		assert.Equal(t, []string{"ibuprofeno", "losartana", GravidadeModerada}, []string{alerta.SubstanciaA, alerta.SubstanciaB, alerta.Gravidade})
+		assert.Equal(t, "3", alerta.MedicamentoAID, "o medicamento A é o da substância A")
+	}
+	_, err = vender(atendente.ID, RoleAtendente, nil, 3, 4)
+	var erroInteracoes *ErroInteracoes
+	if assert.ErrorIs(t, err, ErrInteracaoNaoConfirmada) && assert.True(t, errors.As(err, &erroInteracoes)) {
+		assert.Len(t, erroInteracoes.Verificacao.Alertas, 1)
+	}
+	resumo, err := vender(atendente.ID, RoleAtendente, &ConfirmacaoInteracoes{Ciente: true}, 3, 4)
+	if assert.NoError(t, err) && assert.Len(t, resumo.Interacoes, 1) {
+		venda, err := b.GetVenda(resumo.VendaID)
+		if assert.NoError(t, err) && assert.Len(t, venda.Interacoes, 1) {
+			assert.Equal(t, atendente.ID, venda.Interacoes[0].CienteUserID)
+			assert.Equal(t, "ana", venda.Interacoes[0].CienteUsername)
+			assert.Equal(t, "Ibuprofeno 400mg", venda.Interacoes[0].MedicamentoANome)
+			assert.Nil(t, venda.Interacoes[0].LiberadoPorUserID)
+		}
+	}
+
+	// Contraindicada: o atendente precisa da liberação de um farmacêutico, com justificativa
+	ciente := &ConfirmacaoInteracoes{Ciente: true, Justificativa: "Troca de antidepressivo orientada pelo psiquiatra"}
+	_, err = vender(atendente.ID, RoleAtendente, &ConfirmacaoInteracoes{Ciente: true}, 1, 2)
+	assert.ErrorIs(t, err, ErrLiberacaoFarmaceutico)
+	_, err = vender(atendente.ID, RoleAtendente, ciente, 1, 2)
+	assert.ErrorIs(t, err, ErrLiberacaoFarmaceutico)
+	ciente.Liberacao = &CredenciaisLiberacao{Username: "ana", Password: "Balcao@2026"}
+	_, err = vender(atendente.ID, RoleAtendente, ciente, 1, 2)
+	assert.ErrorContains(t, err, "não é farmacêutico")
+	ciente.Liberacao = &CredenciaisLiberacao{Username: "carlos", Password: "errada"}
+	_, err = vender(atendente.ID, RoleAtendente, ciente, 1, 2)
+	assert.ErrorIs(t, err, ErrLiberacaoFarmaceutico)
+
+	ciente.Liberacao = &CredenciaisLiberacao{Username: "carlos", Password: "Farmacia@2026"}
+	resumo, err = vender(atendente.ID, RoleAtendente, ciente, 1, 2)
+	if assert.NoError(t, err) {
+		venda, err := b.GetVenda(resumo.VendaID)
+		if assert.NoError(t, err) && assert.Len(t, venda.Interacoes, 1) {
+			assert.Equal(t, GravidadeContraindicada, venda.Interacoes[0].Gravidade)
+			assert.Equal(t, &farmaceutico.ID, venda.Interacoes[0].LiberadoPorUserID)
+			assert.Equal(t, "carlos", venda.Interacoes[0].LiberadoPorUsername)
+			assert.Equal(t, ciente.Justificativa, venda.Interacoes[0].Justificativa)
+		}
+	}
+
+	// O farmacêutico libera a própria venda
+	resumo, err = vender(farmaceutico.ID, RoleFarmaceutico, &ConfirmacaoInteracoes{Ciente: true, Justificativa: "Prescrição conferida"}, 2, 1)
+	if assert.NoError(t, err) {
+		venda, err := b.GetVenda(resumo.VendaID)
+		if assert.NoError(t, err) && assert.Len(t, venda.Interacoes, 1) {
+			assert.Equal(t, &farmaceutico.ID, venda.Interacoes[0].LiberadoPorUserID)
+		}
+	}
+
+	// A carga sem substituir atualiza o par e mantém os demais
+	resumoCarga, err := b.ImportarInteracoes([]byte("substancia_a;substancia_b;gravidade\nIbuprofeno;Losartana;leve\n"), false, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 6, resumoCarga.Total)
+	}
+	interacoes, err := b.ListarInteracoes("losartana")
+	if assert.NoError(t, err) && assert.Len(t, interacoes, 1) {
+		assert.Equal(t, GravidadeLeve, interacoes[0].Gravidade)
+	}
+}
@@ -0,0 +1,747 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status do inventário
+const (
+	StatusInventarioAberto    = "aberto"
+	StatusInventarioAprovado  = "aprovado"
+	StatusInventarioCancelado = "cancelado"
+)
+
+// Tipos de ajuste gravados na aprovação do inventário
+const (
+	AjusteInventarioEntrada = "entrada"
+	AjusteInventarioSaida   = "saida"
+	// AjusteInventarioNormalizacao alinha a quantidade do medicamento com o saldo dos lotes antes da
+	// contagem por lote, quando as duas divergem (estoque editado manualmente, por exemplo)
+	AjusteInventarioNormalizacao = "normalizacao"
+)
+
+// loteSemRegistro recebe, na contagem por lote, as unidades contadas sem número de lote e as
+// que o sistema tem registradas fora de qualquer lote.
+const loteSemRegistro = "SEM-LOTE"
+
+var (
+	// ErrInventarioNaoEncontrado indica que o inventário informado não existe
+	ErrInventarioNaoEncontrado = errors.New("inventário não encontrado")
+	// ErrInventarioInvalido indica dados inválidos na abertura, contagem ou aprovação
+	ErrInventarioInvalido = errors.New("inventário inválido")
+	// ErrInventarioJaAberto indica outro inventário aberto que cobre os mesmos medicamentos
+	ErrInventarioJaAberto = errors.New("já existe um inventário aberto para esses medicamentos")
+	// ErrStatusInventario indica uma operação que o status do inventário não permite
+	ErrStatusInventario = errors.New("operação não permitida no status atual do inventário")
+)
+
+// Inventario é uma contagem física do estoque, da loja inteira ou de uma categoria
+type Inventario struct {
+	ID            int64      `json:"id"`
+	CategoriaID   string     `json:"categoria_id,omitempty"` // Vazio para a loja inteira
+	CategoriaNome string     `json:"categoria_nome,omitempty"`
+	Status        string     `json:"status"`
+	Observacao    string     `json:"observacao,omitempty"`
+	AbertoPor     int        `json:"aberto_por"`
+	AbertoEm      time.Time  `json:"aberto_em"`
+	EncerradoPor  int        `json:"encerrado_por,omitempty"`
+	EncerradoEm   *time.Time `json:"encerrado_em,omitempty"`
+	MotivoAjuste  string     `json:"motivo_ajuste,omitempty"`
+}
+
+// ContagemInventario é a quantidade que um contador encontrou de um medicamento, opcionalmente de um lote.
+// Contagens de contadores diferentes se somam (cada um conta uma parte do estoque); uma nova contagem
+// do mesmo contador para o mesmo medicamento e lote substitui a anterior.
+type ContagemInventario struct {
+	ID            int64     `json:"id"`
+	InventarioID  int64     `json:"inventario_id"`
+	MedicamentoID string    `json:"medicamento_id"`
+	NumeroLote    string    `json:"numero_lote,omitempty"`
+	Validade      string    `json:"validade,omitempty"` // Usada se o lote ainda não existir no sistema
+	Quantidade    int       `json:"quantidade"`
+	UsuarioID     int       `json:"usuario_id"`
+	ContadoEm     time.Time `json:"contado_em"`
+}
+
+// ContagemInventarioRequest é uma linha da contagem enviada pelo contador
+type ContagemInventarioRequest struct {
+	MedicamentoID string `json:"medicamento_id"`
+	NumeroLote    string `json:"numero_lote"`
+	Validade      string `json:"validade"`
+	Quantidade    *int   `json:"quantidade"`
+}
+
+// LoteInventario compara o saldo de um lote com a quantidade contada
+type LoteInventario struct {
+	NumeroLote        string `json:"numero_lote"`
+	Validade          string `json:"validade,omitempty"`
+	QuantidadeSistema int    `json:"quantidade_sistema"`
+	QuantidadeContada int    `json:"quantidade_contada"`
+	Diferenca         int    `json:"diferenca"`
+}
+
+// ItemInventario compara o estoque de um medicamento com o total contado. Os lotes só aparecem
+// quando o medicamento foi contado por lote.
+type ItemInventario struct {
+	MedicamentoID     string           `json:"medicamento_id"`
+	MedicamentoNome   string           `json:"medicamento_nome"`
+	QuantidadeSistema int              `json:"quantidade_sistema"`
+	QuantidadeContada *int             `json:"quantidade_contada"` // Nulo enquanto não for contado
+	Diferenca         int              `json:"diferenca"`
+	ValorDiferenca    float64          `json:"valor_diferenca"` // Diferença ao custo médio
+	Contadores        int              `json:"contadores"`
+	Lotes             []LoteInventario `json:"lotes,omitempty"`
+}
+
+// AjusteInventario é uma correção de estoque feita na aprovação do inventário
+type AjusteInventario struct {
+	MedicamentoID  string `json:"medicamento_id"`
+	NumeroLote     string `json:"numero_lote,omitempty"`
+	Tipo           string `json:"tipo"`
+	Quantidade     int    `json:"quantidade"`
+	MovimentacaoID string `json:"movimentacao_id,omitempty"`
+}
+
+// ResumoInventario traz as diferenças de um inventário aberto ou os ajustes de um já aprovado
+type ResumoInventario struct {
+	Inventario
+	ItensContados     int                `json:"itens_contados"`
+	ItensNaoContados  int                `json:"itens_nao_contados"`
+	ItensComDiferenca int                `json:"itens_com_diferenca"`
+	ValorSobras       float64            `json:"valor_sobras"`
+	ValorFaltas       float64            `json:"valor_faltas"`
+	Itens             []ItemInventario   `json:"itens,omitempty"`
+	Ajustes           []AjusteInventario `json:"ajustes,omitempty"`
+}
+
+// dadosInventario reúne o que o cálculo das diferenças precisa, lido na mesma conexão ou transação.
+type dadosInventario struct {
+	medicamentos []Medicamento
+	lotes        map[string][]Lote
+	contagens    map[string][]ContagemInventario
+}
+
+// AbrirInventario inicia a contagem da loja inteira (categoriaID vazio) ou de uma categoria.
+// Não pode haver dois inventários abertos sobre os mesmos medicamentos.
+func (b *Banco) AbrirInventario(categoriaID, observacao string, usuarioID int) (*ResumoInventario, error) {
+	queryAbertos := b.queries.GetQuery(qSelecionarInventariosAbertos)
+	queryInserir := b.queries.GetQuery(qInserirInventario)
+	if queryAbertos == "" || queryInserir == "" {
+		return nil, errors.New("queries de inventário não encontradas")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(queryAbertos)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var categoria string
+		if err := rows.Scan(&id, &categoria); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if categoria == "" || categoriaID == "" || categoria == categoriaID {
+			rows.Close()
+			return nil, fmt.Errorf("%w: inventário %d", ErrInventarioJaAberto, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if categoriaID != "" {
+		query, err := b.queryObrigatoria(qContarMedicamentosCategoria)
+		if err != nil {
+			return nil, err
+		}
+		var total int
+		if err := tx.QueryRow(query, categoriaID).Scan(&total); err != nil {
+			return nil, err
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("%w: nenhum medicamento na categoria '%s'", ErrInventarioInvalido, categoriaID)
+		}
+	}
+
+	inv := &Inventario{
+		CategoriaID: categoriaID,
+		Status:      StatusInventarioAberto,
+		Observacao:  observacao,
+		AbertoPor:   usuarioID,
+		AbertoEm:    b.Agora(),
+	}
+	inv.ID, err = b.inserirComID(tx, queryInserir, sql.NullString{String: categoriaID, Valid: categoriaID != ""}, inv.Status, inv.Observacao, inv.AbertoPor, inv.AbertoEm)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir inventário: %w", err)
+	}
+
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "inventario", strconv.FormatInt(inv.ID, 10), nil, inv); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return b.GetInventario(inv.ID)
+}
+
+// RegistrarContagensInventario grava as contagens de um contador. Cada medicamento deve estar no
+// escopo do inventário e a quantidade não pode ser negativa.
+func (b *Banco) RegistrarContagensInventario(inventarioID int64, contagens []ContagemInventarioRequest, usuarioID int) ([]ContagemInventario, error) {
+	if len(contagens) == 0 {
+		return nil, fmt.Errorf("%w: informe ao menos uma contagem", ErrInventarioInvalido)
+	}
+	query := b.queries.GetQuery(qSalvarContagemInventario)
+	if query == "" {
+		return nil, errors.New("query 'salvar_contagem_inventario' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	inv, err := b.inventario(tx, inventarioID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status != StatusInventarioAberto {
+		return nil, fmt.Errorf("%w: o inventário está '%s'", ErrStatusInventario, inv.Status)
+	}
+
+	agora := b.Agora()
+	gravadas := make([]ContagemInventario, 0, len(contagens))
+	for i, req := range contagens {
+		referencia := fmt.Sprintf("contagem %d", i+1)
+		if req.Quantidade == nil || *req.Quantidade < 0 {
+			return nil, fmt.Errorf("%w: %s: a quantidade deve ser informada e não pode ser negativa", ErrInventarioInvalido, referencia)
+		}
+		med := b.buscarMedicamento(tx, req.MedicamentoID)
+		if med == nil {
+			return nil, fmt.Errorf("%w: %s: medicamento '%s' não encontrado", ErrInventarioInvalido, referencia, req.MedicamentoID)
+		}
+		if inv.CategoriaID != "" && med.CategoriaID != inv.CategoriaID {
+			return nil, fmt.Errorf("%w: %s: '%s' não pertence à categoria do inventário", ErrInventarioInvalido, referencia, med.Nome)
+		}
+		if req.Validade != "" {
+			if _, err := parseData(req.Validade); err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInventarioInvalido, referencia, err)
+			}
+		}
+
+		c := ContagemInventario{
+			InventarioID:  inventarioID,
+			MedicamentoID: med.ID,
+			NumeroLote:    strings.TrimSpace(req.NumeroLote),
+			Validade:      req.Validade,
+			Quantidade:    *req.Quantidade,
+			UsuarioID:     usuarioID,
+			ContadoEm:     agora,
+		}
+		if _, err := tx.Exec(query, c.InventarioID, c.MedicamentoID, c.NumeroLote,
+			sql.NullString{String: c.Validade, Valid: c.Validade != ""}, c.Quantidade, c.UsuarioID, c.ContadoEm); err != nil {
+			return nil, fmt.Errorf("erro ao gravar %s: %w", referencia, err)
+		}
+		gravadas = append(gravadas, c)
+	}
+
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "inventario_contagem", strconv.FormatInt(inventarioID, 10), nil, gravadas); err != nil {
+		return nil, err
+	}
+	return gravadas, tx.Commit()
+}
+
+// GetInventario retorna o inventário com as diferenças atuais, se estiver aberto, ou com os ajustes
+// gravados na aprovação.
+func (b *Banco) GetInventario(id int64) (*ResumoInventario, error) {
+	return b.resumoInventario(b.db, id)
+}
+
+// ListarInventarios lista os inventários do mais recente para o mais antigo, opcionalmente por status.
+func (b *Banco) ListarInventarios(status string) ([]Inventario, error) {
+	query := `
+		SELECT i.id, COALESCE(i.categoria_id, ''), COALESCE(c.nome, ''), i.status, COALESCE(i.observacao, ''), i.aberto_por, i.aberto_em,
+		       COALESCE(i.encerrado_por, 0), i.encerrado_em, COALESCE(i.motivo_ajuste, '')
+		FROM inventarios i
+		LEFT JOIN categorias c ON c.id = i.categoria_id
+		WHERE 1 = 1`
+	var args []interface{}
+	if status != "" {
+		query += " AND i.status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY i.aberto_em DESC, i.id DESC"
+
+	rows, err := b.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar inventários: %w", err)
+	}
+	defer rows.Close()
+
+	var inventarios []Inventario
+	for rows.Next() {
+		inv, err := scanInventario(rows)
+		if err != nil {
+			return nil, err
+		}
+		inventarios = append(inventarios, *inv)
+	}
+	return inventarios, rows.Err()
+}
+
+// AprovarInventario lança as diferenças contadas como movimentações de ajuste, com o motivo informado,
+// e encerra o inventário. As diferenças são recalculadas na aprovação, contra o estoque do momento;
+// medicamentos não contados não são ajustados.
+func (b *Banco) AprovarInventario(id int64, motivo string, usuarioID int) (*ResumoInventario, error) {
+	motivo = strings.TrimSpace(motivo)
+	if motivo == "" {
+		return nil, fmt.Errorf("%w: informe o motivo do ajuste", ErrInventarioInvalido)
+	}
+	queryAjuste := b.queries.GetQuery(qInserirInventarioAjuste)
+	if queryAjuste == "" {
+		return nil, errors.New("query 'inserir_inventario_ajuste' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	inv, err := b.inventario(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status != StatusInventarioAberto {
+		return nil, fmt.Errorf("%w: o inventário está '%s'", ErrStatusInventario, inv.Status)
+	}
+	dados, err := b.carregarDadosInventario(tx, inv)
+	if err != nil {
+		return nil, err
+	}
+	if len(dados.contagens) == 0 {
+		return nil, fmt.Errorf("%w: nenhuma contagem registrada", ErrInventarioInvalido)
+	}
+
+	observacao := fmt.Sprintf("Ajuste de inventário #%d: %s", id, motivo)
+	var ajustes []AjusteInventario
+	for _, med := range dados.medicamentos {
+		contagens := dados.contagens[med.ID]
+		if len(contagens) == 0 {
+			continue
+		}
+		item := calcularItemInventario(med, dados.lotes[med.ID], contagens)
+		ajustesItem, err := b.ajustarItemInventario(tx, id, med, dados.lotes[med.ID], item, observacao, usuarioID)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ajustar '%s': %w", med.Nome, err)
+		}
+		ajustes = append(ajustes, ajustesItem...)
+	}
+
+	for _, a := range ajustes {
+		if _, err := tx.Exec(queryAjuste, id, a.MedicamentoID, a.NumeroLote, a.Tipo, a.Quantidade,
+			sql.NullString{String: a.MovimentacaoID, Valid: a.MovimentacaoID != ""}); err != nil {
+			return nil, fmt.Errorf("erro ao gravar ajuste de inventário: %w", err)
+		}
+	}
+	if err := b.encerrarInventario(tx, id, StatusInventarioAprovado, motivo, usuarioID); err != nil {
+		return nil, err
+	}
+
+	depois := map[string]interface{}{"status": StatusInventarioAprovado, "motivo_ajuste": motivo, "ajustes": ajustes}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "inventario", strconv.FormatInt(id, 10), inv, depois); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("Inventário %d aprovado com %d ajustes", id, len(ajustes))
+	return b.GetInventario(id)
+}
+
+// CancelarInventario encerra um inventário aberto sem ajustar o estoque.
+func (b *Banco) CancelarInventario(id int64, usuarioID int) (*ResumoInventario, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	inv, err := b.inventario(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Status != StatusInventarioAberto {
+		return nil, fmt.Errorf("%w: o inventário está '%s'", ErrStatusInventario, inv.Status)
+	}
+	if err := b.encerrarInventario(tx, id, StatusInventarioCancelado, "", usuarioID); err != nil {
+		return nil, err
+	}
+
+	depois := map[string]interface{}{"status": StatusInventarioCancelado}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "inventario", strconv.FormatInt(id, 10), inv, depois); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return b.GetInventario(id)
+}
+
+// calcularItemInventario compara o estoque do medicamento com as contagens. Se alguma contagem
+// informar o lote, a comparação é feita também por lote: os lotes com saldo não contados valem zero
+// e as unidades sem lote, contadas ou registradas, ficam no lote SEM-LOTE.
+func calcularItemInventario(med Medicamento, lotes []Lote, contagens []ContagemInventario) ItemInventario {
+	item := ItemInventario{MedicamentoID: med.ID, MedicamentoNome: med.Nome, QuantidadeSistema: med.Quantidade}
+	if len(contagens) == 0 {
+		return item
+	}
+
+	contada := 0
+	porLote := false
+	contadores := map[int]bool{}
+	for _, c := range contagens {
+		contada += c.Quantidade
+		porLote = porLote || c.NumeroLote != ""
+		contadores[c.UsuarioID] = true
+	}
+	item.QuantidadeContada = &contada
+	item.Contadores = len(contadores)
+	item.Diferenca = contada - med.Quantidade
+	item.ValorDiferenca = arredondar(float64(item.Diferenca) * med.CustoMedio)
+	if !porLote {
+		return item
+	}
+
+	indice := map[string]int{}
+	linha := func(numero, validade string) *LoteInventario {
+		i, ok := indice[numero]
+		if !ok {
+			i = len(item.Lotes)
+			indice[numero] = i
+			item.Lotes = append(item.Lotes, LoteInventario{NumeroLote: numero, Validade: validade})
+		}
+		return &item.Lotes[i]
+	}
+
+	saldoLotes := 0
+	for _, l := range lotes {
+		if l.Quantidade <= 0 {
+			continue
+		}
+		linha(l.NumeroLote, l.Validade).QuantidadeSistema += l.Quantidade
+		saldoLotes += l.Quantidade
+	}
+	if semLote := med.Quantidade - saldoLotes; semLote > 0 {
+		linha(loteSemRegistro, med.Validade).QuantidadeSistema += semLote
+	}
+	for _, c := range contagens {
+		numero, validade := c.NumeroLote, c.Validade
+		if numero == "" {
+			numero, validade = loteSemRegistro, med.Validade
+		}
+		linha(numero, validade).QuantidadeContada += c.Quantidade
+	}
+	for i := range item.Lotes {
+		item.Lotes[i].Diferenca = item.Lotes[i].QuantidadeContada - item.Lotes[i].QuantidadeSistema
+	}
+	return item
+}
+
+// ajustarItemInventario lança as movimentações que levam o estoque do medicamento ao contado.
+// Sem contagem por lote, as sobras entram no lote INV-<id> e as faltas saem por FEFO; com contagem
+// por lote, cada lote é ajustado individualmente.
+func (b *Banco) ajustarItemInventario(tx *sql.Tx, inventarioID int64, med Medicamento, lotes []Lote, item ItemInventario, observacao string, usuarioID int) ([]AjusteInventario, error) {
+	var ajustes []AjusteInventario
+	movimentar := func(tipo, numeroLote, validade string, quantidade int) error {
+		// Os ajustes têm tipo próprio para não passarem por compras ou dispensações no SNGPC
+		tipoMovimentacao := TipoAjusteSaida
+		if tipo == AjusteInventarioEntrada {
+			tipoMovimentacao = TipoAjusteEntrada
+		}
+		mov := Movimentacao{
+			MedicamentoID: med.ID,
+			Tipo:          tipoMovimentacao,
+			Quantidade:    quantidade,
+			Observacao:    observacao,
+			UsuarioID:     usuarioID,
+			Lote:          numeroLote,
+			Validade:      validade,
+		}
+		// As sobras entram pelo custo médio atual, para não alterá-lo
+		if tipo == AjusteInventarioEntrada {
+			mov.CustoUnitario = med.CustoMedio
+		}
+		if err := b.registrarMovimentacaoTx(tx, &mov); err != nil {
+			return err
+		}
+		ajustes = append(ajustes, AjusteInventario{
+			MedicamentoID:  med.ID,
+			NumeroLote:     numeroLote,
+			Tipo:           tipo,
+			Quantidade:     quantidade,
+			MovimentacaoID: mov.ID,
+		})
+		return nil
+	}
+
+	// As unidades do medicamento além do saldo dos lotes vão para o lote SEM-LOTE, para que as faltas,
+	// mesmo por FEFO, tenham de onde sair
+	saldoLotes := 0
+	for _, l := range lotes {
+		if l.Quantidade > 0 {
+			saldoLotes += l.Quantidade
+		}
+	}
+	divergencia := med.Quantidade - saldoLotes
+	if divergencia > 0 {
+		lote := Lote{MedicamentoID: med.ID, NumeroLote: loteSemRegistro, Validade: med.Validade, Quantidade: divergencia}
+		if err := b.entradaLote(tx, &lote); err != nil {
+			return nil, err
+		}
+		ajustes = append(ajustes, AjusteInventario{MedicamentoID: med.ID, NumeroLote: loteSemRegistro, Tipo: AjusteInventarioNormalizacao, Quantidade: divergencia})
+	}
+
+	if len(item.Lotes) == 0 {
+		switch {
+		case item.Diferenca > 0:
+			return ajustes, movimentar(AjusteInventarioEntrada, fmt.Sprintf("INV-%d", inventarioID), med.Validade, item.Diferenca)
+		case item.Diferenca < 0:
+			return ajustes, movimentar(AjusteInventarioSaida, "", "", -item.Diferenca)
+		}
+		return ajustes, nil
+	}
+
+	// Antes de ajustar lote a lote, a quantidade do medicamento precisa bater com o saldo dos lotes
+	if divergencia < 0 {
+		// Os lotes têm mais unidades que o medicamento: a quantidade sobe até o saldo dos lotes, sem
+		// mexer neles, e a diferença fica registrada como ajuste para fechar o livro dos controlados
+		mov := Movimentacao{
+			MedicamentoID: med.ID,
+			Tipo:          TipoAjusteEntrada,
+			Quantidade:    -divergencia,
+			Observacao:    observacao + " (normalização com o saldo dos lotes)",
+			UsuarioID:     usuarioID,
+		}
+		if err := b.normalizarEstoqueComLotes(tx, med, &mov, saldoLotes); err != nil {
+			return nil, err
+		}
+		ajustes = append(ajustes, AjusteInventario{MedicamentoID: med.ID, Tipo: AjusteInventarioNormalizacao, Quantidade: -divergencia, MovimentacaoID: mov.ID})
+	}
+
+	for _, l := range item.Lotes {
+		var err error
+		switch {
+		case l.Diferenca > 0:
+			err = movimentar(AjusteInventarioEntrada, l.NumeroLote, l.Validade, l.Diferenca)
+		case l.Diferenca < 0:
+			err = movimentar(AjusteInventarioSaida, l.NumeroLote, "", -l.Diferenca)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ajustes, nil
+}
+
+// normalizarEstoqueComLotes leva a quantidade do medicamento ao saldo dos lotes e grava a movimentação
+// do ajuste, sem lotes, porque as unidades já estão neles.
+func (b *Banco) normalizarEstoqueComLotes(tx *sql.Tx, med Medicamento, mov *Movimentacao, saldoLotes int) error {
+	mov.ID = uuid.New().String()
+	mov.Data = b.Agora()
+	if err := b.repos.Medicamentos.AtualizarEstoque(tx, med.ID, saldoLotes, med.CustoMedio); err != nil {
+		return err
+	}
+	if err := b.repos.Movimentacoes.Inserir(tx, mov); err != nil {
+		return err
+	}
+	antes := map[string]interface{}{"medicamento_id": med.ID, "quantidade_estoque": med.Quantidade}
+	depois := map[string]interface{}{"movimentacao": mov, "quantidade_estoque": saldoLotes}
+	return b.registrarAuditoria(tx, mov.UsuarioID, AcaoCriar, "movimentacao", mov.ID, antes, depois)
+}
+
+func (b *Banco) resumoInventario(db execer, id int64) (*ResumoInventario, error) {
+	inv, err := b.inventario(db, id)
+	if err != nil {
+		return nil, err
+	}
+	resumo := &ResumoInventario{Inventario: *inv}
+
+	if inv.Status != StatusInventarioAberto {
+		resumo.Ajustes, err = b.ajustesInventario(db, id)
+		return resumo, err
+	}
+
+	dados, err := b.carregarDadosInventario(db, inv)
+	if err != nil {
+		return nil, err
+	}
+	resumo.Itens = make([]ItemInventario, 0, len(dados.medicamentos))
+	for _, med := range dados.medicamentos {
+		item := calcularItemInventario(med, dados.lotes[med.ID], dados.contagens[med.ID])
+		resumo.Itens = append(resumo.Itens, item)
+		if item.QuantidadeContada == nil {
+			resumo.ItensNaoContados++
+			continue
+		}
+		resumo.ItensContados++
+		if item.Diferenca != 0 || lotesComDiferenca(item.Lotes) {
+			resumo.ItensComDiferenca++
+		}
+		if item.ValorDiferenca > 0 {
+			resumo.ValorSobras += item.ValorDiferenca
+		} else {
+			resumo.ValorFaltas -= item.ValorDiferenca
+		}
+	}
+	resumo.ValorSobras = arredondar(resumo.ValorSobras)
+	resumo.ValorFaltas = arredondar(resumo.ValorFaltas)
+	return resumo, nil
+}
+
+func lotesComDiferenca(lotes []LoteInventario) bool {
+	for _, l := range lotes {
+		if l.Diferenca != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// carregarDadosInventario lê os medicamentos do escopo do inventário, os lotes com saldo e as contagens.
+func (b *Banco) carregarDadosInventario(db execer, inv *Inventario) (*dadosInventario, error) {
+	queryMedicamentos := b.queries.GetQuery(qSelecionarMedicamentosInventario)
+	queryLotes := b.queries.GetQuery(qSelecionarLotesInventario)
+	queryContagens := b.queries.GetQuery(qSelecionarContagensInventario)
+	if queryMedicamentos == "" || queryLotes == "" || queryContagens == "" {
+		return nil, errors.New("queries de inventário não encontradas")
+	}
+	dados := &dadosInventario{lotes: map[string][]Lote{}, contagens: map[string][]ContagemInventario{}}
+
+	rows, err := db.Query(queryMedicamentos, inv.CategoriaID, inv.CategoriaID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var med Medicamento
+		if err := rows.Scan(&med.ID, &med.Nome, &med.Quantidade, &med.Validade, &med.CustoMedio); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dados.medicamentos = append(dados.medicamentos, med)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(queryLotes, inv.CategoriaID, inv.CategoriaID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var l Lote
+		var fornecedor, validade sql.NullString
+		if err := rows.Scan(&l.ID, &l.MedicamentoID, &l.NumeroLote, &validade, &l.Quantidade, &fornecedor, &l.DataEntrada, &l.CustoUnitario); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("erro ao escanear lote: %w", err)
+		}
+		l.Validade = validade.String
+		l.Fornecedor = fornecedor.String
+		dados.lotes[l.MedicamentoID] = append(dados.lotes[l.MedicamentoID], l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, lotes := range dados.lotes {
+		ordenarLotesFEFO(lotes)
+	}
+
+	rows, err = db.Query(queryContagens, inv.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c ContagemInventario
+		if err := rows.Scan(&c.ID, &c.InventarioID, &c.MedicamentoID, &c.NumeroLote, &c.Validade, &c.Quantidade, &c.UsuarioID, &c.ContadoEm); err != nil {
+			return nil, err
+		}
+		dados.contagens[c.MedicamentoID] = append(dados.contagens[c.MedicamentoID], c)
+	}
+	return dados, rows.Err()
+}
+
+func (b *Banco) ajustesInventario(db execer, id int64) ([]AjusteInventario, error) {
+	query := b.queries.GetQuery(qSelecionarInventarioAjustes)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_inventario_ajustes' não encontrada")
+	}
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ajustes []AjusteInventario
+	for rows.Next() {
+		var a AjusteInventario
+		if err := rows.Scan(&a.MedicamentoID, &a.NumeroLote, &a.Tipo, &a.Quantidade, &a.MovimentacaoID); err != nil {
+			return nil, err
+		}
+		ajustes = append(ajustes, a)
+	}
+	return ajustes, rows.Err()
+}
+
+func (b *Banco) encerrarInventario(tx *sql.Tx, id int64, status, motivo string, usuarioID int) error {
+	query := b.queries.GetQuery(qEncerrarInventario)
+	if query == "" {
+		return errors.New("query 'encerrar_inventario' não encontrada")
+	}
+	_, err := tx.Exec(query, status, usuarioID, b.Agora(), sql.NullString{String: motivo, Valid: motivo != ""}, id)
+	return err
+}
+
+func (b *Banco) inventario(db execer, id int64) (*Inventario, error) {
+	query := b.queries.GetQuery(qSelecionarInventarioPorId)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_inventario_por_id' não encontrada")
+	}
+	inv, err := scanInventario(db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrInventarioNaoEncontrado
+	}
+	return inv, err
+}
+
+func scanInventario(row scanner) (*Inventario, error) {
+	var inv Inventario
+	var encerradoEm sql.NullTime
+	err := row.Scan(&inv.ID, &inv.CategoriaID, &inv.CategoriaNome, &inv.Status, &inv.Observacao, &inv.AbertoPor, &inv.AbertoEm,
+		&inv.EncerradoPor, &encerradoEm, &inv.MotivoAjuste)
+	if err != nil {
+		return nil, err
+	}
+	if encerradoEm.Valid {
+		inv.EncerradoEm = &encerradoEm.Time
+	}
+	return &inv, nil
+}
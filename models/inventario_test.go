@@ -0,0 +1,121 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcularItemInventarioSemLote(t *testing.T) {
+	med := Medicamento{ID: "m1", Nome: "Dipirona 500mg", Quantidade: 50, CustoMedio: 1.5}
+
+	// Nada contado ainda
+	item := calcularItemInventario(med, nil, nil)
+	assert.Nil(t, item.QuantidadeContada)
+	assert.Equal(t, 0, item.Diferenca)
+
+	// Dois contadores, cada um em uma prateleira
+	item = calcularItemInventario(med, nil, []ContagemInventario{
+		{MedicamentoID: "m1", Quantidade: 30, UsuarioID: 1},
+		{MedicamentoID: "m1", Quantidade: 17, UsuarioID: 2},
+	})
+	if assert.NotNil(t, item.QuantidadeContada) {
+		assert.Equal(t, 47, *item.QuantidadeContada)
+	}
+	assert.Equal(t, -3, item.Diferenca)
+	assert.Equal(t, -4.5, item.ValorDiferenca)
+	assert.Equal(t, 2, item.Contadores)
+	assert.Empty(t, item.Lotes)
+}
+
+func TestCalcularItemInventarioPorLote(t *testing.T) {
+	// 5 unidades estão no medicamento mas em nenhum lote
+	med := Medicamento{ID: "m1", Quantidade: 35, Validade: "2026-01-31", CustoMedio: 2}
+	lotes := []Lote{
+		{NumeroLote: "A1", Validade: "2025-12-31", Quantidade: 10},
+		{NumeroLote: "B2", Validade: "2026-06-30", Quantidade: 20},
+		{NumeroLote: "Z9", Validade: "2026-09-30", Quantidade: 0},
+	}
+	item := calcularItemInventario(med, lotes, []ContagemInventario{
+		{NumeroLote: "A1", Quantidade: 8, UsuarioID: 1},
+		{NumeroLote: "C3", Validade: "2027-01-31", Quantidade: 4, UsuarioID: 1},
+		{Quantidade: 2, UsuarioID: 2},
+	})
+
+	assert.Equal(t, 14, *item.QuantidadeContada)
+	assert.Equal(t, -21, item.Diferenca)
+	assert.Equal(t, []LoteInventario{
+		{NumeroLote: "A1", Validade: "2025-12-31", QuantidadeSistema: 10, QuantidadeContada: 8, Diferenca: -2},
+		// Lote com saldo que ninguém contou vale zero
+		{NumeroLote: "B2", Validade: "2026-06-30", QuantidadeSistema: 20, QuantidadeContada: 0, Diferenca: -20},
+		{NumeroLote: loteSemRegistro, Validade: "2026-01-31", QuantidadeSistema: 5, QuantidadeContada: 2, Diferenca: -3},
+		// Lote encontrado na prateleira e ainda não cadastrado
+		{NumeroLote: "C3", Validade: "2027-01-31", QuantidadeSistema: 0, QuantidadeContada: 4, Diferenca: 4},
+	}, item.Lotes)
+}
+
+func TestAprovarInventarioDeControlado(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	b1 := "B1"
+	med := &Medicamento{ID: "1", Nome: "Clonazepam 2mg", CodigoANVISA: "1234567890123", ListaControle: &b1, Validade: "2027-12-31", Preco: 10}
+	if !assert.NoError(t, b.AddMedicamento(med, 0)) {
+		return
+	}
+	if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoEntrada, Quantidade: 10, Lote: "L1",
+		Validade: "2027-06-30", NotaFiscal: "123", CNPJFornecedor: "12.345.678/0001-95"})) {
+		return
+	}
+	assert.Error(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoAjusteEntrada, Quantidade: 1}),
+		"os ajustes só vêm do inventário")
+
+	// A edição do cadastro não muda o estoque
+	med.Quantidade = 8
+	if !assert.NoError(t, b.UpdateMedicamento(med, 0)) {
+		return
+	}
+	assert.Equal(t, 10, med.Quantidade)
+	// Um estoque gravado fora dos lotes, como o dos cadastros anteriores aos lotes, fica abaixo do saldo deles
+	if !assert.NoError(t, b.repos.Medicamentos.AtualizarEstoque(b.db, "1", 8, 0)) {
+		return
+	}
+	inv, err := b.AbrirInventario("", "", 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	contada := 12
+	if _, err := b.RegistrarContagensInventario(inv.ID, []ContagemInventarioRequest{{MedicamentoID: "1", NumeroLote: "L1", Quantidade: &contada}}, 1); !assert.NoError(t, err) {
+		return
+	}
+	resumo, err := b.AprovarInventario(inv.ID, "Contagem anual", 1)
+	if !assert.NoError(t, err) || !assert.Len(t, resumo.Ajustes, 2) {
+		return
+	}
+	assert.Equal(t, AjusteInventarioNormalizacao, resumo.Ajustes[0].Tipo)
+	assert.Equal(t, 2, resumo.Ajustes[0].Quantidade)
+	assert.NotEmpty(t, resumo.Ajustes[0].MovimentacaoID, "a normalização também é uma movimentação")
+	assert.Equal(t, AjusteInventarioEntrada, resumo.Ajustes[1].Tipo)
+	assert.Equal(t, 2, resumo.Ajustes[1].Quantidade)
+	assert.Equal(t, 12, b.GetMedicamento("1").Quantidade)
+
+	movimentacoes, err := b.GetMovimentacoes()
+	if assert.NoError(t, err) {
+		tipos := map[interface{}]int{}
+		for _, mov := range movimentacoes {
+			tipos[mov["tipo"]]++
+		}
+		assert.Equal(t, map[interface{}]int{TipoEntrada: 1, TipoAjusteEntrada: 2}, tipos)
+	}
+
+	// As sobras não são compras: o SNGPC do período só tem a entrada com nota fiscal
+	hoje := time.Now().Truncate(24 * time.Hour)
+	xml, err := b.GerarSNGPCMovimentacao(EmitenteSNGPC{CNPJ: "12345678000195", CPFTransmissor: "12345678909"}, hoje.AddDate(0, 0, -1), hoje.AddDate(0, 0, 2))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, strings.Count(string(xml), "<entradaMedicamentos>"))
+	}
+}
@@ -0,0 +1,330 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Lote representa um lote de um medicamento, com validade e quantidade próprias
+type Lote struct {
+	ID            string    `json:"id"`
+	MedicamentoID string    `json:"medicamento_id"`
+	NumeroLote    string    `json:"numero_lote"`
+	Validade      string    `json:"validade"` // Formato: YYYY-MM-DD
+	Quantidade    int       `json:"quantidade"`
+	Fornecedor    string    `json:"fornecedor"`
+	DataEntrada   time.Time `json:"data_entrada"`
+	CustoUnitario float64   `json:"custo_unitario,omitempty"` // Custo de compra, quando conhecido
+}
+
+// LoteConsumido indica quanto de um lote foi utilizado em uma saída ou venda
+type LoteConsumido struct {
+	LoteID     string `json:"lote_id"`
+	NumeroLote string `json:"numero_lote"`
+	Validade   string `json:"validade"`
+	Quantidade int    `json:"quantidade"`
+}
+
+// layoutsData lista os formatos de data aceitos nos campos de validade
+var layoutsData = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"02/01/2006",
+}
+
+// layoutsMes são as validades só com mês e ano, que valem até o último dia do mês,
+// como o dia "00" das datas GS1 (ver dataGS1)
+var layoutsMes = []string{
+	"01/2006",
+	"2006-01",
+}
+
+// parseData converte uma data de validade em time.Time, aceitando os formatos mais comuns.
+func parseData(valor string) (time.Time, error) {
+	valor = strings.TrimSpace(valor)
+	for _, layout := range layoutsData {
+		if t, err := time.Parse(layout, valor); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range layoutsMes {
+		if t, err := time.Parse(layout, valor); err == nil {
+			return t.AddDate(0, 1, -1), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("data '%s' em formato inválido", valor)
+}
+
+// migrarEstoqueParaLotes cria um lote inicial para medicamentos com estoque e nenhum lote cadastrado,
+// para que o saldo dos lotes acompanhe a quantidade registrada no medicamento.
+func (b *Banco) migrarEstoqueParaLotes() error {
+	query := b.queries.GetQuery(qSelecionarMedicamentosSemLote)
+	if query == "" {
+		return errors.New("query 'selecionar_medicamentos_sem_lote' não encontrada")
+	}
+
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return err
+	}
+	var pendentes []Medicamento
+	for rows.Next() {
+		var med Medicamento
+		var validade sql.NullString
+		if err := rows.Scan(&med.ID, &med.Quantidade, &validade); err != nil {
+			rows.Close()
+			return err
+		}
+		med.Validade = validade.String
+		pendentes = append(pendentes, med)
+	}
+	rows.Close()
+
+	for _, med := range pendentes {
+		lote := Lote{
+			MedicamentoID: med.ID,
+			NumeroLote:    "LOTE-INICIAL",
+			Validade:      med.Validade,
+			Quantidade:    med.Quantidade,
+		}
+		if err := b.inserirLote(b.db, &lote); err != nil {
+			return err
+		}
+	}
+	if len(pendentes) > 0 {
+		log.Printf("Lote inicial criado para %d medicamentos sem lote.", len(pendentes))
+	}
+	return nil
+}
+
+// scanner é satisfeito tanto por *sql.Row quanto por *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// inserirLote grava um novo lote.
+func (b *Banco) inserirLote(db execer, lote *Lote) error {
+	if lote.ID == "" {
+		lote.ID = uuid.New().String()
+	}
+	if lote.DataEntrada.IsZero() {
+		lote.DataEntrada = b.Agora()
+	}
+	query := b.queries.GetQuery(qInserirLote)
+	if query == "" {
+		return errors.New("query 'inserir_lote' não encontrada")
+	}
+	_, err := db.Exec(query, lote.ID, lote.MedicamentoID, lote.NumeroLote, lote.Validade, lote.Quantidade, lote.Fornecedor, lote.DataEntrada,
+		sql.NullFloat64{Float64: lote.CustoUnitario, Valid: lote.CustoUnitario > 0})
+	if err != nil {
+		log.Printf("Erro ao inserir lote '%s': %v", lote.NumeroLote, err)
+	}
+	return err
+}
+
+// GetLotesPorMedicamento retorna os lotes de um medicamento ordenados pela validade (FEFO).
+func (b *Banco) GetLotesPorMedicamento(medicamentoID string) ([]Lote, error) {
+	return b.lotesPorMedicamento(b.db, medicamentoID, false)
+}
+
+// lotesPorMedicamento busca os lotes de um medicamento, opcionalmente apenas os com saldo,
+// ordenados do vencimento mais próximo para o mais distante.
+func (b *Banco) lotesPorMedicamento(db execer, medicamentoID string, somenteComSaldo bool) ([]Lote, error) {
+	query := b.queries.GetQuery(qSelecionarLotesPorMedicamento)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_lotes_por_medicamento' não encontrada")
+	}
+
+	rows, err := db.Query(query, medicamentoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lotes []Lote
+	for rows.Next() {
+		var l Lote
+		var fornecedor, validade sql.NullString
+		if err := rows.Scan(&l.ID, &l.MedicamentoID, &l.NumeroLote, &validade, &l.Quantidade, &fornecedor, &l.DataEntrada, &l.CustoUnitario); err != nil {
+			return nil, fmt.Errorf("erro ao escanear lote: %w", err)
+		}
+		l.Validade = validade.String
+		l.Fornecedor = fornecedor.String
+		if somenteComSaldo && l.Quantidade <= 0 {
+			continue
+		}
+		lotes = append(lotes, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ordenarLotesFEFO(lotes)
+	return lotes, nil
+}
+
+// ordenarLotesFEFO ordena os lotes pela data de validade; lotes sem validade válida vão para o final.
+func ordenarLotesFEFO(lotes []Lote) {
+	sort.SliceStable(lotes, func(i, j int) bool {
+		vi, errI := parseData(lotes[i].Validade)
+		vj, errJ := parseData(lotes[j].Validade)
+		switch {
+		case errI != nil && errJ != nil:
+			return lotes[i].DataEntrada.Before(lotes[j].DataEntrada)
+		case errI != nil:
+			return false
+		case errJ != nil:
+			return true
+		case vi.Equal(vj):
+			return lotes[i].DataEntrada.Before(lotes[j].DataEntrada)
+		}
+		return vi.Before(vj)
+	})
+}
+
+// entradaLote soma a quantidade ao lote informado, criando-o se ainda não existir.
+func (b *Banco) entradaLote(tx *sql.Tx, lote *Lote) error {
+	query := b.queries.GetQuery(qSelecionarLotePorNumero)
+	if query == "" {
+		return errors.New("query 'selecionar_lote_por_numero' não encontrada")
+	}
+
+	var id string
+	var quantidade int
+	err := tx.QueryRow(query, lote.MedicamentoID, lote.NumeroLote).Scan(&id, &quantidade)
+	if err == sql.ErrNoRows {
+		return b.inserirLote(tx, lote)
+	}
+	if err != nil {
+		return err
+	}
+
+	lote.ID = id
+	return b.atualizarQuantidadeLote(tx, id, quantidade+lote.Quantidade)
+}
+
+// consumirLotesFEFO retira a quantidade dos lotes do medicamento, começando pelo que vence primeiro.
+// Retorna quanto foi retirado de cada lote. O saldo dos lotes precisa cobrir a quantidade, para que
+// toda unidade baixada tenha lote; um estoque acima dele (de antes dos lotes, por exemplo) é alinhado
+// pelo inventário.
+func (b *Banco) consumirLotesFEFO(tx execer, medicamentoID string, quantidade int) ([]LoteConsumido, error) {
+	lotes, err := b.lotesPorMedicamento(tx, medicamentoID, true)
+	if err != nil {
+		return nil, err
+	}
+	saldo := 0
+	for _, l := range lotes {
+		saldo += l.Quantidade
+	}
+	if saldo < quantidade {
+		return nil, fmt.Errorf("saldo dos lotes (%d) insuficiente para a saída de %d; conte o medicamento no inventário para alinhar o estoque aos lotes", saldo, quantidade)
+	}
+
+	var consumidos []LoteConsumido
+	restante := quantidade
+	for _, l := range lotes {
+		if restante == 0 {
+			break
+		}
+		retirar := l.Quantidade
+		if retirar > restante {
+			retirar = restante
+		}
+		if err := b.atualizarQuantidadeLote(tx, l.ID, l.Quantidade-retirar); err != nil {
+			return nil, err
+		}
+		consumidos = append(consumidos, LoteConsumido{
+			LoteID:     l.ID,
+			NumeroLote: l.NumeroLote,
+			Validade:   l.Validade,
+			Quantidade: retirar,
+		})
+		restante -= retirar
+	}
+	return consumidos, nil
+}
+
+// consumirLote retira a quantidade de um lote específico, como no descarte de um lote vencido
+// ou no ajuste de inventário. Diferente do FEFO, o saldo do lote precisa cobrir a quantidade.
+func (b *Banco) consumirLote(tx execer, medicamentoID, numeroLote string, quantidade int) ([]LoteConsumido, error) {
+	lotes, err := b.lotesPorMedicamento(tx, medicamentoID, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lotes {
+		if l.NumeroLote != numeroLote {
+			continue
+		}
+		if l.Quantidade < quantidade {
+			return nil, fmt.Errorf("saldo do lote '%s' (%d) insuficiente para a saída de %d", numeroLote, l.Quantidade, quantidade)
+		}
+		if err := b.atualizarQuantidadeLote(tx, l.ID, l.Quantidade-quantidade); err != nil {
+			return nil, err
+		}
+		return []LoteConsumido{{LoteID: l.ID, NumeroLote: l.NumeroLote, Validade: l.Validade, Quantidade: quantidade}}, nil
+	}
+	return nil, fmt.Errorf("lote '%s' não encontrado para o medicamento", numeroLote)
+}
+
+// atualizarQuantidadeLote define o saldo de um lote.
+func (b *Banco) atualizarQuantidadeLote(tx execer, loteID string, quantidade int) error {
+	query := b.queries.GetQuery(qAtualizarQuantidadeLote)
+	if query == "" {
+		return errors.New("query 'atualizar_quantidade_lote' não encontrada")
+	}
+	_, err := tx.Exec(query, quantidade, loteID)
+	return err
+}
+
+// atualizarValidadeMedicamento faz a validade do medicamento refletir o lote com saldo que vence primeiro.
+func (b *Banco) atualizarValidadeMedicamento(tx execer, medicamentoID string) error {
+	lotes, err := b.lotesPorMedicamento(tx, medicamentoID, true)
+	if err != nil {
+		return err
+	}
+	if len(lotes) == 0 || lotes[0].Validade == "" {
+		return nil
+	}
+	query, err := b.queryObrigatoria(qAtualizarValidadeMedicamento)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(query, lotes[0].Validade, medicamentoID)
+	return err
+}
+
+// registrarLotesMovimentacao grava de quais lotes saiu (ou para qual lote entrou) uma movimentação.
+func (b *Banco) registrarLotesMovimentacao(tx *sql.Tx, movimentacaoID string, lotes []LoteConsumido) error {
+	query := b.queries.GetQuery(qInserirMovimentacaoLote)
+	if query == "" {
+		return errors.New("query 'inserir_movimentacao_lote' não encontrada")
+	}
+	for _, l := range lotes {
+		if _, err := tx.Exec(query, movimentacaoID, l.LoteID, l.Quantidade); err != nil {
+			return fmt.Errorf("erro ao vincular lote %s à movimentação: %w", l.NumeroLote, err)
+		}
+	}
+	return nil
+}
+
+// registrarLotesVendaItem grava de quais lotes saiu um item de venda.
+func (b *Banco) registrarLotesVendaItem(tx execer, vendaItemID int64, lotes []LoteConsumido) error {
+	query := b.queries.GetQuery(qInserirVendaItemLote)
+	if query == "" {
+		return errors.New("query 'inserir_venda_item_lote' não encontrada")
+	}
+	for _, l := range lotes {
+		if _, err := tx.Exec(query, vendaItemID, l.LoteID, l.Quantidade); err != nil {
+			return fmt.Errorf("erro ao vincular lote %s ao item de venda: %w", l.NumeroLote, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseData(t *testing.T) {
+	for valor, esperado := range map[string]string{
+		"2030-05-10":          "2030-05-10",
+		"10/05/2030":          "2030-05-10",
+		"2030-05-10 08:30:00": "2030-05-10",
+		// Só mês e ano: vale até o fim do mês, como o dia "00" do GS1
+		"2030-05": "2030-05-31",
+		"05/2030": "2030-05-31",
+		"02/2028": "2028-02-29",
+	} {
+		data, err := parseData(valor)
+		if assert.NoError(t, err, valor) {
+			assert.Equal(t, esperado, data.Format("2006-01-02"), valor)
+		}
+	}
+	gs1, err := dataGS1("300500")
+	if assert.NoError(t, err) {
+		data, _ := parseData("2030-05")
+		assert.Equal(t, gs1, data.Format("2006-01-02"))
+	}
+
+	for _, invalido := range []string{"", "31/02/2030", "2030/05/10", "13/2030"} {
+		_, err := parseData(invalido)
+		assert.Error(t, err, invalido)
+	}
+}
+
+func TestOrdenarLotesFEFO(t *testing.T) {
+	entrada := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	lotes := []Lote{
+		{NumeroLote: "sem-validade", DataEntrada: entrada},
+		{NumeroLote: "fim-de-maio", Validade: "2030-05", DataEntrada: entrada},
+		{NumeroLote: "meio-de-maio", Validade: "2030-05-15", DataEntrada: entrada},
+		{NumeroLote: "maio-depois", Validade: "31/05/2030", DataEntrada: entrada.AddDate(0, 0, 1)},
+		{NumeroLote: "abril", Validade: "2030-04-30", DataEntrada: entrada},
+	}
+	ordenarLotesFEFO(lotes)
+
+	var ordem []string
+	for _, l := range lotes {
+		ordem = append(ordem, l.NumeroLote)
+	}
+	// Mesma validade: sai primeiro o que entrou antes
+	assert.Equal(t, []string{"abril", "meio-de-maio", "fim-de-maio", "maio-depois", "sem-validade"}, ordem)
+}
+
+func TestSaidaConsumindoLotesFEFO(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Amoxicilina 500mg", Preco: 30}, 0)) {
+		return
+	}
+	for _, l := range []Lote{
+		{NumeroLote: "A", Validade: "2031-01-31", Quantidade: 5},
+		{NumeroLote: "B", Validade: "2030-06", Quantidade: 3},
+		{NumeroLote: "C", Validade: "2030-06-15", Quantidade: 2},
+	} {
+		if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoEntrada, Quantidade: l.Quantidade, Lote: l.NumeroLote, Validade: l.Validade})) {
+			return
+		}
+	}
+	// "2030-06" vale até 30/06, depois do lote C
+	assert.Equal(t, "2030-06-15", b.GetMedicamento("1").Validade)
+
+	// Sem lote informado, a saída consome do que vence primeiro
+	if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoSaida, Quantidade: 4})) {
+		return
+	}
+	lotes, err := b.GetLotesPorMedicamento("1")
+	if !assert.NoError(t, err) || !assert.Len(t, lotes, 3) {
+		return
+	}
+	saldos := map[string]int{}
+	for _, l := range lotes {
+		saldos[l.NumeroLote] = l.Quantidade
+	}
+	assert.Equal(t, map[string]int{"C": 0, "B": 1, "A": 5}, saldos)
+	assert.Equal(t, 6, b.GetMedicamento("1").Quantidade)
+	assert.Equal(t, "2030-06", b.GetMedicamento("1").Validade)
+
+	// Com o lote informado, sai só dele; a validade do medicamento passa ao próximo lote com saldo
+	if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoSaida, Quantidade: 1, Lote: "B"})) {
+		return
+	}
+	assert.Equal(t, "2031-01-31", b.GetMedicamento("1").Validade)
+	assert.Error(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoSaida, Quantidade: 6, Lote: "A"}),
+		"o saldo do lote precisa cobrir a saída")
+}
+
+func TestAtualizarMedicamentoMantemEstoqueDosLotes(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	med := &Medicamento{ID: "1", Nome: "Amoxicilina 500mg", Quantidade: 8, Validade: "2030-06-30", Preco: 30}
+	if !assert.NoError(t, b.AddMedicamento(med, 0)) {
+		return
+	}
+
+	// O corpo sem quantidade nem validade, ou com outras, não mexe no que vem dos lotes
+	if !assert.NoError(t, b.UpdateMedicamento(&Medicamento{ID: "1", Nome: "Amoxicilina 500mg cápsula", Preco: 32}, 0)) {
+		return
+	}
+	med.Quantidade, med.Validade = 50, "2099-12-31"
+	if !assert.NoError(t, b.UpdateMedicamento(med, 0)) {
+		return
+	}
+	assert.Equal(t, 8, med.Quantidade, "med traz o que ficou gravado")
+	atual := b.GetMedicamento("1")
+	assert.Equal(t, "Amoxicilina 500mg", atual.Nome)
+	assert.Equal(t, 8, atual.Quantidade)
+	assert.Equal(t, "2030-06-30", atual.Validade)
+	lotes, err := b.GetLotesPorMedicamento("1")
+	if assert.NoError(t, err) && assert.Len(t, lotes, 1) {
+		assert.Equal(t, 8, lotes[0].Quantidade)
+	}
+}
+
+func TestSaidaFEFOSemSaldoNosLotes(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Amoxicilina 500mg", Quantidade: 5, Validade: "2030-06-30", Preco: 30}, 0)) {
+		return
+	}
+	// Um estoque gravado acima dos lotes, como o de antes dos lotes
+	if !assert.NoError(t, b.repos.Medicamentos.AtualizarEstoque(b.db, "1", 8, 0)) {
+		return
+	}
+
+	// A saída e a venda que passam do saldo dos lotes são recusadas inteiras, sem baixar nada
+	assert.ErrorContains(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoSaida, Quantidade: 7}), "saldo dos lotes (5)")
+	if _, err := b.AbrirCaixa(1, 0); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+	_, err := b.RegistrarVenda(RegistrarVendaRequest{
+		Itens:      []ItemVendaRequest{{MedicamentoID: 1, Quantidade: 6}},
+		Pagamentos: []PagamentoVenda{{Forma: FormaDinheiro, Valor: 180}},
+	}, 1, RoleAtendente)
+	assert.ErrorContains(t, err, "saldo dos lotes")
+	assert.Equal(t, 8, b.GetMedicamento("1").Quantidade)
+	lotes, err := b.GetLotesPorMedicamento("1")
+	if assert.NoError(t, err) && assert.Len(t, lotes, 1) {
+		assert.Equal(t, 5, lotes[0].Quantidade)
+	}
+	vendas, err := b.BuscarVendas(FiltroVendas{})
+	if assert.NoError(t, err) {
+		assert.Zero(t, vendas.Total)
+	}
+
+	// O inventário, mesmo sem contagem por lote, leva o excesso para o lote SEM-LOTE antes de baixar as faltas
+	inv, err := b.AbrirInventario("", "", 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	contada := 6
+	if _, err := b.RegistrarContagensInventario(inv.ID, []ContagemInventarioRequest{{MedicamentoID: "1", Quantidade: &contada}}, 1); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := b.AprovarInventario(inv.ID, "Contagem", 1); !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 6, b.GetMedicamento("1").Quantidade)
+	assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoSaida, Quantidade: 6}))
+}
@@ -6,14 +6,12 @@ import (
 
 	// "io/ioutil" // Não será mais necessário diretamente aqui se saveDB e loadDB forem removidas
 	"log"
-	"os"
 	"strings"
 	"time"
 
-	"medicontrol/sqlutils" // Para carregar a query de criação da tabela
+	"medicontrol/sqlutils"
 
 	"github.com/google/uuid"
-	_ "github.com/mattn/go-sqlite3" // Driver SQLite
 )
 
 // Medicamento representa a estrutura de um medicamento
@@ -24,173 +22,199 @@ type Medicamento struct {
 	Tipo         string    `json:"tipo"` // "comprimido", "suspensão", "injetável", etc.
 	CodigoANVISA string    `json:"codigo_anvisa"`
 	Quantidade   int       `json:"quantidade"`
-	Validade     string    `json:"validade"` // Formato: YYYY-MM-DD
+	Validade     string    `json:"validade"` // Formato: YYYY-MM-DD; a do lote com saldo que vence primeiro
 	Preco        float64   `json:"preco"`
 	CriadoEm     time.Time `json:"criado_em"`
 	CategoriaID  string    `json:"categoria_id"`
 	Categoria    Categoria `json:"categoria"` // Para incluir dados da categoria aninhados
+	// Lista da Portaria 344/98 (A1, B1, C1, ...). Vazia para medicamentos não controlados. Na
+	// atualização, ausente mantém a lista cadastrada e vazia remove a classificação.
+	ListaControle *string `json:"lista_controle"`
+	Controlado    bool    `json:"controlado"` // Preenchido a partir de ListaControle
+	// Custo médio ponderado das entradas, recalculado pelo sistema a cada entrada com custo.
+	// Na criação, é o custo do estoque inicial.
+	CustoMedio float64 `json:"custo_medio"`
+	// GTINs das embalagens (EAN-13, ou GTIN-14 nas de agrupamento). Na atualização, ausente mantém
+	// os códigos cadastrados e uma lista vazia remove todos.
+	CodigosBarras []string `json:"codigos_barras"`
+	// Composição do medicamento. Na atualização, ausente mantém a composição cadastrada.
+	PrincipiosAtivos []PrincipioAtivo `json:"principios_ativos"`
+	// referencia, generico ou similar (intercambiável); vazia nas demais categorias, que não entram
+	// nas alternativas de substituição
+	CategoriaRegulatoria string `json:"categoria_regulatoria"`
+}
+
+// Tipos de movimentação. Os ajustes são lançados só pela aprovação do inventário: movimentam o
+// estoque como as entradas e saídas, mas não são compras nem dispensações no SNGPC.
+const (
+	TipoEntrada       = "entrada"
+	TipoSaida         = "saida"
+	TipoAjusteEntrada = "ajuste_entrada"
+	TipoAjusteSaida   = "ajuste_saida"
+)
+
+// movimentacaoEntrada informa se o tipo de movimentação soma ao estoque
+func movimentacaoEntrada(tipo string) bool {
+	return tipo == TipoEntrada || tipo == TipoAjusteEntrada
 }
 
 // Movimentacao representa uma entrada ou saída de medicamento
 type Movimentacao struct {
 	ID            string    `json:"id"`
 	MedicamentoID string    `json:"medicamento_id"`
-	Tipo          string    `json:"tipo"` // "entrada" ou "saida"
+	Tipo          string    `json:"tipo"` // "entrada" ou "saida"; "ajuste_entrada" ou "ajuste_saida" no inventário
 	Quantidade    int       `json:"quantidade"`
 	Data          time.Time `json:"data"`
 	Observacao    string    `json:"observacao"`
+	UsuarioID     int       `json:"usuario_id"`
+	// Dados do lote, usados nas entradas. Se o número do lote não for informado, um é gerado.
+	// Numa saída, o número do lote tira a quantidade só daquele lote, em vez de seguir o FEFO.
+	Lote       string `json:"lote,omitempty"`
+	Validade   string `json:"validade,omitempty"`
+	Fornecedor string `json:"fornecedor,omitempty"`
+	// Documento fiscal da entrada, exigido no SNGPC para medicamentos controlados
+	NotaFiscal     string `json:"nota_fiscal,omitempty"`
+	CNPJFornecedor string `json:"cnpj_fornecedor,omitempty"`
+	// Venda de origem, nas entradas de estorno de cancelamentos e devoluções
+	VendaID int64 `json:"venda_id,omitempty"`
+	// Custo unitário de compra, nas entradas
+	CustoUnitario float64 `json:"custo_unitario,omitempty"`
+	// Lotes afetados pela movimentação (preenchido pelo sistema)
+	Lotes []LoteConsumido `json:"lotes,omitempty"`
 }
 
-var sqlDB *sql.DB // Variável global para a conexão com o banco de dados SQL
+// ErrMedicamentoNaoEncontrado indica que o medicamento informado não existe
+var ErrMedicamentoNaoEncontrado = errors.New("medicamento não encontrado")
 
-// InitDB inicializa o banco de dados SQLite
-func InitDB() error {
-	log.Println("Iniciando banco de dados SQLite...")
+// Campos aceitos na ordenação da listagem de medicamentos
+const (
+	OrdenarPorNome       = "nome"
+	OrdenarPorPreco      = "preco"
+	OrdenarPorQuantidade = "quantidade"
+	OrdenarPorValidade   = "validade"
+	OrdenarPorRelevancia = "relevancia" // Padrão quando há termo de busca
+)
 
-	// Criar diretório data se não existir
-	if err := os.MkdirAll("data", 0755); err != nil {
-		log.Printf("Erro ao criar diretório data: %v", err)
-		return err
-	}
+// ErrOrdenacaoInvalida indica um campo de ordenação desconhecido na listagem de medicamentos
+var ErrOrdenacaoInvalida = errors.New("ordenação inválida: use relevancia, nome, preco, quantidade ou validade")
+
+// FiltroMedicamentos define a busca, os filtros, a ordenação e a página da listagem de medicamentos
+type FiltroMedicamentos struct {
+	Termo         string // Procurado no índice de busca (nome, fabricante, princípio ativo e códigos)
+	CategoriaID   string
+	Fabricante    string
+	Tipo          string
+	QuantidadeMin *int
+	QuantidadeMax *int
+	ValidadeDe    time.Time
+	ValidadeAte   time.Time // exclusivo
+	Ordenacao     string    // Padrão: relevância com termo, nome sem termo
+	Decrescente   bool
+	Pagina        int
+	PorPagina     int // Zero lista todos
+}
 
-	sqliteDbPath := "./data/medicontrol.db"
-	var err error
-	sqlDB, err = sql.Open("sqlite3", sqliteDbPath)
+// PaginaMedicamentos é uma página da listagem de medicamentos
+type PaginaMedicamentos struct {
+	Medicamentos []Medicamento `json:"medicamentos"`
+	Total        int           `json:"total"`
+	Pagina       int           `json:"pagina"`
+	PorPagina    int           `json:"por_pagina"`
+}
+
+// TemProxima informa se há medicamentos depois desta página
+func (p *PaginaMedicamentos) TemProxima() bool {
+	return p.PorPagina > 0 && p.Pagina*p.PorPagina < p.Total
+}
+
+// InitDB abre o banco de dados, aplica as migrações pendentes do esquema e prepara os dados iniciais
+func InitDB(cfg ConfigBanco, queries *sqlutils.Queries) (*Banco, error) {
+	b, err := AbrirBanco(cfg, queries)
 	if err != nil {
-		log.Fatalf("Erro ao abrir banco de dados SQLite: %v", err)
-		return err
+		return nil, err
 	}
-
-	// Verificar a conexão
-	if err = sqlDB.Ping(); err != nil {
-		log.Fatalf("Erro ao conectar com o banco de dados SQLite (ping): %v", err)
-		return err
+	if err := b.inicializar(); err != nil {
+		b.Fechar()
+		return nil, err
 	}
-
-	log.Println("Conectado ao banco de dados SQLite com sucesso.")
-
-	// Criar tabela de categorias se não existir
-	if err := criarTabelaCategorias(); err != nil {
-		// O erro já é logado dentro da função
-		return err
+	// Com o esquema atualizado, conferir e preparar todas as queries antes de atender requisições
+	if err := b.prepararQueries(); err != nil {
+		b.Fechar()
+		return nil, err
 	}
+	return b, nil
+}
 
-	// Criar tabela de medicamentos se não existir
-	queryCreateTable := sqlutils.GetQuery("criar_tabela_medicamentos")
-	if queryCreateTable == "" {
-		log.Fatal("Query 'criar_tabela_medicamentos' não encontrada.")
-	} else {
-		_, err = sqlDB.Exec(queryCreateTable)
-		if err != nil {
-			log.Fatalf("Erro ao criar tabela 'medicamentos': %v", err)
-			return err
-		}
-		log.Println("Tabela 'medicamentos' verificada/criada com sucesso.")
+// inicializar aplica as migrações pendentes do esquema e prepara os dados iniciais
+func (b *Banco) inicializar() error {
+	// Aplicar as migrações pendentes para garantir que o esquema esteja atualizado
+	if _, err := b.Migrar(0); err != nil {
+		return err
 	}
 
-	// Aplicar migrações para garantir que o esquema esteja atualizado
-	if err := applyMigrations(); err != nil {
-		log.Fatalf("Erro ao aplicar migrações de banco de dados: %v", err)
+	// Garantir que o estoque existente esteja em algum lote e que todo medicamento tenha custo médio
+	if err := b.migrarEstoqueParaLotes(); err != nil {
+		log.Printf("Erro ao migrar estoque existente para lotes: %v", err)
 		return err
 	}
-
-	// Criar tabela de movimentações se não existir
-	if err := criarTabelaMovimentacoes(); err != nil {
+	if err := b.inicializarCustoMedio(); err != nil {
 		return err
 	}
 
-	// Criar tabelas de vendas se não existirem
-	if err := criarTabelaVendas(); err != nil {
+	// Criar o administrador inicial
+	if err := b.garantirAdminPadrao(); err != nil {
+		log.Printf("Erro ao criar usuário administrador padrão: %v", err)
 		return err
 	}
 
-	log.Println("Banco de dados SQLite inicializado com sucesso.")
+	log.Printf("Banco de dados (%s) inicializado com sucesso.", b.driver)
 	return nil
 }
 
-// GetMedicamentos retorna todos os medicamentos do banco de dados SQLite
-func GetMedicamentos() ([]Medicamento, error) {
-	query := sqlutils.GetQuery("selecionar_todos_medicamentos")
-	if query == "" {
-		log.Println("Erro: Query 'selecionar_todos_medicamentos' não encontrada.")
-		return nil, errors.New("query para selecionar medicamentos não encontrada")
-	}
-
-	rows, err := sqlDB.Query(query)
+// GetMedicamentos retorna todos os medicamentos do banco de dados
+func (b *Banco) GetMedicamentos() ([]Medicamento, error) {
+	medicamentos, err := b.repos.Medicamentos.Listar(b.db)
 	if err != nil {
-		log.Printf("Erro ao executar query para buscar medicamentos: %v", err)
+		log.Printf("Erro ao buscar medicamentos: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-
-	var medicamentos []Medicamento
-	for rows.Next() {
-		var med Medicamento
-		// Campos da Categoria precisam ser `sql.NullString` para o caso de LEFT JOIN com categoria nula
-		var categoriaID, categoriaNome sql.NullString
-		var preco sql.NullFloat64
-
-		err := rows.Scan(
-			&med.ID, &med.Nome, &med.Fabricante, &med.Tipo,
-			&med.CodigoANVISA, &med.Quantidade, &med.Validade, &med.CriadoEm,
-			&preco,
-			&categoriaID, &categoriaNome,
-		)
-		if err != nil {
-			log.Printf("Erro ao escanear linha do medicamento: %v", err)
-			continue
-		}
-
-		if preco.Valid {
-			med.Preco = preco.Float64
-		} else {
-			med.Preco = 0.0
-		}
-
-		// Preencher dados da categoria
-		if categoriaID.Valid {
-			med.Categoria.ID = categoriaID.String
-			med.CategoriaID = categoriaID.String
-		}
-		if categoriaNome.Valid {
-			med.Categoria.Nome = categoriaNome.String
-		}
-
-		medicamentos = append(medicamentos, med)
-	}
-
-	if err = rows.Err(); err != nil {
-		log.Printf("Erro após iterar sobre as linhas de medicamentos: %v", err)
-		return nil, err
-	}
-
 	return medicamentos, nil
 }
 
 // GetMedicamento retorna um medicamento específico pelo seu ID.
-func GetMedicamento(id string) *Medicamento {
-	query := sqlutils.GetQuery("selecionar_medicamento_por_id")
-	if query == "" {
-		log.Printf("Query 'selecionar_medicamento_por_id' não encontrada.")
+func (b *Banco) GetMedicamento(id string) *Medicamento {
+	return b.buscarMedicamento(b.db, id)
+}
+
+// buscarMedicamento busca um medicamento pelo ID usando a conexão ou transação informada.
+func (b *Banco) buscarMedicamento(db execer, id string) *Medicamento {
+	med, err := b.repos.Medicamentos.BuscarPorID(db, id)
+	if err != nil {
+		log.Printf("Erro ao buscar medicamento por ID %s: %v", id, err)
 		return nil
 	}
+	return med
+}
 
+// scanMedicamento lê uma linha no formato das queries selecionar_*_medicamento(s).
+func scanMedicamento(row scanner) (*Medicamento, error) {
 	var med Medicamento
+	// Campos da Categoria precisam ser `sql.NullString` para o caso de LEFT JOIN com categoria nula
 	var categoriaID, categoriaNome sql.NullString
 	var preco sql.NullFloat64
+	var listaControle string
 
-	err := sqlDB.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&med.ID, &med.Nome, &med.Fabricante, &med.Tipo,
 		&med.CodigoANVISA, &med.Quantidade, &med.Validade, &med.CriadoEm,
 		&preco,
 		&categoriaID, &categoriaNome,
+		&listaControle,
+		&med.CustoMedio,
+		&med.CategoriaRegulatoria,
 	)
-
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Erro ao buscar medicamento por ID %s: %v", id, err)
-		}
-		return nil
+		return nil, err
 	}
 
 	if preco.Valid {
@@ -207,292 +231,303 @@ func GetMedicamento(id string) *Medicamento {
 	if categoriaNome.Valid {
 		med.Categoria.Nome = categoriaNome.String
 	}
+	med.ListaControle = &listaControle
+	med.Controlado = listaControle != ""
 
-	return &med
+	return &med, nil
 }
 
 // GetMedicamentoByCodigoANVISA retorna um medicamento específico pelo código ANVISA
-func GetMedicamentoByCodigoANVISA(codigoANVISA string) *Medicamento {
-	query := sqlutils.GetQuery("selecionar_medicamento_por_codigo_anvisa")
-	if query == "" {
-		log.Printf("Query 'selecionar_medicamento_por_codigo_anvisa' não encontrada.")
+func (b *Banco) GetMedicamentoByCodigoANVISA(codigoANVISA string) *Medicamento {
+	med, err := b.repos.Medicamentos.BuscarPorCodigoANVISA(b.db, codigoANVISA)
+	if err != nil {
+		log.Printf("Erro ao buscar medicamento por código ANVISA %s: %v", codigoANVISA, err)
 		return nil
 	}
+	return med
+}
 
-	var med Medicamento
-	var categoriaID, categoriaNome sql.NullString
-	var preco sql.NullFloat64
+// GetMedicamentoByCodigo é um wrapper para GetMedicamentoByCodigoANVISA
+func (b *Banco) GetMedicamentoByCodigo(codigo string) *Medicamento {
+	return b.GetMedicamentoByCodigoANVISA(codigo)
+}
 
-	err := sqlDB.QueryRow(query, codigoANVISA).Scan(
-		&med.ID, &med.Nome, &med.Fabricante, &med.Tipo,
-		&med.CodigoANVISA, &med.Quantidade, &med.Validade, &med.CriadoEm,
-		&preco,
-		&categoriaID, &categoriaNome,
-	)
+// BuscarMedicamentos lista os medicamentos com busca, filtros, ordenação e paginação.
+// Com o índice de busca, o termo ignora acentos e maiúsculas, vale pelo começo das palavras e
+// tolera erros de digitação.
+// Sem filtros nem página, retorna todos os medicamentos em ordem de nome.
+func (b *Banco) BuscarMedicamentos(filtro FiltroMedicamentos) (*PaginaMedicamentos, error) {
+	return b.repos.Medicamentos.Pesquisar(b.db, filtro)
+}
 
-	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Erro ao buscar medicamento por código ANVISA %s: %v", codigoANVISA, err)
-		}
-		// Se for sql.ErrNoRows, simplesmente retorna nil, o que é o comportamento esperado.
-		return nil
+// AddMedicamento adiciona um novo medicamento ao banco de dados SQLite.
+// usuarioID identifica o responsável na trilha de auditoria (0 para operações do sistema).
+func (b *Banco) AddMedicamento(med *Medicamento, usuarioID int) error {
+	// Garante que o ID seja gerado se estiver vazio
+	if med.ID == "" {
+		med.ID = uuid.New().String()
 	}
+	med.CriadoEm = b.Agora()
 
-	if preco.Valid {
-		med.Preco = preco.Float64
-	} else {
-		med.Preco = 0.0
+	if err := normalizarListaControle(med); err != nil {
+		return err
 	}
-
-	// Preencher dados da categoria
-	if categoriaID.Valid {
-		med.Categoria.ID = categoriaID.String
-		med.CategoriaID = categoriaID.String
+	if err := normalizarCodigosBarras(med); err != nil {
+		return err
 	}
-	if categoriaNome.Valid {
-		med.Categoria.Nome = categoriaNome.String
+	if err := normalizarCategoriaRegulatoria(med); err != nil {
+		return err
 	}
 
-	return &med
-}
-
-// GetMedicamentoByCodigo é um wrapper para GetMedicamentoByCodigoANVISA
-func GetMedicamentoByCodigo(codigo string) *Medicamento {
-	return GetMedicamentoByCodigoANVISA(codigo)
-}
-
-// BuscarMedicamentos busca medicamentos por nome, fabricante ou código ANVISA.
-// Esta é a versão FINAL, robusta e que lida com campos nulos corretamente.
-func BuscarMedicamentos(termoBusca string) ([]Medicamento, error) {
-	query := `
-		SELECT 
-			m.ID, m.Nome, m.Fabricante, m.Tipo, m.CodigoANVISA, 
-			m.Quantidade, m.Validade, m.Preco, m.CriadoEm, 
-			c.ID as CategoriaID, c.Nome as CategoriaNome
-		FROM medicamentos m
-		LEFT JOIN categorias c ON m.CategoriaID = c.ID`
-
-	var args []interface{}
-	if termoBusca != "" {
-		query += " WHERE m.Nome LIKE ? OR m.Fabricante LIKE ? OR m.CodigoANVISA LIKE ?"
-		searchTerm := "%" + termoBusca + "%"
-		args = append(args, searchTerm, searchTerm, searchTerm)
-	}
-
-	rows, err := sqlDB.Query(query, args...)
+	tx, err := b.db.Begin()
 	if err != nil {
-		log.Printf("Erro ao buscar medicamentos com termo '%s': %v", termoBusca, err)
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var medicamentos []Medicamento
-	for rows.Next() {
-		var med Medicamento
-		// Usar tipos sql.Null* para todos os campos que podem ser nulos
-		var fabricante, tipo, validade, categoriaID, categoriaNome sql.NullString
-		var preco sql.NullFloat64
-
-		err := rows.Scan(
-			&med.ID,
-			&med.Nome,
-			&fabricante,
-			&tipo,
-			&med.CodigoANVISA,
-			&med.Quantidade,
-			&validade,
-			&preco,
-			&med.CriadoEm,
-			&categoriaID,
-			&categoriaNome,
-		)
-		if err != nil {
-			log.Printf("Erro ao escanear linha da busca por medicamento: %v", err)
-			continue // Pula para a próxima linha em caso de erro
-		}
+	if err := b.validarPrecoPMC(tx, med.CodigoANVISA, med.Preco); err != nil {
+		return err
+	}
 
-		// Atribuir valores somente se eles forem válidos (não nulos)
-		med.Fabricante = fabricante.String
-		med.Tipo = tipo.String
-		med.Validade = validade.String
-		if preco.Valid {
-			med.Preco = preco.Float64
-		} else {
-			med.Preco = 0.0 // Valor padrão se o preço for nulo
-		}
-		if categoriaID.Valid {
-			med.Categoria.ID = categoriaID.String
-			med.CategoriaID = categoriaID.String
+	if err := b.resolverPrincipiosAtivos(tx, med); err != nil {
+		return err
+	}
+	if err := b.repos.Medicamentos.Inserir(tx, med); err != nil {
+		log.Printf("Erro ao inserir medicamento no banco de dados: %v", err)
+		return err
+	}
+
+	// O estoque inicial entra como um lote próprio
+	if med.Quantidade > 0 {
+		lote := Lote{
+			MedicamentoID: med.ID,
+			NumeroLote:    "LOTE-INICIAL",
+			Validade:      med.Validade,
+			Quantidade:    med.Quantidade,
+			CustoUnitario: med.CustoMedio,
 		}
-		if categoriaNome.Valid {
-			med.Categoria.Nome = categoriaNome.String
+		if err := b.inserirLote(tx, &lote); err != nil {
+			return err
 		}
-
-		medicamentos = append(medicamentos, med)
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Erro após iterar sobre os resultados da busca por medicamentos: %v", err)
-		return nil, err
+	if err := b.registrarHistoricoPreco(tx, med.ID, 0, med.Preco, OrigemPrecoCadastro, 0, usuarioID); err != nil {
+		return err
 	}
-
-	return medicamentos, nil
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "medicamento", med.ID, nil, med); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// AddMedicamento adiciona um novo medicamento ao banco de dados SQLite
-func AddMedicamento(med *Medicamento) error {
-	// Garante que o ID seja gerado se estiver vazio
-	if med.ID == "" {
-		med.ID = uuid.New().String()
+// UpdateMedicamento atualiza o cadastro de um medicamento existente. A quantidade e a validade
+// informadas são ignoradas: as duas vêm dos lotes, que só mudam por movimentações e pela aprovação
+// do inventário, que ficam registradas. Ao final, med passa a ter os dados gravados.
+func (b *Banco) UpdateMedicamento(med *Medicamento, usuarioID int) error {
+	if err := normalizarListaControle(med); err != nil {
+		return err
 	}
-	med.CriadoEm = time.Now()
-
-	query := sqlutils.GetQuery("inserir_medicamento")
-	if query == "" {
-		return errors.New("query 'inserir_medicamento' não encontrada")
+	if err := normalizarCodigosBarras(med); err != nil {
+		return err
+	}
+	if err := normalizarCategoriaRegulatoria(med); err != nil {
+		return err
 	}
 
-	_, err := sqlDB.Exec(query,
-		med.ID,
-		med.Nome,
-		med.Fabricante,
-		med.Tipo,
-		med.CodigoANVISA,
-		med.Quantidade,
-		med.Validade,
-		med.Preco,
-		med.CriadoEm,
-		med.CategoriaID,
-	)
-
+	tx, err := b.db.Begin()
 	if err != nil {
-		log.Printf("Erro ao inserir medicamento no banco de dados: %v", err)
 		return err
 	}
-	return nil
-}
+	defer tx.Rollback()
 
-// UpdateMedicamento atualiza um medicamento existente no banco de dados SQLite
-func UpdateMedicamento(med *Medicamento) error {
-	query := sqlutils.GetQuery("atualizar_medicamento")
-	if query == "" {
-		return errors.New("query 'atualizar_medicamento' não encontrada")
-	}
-
-	_, err := sqlDB.Exec(query,
-		med.Nome,
-		med.Fabricante,
-		med.Tipo,
-		med.CodigoANVISA,
-		med.Quantidade,
-		med.Validade,
-		med.Preco,
-		med.CategoriaID,
-		med.ID,
-	)
+	anterior := b.buscarMedicamento(tx, med.ID)
+	if anterior == nil {
+		return ErrMedicamentoNaoEncontrado
+	}
+	if med.ListaControle == nil {
+		med.ListaControle = anterior.ListaControle
+		med.Controlado = anterior.Controlado
+	}
 
-	if err != nil {
+	// O teto só é conferido quando o preço ou o registro mudam, para que um medicamento já acima
+	// de um PMC recém-importado continue editável até o preço ser corrigido
+	if arredondar(med.Preco) != arredondar(anterior.Preco) || med.CodigoANVISA != anterior.CodigoANVISA {
+		if err := b.validarPrecoPMC(tx, med.CodigoANVISA, med.Preco); err != nil {
+			return err
+		}
+	}
+
+	if err := b.resolverPrincipiosAtivos(tx, med); err != nil {
+		return err
+	}
+	if err := b.repos.Medicamentos.Atualizar(tx, med); err != nil {
 		log.Printf("Erro ao atualizar medicamento no banco de dados: %v", err)
 		return err
 	}
+
+	if err := b.registrarHistoricoPreco(tx, med.ID, anterior.Preco, med.Preco, OrigemPrecoManual, 0, usuarioID); err != nil {
+		return err
+	}
+	atualizado := b.buscarMedicamento(tx, med.ID)
+	if atualizado == nil {
+		return ErrMedicamentoNaoEncontrado
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "medicamento", med.ID, anterior, atualizado); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*med = *atualizado
 	return nil
 }
 
 // DeleteMedicamento remove um medicamento do banco de dados SQLite
-func DeleteMedicamento(id string) error {
-	query := sqlutils.GetQuery("deletar_medicamento")
-	if query == "" {
-		return errors.New("query 'deletar_medicamento' não encontrada")
+func (b *Banco) DeleteMedicamento(id string, usuarioID int) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	_, err := sqlDB.Exec(query, id)
+	anterior := b.buscarMedicamento(tx, id)
+	if anterior == nil {
+		return ErrMedicamentoNaoEncontrado
+	}
 
-	if err != nil {
+	if err := b.repos.Medicamentos.Excluir(tx, id); err != nil {
 		log.Printf("Erro ao deletar medicamento do banco de dados: %v", err)
 		return err
 	}
-	return nil
+
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoExcluir, "medicamento", id, anterior, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // RegistrarMovimentacao registra uma entrada ou saída de medicamento e atualiza o estoque
-func RegistrarMovimentacao(mov Movimentacao) error {
-	tx, err := sqlDB.Begin()
+func (b *Banco) RegistrarMovimentacao(mov Movimentacao) error {
+	if mov.Tipo != TipoEntrada && mov.Tipo != TipoSaida {
+		return errors.New("tipo de movimentação inválido: use 'entrada' ou 'saida'")
+	}
+	tx, err := b.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := b.registrarMovimentacaoTx(tx, &mov); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// registrarMovimentacaoTx aplica a movimentação dentro de uma transação existente.
+// Entradas são somadas ao lote informado; saídas saem do lote informado ou, sem lote,
+// consomem os lotes por ordem de vencimento (FEFO).
+func (b *Banco) registrarMovimentacaoTx(tx *sql.Tx, mov *Movimentacao) error {
+	if mov.Quantidade <= 0 {
+		return errors.New("a quantidade da movimentação deve ser maior que zero")
+	}
 
 	// Primeiro, busca o medicamento para verificar o estoque
-	medicamento := GetMedicamento(mov.MedicamentoID)
-	if medicamento == nil {
-		tx.Rollback()
+	med, err := b.repos.Medicamentos.BuscarPorID(tx, mov.MedicamentoID)
+	if err != nil {
+		return err
+	}
+	if med == nil {
 		return errors.New("medicamento não encontrado para a movimentação")
 	}
+	quantidadeAtual, custoMedio := med.Quantidade, med.CustoMedio
 
-	// Calcula a nova quantidade
-	novaQuantidade := medicamento.Quantidade
-	if mov.Tipo == "entrada" {
+	mov.ID = uuid.New().String()
+	mov.Data = b.Agora()
+
+	// Calcula a nova quantidade e movimenta os lotes
+	novaQuantidade := quantidadeAtual
+	switch mov.Tipo {
+	case TipoEntrada, TipoAjusteEntrada:
+		if mov.Lote == "" {
+			mov.Lote = "LOTE-" + mov.Data.Format("20060102")
+		}
+		if mov.Validade == "" {
+			mov.Validade = med.Validade
+		}
+		lote := Lote{
+			MedicamentoID: mov.MedicamentoID,
+			NumeroLote:    mov.Lote,
+			Validade:      mov.Validade,
+			Quantidade:    mov.Quantidade,
+			Fornecedor:    mov.Fornecedor,
+			DataEntrada:   mov.Data,
+			CustoUnitario: mov.CustoUnitario,
+		}
+		if err := b.entradaLote(tx, &lote); err != nil {
+			return err
+		}
+		mov.Lotes = []LoteConsumido{{LoteID: lote.ID, NumeroLote: lote.NumeroLote, Validade: lote.Validade, Quantidade: mov.Quantidade}}
+		custoMedio = custoMedioPonderado(quantidadeAtual, custoMedio, mov.Quantidade, mov.CustoUnitario)
 		novaQuantidade += mov.Quantidade
-	} else if mov.Tipo == "saida" {
+	case TipoSaida, TipoAjusteSaida:
 		if novaQuantidade < mov.Quantidade {
-			tx.Rollback()
 			return errors.New("quantidade em estoque insuficiente para a saída")
 		}
+		if mov.Lote != "" {
+			mov.Lotes, err = b.consumirLote(tx, mov.MedicamentoID, mov.Lote, mov.Quantidade)
+		} else {
+			mov.Lotes, err = b.consumirLotesFEFO(tx, mov.MedicamentoID, mov.Quantidade)
+		}
+		if err != nil {
+			return err
+		}
 		novaQuantidade -= mov.Quantidade
+	default:
+		return errors.New("tipo de movimentação inválido: use 'entrada' ou 'saida'")
 	}
 
-	// Atualiza a quantidade do medicamento
-	updateQuery := "UPDATE medicamentos SET Quantidade = ? WHERE ID = ?"
-	_, err = tx.Exec(updateQuery, novaQuantidade, mov.MedicamentoID)
-	if err != nil {
-		tx.Rollback()
+	// Atualiza a quantidade e o custo médio do medicamento
+	if err := b.repos.Medicamentos.AtualizarEstoque(tx, mov.MedicamentoID, novaQuantidade, custoMedio); err != nil {
+		return err
+	}
+	if err := b.atualizarValidadeMedicamento(tx, mov.MedicamentoID); err != nil {
 		return err
 	}
 
 	// Insere o registro da movimentação
-	mov.ID = uuid.New().String()
-	mov.Data = time.Now()
-	insertQuery := sqlutils.GetQuery("inserir_movimentacao")
-	if insertQuery == "" {
-		tx.Rollback()
-		return errors.New("query 'inserir_movimentacao' não encontrada")
+	if err := b.repos.Movimentacoes.Inserir(tx, mov); err != nil {
+		return err
 	}
 
-	_, err = tx.Exec(insertQuery, mov.ID, mov.MedicamentoID, mov.Tipo, mov.Quantidade, mov.Data, mov.Observacao)
-	if err != nil {
-		tx.Rollback()
+	if err := b.registrarLotesMovimentacao(tx, mov.ID, mov.Lotes); err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	antes := map[string]interface{}{
+		"medicamento_id":     mov.MedicamentoID,
+		"quantidade_estoque": quantidadeAtual,
+	}
+	depois := map[string]interface{}{
+		"movimentacao":       mov,
+		"quantidade_estoque": novaQuantidade,
+	}
+	return b.registrarAuditoria(tx, mov.UsuarioID, AcaoCriar, "movimentacao", mov.ID, antes, depois)
 }
 
 // GetMovimentacoes retorna todas as movimentações com detalhes do medicamento
-func GetMovimentacoes() ([]map[string]interface{}, error) {
-	query := sqlutils.GetQuery("selecionar_todas_movimentacoes")
-	if query == "" {
-		return nil, errors.New("query 'selecionar_todas_movimentacoes' não encontrada")
-	}
-
-	rows, err := sqlDB.Query(query)
+func (b *Banco) GetMovimentacoes() ([]map[string]interface{}, error) {
+	listadas, err := b.repos.Movimentacoes.Listar(b.db)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	var movimentacoes []map[string]interface{}
-	for rows.Next() {
-		var mov Movimentacao
-		var nomeMedicamento, tipoMedicamento string
-		err := rows.Scan(&mov.ID, &mov.MedicamentoID, &mov.Tipo, &mov.Quantidade, &mov.Data, &mov.Observacao, &nomeMedicamento, &tipoMedicamento)
-		if err != nil {
-			log.Printf("Erro ao escanear movimentação: %v", err)
-			continue
-		}
+	for _, mov := range listadas {
 		movimentacoes = append(movimentacoes, map[string]interface{}{
 			"id":               mov.ID,
 			"medicamento_id":   mov.MedicamentoID,
-			"nome_medicamento": nomeMedicamento,
-			"tipo_medicamento": tipoMedicamento,
+			"nome_medicamento": mov.NomeMedicamento,
+			"tipo_medicamento": mov.TipoMedicamento,
 			"tipo":             mov.Tipo,
 			"quantidade":       mov.Quantidade,
 			"data":             mov.Data,
@@ -502,89 +537,25 @@ func GetMovimentacoes() ([]map[string]interface{}, error) {
 	return movimentacoes, nil
 }
 
-// GetTotalVendas retorna a soma de todas as quantidades de itens de venda.
-func GetTotalVendas() (int, error) {
-	query := sqlutils.GetQuery("contar_total_vendas")
-	if query == "" {
-		return 0, errors.New("query 'contar_total_vendas' não encontrada")
-	}
-
-	var totalVendas sql.NullInt64 // Usar NullInt64 para o caso de não haver vendas
-	err := sqlDB.QueryRow(query).Scan(&totalVendas)
-	if err != nil {
-		// Se não houver linhas, o que significa nenhuma venda, o total é 0.
-		if err == sql.ErrNoRows {
-			return 0, nil
-		}
-		return 0, err
-	}
-
-	if totalVendas.Valid {
-		return int(totalVendas.Int64), nil
-	}
-	return 0, nil
-}
-
-// criarTabelaMovimentacoes cria a tabela 'movimentacoes' se ela não existir.
-func criarTabelaMovimentacoes() error {
-	query := sqlutils.GetQuery("criar_tabela_movimentacoes")
-	if query == "" {
-		return errors.New("query 'criar_tabela_movimentacoes' não encontrada")
-	}
-	_, err := sqlDB.Exec(query)
-	if err != nil {
-		log.Printf("Erro ao criar tabela 'movimentacoes': %v", err)
-	}
-	return err
-}
-
-// criarTabelaVendas cria as tabelas 'vendas' e 'venda_items' se não existirem.
-func criarTabelaVendas() error {
-	queryVendas := sqlutils.GetQuery("criar_tabela_vendas")
-	if queryVendas == "" {
-		return errors.New("query 'criar_tabela_vendas' não encontrada")
-	}
-	if _, err := sqlDB.Exec(queryVendas); err != nil {
-		log.Printf("Erro ao criar tabela 'vendas': %v", err)
-		return err
-	}
-
-	queryVendaItems := sqlutils.GetQuery("criar_tabela_venda_items")
-	if queryVendaItems == "" {
-		return errors.New("query 'criar_tabela_venda_items' não encontrada")
-	}
-	if _, err := sqlDB.Exec(queryVendaItems); err != nil {
-		log.Printf("Erro ao criar tabela 'venda_items': %v", err)
-		return err
-	}
-
-	return nil
-}
-
-// applyMigrations aplica migrações no banco de dados, como adicionar novas colunas.
-func applyMigrations() error {
-	// Adiciona a coluna 'preco' se ela não existir
-	if err := addColumnIfNotExists("medicamentos", "Preco", "REAL DEFAULT 0.0"); err != nil {
-		return err
-	}
-	// Adiciona a coluna 'categoria_id' se ela não existir
-	if err := addColumnIfNotExists("medicamentos", "CategoriaID", "TEXT"); err != nil {
-		return err
-	}
-	return nil
+// GetTotalVendas retorna a soma das quantidades vendidas, descontando vendas canceladas e itens devolvidos.
+func (b *Banco) GetTotalVendas() (int, error) {
+	return b.repos.Vendas.TotalUnidadesVendidas(b.db)
 }
 
 // addColumnIfNotExists verifica se uma coluna existe e a adiciona se não existir.
-func addColumnIfNotExists(tableName, columnName, columnType string) error {
+// Tabelas inexistentes são ignoradas: elas são criadas completas pela migração inicial.
+func (b *Banco) addColumnIfNotExists(tableName, columnName, columnType string) error {
 	// Query para verificar se a coluna existe
 	// Note que PRAGMA_table_info é específico do SQLite
-	rows, err := sqlDB.Query("PRAGMA table_info(" + tableName + ")")
+	rows, err := b.db.Query("PRAGMA table_info(" + tableName + ")")
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
+	encontrada := false
 	for rows.Next() {
+		encontrada = true
 		var cid int
 		var name string
 		var type_ string
@@ -598,9 +569,12 @@ func addColumnIfNotExists(tableName, columnName, columnType string) error {
 			return nil // Coluna já existe
 		}
 	}
+	if !encontrada {
+		return nil
+	}
 
 	// Coluna não encontrada, então a adiciona
-	_, err = sqlDB.Exec("ALTER TABLE " + tableName + " ADD COLUMN " + columnName + " " + columnType)
+	_, err = b.db.Exec("ALTER TABLE " + tableName + " ADD COLUMN " + columnName + " " + columnType)
 	if err != nil {
 		// Log para ajudar a depurar erros de sintaxe SQL, etc.
 		log.Printf("Erro ao adicionar coluna '%s' à tabela '%s': %v", columnName, tableName, err)
@@ -610,27 +584,8 @@ func addColumnIfNotExists(tableName, columnName, columnType string) error {
 	return err
 }
 
-// GetMedicamentosBaixoEstoque retorna medicamentos com quantidade abaixo de um limite.
-func GetMedicamentosBaixoEstoque(limite int) ([]Medicamento, error) {
-	query := sqlutils.GetQuery("selecionar_medicamentos_baixo_estoque")
-	if query == "" {
-		return nil, errors.New("query 'selecionar_medicamentos_baixo_estoque' não encontrada")
-	}
-	rows, err := sqlDB.Query(query, limite)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var medicamentos []Medicamento
-	for rows.Next() {
-		var med Medicamento
-		err := rows.Scan(&med.ID, &med.Nome, &med.Fabricante, &med.Quantidade)
-		if err != nil {
-			log.Printf("Erro ao escanear medicamento com baixo estoque: %v", err)
-			continue
-		}
-		medicamentos = append(medicamentos, med)
-	}
-	return medicamentos, nil
+// GetMedicamentosBaixoEstoque retorna medicamentos com quantidade abaixo do estoque mínimo cadastrado
+// em parametros_reposicao ou, se não houver, abaixo do limite informado.
+func (b *Banco) GetMedicamentosBaixoEstoque(limite int) ([]Medicamento, error) {
+	return b.repos.Medicamentos.ListarBaixoEstoque(b.db, limite)
 }
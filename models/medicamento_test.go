@@ -0,0 +1,110 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuscarMedicamentos(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if err := b.repos.Categorias.Inserir(b.db, &Categoria{ID: "cat-antibioticos", Nome: "Antibióticos"}); err != nil {
+		t.Fatalf("erro ao inserir categoria: %v", err)
+	}
+
+	cadastrar := func(id, nome, fabricante, tipo string, quantidade int, validade string, preco float64, categoriaID string) {
+		med := &Medicamento{
+			ID: id, Nome: nome, Fabricante: fabricante, Tipo: tipo, Quantidade: quantidade,
+			Validade: validade, Preco: preco, CriadoEm: time.Now(), CategoriaID: categoriaID,
+		}
+		if err := b.repos.Medicamentos.Inserir(b.db, med); err != nil {
+			t.Fatalf("erro ao inserir medicamento %s: %v", nome, err)
+		}
+	}
+	cadastrar("1", "Amoxicilina 500mg", "EMS", "cápsula", 40, "2027-03-31", 22.9, "cat-antibioticos")
+	cadastrar("2", "Azitromicina 500mg", "Medley", "comprimido", 5, "2026-11-30", 31.5, "cat-antibioticos")
+	cadastrar("3", "Dipirona 500mg", "EMS", "comprimido", 120, "2028-01-31", 6.4, "")
+	cadastrar("4", "Loratadina 10mg", "Neo Química", "comprimido", 0, "", 9.9, "")
+	cadastrar("5", "Paracetamol 750mg", "ems", "comprimido", 60, "2026-10-31", 7.2, "")
+
+	ids := func(p *PaginaMedicamentos) []string {
+		var ids []string
+		for _, med := range p.Medicamentos {
+			ids = append(ids, med.ID)
+		}
+		return ids
+	}
+
+	// Sem filtros, todos em ordem de nome
+	pagina, err := b.BuscarMedicamentos(FiltroMedicamentos{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"1", "2", "3", "4", "5"}, ids(pagina))
+		assert.Equal(t, 5, pagina.Total)
+		assert.False(t, pagina.TemProxima())
+	}
+
+	// A página traz o total de todos os filtrados
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{Ordenacao: OrdenarPorPreco, Decrescente: true, Pagina: 2, PorPagina: 2})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"4", "5"}, ids(pagina))
+		assert.Equal(t, 5, pagina.Total)
+		assert.True(t, pagina.TemProxima())
+	}
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{Ordenacao: OrdenarPorPreco, Decrescente: true, Pagina: 3, PorPagina: 2})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"3"}, ids(pagina))
+		assert.False(t, pagina.TemProxima())
+	}
+
+	// Sem validade fica no fim nas duas direções
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{Ordenacao: OrdenarPorValidade})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"5", "2", "1", "3", "4"}, ids(pagina))
+	}
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{Ordenacao: OrdenarPorValidade, Decrescente: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"3", "1", "2", "5", "4"}, ids(pagina))
+	}
+
+	// Fabricante e tipo não diferenciam maiúsculas
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{Fabricante: "EMS", Tipo: "Comprimido"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"3", "5"}, ids(pagina))
+	}
+
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{CategoriaID: "cat-antibioticos", Ordenacao: OrdenarPorQuantidade})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"2", "1"}, ids(pagina))
+		assert.Equal(t, "Antibióticos", pagina.Medicamentos[0].Categoria.Nome)
+	}
+
+	minimo, maximo := 1, 60
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{QuantidadeMin: &minimo, QuantidadeMax: &maximo})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"1", "2", "5"}, ids(pagina))
+	}
+
+	// A validade até é exclusiva e os sem validade não entram no intervalo
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{
+		ValidadeDe:  time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		ValidadeAte: time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"1", "2"}, ids(pagina))
+	}
+
+	// A busca combina com os filtros
+	pagina, err = b.BuscarMedicamentos(FiltroMedicamentos{Termo: "500", Fabricante: "ems", Ordenacao: OrdenarPorNome})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"1", "3"}, ids(pagina))
+		assert.Equal(t, 2, pagina.Total)
+	}
+
+	_, err = b.BuscarMedicamentos(FiltroMedicamentos{Ordenacao: "fabricante"})
+	assert.ErrorIs(t, err, ErrOrdenacaoInvalida)
+}
@@ -0,0 +1,299 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"medicontrol/sqlutils"
+)
+
+// ErrMigracao indica que uma migração de esquema não pôde ser aplicada ou revertida.
+// A transação da migração com falha é desfeita; as anteriores continuam aplicadas.
+var ErrMigracao = errors.New("falha na migração do esquema")
+
+// StatusMigracao descreve uma migração conhecida pelo binário ou registrada no banco
+type StatusMigracao struct {
+	Versao     int        `json:"versao"`
+	Nome       string     `json:"nome"`
+	Aplicada   bool       `json:"aplicada"`
+	AplicadaEm *time.Time `json:"aplicada_em,omitempty"`
+	// O SQL da migração mudou depois de aplicado
+	Alterada bool `json:"alterada"`
+	// Registrada no banco, mas sem arquivo nesta versão do sistema
+	Ausente bool `json:"ausente"`
+}
+
+// migracaoAplicada é uma linha da tabela schema_migrations
+type migracaoAplicada struct {
+	versao     int
+	nome       string
+	checksum   string
+	aplicadaEm time.Time
+}
+
+// Migrar aplica, em ordem de versão, as migrações pendentes até a versão alvo (0 aplica todas)
+// e retorna as que foram aplicadas. Cada migração roda numa transação própria, junto com o seu
+// registro em schema_migrations. Em seguida prepara o índice de busca, que fica fora das migrações.
+func (b *Banco) Migrar(alvo int) ([]StatusMigracao, error) {
+	aplicadas, err := b.aplicarMigracoes(b.queries.GetMigrations(), alvo)
+	if err != nil {
+		return aplicadas, err
+	}
+	return aplicadas, b.prepararIndiceBusca()
+}
+
+// ReverterMigracoes desfaz as últimas migrações aplicadas, da mais recente para a mais antiga,
+// e retorna as que foram revertidas.
+func (b *Banco) ReverterMigracoes(passos int) ([]StatusMigracao, error) {
+	revertidas, err := b.reverterMigracoes(b.queries.GetMigrations(), passos)
+	if err != nil {
+		return revertidas, err
+	}
+	return revertidas, b.prepararIndiceBusca()
+}
+
+// GetStatusMigracoes lista as migrações em ordem de versão, indicando quais já foram aplicadas.
+func (b *Banco) GetStatusMigracoes() ([]StatusMigracao, error) {
+	return b.statusMigracoes(b.queries.GetMigrations())
+}
+
+func (b *Banco) aplicarMigracoes(migracoes []sqlutils.Migration, alvo int) ([]StatusMigracao, error) {
+	aplicadas, err := b.migracoesAplicadas()
+	if err != nil {
+		return nil, err
+	}
+	if len(aplicadas) == 0 {
+		if err := b.adotarEsquemaLegado(); err != nil {
+			return nil, err
+		}
+	}
+
+	inserir := b.queries.GetQuery(qInserirSchemaMigration)
+	if inserir == "" {
+		return nil, errors.New("query 'inserir_schema_migration' não encontrada")
+	}
+
+	var resultado []StatusMigracao
+	for _, m := range migracoes {
+		if alvo > 0 && m.Version > alvo {
+			break
+		}
+		if registro, ok := aplicadas[m.Version]; ok {
+			if registro.checksum != m.Checksum() {
+				log.Printf("Aviso: a migração %04d_%s foi alterada depois de aplicada.", m.Version, m.Name)
+			}
+			continue
+		}
+
+		agora := b.Agora()
+		err := b.executarMigracao(m, "up", m.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(inserir, m.Version, m.Name, m.Checksum(), agora)
+			return err
+		})
+		if err != nil {
+			return resultado, err
+		}
+		log.Printf("Migração %04d_%s aplicada.", m.Version, m.Name)
+		resultado = append(resultado, StatusMigracao{Versao: m.Version, Nome: m.Name, Aplicada: true, AplicadaEm: &agora})
+	}
+	return resultado, nil
+}
+
+func (b *Banco) reverterMigracoes(migracoes []sqlutils.Migration, passos int) ([]StatusMigracao, error) {
+	if passos < 1 {
+		return nil, fmt.Errorf("%w: informe ao menos uma migração a reverter", ErrMigracao)
+	}
+
+	aplicadas, err := b.migracoesAplicadas()
+	if err != nil {
+		return nil, err
+	}
+	porVersao := make(map[int]sqlutils.Migration, len(migracoes))
+	for _, m := range migracoes {
+		porVersao[m.Version] = m
+	}
+
+	excluir := b.queries.GetQuery(qExcluirSchemaMigration)
+	if excluir == "" {
+		return nil, errors.New("query 'excluir_schema_migration' não encontrada")
+	}
+
+	var resultado []StatusMigracao
+	for _, registro := range ordenarMigracoesAplicadas(aplicadas) {
+		if len(resultado) == passos {
+			break
+		}
+		m, ok := porVersao[registro.versao]
+		if !ok {
+			return resultado, fmt.Errorf("%w: %04d_%s não tem arquivo de migração nesta versão do sistema", ErrMigracao, registro.versao, registro.nome)
+		}
+
+		err := b.executarMigracao(m, "down", m.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(excluir, m.Version)
+			return err
+		})
+		if err != nil {
+			return resultado, err
+		}
+		log.Printf("Migração %04d_%s revertida.", m.Version, m.Name)
+		resultado = append(resultado, StatusMigracao{Versao: m.Version, Nome: m.Name})
+	}
+	return resultado, nil
+}
+
+// executarMigracao roda o SQL da migração e o registro em schema_migrations na mesma transação.
+func (b *Banco) executarMigracao(m sqlutils.Migration, direcao, script string, registrar func(tx *sql.Tx) error) error {
+	falha := func(err error) error {
+		return fmt.Errorf("%w: %04d_%s (%s): %w", ErrMigracao, m.Version, m.Name, direcao, err)
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return falha(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return falha(err)
+	}
+	if err := registrar(tx); err != nil {
+		return falha(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return falha(err)
+	}
+	return nil
+}
+
+func (b *Banco) statusMigracoes(migracoes []sqlutils.Migration) ([]StatusMigracao, error) {
+	aplicadas, err := b.migracoesAplicadas()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]StatusMigracao, 0, len(migracoes))
+	conhecidas := make(map[int]bool, len(migracoes))
+	for _, m := range migracoes {
+		conhecidas[m.Version] = true
+		s := StatusMigracao{Versao: m.Version, Nome: m.Name}
+		if registro, ok := aplicadas[m.Version]; ok {
+			aplicadaEm := registro.aplicadaEm
+			s.Aplicada = true
+			s.AplicadaEm = &aplicadaEm
+			s.Alterada = registro.checksum != m.Checksum()
+		}
+		status = append(status, s)
+	}
+	for _, registro := range ordenarMigracoesAplicadas(aplicadas) {
+		if conhecidas[registro.versao] {
+			continue
+		}
+		aplicadaEm := registro.aplicadaEm
+		status = append(status, StatusMigracao{Versao: registro.versao, Nome: registro.nome, Aplicada: true, AplicadaEm: &aplicadaEm, Ausente: true})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Versao < status[j].Versao })
+	return status, nil
+}
+
+// migracoesAplicadas cria a tabela schema_migrations, se preciso, e retorna os registros por versão.
+func (b *Banco) migracoesAplicadas() (map[int]migracaoAplicada, error) {
+	criar := b.queries.GetQuery(qCriarTabelaSchemaMigrations)
+	if criar == "" {
+		return nil, errors.New("query 'criar_tabela_schema_migrations' não encontrada")
+	}
+	if _, err := b.db.Exec(criar); err != nil {
+		return nil, err
+	}
+
+	query := b.queries.GetQuery(qSelecionarSchemaMigrations)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_schema_migrations' não encontrada")
+	}
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aplicadas := make(map[int]migracaoAplicada)
+	for rows.Next() {
+		var registro migracaoAplicada
+		if err := rows.Scan(&registro.versao, &registro.nome, &registro.checksum, &registro.aplicadaEm); err != nil {
+			return nil, err
+		}
+		aplicadas[registro.versao] = registro
+	}
+	return aplicadas, rows.Err()
+}
+
+// ordenarMigracoesAplicadas retorna os registros da versão mais recente para a mais antiga.
+func ordenarMigracoesAplicadas(aplicadas map[int]migracaoAplicada) []migracaoAplicada {
+	lista := make([]migracaoAplicada, 0, len(aplicadas))
+	for _, registro := range aplicadas {
+		lista = append(lista, registro)
+	}
+	sort.Slice(lista, func(i, j int) bool { return lista[i].versao > lista[j].versao })
+	return lista
+}
+
+// adotarEsquemaLegado completa as tabelas de um banco criado antes do controle de versão do esquema,
+// quando as colunas eram adicionadas na inicialização. Assim a migração inicial, que só cria as
+// tabelas ausentes, deixa o banco igual a um criado do zero.
+func (b *Banco) adotarEsquemaLegado() error {
+	// Só o SQLite tem bancos anteriores às migrações
+	if b.queries.Dialeto() != sqlutils.DialetoSQLite {
+		return nil
+	}
+	existe, err := b.tabelaExiste("medicamentos")
+	if err != nil || !existe {
+		return err
+	}
+
+	log.Println("Banco sem controle de versão do esquema encontrado; atualizando as tabelas existentes.")
+	for _, coluna := range []struct{ tabela, nome, tipo string }{
+		{"medicamentos", "Preco", "REAL DEFAULT 0.0"},
+		{"medicamentos", "CategoriaID", "TEXT"},
+		{"medicamentos", "ListaControle", "TEXT"},
+		{"medicamentos", "CustoMedio", "REAL NOT NULL DEFAULT 0"},
+		{"movimentacoes", "UsuarioID", "INTEGER"},
+		{"movimentacoes", "NotaFiscal", "TEXT"},
+		{"movimentacoes", "CNPJFornecedor", "TEXT"},
+		{"movimentacoes", "VendaID", "INTEGER"},
+		{"movimentacoes", "CustoUnitario", "REAL"},
+		{"vendas", "status", "TEXT NOT NULL DEFAULT 'ativa'"},
+		{"vendas", "motivo_estorno", "TEXT"},
+		{"vendas", "subtotal", "REAL"},
+		{"vendas", "desconto", "REAL NOT NULL DEFAULT 0"},
+		{"vendas", "total", "REAL"},
+		{"vendas", "valor_recebido", "REAL"},
+		{"vendas", "troco", "REAL NOT NULL DEFAULT 0"},
+		{"vendas", "caixa_id", "INTEGER"},
+		{"venda_items", "quantidade_devolvida", "INTEGER NOT NULL DEFAULT 0"},
+		{"venda_items", "desconto", "REAL NOT NULL DEFAULT 0"},
+		{"venda_items", "valor_total", "REAL"},
+		{"venda_items", "custo_unitario", "REAL"},
+		{"venda_item_lotes", "quantidade_devolvida", "INTEGER NOT NULL DEFAULT 0"},
+		{"lotes", "custo_unitario", "REAL"},
+	} {
+		if err := b.addColumnIfNotExists(coluna.tabela, coluna.nome, coluna.tipo); err != nil {
+			return fmt.Errorf("%w: esquema legado: %w", ErrMigracao, err)
+		}
+	}
+	return nil
+}
+
+func (b *Banco) tabelaExiste(nome string) (bool, error) {
+	query := b.queries.GetQuery(qContarTabela)
+	if query == "" {
+		return false, errors.New("query 'contar_tabela' não encontrada")
+	}
+	var total int
+	if err := b.db.QueryRow(query, nome).Scan(&total); err != nil {
+		return false, err
+	}
+	return total > 0, nil
+}
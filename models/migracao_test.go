@@ -0,0 +1,169 @@
+package models
+
+import (
+	"testing"
+
+	"medicontrol/sqlutils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// abrirBancoTeste abre um SQLite em memória, próprio do teste, sem esquema.
+func abrirBancoTeste(t *testing.T) *Banco {
+	t.Helper()
+	return abrirBancoTesteCom(t, ConfigBanco{Driver: DriverSQLite, Caminho: ":memory:"})
+}
+
+// abrirBancoTesteCom abre o banco informado e o fecha ao fim do teste.
+func abrirBancoTesteCom(t *testing.T, cfg ConfigBanco) *Banco {
+	t.Helper()
+	queries, err := sqlutils.LoadEmbeddedSQL()
+	if err != nil {
+		t.Fatalf("erro ao carregar arquivos SQL: %v", err)
+	}
+
+	b, err := AbrirBanco(cfg, queries)
+	if err != nil {
+		t.Fatalf("erro ao abrir banco de teste: %v", err)
+	}
+	t.Cleanup(func() { b.Fechar() })
+	return b
+}
+
+func TestMigrarEReverterEsquemaInicial(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	migracoes := b.queries.GetMigrations()
+	if !assert.NotEmpty(t, migracoes) {
+		return
+	}
+
+	aplicadas, err := b.Migrar(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, aplicadas, len(migracoes))
+	for _, tabela := range []string{"medicamentos", "vendas", "venda_items", "lotes", "inventarios"} {
+		existe, err := b.tabelaExiste(tabela)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, existe, tabela)
+	}
+
+	// Sem migrações pendentes, nada é reaplicado
+	aplicadas, err = b.Migrar(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, aplicadas)
+
+	revertidas, err := b.ReverterMigracoes(len(migracoes))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, revertidas, len(migracoes))
+	existe, err := b.tabelaExiste("medicamentos")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, existe)
+
+	status, err := b.GetStatusMigracoes()
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, s := range status {
+		assert.False(t, s.Aplicada)
+	}
+}
+
+func TestMigracaoComFalhaEDesfeita(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	migracoes := []sqlutils.Migration{
+		{Version: 1, Name: "criar_a", Up: "CREATE TABLE a (id INTEGER);", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "criar_b", Up: "CREATE TABLE b (id INTEGER); INSERT INTO inexistente VALUES (1);", Down: "DROP TABLE b;"},
+	}
+
+	aplicadas, err := b.aplicarMigracoes(migracoes, 0)
+	assert.ErrorIs(t, err, ErrMigracao)
+	assert.ErrorContains(t, err, "0002_criar_b")
+	if !assert.Len(t, aplicadas, 1) {
+		return
+	}
+	assert.Equal(t, 1, aplicadas[0].Versao)
+
+	// A tabela criada antes do erro foi desfeita junto com a migração
+	existe, err := b.tabelaExiste("b")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, existe)
+
+	status, err := b.statusMigracoes(migracoes)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, status, 2) {
+		return
+	}
+	assert.True(t, status[0].Aplicada)
+	assert.False(t, status[1].Aplicada)
+
+	// Com a migração corrigida, a aplicação continua de onde parou
+	migracoes[1].Up = "CREATE TABLE b (id INTEGER);"
+	aplicadas, err = b.aplicarMigracoes(migracoes, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, aplicadas, 1) {
+		return
+	}
+	assert.Equal(t, 2, aplicadas[0].Versao)
+
+	status, err = b.statusMigracoes(migracoes[:1])
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, status, 2) {
+		return
+	}
+	assert.True(t, status[1].Ausente)
+	_, err = b.reverterMigracoes(migracoes[:1], 1)
+	assert.ErrorIs(t, err, ErrMigracao)
+}
+
+func TestMigrarAdotaBancoLegado(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	_, err := b.db.Exec(`CREATE TABLE medicamentos (ID TEXT PRIMARY KEY, Nome TEXT, Fabricante TEXT, Tipo TEXT,
+		CodigoANVISA TEXT, Quantidade INTEGER, Validade TEXT, CriadoEm DATETIME)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = b.db.Exec(`INSERT INTO medicamentos (ID, Nome, Quantidade) VALUES ('m1', 'Dipirona', 10)`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = b.Migrar(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var nome string
+	var custoMedio float64
+	err = b.db.QueryRow(`SELECT Nome, CustoMedio FROM medicamentos WHERE ID = 'm1'`).Scan(&nome, &custoMedio)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Dipirona", nome)
+	assert.Zero(t, custoMedio)
+
+	existe, err := b.tabelaExiste("vendas")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, existe)
+}
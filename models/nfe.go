@@ -0,0 +1,649 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status da importação de uma NF-e
+const (
+	StatusNFePendente   = "pendente"
+	StatusNFeConfirmada = "confirmada"
+)
+
+// Critérios usados para relacionar um item da NF-e a um medicamento do cadastro
+const (
+	CriterioEAN              = "ean"               // EAN já relacionado em uma importação anterior
+	CriterioCodigoFornecedor = "codigo_fornecedor" // código do produto no fornecedor já relacionado antes
+	CriterioCodigoANVISA     = "codigo_anvisa"
+	CriterioNome             = "nome"
+	CriterioManual           = "manual"
+)
+
+var (
+	// ErrNFeInvalida indica um XML que não é uma NF-e ou que tem dados inconsistentes
+	ErrNFeInvalida = errors.New("NF-e inválida")
+	// ErrNFeJaImportada indica uma NF-e cuja entrada no estoque já foi confirmada
+	ErrNFeJaImportada = errors.New("NF-e já importada")
+	// ErrImportacaoNFeNaoEncontrada indica que a importação informada não existe
+	ErrImportacaoNFeNaoEncontrada = errors.New("importação de NF-e não encontrada")
+	// ErrNFeItensPendentes indica itens sem medicamento correspondente na confirmação
+	ErrNFeItensPendentes = errors.New("itens da NF-e sem medicamento correspondente")
+)
+
+// ErroItensPendentesNFe lista os itens que precisam ser relacionados ou ignorados antes da confirmação
+type ErroItensPendentesNFe struct {
+	Itens []int
+}
+
+func (e *ErroItensPendentesNFe) Error() string {
+	itens := make([]string, len(e.Itens))
+	for i, n := range e.Itens {
+		itens[i] = strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%s: itens %s", ErrNFeItensPendentes, strings.Join(itens, ", "))
+}
+
+func (e *ErroItensPendentesNFe) Unwrap() error { return ErrNFeItensPendentes }
+
+// Estrutura do XML da NF-e (modelo 55, layout 4.00), apenas com os campos usados na entrada de estoque.
+// O arquivo pode vir com a nota autorizada (nfeProc) ou só com o elemento NFe.
+type nfeProcXML struct {
+	NFe nfeXML `xml:"NFe"`
+}
+
+type nfeXML struct {
+	InfNFe infNFeXML `xml:"infNFe"`
+}
+
+type infNFeXML struct {
+	ID  string `xml:"Id,attr"`
+	Ide struct {
+		Serie string `xml:"serie"`
+		NNF   string `xml:"nNF"`
+		DhEmi string `xml:"dhEmi"`
+	} `xml:"ide"`
+	Emit struct {
+		CNPJ  string `xml:"CNPJ"`
+		XNome string `xml:"xNome"`
+	} `xml:"emit"`
+	Det []detNFeXML `xml:"det"`
+}
+
+type detNFeXML struct {
+	NItem int `xml:"nItem,attr"`
+	Prod  struct {
+		CProd  string `xml:"cProd"`
+		CEAN   string `xml:"cEAN"`
+		XProd  string `xml:"xProd"`
+		UCom   string `xml:"uCom"`
+		QCom   string `xml:"qCom"`
+		VUnCom string `xml:"vUnCom"`
+		VProd  string `xml:"vProd"`
+		Rastro []struct {
+			NLote string `xml:"nLote"`
+			QLote string `xml:"qLote"`
+			DFab  string `xml:"dFab"`
+			DVal  string `xml:"dVal"`
+		} `xml:"rastro"`
+		Med struct {
+			CProdANVISA string `xml:"cProdANVISA"`
+		} `xml:"med"`
+	} `xml:"prod"`
+}
+
+// NotaFiscalNFe são os dados da NF-e usados na entrada de estoque
+type NotaFiscalNFe struct {
+	Chave        string    `json:"chave"`
+	Numero       string    `json:"numero"`
+	Serie        string    `json:"serie"`
+	DataEmissao  string    `json:"data_emissao"` // YYYY-MM-DD
+	EmitenteCNPJ string    `json:"emitente_cnpj"`
+	EmitenteNome string    `json:"emitente_nome"`
+	Itens        []ItemNFe `json:"itens"`
+}
+
+// ItemNFe é um produto da nota, com os lotes informados no grupo de rastreabilidade
+type ItemNFe struct {
+	NumeroItem    int       `json:"numero_item"`
+	Codigo        string    `json:"codigo"` // Código do produto no fornecedor
+	EAN           string    `json:"ean,omitempty"`
+	CodigoANVISA  string    `json:"codigo_anvisa,omitempty"`
+	Descricao     string    `json:"descricao"`
+	Unidade       string    `json:"unidade"`
+	Quantidade    int       `json:"quantidade"`
+	CustoUnitario float64   `json:"custo_unitario"`
+	ValorTotal    float64   `json:"valor_total"`
+	Lotes         []LoteNFe `json:"lotes"`
+	// Medicamento correspondente, preenchido na importação
+	MedicamentoID   string `json:"medicamento_id,omitempty"`
+	MedicamentoNome string `json:"medicamento_nome,omitempty"`
+	Criterio        string `json:"criterio,omitempty"`
+}
+
+// LoteNFe é um lote de um item da nota
+type LoteNFe struct {
+	Numero     string `json:"numero"`
+	Quantidade int    `json:"quantidade"`
+	Fabricacao string `json:"fabricacao,omitempty"`
+	Validade   string `json:"validade"`
+}
+
+// ImportacaoNFe é uma NF-e carregada para conferência antes de dar entrada no estoque
+type ImportacaoNFe struct {
+	ID int64 `json:"id"`
+	NotaFiscalNFe
+	Status       string     `json:"status"`
+	FornecedorID int        `json:"fornecedor_id,omitempty"` // Fornecedor cadastrado com o CNPJ do emitente
+	CriadoEm     time.Time  `json:"criado_em"`
+	ConfirmadoEm *time.Time `json:"confirmado_em,omitempty"`
+	Pendentes    []int      `json:"pendentes"` // Itens sem medicamento correspondente
+	// Entradas geradas na confirmação
+	Movimentacoes []Movimentacao `json:"movimentacoes,omitempty"`
+}
+
+// ConfirmacaoNFeRequest relaciona manualmente os itens pendentes e indica os que não entram no estoque
+type ConfirmacaoNFeRequest struct {
+	Mapeamentos []MapeamentoItemNFe `json:"mapeamentos"`
+	Ignorar     []int               `json:"ignorar"`
+}
+
+// MapeamentoItemNFe relaciona um item da nota a um medicamento do cadastro
+type MapeamentoItemNFe struct {
+	NumeroItem    int    `json:"numero_item"`
+	MedicamentoID string `json:"medicamento_id"`
+}
+
+// ParseNFe lê o XML de uma NF-e e confere os dados necessários para a entrada no estoque:
+// chave de acesso, emitente, quantidades inteiras e lotes que não ultrapassam a quantidade do item.
+func ParseNFe(data []byte) (*NotaFiscalNFe, error) {
+	var proc nfeProcXML
+	if err := xml.Unmarshal(data, &proc); err != nil {
+		return nil, fmt.Errorf("%w: XML malformado: %v", ErrNFeInvalida, err)
+	}
+	inf := proc.NFe.InfNFe
+	if inf.ID == "" {
+		var nfe nfeXML
+		if err := xml.Unmarshal(data, &nfe); err != nil {
+			return nil, fmt.Errorf("%w: XML malformado: %v", ErrNFeInvalida, err)
+		}
+		inf = nfe.InfNFe
+	}
+
+	chave := strings.TrimPrefix(inf.ID, "NFe")
+	if len(chave) != 44 || somenteDigitos(chave) != chave {
+		return nil, fmt.Errorf("%w: chave de acesso ausente ou inválida", ErrNFeInvalida)
+	}
+	nota := &NotaFiscalNFe{
+		Chave:        chave,
+		Numero:       strings.TrimSpace(inf.Ide.NNF),
+		Serie:        strings.TrimSpace(inf.Ide.Serie),
+		EmitenteCNPJ: somenteDigitos(inf.Emit.CNPJ),
+		EmitenteNome: strings.TrimSpace(inf.Emit.XNome),
+	}
+	if nota.Numero == "" {
+		return nil, fmt.Errorf("%w: número da nota ausente", ErrNFeInvalida)
+	}
+	if len(nota.EmitenteCNPJ) != 14 {
+		return nil, fmt.Errorf("%w: CNPJ do emitente ausente ou inválido", ErrNFeInvalida)
+	}
+	if len(inf.Ide.DhEmi) >= 10 {
+		nota.DataEmissao = inf.Ide.DhEmi[:10]
+	}
+	if len(inf.Det) == 0 {
+		return nil, fmt.Errorf("%w: a nota não tem itens", ErrNFeInvalida)
+	}
+
+	for _, det := range inf.Det {
+		item, err := parseItemNFe(det)
+		if err != nil {
+			return nil, err
+		}
+		nota.Itens = append(nota.Itens, item)
+	}
+	return nota, nil
+}
+
+func parseItemNFe(det detNFeXML) (ItemNFe, error) {
+	p := det.Prod
+	item := ItemNFe{
+		NumeroItem:   det.NItem,
+		Codigo:       strings.TrimSpace(p.CProd),
+		CodigoANVISA: strings.TrimSpace(p.Med.CProdANVISA),
+		Descricao:    strings.TrimSpace(p.XProd),
+		Unidade:      strings.TrimSpace(p.UCom),
+		Lotes:        []LoteNFe{},
+	}
+	// "SEM GTIN" é o valor usado na nota para produtos sem código de barras
+	if ean := somenteDigitos(p.CEAN); ean != "" {
+		item.EAN = ean
+	}
+
+	var err error
+	if item.Quantidade, err = quantidadeNFe(p.QCom); err != nil {
+		return item, fmt.Errorf("%w: item %d: %v", ErrNFeInvalida, det.NItem, err)
+	}
+	if item.CustoUnitario, err = strconv.ParseFloat(p.VUnCom, 64); err != nil {
+		return item, fmt.Errorf("%w: item %d: valor unitário '%s'", ErrNFeInvalida, det.NItem, p.VUnCom)
+	}
+	item.CustoUnitario = arredondar(item.CustoUnitario)
+	if item.ValorTotal, err = strconv.ParseFloat(p.VProd, 64); err != nil {
+		return item, fmt.Errorf("%w: item %d: valor do produto '%s'", ErrNFeInvalida, det.NItem, p.VProd)
+	}
+
+	totalLotes := 0
+	for _, r := range p.Rastro {
+		lote := LoteNFe{Numero: strings.TrimSpace(r.NLote), Fabricacao: r.DFab, Validade: r.DVal}
+		if lote.Numero == "" {
+			return item, fmt.Errorf("%w: item %d: lote sem número", ErrNFeInvalida, det.NItem)
+		}
+		if lote.Quantidade, err = quantidadeNFe(r.QLote); err != nil {
+			return item, fmt.Errorf("%w: item %d, lote %s: %v", ErrNFeInvalida, det.NItem, lote.Numero, err)
+		}
+		// O leiaute da NF-e usa sempre AAAA-MM-DD, o mesmo formato gravado nos lotes
+		if _, err := time.Parse("2006-01-02", lote.Validade); err != nil {
+			return item, fmt.Errorf("%w: item %d, lote %s: validade '%s'", ErrNFeInvalida, det.NItem, lote.Numero, lote.Validade)
+		}
+		totalLotes += lote.Quantidade
+		item.Lotes = append(item.Lotes, lote)
+	}
+	if totalLotes > item.Quantidade {
+		return item, fmt.Errorf("%w: item %d: os lotes somam %d unidades, mais que as %d da nota",
+			ErrNFeInvalida, det.NItem, totalLotes, item.Quantidade)
+	}
+	return item, nil
+}
+
+// quantidadeNFe converte a quantidade da nota ("10.0000") em unidades inteiras.
+func quantidadeNFe(valor string) (int, error) {
+	q, err := strconv.ParseFloat(strings.TrimSpace(valor), 64)
+	if err != nil {
+		return 0, fmt.Errorf("quantidade '%s' inválida", valor)
+	}
+	if q <= 0 || q != math.Trunc(q) {
+		return 0, fmt.Errorf("quantidade '%s' deve ser um número inteiro de unidades maior que zero", valor)
+	}
+	return int(q), nil
+}
+
+// entradasItemNFe monta as movimentações de entrada de um item: uma por lote da nota e,
+// se os lotes não cobrirem a quantidade do item, uma entrada sem lote informado com o restante.
+func entradasItemNFe(item ItemNFe, nota *NotaFiscalNFe, usuarioID int) []Movimentacao {
+	base := Movimentacao{
+		MedicamentoID:  item.MedicamentoID,
+		Tipo:           "entrada",
+		Observacao:     fmt.Sprintf("NF-e %s, item %d: %s", nota.Numero, item.NumeroItem, item.Descricao),
+		UsuarioID:      usuarioID,
+		Fornecedor:     nota.EmitenteNome,
+		NotaFiscal:     nota.Numero,
+		CNPJFornecedor: nota.EmitenteCNPJ,
+		CustoUnitario:  item.CustoUnitario,
+	}
+
+	var entradas []Movimentacao
+	restante := item.Quantidade
+	for _, lote := range item.Lotes {
+		mov := base
+		mov.Quantidade = lote.Quantidade
+		mov.Lote = lote.Numero
+		mov.Validade = lote.Validade
+		entradas = append(entradas, mov)
+		restante -= lote.Quantidade
+	}
+	if restante > 0 {
+		mov := base
+		mov.Quantidade = restante
+		entradas = append(entradas, mov)
+	}
+	return entradas
+}
+
+// acentos mapeia as letras acentuadas usadas nas descrições de produtos para as sem acento.
+var acentos = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "Ê", "E", "È", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C",
+)
+
+// normalizarNomeProduto deixa o nome em maiúsculas, sem acentos e com um único espaço entre as palavras.
+func normalizarNomeProduto(nome string) string {
+	return strings.Join(strings.Fields(acentos.Replace(strings.ToUpper(nome))), " ")
+}
+
+// candidatoNome é um medicamento do cadastro com o nome já normalizado
+type candidatoNome struct {
+	ID   string
+	Nome string
+}
+
+// casarPorNome procura o medicamento cujo nome é igual à descrição do item ou é o início dela
+// ("DIPIRONA 500MG" casa com "DIPIRONA 500MG CX 10 CPR"). Vence o nome mais longo; empates entre
+// medicamentos diferentes deixam o item sem correspondência.
+func casarPorNome(descricao string, candidatos []candidatoNome) (string, bool) {
+	descricao = normalizarNomeProduto(descricao)
+	melhor, tamanho, empate := "", 0, false
+	for _, c := range candidatos {
+		if c.Nome == "" {
+			continue
+		}
+		if c.Nome != descricao && !strings.HasPrefix(descricao, c.Nome+" ") {
+			continue
+		}
+		switch {
+		case len(c.Nome) > tamanho:
+			melhor, tamanho, empate = c.ID, len(c.Nome), false
+		case len(c.Nome) == tamanho && c.ID != melhor:
+			empate = true
+		}
+	}
+	if melhor == "" || empate {
+		return "", false
+	}
+	return melhor, true
+}
+
+// ImportarNFe carrega o XML de uma NF-e para conferência e relaciona seus itens aos medicamentos.
+// Nada entra no estoque até a confirmação. Reenviar uma nota ainda pendente substitui o XML guardado.
+func (b *Banco) ImportarNFe(data []byte, usuarioID int) (*ImportacaoNFe, error) {
+	nota, err := ParseNFe(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	importacao := &ImportacaoNFe{NotaFiscalNFe: *nota, Status: StatusNFePendente, CriadoEm: b.Agora()}
+	existente, err := b.selecionarImportacaoNFe(tx, qSelecionarNfeImportacaoPorChave, nota.Chave)
+	switch {
+	case errors.Is(err, ErrImportacaoNFeNaoEncontrada):
+		query := b.queries.GetQuery(qInserirNfeImportacao)
+		if query == "" {
+			return nil, errors.New("query 'inserir_nfe_importacao' não encontrada")
+		}
+		importacao.ID, err = b.inserirComID(tx, query, nota.Chave, nota.Numero, nota.Serie, nota.EmitenteCNPJ, nota.EmitenteNome, string(data),
+			importacao.Status, usuarioID, importacao.CriadoEm)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao registrar importação da NF-e: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	case existente.Status == StatusNFeConfirmada:
+		return nil, fmt.Errorf("%w: a nota %s foi confirmada na importação %d", ErrNFeJaImportada, nota.Numero, existente.ID)
+	default:
+		query := b.queries.GetQuery(qAtualizarXmlNfeImportacao)
+		if query == "" {
+			return nil, errors.New("query 'atualizar_xml_nfe_importacao' não encontrada")
+		}
+		if _, err := tx.Exec(query, string(data), usuarioID, importacao.CriadoEm, existente.ID); err != nil {
+			return nil, err
+		}
+		importacao.ID = existente.ID
+	}
+
+	if err := b.corresponderItensNFe(tx, importacao); err != nil {
+		return nil, err
+	}
+	return importacao, tx.Commit()
+}
+
+// GetImportacaoNFe retorna uma importação com as correspondências calculadas a partir do cadastro atual.
+func (b *Banco) GetImportacaoNFe(id int64) (*ImportacaoNFe, error) {
+	importacao, err := b.selecionarImportacaoNFe(b.db, qSelecionarNfeImportacaoPorId, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.corresponderItensNFe(b.db, importacao); err != nil {
+		return nil, err
+	}
+	return importacao, nil
+}
+
+// ConfirmarImportacaoNFe dá entrada no estoque dos itens da nota, com as mesmas regras de
+// RegistrarMovimentacao: cada lote da nota vira uma entrada com quantidade, validade, custo unitário,
+// número da nota e CNPJ do emitente. Itens sem correspondência precisam ser relacionados em
+// req.Mapeamentos ou listados em req.Ignorar. As correspondências confirmadas ficam guardadas por
+// fornecedor, para que as próximas notas sejam relacionadas pelo EAN ou pelo código do produto.
+func (b *Banco) ConfirmarImportacaoNFe(id int64, req ConfirmacaoNFeRequest, usuarioID int) (*ImportacaoNFe, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	importacao, err := b.selecionarImportacaoNFe(tx, qSelecionarNfeImportacaoPorId, id)
+	if err != nil {
+		return nil, err
+	}
+	if importacao.Status == StatusNFeConfirmada {
+		return nil, fmt.Errorf("%w: a importação %d já foi confirmada", ErrNFeJaImportada, id)
+	}
+	if err := b.corresponderItensNFe(tx, importacao); err != nil {
+		return nil, err
+	}
+
+	porNumero := make(map[int]*ItemNFe, len(importacao.Itens))
+	for i := range importacao.Itens {
+		porNumero[importacao.Itens[i].NumeroItem] = &importacao.Itens[i]
+	}
+	for _, m := range req.Mapeamentos {
+		item, ok := porNumero[m.NumeroItem]
+		if !ok {
+			return nil, fmt.Errorf("%w: a nota não tem o item %d", ErrNFeInvalida, m.NumeroItem)
+		}
+		med := b.buscarMedicamento(tx, m.MedicamentoID)
+		if med == nil {
+			return nil, fmt.Errorf("%w: medicamento '%s' do item %d não encontrado", ErrNFeInvalida, m.MedicamentoID, m.NumeroItem)
+		}
+		item.MedicamentoID, item.MedicamentoNome, item.Criterio = med.ID, med.Nome, CriterioManual
+	}
+	ignorados := make(map[int]bool, len(req.Ignorar))
+	for _, n := range req.Ignorar {
+		ignorados[n] = true
+	}
+
+	var pendentes []int
+	for _, item := range importacao.Itens {
+		if item.MedicamentoID == "" && !ignorados[item.NumeroItem] {
+			pendentes = append(pendentes, item.NumeroItem)
+		}
+	}
+	if len(pendentes) > 0 {
+		return nil, &ErroItensPendentesNFe{Itens: pendentes}
+	}
+
+	querySalvar := b.queries.GetQuery(qSalvarProdutoFornecedor)
+	if querySalvar == "" {
+		return nil, errors.New("query 'salvar_produto_fornecedor' não encontrada")
+	}
+	agora := b.Agora()
+	for _, item := range importacao.Itens {
+		if ignorados[item.NumeroItem] {
+			continue
+		}
+		for _, mov := range entradasItemNFe(item, &importacao.NotaFiscalNFe, usuarioID) {
+			if err := b.registrarMovimentacaoTx(tx, &mov); err != nil {
+				return nil, fmt.Errorf("item %d da NF-e: %w", item.NumeroItem, err)
+			}
+			importacao.Movimentacoes = append(importacao.Movimentacoes, mov)
+		}
+		if item.Codigo != "" {
+			_, err := tx.Exec(querySalvar, importacao.EmitenteCNPJ, item.Codigo, sql.NullString{String: item.EAN, Valid: item.EAN != ""}, item.MedicamentoID, agora)
+			if err != nil {
+				return nil, fmt.Errorf("erro ao guardar a correspondência do item %d: %w", item.NumeroItem, err)
+			}
+		}
+	}
+
+	query := b.queries.GetQuery(qConfirmarNfeImportacao)
+	if query == "" {
+		return nil, errors.New("query 'confirmar_nfe_importacao' não encontrada")
+	}
+	if _, err := tx.Exec(query, StatusNFeConfirmada, agora, usuarioID, id); err != nil {
+		return nil, err
+	}
+	importacao.Status = StatusNFeConfirmada
+	importacao.ConfirmadoEm = &agora
+	importacao.Pendentes = []int{}
+
+	depois := map[string]interface{}{
+		"chave":       importacao.Chave,
+		"numero":      importacao.Numero,
+		"emitente":    importacao.EmitenteCNPJ,
+		"ignorados":   req.Ignorar,
+		"mapeamentos": req.Mapeamentos,
+		"entradas":    len(importacao.Movimentacoes),
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "nfe_importacao", strconv.FormatInt(id, 10), nil, depois); err != nil {
+		return nil, err
+	}
+	return importacao, tx.Commit()
+}
+
+// selecionarImportacaoNFe carrega a importação e interpreta novamente o XML guardado.
+func (b *Banco) selecionarImportacaoNFe(db execer, nomeQuery string, arg interface{}) (*ImportacaoNFe, error) {
+	query := b.queries.GetQuery(nomeQuery)
+	if query == "" {
+		return nil, fmt.Errorf("query '%s' não encontrada", nomeQuery)
+	}
+
+	var importacao ImportacaoNFe
+	var chave, xmlNota string
+	var confirmadoEm sql.NullTime
+	var usuarioID int
+	err := db.QueryRow(query, arg).Scan(&importacao.ID, &chave, &importacao.Status, &xmlNota, &usuarioID, &importacao.CriadoEm, &confirmadoEm)
+	if err == sql.ErrNoRows {
+		return nil, ErrImportacaoNFeNaoEncontrada
+	}
+	if err != nil {
+		return nil, err
+	}
+	if confirmadoEm.Valid {
+		importacao.ConfirmadoEm = &confirmadoEm.Time
+	}
+
+	nota, err := ParseNFe(bytes.TrimSpace([]byte(xmlNota)))
+	if err != nil {
+		return nil, fmt.Errorf("XML guardado da importação %d: %w", importacao.ID, err)
+	}
+	importacao.NotaFiscalNFe = *nota
+	return &importacao, nil
+}
+
+// corresponderItensNFe relaciona cada item da nota a um medicamento, nesta ordem: EAN já relacionado
+// antes, código do produto no mesmo fornecedor, código ANVISA e, por último, nome. Também identifica
+// o fornecedor cadastrado com o CNPJ do emitente.
+func (b *Banco) corresponderItensNFe(db execer, importacao *ImportacaoNFe) error {
+	fornecedor, err := b.selecionarFornecedor(db, qSelecionarFornecedorPorCnpj, importacao.EmitenteCNPJ)
+	if err != nil {
+		return err
+	}
+	if fornecedor != nil {
+		importacao.FornecedorID = fornecedor.ID
+	}
+
+	queryEAN := b.queries.GetQuery(qSelecionarProdutoFornecedorPorEan)
+	queryCodigo := b.queries.GetQuery(qSelecionarProdutoFornecedorPorCodigo)
+	queryANVISA := b.queries.GetQuery(qSelecionarMedicamentoPorCodigoAnvisa)
+	if queryEAN == "" || queryCodigo == "" || queryANVISA == "" {
+		return errors.New("queries de correspondência de produtos da NF-e não encontradas")
+	}
+	candidatos, err := b.candidatosPorNome(db)
+	if err != nil {
+		return err
+	}
+
+	// buscarID executa uma consulta que retorna o ID do medicamento, se houver
+	buscarID := func(query string, args ...interface{}) (string, error) {
+		var id string
+		err := db.QueryRow(query, args...).Scan(&id)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return id, err
+	}
+
+	importacao.Pendentes = []int{}
+	for i := range importacao.Itens {
+		item := &importacao.Itens[i]
+		item.MedicamentoID, item.MedicamentoNome, item.Criterio = "", "", ""
+
+		var id, criterio string
+		if item.EAN != "" {
+			if id, err = buscarID(queryEAN, item.EAN); err != nil {
+				return err
+			}
+			criterio = CriterioEAN
+		}
+		if id == "" && item.Codigo != "" {
+			if id, err = buscarID(queryCodigo, importacao.EmitenteCNPJ, item.Codigo); err != nil {
+				return err
+			}
+			criterio = CriterioCodigoFornecedor
+		}
+		if id == "" && item.CodigoANVISA != "" {
+			med, err := scanMedicamento(db.QueryRow(queryANVISA, item.CodigoANVISA))
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if med != nil {
+				id, criterio = med.ID, CriterioCodigoANVISA
+			}
+		}
+		if id == "" {
+			var ok bool
+			if id, ok = casarPorNome(item.Descricao, candidatos); ok {
+				criterio = CriterioNome
+			}
+		}
+
+		if id == "" {
+			importacao.Pendentes = append(importacao.Pendentes, item.NumeroItem)
+			continue
+		}
+		item.MedicamentoID, item.Criterio = id, criterio
+		if med := b.buscarMedicamento(db, id); med != nil {
+			item.MedicamentoNome = med.Nome
+		}
+	}
+	return nil
+}
+
+// candidatosPorNome lista os medicamentos com o nome normalizado, ordenados pelo nome.
+func (b *Banco) candidatosPorNome(db execer) ([]candidatoNome, error) {
+	query := b.queries.GetQuery(qSelecionarNomesMedicamentos)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_nomes_medicamentos' não encontrada")
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidatos []candidatoNome
+	for rows.Next() {
+		var c candidatoNome
+		if err := rows.Scan(&c.ID, &c.Nome); err != nil {
+			return nil, err
+		}
+		c.Nome = normalizarNomeProduto(c.Nome)
+		candidatos = append(candidatos, c)
+	}
+	sort.Slice(candidatos, func(i, j int) bool { return candidatos[i].Nome < candidatos[j].Nome })
+	return candidatos, rows.Err()
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lerNFeTeste(t *testing.T, arquivo string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + arquivo)
+	if err != nil {
+		t.Fatalf("erro ao ler %s: %v", arquivo, err)
+	}
+	return data
+}
+
+func TestParseNFeComRastro(t *testing.T) {
+	nota, err := ParseNFe(lerNFeTeste(t, "nfe_distribuidora.xml"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "35240511222333000181550010000123451000123456", nota.Chave)
+	assert.Equal(t, "12345", nota.Numero)
+	assert.Equal(t, "1", nota.Serie)
+	assert.Equal(t, "2024-05-10", nota.DataEmissao)
+	assert.Equal(t, "11222333000181", nota.EmitenteCNPJ)
+	assert.Len(t, nota.Itens, 2)
+
+	dipirona := nota.Itens[0]
+	assert.Equal(t, 1, dipirona.NumeroItem)
+	assert.Equal(t, "00451", dipirona.Codigo)
+	assert.Equal(t, "7896004700014", dipirona.EAN)
+	assert.Equal(t, "1058300140017", dipirona.CodigoANVISA)
+	assert.Equal(t, 30, dipirona.Quantidade)
+	assert.Equal(t, 4.5, dipirona.CustoUnitario)
+	assert.Equal(t, []LoteNFe{
+		{Numero: "DIP2401", Quantidade: 20, Fabricacao: "2024-01-15", Validade: "2026-01-15"},
+		{Numero: "DIP2402", Quantidade: 10, Fabricacao: "2024-02-20", Validade: "2026-02-20"},
+	}, dipirona.Lotes)
+}
+
+func TestParseNFeSemProtocoloESemGTIN(t *testing.T) {
+	nota, err := ParseNFe(lerNFeTeste(t, "nfe_sem_rastro.xml"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// O CNPJ vem com pontuação no arquivo e "SEM GTIN" não é um EAN
+	assert.Equal(t, "11222333000181", nota.EmitenteCNPJ)
+	assert.Equal(t, "", nota.Itens[0].EAN)
+	assert.Equal(t, 24, nota.Itens[0].Quantidade)
+	assert.Empty(t, nota.Itens[0].Lotes)
+}
+
+func TestParseNFeInvalida(t *testing.T) {
+	valida := string(lerNFeTeste(t, "nfe_distribuidora.xml"))
+
+	casos := map[string]string{
+		"xml malformado":        "<nfeProc><NFe>",
+		"sem chave":             strings.Replace(valida, `Id="NFe35240511222333000181550010000123451000123456"`, "", 1),
+		"quantidade fracionada": strings.Replace(valida, "<qCom>30.0000</qCom>", "<qCom>30.5000</qCom>", 1),
+		"lotes acima do item":   strings.Replace(valida, "<qLote>20.000</qLote>", "<qLote>25.000</qLote>", 1),
+		"validade do lote":      strings.Replace(valida, "<dVal>2026-01-15</dVal>", "<dVal>15/01/2026</dVal>", 1),
+	}
+	for nome, xmlNota := range casos {
+		_, err := ParseNFe([]byte(xmlNota))
+		assert.True(t, errors.Is(err, ErrNFeInvalida), "%s: esperado ErrNFeInvalida, obtido %v", nome, err)
+	}
+}
+
+func TestEntradasItemNFe(t *testing.T) {
+	nota, err := ParseNFe(lerNFeTeste(t, "nfe_distribuidora.xml"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// A amoxicilina tem 12 unidades e só 8 em lote: o restante entra sem lote informado
+	item := nota.Itens[1]
+	item.MedicamentoID = "med-amoxicilina"
+	entradas := entradasItemNFe(item, nota, 7)
+	if !assert.Len(t, entradas, 2) {
+		return
+	}
+
+	assert.Equal(t, "AMX0987", entradas[0].Lote)
+	assert.Equal(t, 8, entradas[0].Quantidade)
+	assert.Equal(t, "2025-09-01", entradas[0].Validade)
+	assert.Equal(t, "", entradas[1].Lote)
+	assert.Equal(t, 4, entradas[1].Quantidade)
+	for _, mov := range entradas {
+		assert.Equal(t, "entrada", mov.Tipo)
+		assert.Equal(t, "med-amoxicilina", mov.MedicamentoID)
+		assert.Equal(t, "12345", mov.NotaFiscal)
+		assert.Equal(t, "11222333000181", mov.CNPJFornecedor)
+		assert.Equal(t, 18.25, mov.CustoUnitario)
+		assert.Equal(t, 7, mov.UsuarioID)
+	}
+}
+
+func TestCasarPorNome(t *testing.T) {
+	candidatos := []candidatoNome{
+		{ID: "1", Nome: normalizarNomeProduto("Dipirona Sódica")},
+		{ID: "2", Nome: normalizarNomeProduto("Dipirona Sódica 500mg")},
+		{ID: "3", Nome: normalizarNomeProduto("Soro Fisiológico 0,9% 500ml")},
+		{ID: "4", Nome: normalizarNomeProduto("Paracetamol 750mg")},
+		{ID: "5", Nome: normalizarNomeProduto("PARACETAMOL  750MG")},
+	}
+
+	// Vence o nome mais longo que é início da descrição
+	id, ok := casarPorNome("DIPIRONA SÓDICA 500MG CX 10 CPR", candidatos)
+	assert.True(t, ok)
+	assert.Equal(t, "2", id)
+
+	// Igualdade ignora acentos, caixa e espaços repetidos
+	id, ok = casarPorNome("soro  fisiologico 0,9% 500ML", candidatos)
+	assert.True(t, ok)
+	assert.Equal(t, "3", id)
+
+	// O nome precisa terminar em fim de palavra
+	id, ok = casarPorNome("DIPIRONA SÓDICA 500MGX", candidatos)
+	assert.True(t, ok)
+	assert.Equal(t, "1", id)
+
+	// Dois medicamentos com o mesmo nome deixam o item sem correspondência
+	_, ok = casarPorNome("PARACETAMOL 750MG CX 20", candidatos)
+	assert.False(t, ok)
+
+	_, ok = casarPorNome("IBUPROFENO 600MG", candidatos)
+	assert.False(t, ok)
+}
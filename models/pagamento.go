@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// Formas de pagamento aceitas nas vendas
+const (
+	FormaDinheiro      = "dinheiro"
+	FormaCartaoDebito  = "cartao_debito"
+	FormaCartaoCredito = "cartao_credito"
+	FormaPix           = "pix"
+	FormaConvenio      = "convenio"
+)
+
+// LimiteDescontoPorRole é o desconto máximo, em percentual, que cada papel pode conceder
+// (por item e sobre o total da venda)
+var LimiteDescontoPorRole = map[string]float64{
+	RoleAdmin:        100,
+	RoleFarmaceutico: 15,
+	RoleAtendente:    5,
+}
+
+var (
+	// ErrPagamentoInvalido indica formas ou valores de pagamento que não fecham com o total da venda
+	ErrPagamentoInvalido = errors.New("pagamento inválido")
+	// ErrDescontoAcimaDoLimite indica um desconto maior que o permitido para o papel do usuário
+	ErrDescontoAcimaDoLimite = errors.New("desconto acima do limite permitido")
+)
+
+// PagamentoVenda é uma das formas de pagamento usadas em uma venda
+type PagamentoVenda struct {
+	ID          int64   `json:"id,omitempty"`
+	Forma       string  `json:"forma"`
+	Valor       float64 `json:"valor"`
+	Autorizacao string  `json:"autorizacao,omitempty"` // NSU do cartão, ID do PIX ou autorização do convênio
+}
+
+// ResumoVenda são os valores calculados e gravados ao registrar uma venda
+type ResumoVenda struct {
+	VendaID       int64   `json:"venda_id"`
+	CaixaID       int64   `json:"caixa_id"`
+	Subtotal      float64 `json:"subtotal"`
+	Desconto      float64 `json:"desconto"`
+	Total         float64 `json:"total"`
+	ValorRecebido float64 `json:"valor_recebido"`
+	Troco         float64 `json:"troco"`
+	// Alertas de interação confirmados na venda
+	Interacoes []AlertaInteracao `json:"interacoes,omitempty"`
+}
+
+// FormaPagamentoValida informa se a forma de pagamento é uma das aceitas.
+func FormaPagamentoValida(forma string) bool {
+	switch forma {
+	case FormaDinheiro, FormaCartaoDebito, FormaCartaoCredito, FormaPix, FormaConvenio:
+		return true
+	}
+	return false
+}
+
+// arredondar arredonda um valor monetário para centavos.
+func arredondar(valor float64) float64 {
+	return math.Round(valor*100) / 100
+}
+
+// validarDesconto confere se o percentual está entre 0 e o limite do papel do usuário.
+func validarDesconto(percentual float64, role, referencia string) error {
+	if percentual < 0 || percentual > 100 {
+		return fmt.Errorf("%w: desconto de %.2f%% em %s", ErrDescontoAcimaDoLimite, percentual, referencia)
+	}
+	if percentual > LimiteDescontoPorRole[role] {
+		return fmt.Errorf("%w: %s pode conceder até %.2f%%, solicitado %.2f%% em %s",
+			ErrDescontoAcimaDoLimite, role, LimiteDescontoPorRole[role], percentual, referencia)
+	}
+	return nil
+}
+
+// descontoCombinado retorna o percentual efetivo no item quando o desconto da venda é aplicado sobre
+// o do item: 5% no item e 5% na venda dão 9,75%. Arredondado para não recusar o limite exato por
+// erro de ponto flutuante.
+func descontoCombinado(item, venda float64) float64 {
+	efetivo := (1 - (1-item/100)*(1-venda/100)) * 100
+	return math.Round(efetivo*10000) / 10000
+}
+
+// ratear distribui um valor proporcionalmente aos valores informados, como o desconto da venda entre
+// os itens ou a devolução entre as formas de pagamento. O último absorve a diferença de arredondamento.
+func ratear(valores []float64, total float64) []float64 {
+	rateio := make([]float64, len(valores))
+	var soma float64
+	for _, v := range valores {
+		soma += v
+	}
+	if soma == 0 || total == 0 {
+		return rateio
+	}
+	var distribuido float64
+	for i, v := range valores {
+		if i == len(valores)-1 {
+			rateio[i] = arredondar(total - distribuido)
+			break
+		}
+		rateio[i] = arredondar(total * v / soma)
+		distribuido += rateio[i]
+	}
+	return rateio
+}
+
+// calcularPagamentos confere se os pagamentos cobrem o total e calcula o troco.
+// Só o dinheiro pode ultrapassar o total; as demais formas não geram troco.
+func calcularPagamentos(pagamentos []PagamentoVenda, total float64) (recebido, troco float64, err error) {
+	if len(pagamentos) == 0 {
+		return 0, 0, fmt.Errorf("%w: informe ao menos uma forma de pagamento", ErrPagamentoInvalido)
+	}
+
+	var dinheiro, outros float64
+	for i := range pagamentos {
+		p := &pagamentos[i]
+		p.Forma = strings.ToLower(strings.TrimSpace(p.Forma))
+		if !FormaPagamentoValida(p.Forma) {
+			return 0, 0, fmt.Errorf("%w: forma '%s' não aceita", ErrPagamentoInvalido, p.Forma)
+		}
+		p.Valor = arredondar(p.Valor)
+		if p.Valor <= 0 {
+			return 0, 0, fmt.Errorf("%w: o valor em %s deve ser maior que zero", ErrPagamentoInvalido, p.Forma)
+		}
+		if p.Forma == FormaDinheiro {
+			dinheiro += p.Valor
+		} else {
+			outros += p.Valor
+		}
+	}
+
+	recebido = arredondar(dinheiro + outros)
+	if outros > total+0.001 {
+		return 0, 0, fmt.Errorf("%w: pagamentos sem ser em dinheiro (R$ %.2f) excedem o total (R$ %.2f)", ErrPagamentoInvalido, outros, total)
+	}
+	if recebido < total-0.001 {
+		return 0, 0, fmt.Errorf("%w: recebido R$ %.2f, total R$ %.2f", ErrPagamentoInvalido, recebido, total)
+	}
+	return recebido, arredondar(recebido - total), nil
+}
+
+// inserirPagamentosVenda grava as formas de pagamento da venda.
+func (b *Banco) inserirPagamentosVenda(tx execer, vendaID int64, pagamentos []PagamentoVenda) error {
+	query := b.queries.GetQuery(qInserirPagamentoVenda)
+	if query == "" {
+		return errors.New("query 'inserir_pagamento_venda' não encontrada")
+	}
+	for i := range pagamentos {
+		p := &pagamentos[i]
+		id, err := b.inserirComID(tx, query, vendaID, p.Forma, p.Valor, p.Autorizacao)
+		if err != nil {
+			return fmt.Errorf("erro ao registrar pagamento em %s: %w", p.Forma, err)
+		}
+		p.ID = id
+	}
+	return nil
+}
+
+// pagamentosVenda lista as formas de pagamento de uma venda.
+func (b *Banco) pagamentosVenda(db execer, vendaID int64) ([]PagamentoVenda, error) {
+	query := b.queries.GetQuery(qSelecionarPagamentosVenda)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_pagamentos_venda' não encontrada")
+	}
+	rows, err := db.Query(query, vendaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pagamentos := []PagamentoVenda{}
+	for rows.Next() {
+		var p PagamentoVenda
+		var autorizacao sql.NullString
+		if err := rows.Scan(&p.ID, &p.Forma, &p.Valor, &autorizacao); err != nil {
+			log.Printf("Erro ao escanear pagamento da venda %d: %v", vendaID, err)
+			return nil, err
+		}
+		p.Autorizacao = autorizacao.String
+		pagamentos = append(pagamentos, p)
+	}
+	return pagamentos, rows.Err()
+}
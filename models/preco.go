@@ -0,0 +1,402 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Origem de uma alteração de preço
+const (
+	OrigemPrecoCadastro    = "cadastro"
+	OrigemPrecoManual      = "manual"
+	OrigemPrecoAgendamento = "agendamento"
+	OrigemPrecoImportacao  = "importacao" // Correção a partir do arquivo de medicamentos na inicialização
+)
+
+// Status de um agendamento de preço
+const (
+	StatusAgendamentoPendente  = "pendente"
+	StatusAgendamentoAplicado  = "aplicado"
+	StatusAgendamentoCancelado = "cancelado"
+	StatusAgendamentoRejeitado = "rejeitado" // O preço passou do PMC ou o medicamento foi excluído antes da vigência
+)
+
+var (
+	// ErrPrecoAcimaPMC indica um preço de venda acima do preço máximo ao consumidor da tabela CMED
+	ErrPrecoAcimaPMC = errors.New("preço acima do PMC da tabela CMED")
+	// ErrAgendamentoPrecoInvalido indica preço, vigência ou medicamento inválidos no agendamento
+	ErrAgendamentoPrecoInvalido = errors.New("agendamento de preço inválido")
+	// ErrAgendamentoPrecoNaoEncontrado indica que o agendamento informado não existe
+	ErrAgendamentoPrecoNaoEncontrado = errors.New("agendamento de preço não encontrado")
+	// ErrStatusAgendamentoPreco indica uma operação que o status do agendamento não permite
+	ErrStatusAgendamentoPreco = errors.New("operação não permitida no status atual do agendamento")
+)
+
+// HistoricoPreco registra cada alteração do preço de venda de um medicamento
+type HistoricoPreco struct {
+	ID            int64     `json:"id"`
+	MedicamentoID string    `json:"medicamento_id"`
+	PrecoAnterior float64   `json:"preco_anterior"`
+	PrecoNovo     float64   `json:"preco_novo"`
+	Origem        string    `json:"origem"`
+	AgendamentoID int64     `json:"agendamento_id,omitempty"`
+	UsuarioID     int       `json:"usuario_id"`
+	AlteradoEm    time.Time `json:"alterado_em"`
+}
+
+// AgendamentoPreco é uma alteração de preço programada para uma data futura
+type AgendamentoPreco struct {
+	ID              int64      `json:"id"`
+	MedicamentoID   string     `json:"medicamento_id"`
+	MedicamentoNome string     `json:"medicamento_nome"`
+	Preco           float64    `json:"preco"`
+	Vigencia        string     `json:"vigencia"` // YYYY-MM-DD; o preço vale a partir do início do dia
+	Status          string     `json:"status"`
+	Motivo          string     `json:"motivo,omitempty"`
+	UsuarioID       int        `json:"usuario_id"`
+	CriadoEm        time.Time  `json:"criado_em"`
+	AplicadoEm      *time.Time `json:"aplicado_em,omitempty"`
+}
+
+// registrarHistoricoPreco grava a alteração de preço, se houver, na transação informada.
+func (b *Banco) registrarHistoricoPreco(tx *sql.Tx, medicamentoID string, anterior, novo float64, origem string, agendamentoID int64, usuarioID int) error {
+	if arredondar(anterior) == arredondar(novo) {
+		return nil
+	}
+	query := b.queries.GetQuery(qInserirHistoricoPreco)
+	if query == "" {
+		return errors.New("query 'inserir_historico_preco' não encontrada")
+	}
+	_, err := tx.Exec(query, medicamentoID, anterior, novo, origem,
+		sql.NullInt64{Int64: agendamentoID, Valid: agendamentoID != 0}, usuarioID, b.Agora())
+	if err != nil {
+		return fmt.Errorf("erro ao registrar histórico de preço: %w", err)
+	}
+	return nil
+}
+
+// validarPrecoPMC recusa um preço acima do PMC do medicamento na tabela CMED importada.
+// Medicamentos sem código ANVISA ou fora da tabela não têm teto.
+func (b *Banco) validarPrecoPMC(db execer, codigoANVISA string, preco float64) error {
+	pmc, ok, err := b.pmcMedicamento(db, codigoANVISA)
+	if err != nil {
+		return err
+	}
+	if ok && arredondar(preco) > pmc {
+		return fmt.Errorf("%w: R$ %.2f ultrapassa o PMC de R$ %.2f", ErrPrecoAcimaPMC, preco, pmc)
+	}
+	return nil
+}
+
+// pmcMedicamento busca o PMC pelo registro ANVISA. Se a tabela tiver mais de uma linha para o
+// registro, vale o menor PMC.
+func (b *Banco) pmcMedicamento(db execer, codigoANVISA string) (float64, bool, error) {
+	registro := somenteDigitos(codigoANVISA)
+	if registro == "" {
+		return 0, false, nil
+	}
+	query := b.queries.GetQuery(qSelecionarPmcPorRegistro)
+	if query == "" {
+		return 0, false, errors.New("query 'selecionar_pmc_por_registro' não encontrada")
+	}
+	var pmc sql.NullFloat64
+	if err := db.QueryRow(query, registro).Scan(&pmc); err != nil {
+		return 0, false, err
+	}
+	return pmc.Float64, pmc.Valid, nil
+}
+
+// GetHistoricoPrecos lista as alterações de preço do medicamento, da mais recente para a mais antiga.
+func (b *Banco) GetHistoricoPrecos(medicamentoID string) ([]HistoricoPreco, error) {
+	if b.GetMedicamento(medicamentoID) == nil {
+		return nil, ErrMedicamentoNaoEncontrado
+	}
+	query := b.queries.GetQuery(qSelecionarHistoricoPrecos)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_historico_precos' não encontrada")
+	}
+
+	rows, err := b.db.Query(query, medicamentoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var historico []HistoricoPreco
+	for rows.Next() {
+		var h HistoricoPreco
+		var agendamentoID sql.NullInt64
+		if err := rows.Scan(&h.ID, &h.MedicamentoID, &h.PrecoAnterior, &h.PrecoNovo, &h.Origem, &agendamentoID, &h.UsuarioID, &h.AlteradoEm); err != nil {
+			return nil, err
+		}
+		h.AgendamentoID = agendamentoID.Int64
+		historico = append(historico, h)
+	}
+	return historico, rows.Err()
+}
+
+// AgendarPreco programa um novo preço de venda a partir de uma data futura. O preço é conferido
+// contra o PMC agora e de novo na vigência, já que a tabela CMED pode mudar nesse intervalo.
+func (b *Banco) AgendarPreco(medicamentoID string, preco float64, vigencia string, usuarioID int) (*AgendamentoPreco, error) {
+	preco = arredondar(preco)
+	if preco <= 0 {
+		return nil, fmt.Errorf("%w: o preço deve ser maior que zero", ErrAgendamentoPrecoInvalido)
+	}
+	data, err := time.ParseInLocation("2006-01-02", vigencia, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: vigência '%s' deve estar no formato YYYY-MM-DD", ErrAgendamentoPrecoInvalido, vigencia)
+	}
+	if !data.After(b.Agora()) {
+		return nil, fmt.Errorf("%w: a vigência deve ser uma data futura; para alterar o preço agora, atualize o medicamento", ErrAgendamentoPrecoInvalido)
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	med := b.buscarMedicamento(tx, medicamentoID)
+	if med == nil {
+		return nil, ErrMedicamentoNaoEncontrado
+	}
+	if err := b.validarPrecoPMC(tx, med.CodigoANVISA, preco); err != nil {
+		return nil, err
+	}
+
+	queryContar := b.queries.GetQuery(qContarAgendamentosPrecoPendentes)
+	queryInserir := b.queries.GetQuery(qInserirAgendamentoPreco)
+	if queryContar == "" || queryInserir == "" {
+		return nil, errors.New("queries de agendamento de preço não encontradas")
+	}
+	var pendentes int
+	if err := tx.QueryRow(queryContar, medicamentoID, vigencia).Scan(&pendentes); err != nil {
+		return nil, err
+	}
+	if pendentes > 0 {
+		return nil, fmt.Errorf("%w: já existe um preço agendado para %s; cancele-o antes de agendar outro", ErrStatusAgendamentoPreco, vigencia)
+	}
+
+	id, err := b.inserirComID(tx, queryInserir, medicamentoID, preco, vigencia, StatusAgendamentoPendente, usuarioID, b.Agora())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao agendar preço: %w", err)
+	}
+	agendamento, err := b.agendamentoPreco(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "agendamento_preco", strconv.FormatInt(id, 10), nil, agendamento); err != nil {
+		return nil, err
+	}
+	return agendamento, tx.Commit()
+}
+
+// CancelarAgendamentoPreco cancela um agendamento que ainda não entrou em vigor.
+func (b *Banco) CancelarAgendamentoPreco(id int64, usuarioID int) (*AgendamentoPreco, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	anterior, err := b.agendamentoPreco(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if anterior.Status != StatusAgendamentoPendente {
+		return nil, fmt.Errorf("%w: o agendamento está '%s'", ErrStatusAgendamentoPreco, anterior.Status)
+	}
+	if err := b.atualizarStatusAgendamentoPreco(tx, id, StatusAgendamentoCancelado, "", nil); err != nil {
+		return nil, err
+	}
+
+	agendamento, err := b.agendamentoPreco(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "agendamento_preco", strconv.FormatInt(id, 10), anterior, agendamento); err != nil {
+		return nil, err
+	}
+	return agendamento, tx.Commit()
+}
+
+// GetAgendamentoPreco retorna um agendamento pelo ID.
+func (b *Banco) GetAgendamentoPreco(id int64) (*AgendamentoPreco, error) {
+	return b.agendamentoPreco(b.db, id)
+}
+
+// ListarAgendamentosPreco lista os agendamentos por vigência, opcionalmente de um medicamento e de um status.
+func (b *Banco) ListarAgendamentosPreco(medicamentoID, status string) ([]AgendamentoPreco, error) {
+	query := b.queries.GetQuery(qSelecionarAgendamentosPreco)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_agendamentos_preco' não encontrada")
+	}
+
+	rows, err := b.db.Query(query, medicamentoID, medicamentoID, status, status)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar agendamentos de preço: %w", err)
+	}
+	defer rows.Close()
+
+	var agendamentos []AgendamentoPreco
+	for rows.Next() {
+		a, err := scanAgendamentoPreco(rows)
+		if err != nil {
+			return nil, err
+		}
+		agendamentos = append(agendamentos, *a)
+	}
+	return agendamentos, rows.Err()
+}
+
+// AplicarPrecosAgendados aplica os agendamentos pendentes cuja vigência já começou, na ordem da vigência.
+// Um preço que ultrapassa o PMC vigente é rejeitado com o motivo, sem alterar o medicamento.
+// Retorna quantos preços foram aplicados.
+func (b *Banco) AplicarPrecosAgendados() (int, error) {
+	query := b.queries.GetQuery(qSelecionarAgendamentosPrecoVencidos)
+	if query == "" {
+		return 0, errors.New("query 'selecionar_agendamentos_preco_vencidos' não encontrada")
+	}
+
+	rows, err := b.db.Query(query, b.Agora().Format("2006-01-02"))
+	if err != nil {
+		return 0, err
+	}
+	var vencidos []AgendamentoPreco
+	for rows.Next() {
+		a, err := scanAgendamentoPreco(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		vencidos = append(vencidos, *a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	aplicados := 0
+	for _, a := range vencidos {
+		aplicado, err := b.aplicarAgendamentoPreco(a)
+		if err != nil {
+			log.Printf("Erro ao aplicar o preço agendado %d: %v", a.ID, err)
+			continue
+		}
+		if aplicado {
+			aplicados++
+		}
+	}
+	return aplicados, nil
+}
+
+// IniciarAgendadorPrecos aplica os preços agendados já vencidos e continua conferindo a cada
+// intervalo, em segundo plano, até o contexto ser cancelado.
+func (b *Banco) IniciarAgendadorPrecos(ctx context.Context, intervalo time.Duration) {
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			aplicados, err := b.AplicarPrecosAgendados()
+			if err != nil {
+				log.Printf("Erro ao aplicar preços agendados: %v", err)
+			} else if aplicados > 0 {
+				log.Printf("%d preço(s) agendado(s) aplicado(s)", aplicados)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (b *Banco) aplicarAgendamentoPreco(a AgendamentoPreco) (bool, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	agora := b.Agora()
+	med := b.buscarMedicamento(tx, a.MedicamentoID)
+	motivo := ""
+	if med == nil {
+		motivo = "medicamento excluído antes da vigência"
+	} else if err := b.validarPrecoPMC(tx, med.CodigoANVISA, a.Preco); errors.Is(err, ErrPrecoAcimaPMC) {
+		motivo = err.Error()
+	} else if err != nil {
+		return false, err
+	}
+
+	if motivo != "" {
+		log.Printf("Preço agendado %d rejeitado: %s", a.ID, motivo)
+		if err := b.atualizarStatusAgendamentoPreco(tx, a.ID, StatusAgendamentoRejeitado, motivo, &agora); err != nil {
+			return false, err
+		}
+		return false, tx.Commit()
+	}
+
+	query := b.queries.GetQuery(qAtualizarPrecoMedicamento)
+	if query == "" {
+		return false, errors.New("query 'atualizar_preco_medicamento' não encontrada")
+	}
+	if _, err := tx.Exec(query, a.Preco, a.MedicamentoID); err != nil {
+		return false, err
+	}
+	if err := b.registrarHistoricoPreco(tx, a.MedicamentoID, med.Preco, a.Preco, OrigemPrecoAgendamento, a.ID, a.UsuarioID); err != nil {
+		return false, err
+	}
+	if err := b.atualizarStatusAgendamentoPreco(tx, a.ID, StatusAgendamentoAplicado, "", &agora); err != nil {
+		return false, err
+	}
+	antes := map[string]interface{}{"preco": med.Preco}
+	depois := map[string]interface{}{"preco": a.Preco, "agendamento_id": a.ID}
+	if err := b.registrarAuditoria(tx, a.UsuarioID, AcaoAtualizar, "medicamento", a.MedicamentoID, antes, depois); err != nil {
+		return false, err
+	}
+	log.Printf("Preço de %s alterado de R$ %.2f para R$ %.2f (agendamento %d)", med.Nome, med.Preco, a.Preco, a.ID)
+	return true, tx.Commit()
+}
+
+func (b *Banco) atualizarStatusAgendamentoPreco(tx *sql.Tx, id int64, status, motivo string, aplicadoEm *time.Time) error {
+	query := b.queries.GetQuery(qAtualizarStatusAgendamentoPreco)
+	if query == "" {
+		return errors.New("query 'atualizar_status_agendamento_preco' não encontrada")
+	}
+	_, err := tx.Exec(query, status, sql.NullString{String: motivo, Valid: motivo != ""}, aplicadoEm, id)
+	return err
+}
+
+func (b *Banco) agendamentoPreco(db execer, id int64) (*AgendamentoPreco, error) {
+	query := b.queries.GetQuery(qSelecionarAgendamentoPrecoPorId)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_agendamento_preco_por_id' não encontrada")
+	}
+	a, err := scanAgendamentoPreco(db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAgendamentoPrecoNaoEncontrado
+	}
+	return a, err
+}
+
+func scanAgendamentoPreco(row scanner) (*AgendamentoPreco, error) {
+	var a AgendamentoPreco
+	var motivo sql.NullString
+	var aplicadoEm sql.NullTime
+	err := row.Scan(&a.ID, &a.MedicamentoID, &a.MedicamentoNome, &a.Preco, &a.Vigencia, &a.Status, &motivo, &a.UsuarioID, &a.CriadoEm, &aplicadoEm)
+	if err != nil {
+		return nil, err
+	}
+	a.Motivo = motivo.String
+	if aplicadoEm.Valid {
+		a.AplicadoEm = &aplicadoEm.Time
+	}
+	return &a, nil
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAplicarPrecosAgendados(t *testing.T) {
+	t.Parallel()
+	agora := time.Now()
+	b := abrirBancoTesteCom(t, ConfigBanco{Driver: DriverSQLite, Caminho: ":memory:", Relogio: func() time.Time { return agora }})
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	for _, med := range []*Medicamento{
+		{ID: "1", Nome: "Dipirona 500mg", CodigoANVISA: "1.0583.0014.001-7", Quantidade: 10, Preco: 4},
+		{ID: "2", Nome: "Losartana 50mg", Quantidade: 10, Preco: 25},
+	} {
+		if !assert.NoError(t, b.AddMedicamento(med, 0)) {
+			return
+		}
+	}
+
+	amanha := agora.AddDate(0, 0, 1).Format("2006-01-02")
+	// Sem a tabela CMED não há teto no agendamento; ela chega antes da vigência com PMC de R$ 4,33
+	acimaPMC, err := b.AgendarPreco("1", 5, amanha, 7)
+	if !assert.NoError(t, err) {
+		return
+	}
+	reajuste, err := b.AgendarPreco("2", 27.5, amanha, 7)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if _, err := b.ImportarTabelaCMED(lerNFeTeste(t, "cmed_exemplo.csv"), "18", 1); !assert.NoError(t, err) {
+		return
+	}
+
+	aplicados, err := b.AplicarPrecosAgendados()
+	if assert.NoError(t, err) {
+		assert.Zero(t, aplicados, "a vigência ainda não começou")
+	}
+
+	agora = agora.AddDate(0, 0, 2)
+	aplicados, err = b.AplicarPrecosAgendados()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, aplicados)
+	}
+
+	assert.Equal(t, 27.5, b.GetMedicamento("2").Preco)
+	a, err := b.GetAgendamentoPreco(reajuste.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatusAgendamentoAplicado, a.Status)
+		if assert.NotNil(t, a.AplicadoEm) {
+			assert.WithinDuration(t, agora, *a.AplicadoEm, time.Second, "aplicado na hora do relógio do banco")
+		}
+	}
+	historico, err := b.GetHistoricoPrecos("2")
+	if assert.NoError(t, err) && assert.NotEmpty(t, historico) {
+		assert.Equal(t, OrigemPrecoAgendamento, historico[0].Origem)
+		assert.Equal(t, reajuste.ID, historico[0].AgendamentoID)
+		assert.Equal(t, 25.0, historico[0].PrecoAnterior)
+		assert.Equal(t, 7, historico[0].UsuarioID)
+	}
+
+	// O preço acima do PMC é rejeitado na vigência e o preço atual fica como estava
+	assert.Equal(t, 4.0, b.GetMedicamento("1").Preco)
+	a, err = b.GetAgendamentoPreco(acimaPMC.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, StatusAgendamentoRejeitado, a.Status)
+		assert.Contains(t, a.Motivo, "PMC")
+	}
+
+	// Os agendamentos já resolvidos não são aplicados de novo
+	aplicados, err = b.AplicarPrecosAgendados()
+	if assert.NoError(t, err) {
+		assert.Zero(t, aplicados)
+	}
+
+	ids := func(medicamentoID, status string) []int64 {
+		agendamentos, err := b.ListarAgendamentosPreco(medicamentoID, status)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		var ids []int64
+		for _, a := range agendamentos {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int64{acimaPMC.ID, reajuste.ID}, ids("", ""))
+	assert.Equal(t, []int64{acimaPMC.ID}, ids("1", ""))
+	assert.Equal(t, []int64{reajuste.ID}, ids("", StatusAgendamentoAplicado))
+	assert.Empty(t, ids("1", StatusAgendamentoAplicado))
+}
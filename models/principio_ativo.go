@@ -0,0 +1,210 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PrincipioAtivo é uma substância numa concentração e forma farmacêutica, como "Losartana potássica,
+// 50 mg, comprimido". Medicamentos com os mesmos princípios ativos são equivalentes.
+type PrincipioAtivo struct {
+	ID           string `json:"id"`
+	Nome         string `json:"nome"`
+	Concentracao string `json:"concentracao"`
+	Forma        string `json:"forma"`
+}
+
+// Categorias regulatórias que permitem a substituição na dispensação (Lei 9.787/99): o genérico e
+// o similar intercambiável substituem o medicamento de referência, e vice-versa.
+const (
+	CategoriaReferencia = "referencia"
+	CategoriaGenerico   = "generico"
+	CategoriaSimilar    = "similar" // Similar intercambiável
+)
+
+// ErrSemPrincipioAtivo indica um medicamento sem composição cadastrada, que não tem equivalentes
+var ErrSemPrincipioAtivo = errors.New("medicamento sem princípio ativo cadastrado")
+
+// AlternativasMedicamento são os medicamentos intercambiáveis em estoque, do mais barato ao mais caro
+type AlternativasMedicamento struct {
+	Medicamento  *Medicamento  `json:"medicamento"`
+	Alternativas []Medicamento `json:"alternativas"`
+}
+
+// normalizarCategoriaRegulatoria aceita a categoria com acentos e maiúsculas, como "Referência".
+func normalizarCategoriaRegulatoria(med *Medicamento) error {
+	categoria := normalizarBusca(med.CategoriaRegulatoria)
+	switch categoria {
+	case "", CategoriaReferencia, CategoriaGenerico, CategoriaSimilar:
+		med.CategoriaRegulatoria = categoria
+		return nil
+	}
+	return fmt.Errorf("categoria regulatória '%s' inválida: use referencia, generico ou similar", med.CategoriaRegulatoria)
+}
+
+// chavePrincipioAtivo identifica o princípio sem depender de acentos, maiúsculas e espaços:
+// "Losartana Potássica", "50 mg" e "losartana potassica", "50mg" são o mesmo princípio.
+// Na concentração a vírgula decimal vira ponto, para que "2,5 mg" não se confunda com "25 mg".
+func chavePrincipioAtivo(p PrincipioAtivo) string {
+	concentracao := strings.ReplaceAll(strings.Join(strings.Fields(p.Concentracao), ""), ",", ".")
+	return normalizarBusca(p.Nome) + "|" + strings.ToLower(concentracao) + "|" + normalizarBusca(p.Forma)
+}
+
+// PrincipiosDaSubstancia separa os princípios ativos do campo de substância da ANVISA, como
+// "LOSARTANA POTÁSSICA + HIDROCLOROTIAZIDA", sem concentração e forma. Sem substância, retorna nil.
+func PrincipiosDaSubstancia(substancia string) []PrincipioAtivo {
+	var principios []PrincipioAtivo
+	for _, nome := range strings.FieldsFunc(substancia, func(r rune) bool { return r == '+' || r == ';' }) {
+		if nome = strings.TrimSpace(nome); nome != "" {
+			principios = append(principios, PrincipioAtivo{Nome: nome})
+		}
+	}
+	return principios
+}
+
+// ListarPrincipiosAtivos lista os princípios ativos cadastrados que contêm o termo, em ordem de nome
+func (b *Banco) ListarPrincipiosAtivos(termo string) ([]PrincipioAtivo, error) {
+	query := b.queries.GetQuery(qSelecionarPrincipiosAtivos)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_principios_ativos' não encontrada")
+	}
+	rows, err := b.db.Query(query, "%"+normalizarBusca(termo)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	principios := []PrincipioAtivo{}
+	for rows.Next() {
+		var p PrincipioAtivo
+		if err := rows.Scan(&p.ID, &p.Nome, &p.Concentracao, &p.Forma); err != nil {
+			return nil, err
+		}
+		principios = append(principios, p)
+	}
+	return principios, rows.Err()
+}
+
+// CriarPrincipioAtivo cadastra o princípio ativo ou, se ele já existe com outra grafia, retorna
+// o cadastrado. Informa se o princípio foi criado; só o cadastro novo entra na trilha de auditoria.
+func (b *Banco) CriarPrincipioAtivo(p *PrincipioAtivo, usuarioID int) (bool, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	cadastrado, criado, err := b.obterPrincipioAtivo(tx, *p)
+	if err != nil {
+		return false, err
+	}
+	if criado {
+		if err := b.registrarAuditoria(tx, usuarioID, AcaoCriar, "principio_ativo", cadastrado.ID, nil, cadastrado); err != nil {
+			return false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	*p = *cadastrado
+	return criado, nil
+}
+
+// obterPrincipioAtivo busca o princípio pelo ID ou, sem ID, pelo nome, concentração e forma,
+// cadastrando-o se ele ainda não existe.
+func (b *Banco) obterPrincipioAtivo(db execer, p PrincipioAtivo) (*PrincipioAtivo, bool, error) {
+	if p.ID != "" {
+		query := b.queries.GetQuery(qSelecionarPrincipioAtivoPorId)
+		if query == "" {
+			return nil, false, errors.New("query 'selecionar_principio_ativo_por_id' não encontrada")
+		}
+		var cadastrado PrincipioAtivo
+		err := db.QueryRow(query, p.ID).Scan(&cadastrado.ID, &cadastrado.Nome, &cadastrado.Concentracao, &cadastrado.Forma)
+		if err == sql.ErrNoRows {
+			return nil, false, fmt.Errorf("princípio ativo '%s' não encontrado", p.ID)
+		}
+		return &cadastrado, false, err
+	}
+
+	p.Nome = strings.TrimSpace(p.Nome)
+	p.Concentracao = strings.TrimSpace(p.Concentracao)
+	p.Forma = strings.TrimSpace(p.Forma)
+	if normalizarBusca(p.Nome) == "" {
+		return nil, false, errors.New("o nome do princípio ativo é obrigatório")
+	}
+	chave := chavePrincipioAtivo(p)
+
+	query := b.queries.GetQuery(qSelecionarPrincipioAtivoPorChave)
+	if query == "" {
+		return nil, false, errors.New("query 'selecionar_principio_ativo_por_chave' não encontrada")
+	}
+	var cadastrado PrincipioAtivo
+	err := db.QueryRow(query, chave).Scan(&cadastrado.ID, &cadastrado.Nome, &cadastrado.Concentracao, &cadastrado.Forma)
+	if err == nil {
+		return &cadastrado, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	query = b.queries.GetQuery(qInserirPrincipioAtivo)
+	if query == "" {
+		return nil, false, errors.New("query 'inserir_principio_ativo' não encontrada")
+	}
+	p.ID = uuid.New().String()
+	if _, err := db.Exec(query, p.ID, p.Nome, p.Concentracao, p.Forma, chave); err != nil {
+		return nil, false, fmt.Errorf("erro ao cadastrar o princípio ativo '%s': %w", p.Nome, err)
+	}
+	return &p, true, nil
+}
+
+// resolverPrincipiosAtivos troca a composição informada no medicamento pelos princípios cadastrados,
+// cadastrando os novos, para que o repositório grave só a ligação. Nil continua nil.
+func (b *Banco) resolverPrincipiosAtivos(tx execer, med *Medicamento) error {
+	if med.PrincipiosAtivos == nil {
+		return nil
+	}
+	principios := []PrincipioAtivo{}
+	vistos := map[string]bool{}
+	for _, p := range med.PrincipiosAtivos {
+		cadastrado, _, err := b.obterPrincipioAtivo(tx, p)
+		if err != nil {
+			return err
+		}
+		if !vistos[cadastrado.ID] {
+			vistos[cadastrado.ID] = true
+			principios = append(principios, *cadastrado)
+		}
+	}
+	med.PrincipiosAtivos = principios
+	return nil
+}
+
+// BuscarAlternativas lista os medicamentos em estoque que podem substituir o informado: os de
+// referência, genéricos e similares intercambiáveis com exatamente os mesmos princípios ativos,
+// na mesma concentração e forma, do mais barato ao mais caro.
+func (b *Banco) BuscarAlternativas(id string) (*AlternativasMedicamento, error) {
+	med, err := b.repos.Medicamentos.BuscarPorID(b.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if med == nil {
+		return nil, ErrMedicamentoNaoEncontrado
+	}
+	if len(med.PrincipiosAtivos) == 0 {
+		return nil, ErrSemPrincipioAtivo
+	}
+
+	alternativas, err := b.repos.Medicamentos.ListarAlternativas(b.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if alternativas == nil {
+		alternativas = []Medicamento{}
+	}
+	return &AlternativasMedicamento{Medicamento: med, Alternativas: alternativas}, nil
+}
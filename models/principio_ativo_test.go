@@ -0,0 +1,126 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChavePrincipioAtivo(t *testing.T) {
+	losartana := chavePrincipioAtivo(PrincipioAtivo{Nome: "Losartana Potássica", Concentracao: "50 mg", Forma: "Comprimido"})
+	assert.Equal(t, "losartana potassica|50mg|comprimido", losartana)
+	assert.Equal(t, losartana, chavePrincipioAtivo(PrincipioAtivo{Nome: " losartana  potassica", Concentracao: "50MG", Forma: "comprimido"}))
+	assert.NotEqual(t,
+		chavePrincipioAtivo(PrincipioAtivo{Nome: "Anlodipino", Concentracao: "2,5 mg"}),
+		chavePrincipioAtivo(PrincipioAtivo{Nome: "Anlodipino", Concentracao: "25 mg"}))
+
+	assert.Equal(t, []PrincipioAtivo{{Nome: "LOSARTANA POTÁSSICA"}, {Nome: "HIDROCLOROTIAZIDA"}},
+		PrincipiosDaSubstancia("LOSARTANA POTÁSSICA + HIDROCLOROTIAZIDA"))
+	assert.Nil(t, PrincipiosDaSubstancia(" "))
+}
+
+func TestCriarPrincipioAtivo(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+
+	losartana := PrincipioAtivo{Nome: "Losartana Potássica", Concentracao: "50 mg", Forma: "Comprimido"}
+	criado, err := b.CriarPrincipioAtivo(&losartana, 3)
+	if !assert.NoError(t, err) || !assert.True(t, criado) {
+		return
+	}
+	// A mesma composição com outra grafia devolve o cadastrado, sem novo registro na auditoria
+	outraGrafia := PrincipioAtivo{Nome: "LOSARTANA POTASSICA", Concentracao: "50MG", Forma: "comprimido"}
+	criado, err = b.CriarPrincipioAtivo(&outraGrafia, 4)
+	if assert.NoError(t, err) {
+		assert.False(t, criado)
+		assert.Equal(t, losartana, outraGrafia)
+	}
+	_, err = b.CriarPrincipioAtivo(&PrincipioAtivo{Concentracao: "50 mg"}, 3)
+	assert.Error(t, err)
+
+	registros, err := b.ListarAuditoria(FiltroAuditoria{Entidade: "principio_ativo"})
+	if assert.NoError(t, err) && assert.Len(t, registros, 1) {
+		assert.Equal(t, AcaoCriar, registros[0].Acao)
+		assert.Equal(t, losartana.ID, registros[0].EntidadeID)
+		assert.Equal(t, 3, registros[0].UsuarioID)
+	}
+}
+
+func TestAlternativasMedicamento(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+
+	losartana := []PrincipioAtivo{{Nome: "Losartana Potássica", Concentracao: "50 mg", Forma: "comprimido"}}
+	referencia := &Medicamento{ID: "1", Nome: "Cozaar 50mg", Quantidade: 10, Preco: 60, CategoriaRegulatoria: "Referência", PrincipiosAtivos: losartana}
+	if !assert.NoError(t, b.AddMedicamento(referencia, 0)) {
+		return
+	}
+	assert.Equal(t, CategoriaReferencia, referencia.CategoriaRegulatoria)
+	if assert.Len(t, referencia.PrincipiosAtivos, 1) {
+		assert.NotEmpty(t, referencia.PrincipiosAtivos[0].ID)
+	}
+
+	for _, med := range []*Medicamento{
+		// A mesma composição com outra grafia é o mesmo princípio
+		{ID: "2", Nome: "Losartana EMS", Quantidade: 5, Preco: 18, CategoriaRegulatoria: "generico",
+			PrincipiosAtivos: []PrincipioAtivo{{Nome: "losartana potassica", Concentracao: "50mg", Forma: "Comprimido"}}},
+		{ID: "3", Nome: "Losartana Similar", Quantidade: 3, Preco: 25, CategoriaRegulatoria: "similar",
+			PrincipiosAtivos: []PrincipioAtivo{{ID: referencia.PrincipiosAtivos[0].ID}}},
+		// Sem estoque, sem categoria intercambiável, em outra concentração ou com outro princípio junto
+		{ID: "4", Nome: "Losartana Genérico Sem Estoque", Quantidade: 0, Preco: 15, CategoriaRegulatoria: "generico", PrincipiosAtivos: losartana},
+		{ID: "5", Nome: "Losartana Manipulada", Quantidade: 8, Preco: 10, PrincipiosAtivos: losartana},
+		{ID: "6", Nome: "Losartana 100mg", Quantidade: 8, Preco: 30, CategoriaRegulatoria: "generico",
+			PrincipiosAtivos: []PrincipioAtivo{{Nome: "Losartana Potássica", Concentracao: "100 mg", Forma: "comprimido"}}},
+		{ID: "7", Nome: "Losartana + HCTZ", Quantidade: 8, Preco: 20, CategoriaRegulatoria: "generico",
+			PrincipiosAtivos: append([]PrincipioAtivo{{Nome: "Hidroclorotiazida", Concentracao: "12,5 mg", Forma: "comprimido"}}, losartana...)},
+		{ID: "8", Nome: "Dipirona 500mg", Quantidade: 8, Preco: 5},
+	} {
+		if !assert.NoError(t, b.AddMedicamento(med, 0), med.Nome) {
+			return
+		}
+	}
+
+	resultado, err := b.BuscarAlternativas("1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1", resultado.Medicamento.ID)
+		var ids []string
+		for _, alt := range resultado.Alternativas {
+			ids = append(ids, alt.ID)
+		}
+		assert.Equal(t, []string{"2", "3"}, ids, "do mais barato ao mais caro")
+		if len(resultado.Alternativas) > 0 {
+			assert.Equal(t, "Losartana Potássica", resultado.Alternativas[0].PrincipiosAtivos[0].Nome)
+		}
+	}
+	principios, err := b.ListarPrincipiosAtivos("losartana")
+	if assert.NoError(t, err) {
+		assert.Len(t, principios, 2)
+	}
+
+	_, err = b.BuscarAlternativas("8")
+	assert.ErrorIs(t, err, ErrSemPrincipioAtivo)
+	_, err = b.BuscarAlternativas("99")
+	assert.ErrorIs(t, err, ErrMedicamentoNaoEncontrado)
+	assert.Error(t, b.AddMedicamento(&Medicamento{ID: "9", Nome: "X", CategoriaRegulatoria: "manipulado"}, 0))
+	assert.Error(t, b.AddMedicamento(&Medicamento{ID: "9", Nome: "X", PrincipiosAtivos: []PrincipioAtivo{{ID: "inexistente"}}}, 0))
+
+	// Na atualização, sem a lista a composição fica; com a lista vazia, é apagada
+	referencia.PrincipiosAtivos = nil
+	if !assert.NoError(t, b.UpdateMedicamento(referencia, 0)) {
+		return
+	}
+	assert.Len(t, b.GetMedicamento("1").PrincipiosAtivos, 1)
+	referencia.PrincipiosAtivos = []PrincipioAtivo{}
+	if !assert.NoError(t, b.UpdateMedicamento(referencia, 0)) {
+		return
+	}
+	assert.Empty(t, b.GetMedicamento("1").PrincipiosAtivos)
+	_, err = b.BuscarAlternativas("1")
+	assert.ErrorIs(t, err, ErrSemPrincipioAtivo)
+}
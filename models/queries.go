@@ -0,0 +1,149 @@
+package models
+
+import "medicontrol/sqlutils"
+
+// queriesUsadas registra as queries da pasta 'sql' usadas pelo pacote. O Banco confere na abertura
+// que todas existem no dialeto em uso e, depois das migrações, prepara cada uma no banco.
+var queriesUsadas sqlutils.Registro
+
+// Nomes das queries; o código do pacote usa sempre estas variáveis, nunca o nome literal.
+var (
+	qAtualizarDevolucaoVendaItem           = queriesUsadas.Query("atualizar_devolucao_venda_item")
+	qAtualizarDevolucaoVendaItemLote       = queriesUsadas.Query("atualizar_devolucao_venda_item_lote")
+	qAtualizarEstoqueCustoMedicamento      = queriesUsadas.Query("atualizar_estoque_custo_medicamento")
+	qAtualizarFornecedor                   = queriesUsadas.Query("atualizar_fornecedor")
+	qAtualizarMedicamento                  = queriesUsadas.Query("atualizar_medicamento")
+	qAtualizarPrecoMedicamento             = queriesUsadas.Query("atualizar_preco_medicamento")
+	qAtualizarQuantidadeLote               = queriesUsadas.Query("atualizar_quantidade_lote")
+	qAtualizarRecebimentoItemPedidoCompra  = queriesUsadas.Query("atualizar_recebimento_item_pedido_compra")
+	qAtualizarStatusAgendamentoPreco       = queriesUsadas.Query("atualizar_status_agendamento_preco")
+	qAtualizarStatusPedidoCompra           = queriesUsadas.Query("atualizar_status_pedido_compra")
+	qAtualizarStatusVenda                  = queriesUsadas.Query("atualizar_status_venda")
+	qAtualizarTotaisVenda                  = queriesUsadas.Query("atualizar_totais_venda")
+	qAtualizarUsuario                      = queriesUsadas.Query("atualizar_usuario")
+	qAtualizarValidadeMedicamento          = queriesUsadas.Query("atualizar_validade_medicamento")
+	qAtualizarValorItemVenda               = queriesUsadas.Query("atualizar_valor_item_venda")
+	qAtualizarXmlNfeImportacao             = queriesUsadas.Query("atualizar_xml_nfe_importacao")
+	qConfirmarNfeImportacao                = queriesUsadas.Query("confirmar_nfe_importacao")
+	qContarAgendamentosPrecoPendentes      = queriesUsadas.Query("contar_agendamentos_preco_pendentes")
+	qContarInteracoes                      = queriesUsadas.Query("contar_interacoes")
+	qContarMedicamentosCategoria           = queriesUsadas.Query("contar_medicamentos_categoria")
+	qContarTabela                          = queriesUsadas.Query("contar_tabela")
+	qContarTotalVendas                     = queriesUsadas.Query("contar_total_vendas")
+	qContarUsuarios                        = queriesUsadas.Query("contar_usuarios")
+	qCriarTabelaSchemaMigrations           = queriesUsadas.Query("criar_tabela_schema_migrations")
+	qDeletarMedicamento                    = queriesUsadas.Query("deletar_medicamento")
+	qEncerrarInventario                    = queriesUsadas.Query("encerrar_inventario")
+	qExcluirCmedPrecos                     = queriesUsadas.Query("excluir_cmed_precos")
+	qExcluirCodigosBarrasMedicamento       = queriesUsadas.Query("excluir_codigos_barras_medicamento")
+	qExcluirInteracoesMedicamentosas       = queriesUsadas.Query("excluir_interacoes_medicamentosas")
+	qExcluirItensPedidoCompra              = queriesUsadas.Query("excluir_itens_pedido_compra")
+	qExcluirPrincipiosMedicamento          = queriesUsadas.Query("excluir_principios_medicamento")
+	qExcluirSchemaMigration                = queriesUsadas.Query("excluir_schema_migration")
+	qFecharCaixa                           = queriesUsadas.Query("fechar_caixa")
+	qInicializarCustoMedio                 = queriesUsadas.Query("inicializar_custo_medio")
+	qInserirAgendamentoPreco               = queriesUsadas.Query("inserir_agendamento_preco")
+	qInserirAuditoria                      = queriesUsadas.Query("inserir_auditoria")
+	qInserirCaixa                          = queriesUsadas.Query("inserir_caixa")
+	qInserirCaixaFechamento                = queriesUsadas.Query("inserir_caixa_fechamento")
+	qInserirCaixaMovimento                 = queriesUsadas.Query("inserir_caixa_movimento")
+	qInserirCategoria                      = queriesUsadas.Query("inserir_categoria")
+	qInserirCmedPreco                      = queriesUsadas.Query("inserir_cmed_preco")
+	qInserirCodigoBarras                   = queriesUsadas.Query("inserir_codigo_barras")
+	qInserirFornecedor                     = queriesUsadas.Query("inserir_fornecedor")
+	qInserirHistoricoPreco                 = queriesUsadas.Query("inserir_historico_preco")
+	qInserirInteracaoMedicamentosa         = queriesUsadas.Query("inserir_interacao_medicamentosa")
+	qInserirInventario                     = queriesUsadas.Query("inserir_inventario")
+	qInserirInventarioAjuste               = queriesUsadas.Query("inserir_inventario_ajuste")
+	qInserirItemPedidoCompra               = queriesUsadas.Query("inserir_item_pedido_compra")
+	qInserirLote                           = queriesUsadas.Query("inserir_lote")
+	qInserirMedicamento                    = queriesUsadas.Query("inserir_medicamento")
+	qInserirMedicamentoPrincipio           = queriesUsadas.Query("inserir_medicamento_principio")
+	qInserirMovimentacao                   = queriesUsadas.Query("inserir_movimentacao")
+	qInserirMovimentacaoLote               = queriesUsadas.Query("inserir_movimentacao_lote")
+	qInserirNfeImportacao                  = queriesUsadas.Query("inserir_nfe_importacao")
+	qInserirPagamentoVenda                 = queriesUsadas.Query("inserir_pagamento_venda")
+	qInserirPedidoCompra                   = queriesUsadas.Query("inserir_pedido_compra")
+	qInserirPrincipioAtivo                 = queriesUsadas.Query("inserir_principio_ativo")
+	qInserirRecebimentoPedidoCompra        = queriesUsadas.Query("inserir_recebimento_pedido_compra")
+	qInserirReceita                        = queriesUsadas.Query("inserir_receita")
+	qInserirSchemaMigration                = queriesUsadas.Query("inserir_schema_migration")
+	qInserirUsuario                        = queriesUsadas.Query("inserir_usuario")
+	qInserirVenda                          = queriesUsadas.Query("InserirVenda")
+	qInserirVendaInteracao                 = queriesUsadas.Query("inserir_venda_interacao")
+	qInserirVendaItem                      = queriesUsadas.Query("InserirVendaItem")
+	qInserirVendaItemLote                  = queriesUsadas.Query("inserir_venda_item_lote")
+	qListarVendas                          = queriesUsadas.Query("ListarVendas")
+	qRegistrarFalhaLoginUsuario            = queriesUsadas.Query("registrar_falha_login_usuario")
+	qRegistrarLoginUsuario                 = queriesUsadas.Query("registrar_login_usuario")
+	qResumirVendasCaixa                    = queriesUsadas.Query("resumir_vendas_caixa")
+	qSalvarContagemInventario              = queriesUsadas.Query("salvar_contagem_inventario")
+	qSalvarParametrosReposicao             = queriesUsadas.Query("salvar_parametros_reposicao")
+	qSalvarProdutoFornecedor               = queriesUsadas.Query("salvar_produto_fornecedor")
+	qSelecionarAgendamentoPrecoPorId       = queriesUsadas.Query("selecionar_agendamento_preco_por_id")
+	qSelecionarAgendamentosPreco           = queriesUsadas.Query("selecionar_agendamentos_preco")
+	qSelecionarAgendamentosPrecoVencidos   = queriesUsadas.Query("selecionar_agendamentos_preco_vencidos")
+	qSelecionarAlternativasMedicamento     = queriesUsadas.Query("selecionar_alternativas_medicamento")
+	qSelecionarCaixaAberto                 = queriesUsadas.Query("selecionar_caixa_aberto")
+	qSelecionarCaixaFechamentos            = queriesUsadas.Query("selecionar_caixa_fechamentos")
+	qSelecionarCaixaPorId                  = queriesUsadas.Query("selecionar_caixa_por_id")
+	qSelecionarCaixaVenda                  = queriesUsadas.Query("selecionar_caixa_venda")
+	qSelecionarCategoriaPorNome            = queriesUsadas.Query("selecionar_categoria_por_nome")
+	qSelecionarContagensInventario         = queriesUsadas.Query("selecionar_contagens_inventario")
+	qSelecionarDadosReposicao              = queriesUsadas.Query("selecionar_dados_reposicao")
+	qSelecionarEntradasControlados         = queriesUsadas.Query("selecionar_entradas_controlados")
+	qSelecionarFornecedorPorCnpj           = queriesUsadas.Query("selecionar_fornecedor_por_cnpj")
+	qSelecionarFornecedorPorId             = queriesUsadas.Query("selecionar_fornecedor_por_id")
+	qSelecionarHistoricoPrecos             = queriesUsadas.Query("selecionar_historico_precos")
+	qSelecionarInteracoesMedicamentosas    = queriesUsadas.Query("selecionar_interacoes_medicamentosas")
+	qSelecionarInteracoesVenda             = queriesUsadas.Query("selecionar_interacoes_venda")
+	qSelecionarInventarioAjustes           = queriesUsadas.Query("selecionar_inventario_ajustes")
+	qSelecionarInventarioControlados       = queriesUsadas.Query("selecionar_inventario_controlados")
+	qSelecionarInventarioPorId             = queriesUsadas.Query("selecionar_inventario_por_id")
+	qSelecionarInventariosAbertos          = queriesUsadas.Query("selecionar_inventarios_abertos")
+	qSelecionarItensPedidoCompra           = queriesUsadas.Query("selecionar_itens_pedido_compra")
+	qSelecionarItensVenda                  = queriesUsadas.Query("selecionar_itens_venda")
+	qSelecionarItensVendaDetalhe           = queriesUsadas.Query("selecionar_itens_venda_detalhe")
+	qSelecionarLotePorNumero               = queriesUsadas.Query("selecionar_lote_por_numero")
+	qSelecionarLotesComSaldo               = queriesUsadas.Query("selecionar_lotes_com_saldo")
+	qSelecionarLotesInventario             = queriesUsadas.Query("selecionar_lotes_inventario")
+	qSelecionarLotesPorMedicamento         = queriesUsadas.Query("selecionar_lotes_por_medicamento")
+	qSelecionarLotesVendaItem              = queriesUsadas.Query("selecionar_lotes_venda_item")
+	qSelecionarMedicamentoPorCodigoAnvisa  = queriesUsadas.Query("selecionar_medicamento_por_codigo_anvisa")
+	qSelecionarMedicamentoPorCodigoBarras  = queriesUsadas.Query("selecionar_medicamento_por_codigo_barras")
+	qSelecionarMedicamentoPorId            = queriesUsadas.Query("selecionar_medicamento_por_id")
+	qSelecionarMedicamentosBaixoEstoque    = queriesUsadas.Query("selecionar_medicamentos_baixo_estoque")
+	qSelecionarMedicamentosControlados     = queriesUsadas.Query("selecionar_medicamentos_controlados")
+	qSelecionarMedicamentosInventario      = queriesUsadas.Query("selecionar_medicamentos_inventario")
+	qSelecionarMedicamentosSemLote         = queriesUsadas.Query("selecionar_medicamentos_sem_lote")
+	qSelecionarMovimentacoesPorMedicamento = queriesUsadas.Query("selecionar_movimentacoes_por_medicamento")
+	qSelecionarMovimentosCaixa             = queriesUsadas.Query("selecionar_movimentos_caixa")
+	qSelecionarNfeImportacaoPorChave       = queriesUsadas.Query("selecionar_nfe_importacao_por_chave")
+	qSelecionarNfeImportacaoPorId          = queriesUsadas.Query("selecionar_nfe_importacao_por_id")
+	qSelecionarNomesMedicamentos           = queriesUsadas.Query("selecionar_nomes_medicamentos")
+	qSelecionarPagamentosVenda             = queriesUsadas.Query("selecionar_pagamentos_venda")
+	qSelecionarParametrosReposicao         = queriesUsadas.Query("selecionar_parametros_reposicao")
+	qSelecionarPedidoCompraPorId           = queriesUsadas.Query("selecionar_pedido_compra_por_id")
+	qSelecionarPerdasControlados           = queriesUsadas.Query("selecionar_perdas_controlados")
+	qSelecionarPmcPorRegistro              = queriesUsadas.Query("selecionar_pmc_por_registro")
+	qSelecionarPrincipioAtivoPorChave      = queriesUsadas.Query("selecionar_principio_ativo_por_chave")
+	qSelecionarPrincipioAtivoPorId         = queriesUsadas.Query("selecionar_principio_ativo_por_id")
+	qSelecionarPrincipiosAtivos            = queriesUsadas.Query("selecionar_principios_ativos")
+	qSelecionarProdutoFornecedorPorCodigo  = queriesUsadas.Query("selecionar_produto_fornecedor_por_codigo")
+	qSelecionarProdutoFornecedorPorEan     = queriesUsadas.Query("selecionar_produto_fornecedor_por_ean")
+	qSelecionarRecebimentosPedidoCompra    = queriesUsadas.Query("selecionar_recebimentos_pedido_compra")
+	qSelecionarSchemaMigrations            = queriesUsadas.Query("selecionar_schema_migrations")
+	qSelecionarStatusVenda                 = queriesUsadas.Query("selecionar_status_venda")
+	qSelecionarTodasCategorias             = queriesUsadas.Query("selecionar_todas_categorias")
+	qSelecionarTodasMovimentacoes          = queriesUsadas.Query("selecionar_todas_movimentacoes")
+	qSelecionarTodosFornecedores           = queriesUsadas.Query("selecionar_todos_fornecedores")
+	qSelecionarTodosMedicamentos           = queriesUsadas.Query("selecionar_todos_medicamentos")
+	qSelecionarTodosUsuarios               = queriesUsadas.Query("selecionar_todos_usuarios")
+	qSelecionarUsuarioPorId                = queriesUsadas.Query("selecionar_usuario_por_id")
+	qSelecionarUsuarioPorUsername          = queriesUsadas.Query("selecionar_usuario_por_username")
+	qSelecionarVendaPorId                  = queriesUsadas.Query("selecionar_venda_por_id")
+	qSelecionarVendasControlado            = queriesUsadas.Query("selecionar_vendas_controlado")
+	qSelecionarVendasControladosSngpc      = queriesUsadas.Query("selecionar_vendas_controlados_sngpc")
+	qSelecionarViolacoesPmc                = queriesUsadas.Query("selecionar_violacoes_pmc")
+	qSomarPagamentosCaixa                  = queriesUsadas.Query("somar_pagamentos_caixa")
+)
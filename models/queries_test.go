@@ -0,0 +1,52 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"medicontrol/sqlutils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueriesUsadasExistemEmTodosOsDialetos(t *testing.T) {
+	queries, err := sqlutils.LoadEmbeddedSQL()
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, dialeto := range []string{sqlutils.DialetoSQLite, sqlutils.DialetoPostgres} {
+		q, err := queries.UsarDialeto(dialeto)
+		if assert.NoError(t, err) {
+			assert.NoError(t, q.Validar(queriesUsadas.Nomes()), dialeto)
+		}
+	}
+	assert.ErrorContains(t, queries.Validar([]string{"query_inexistente"}), "query_inexistente")
+}
+
+func TestPrepararQueriesDepoisDasMigracoes(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	// Sem o esquema, as queries citam tabelas que não existem
+	assert.Error(t, b.prepararQueries())
+	assert.Nil(t, b.preparadas)
+
+	if _, err := b.Migrar(0); !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, b.prepararQueries()) {
+		return
+	}
+	assert.NotNil(t, b.preparadas.Stmt(b.queries.GetQuery(qInserirVendaItem)))
+
+	// Numa transação, a query preparada é usada no lugar do texto
+	tx, err := b.db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tx.Rollback()
+	exec := b.comPreparadas(tx)
+	var total sql.NullInt64
+	assert.NoError(t, exec.QueryRow(b.queries.GetQuery(qContarTotalVendas)).Scan(&total))
+	assert.False(t, total.Valid)
+	assert.Len(t, exec.(*execPreparado).daTransacao, 1)
+}
@@ -0,0 +1,324 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Valores padrão do cálculo de reposição
+const (
+	JanelaConsumoPadraoDias = 30  // Período usado para calcular o consumo médio diário
+	CoberturaPadraoDias     = 30  // Dias de consumo que o pedido deve cobrir além do prazo de entrega
+	PrazoEntregaPadraoDias  = 7   // Prazo usado quando o medicamento não tem fornecedor conhecido
+	limiteDiasReposicao     = 365 // Limite da janela de consumo e da cobertura
+)
+
+// ErrParametrosReposicaoInvalidos indica estoque mínimo, máximo ou ponto de pedido inconsistentes
+var ErrParametrosReposicaoInvalidos = errors.New("parâmetros de reposição inválidos")
+
+// ParametrosReposicao são os limites de estoque de um medicamento. Campos nulos são calculados
+// a partir do consumo: o ponto de pedido cobre o prazo de entrega e o máximo cobre também a cobertura.
+type ParametrosReposicao struct {
+	MedicamentoID string    `json:"medicamento_id"`
+	EstoqueMinimo *int      `json:"estoque_minimo"` // Estoque de segurança
+	EstoqueMaximo *int      `json:"estoque_maximo"`
+	PontoPedido   *int      `json:"ponto_pedido"`
+	FornecedorID  *int      `json:"fornecedor_id"` // Fornecedor preferencial, que define o prazo de entrega
+	AtualizadoEm  time.Time `json:"atualizado_em"`
+}
+
+// FiltroReposicao configura o cálculo das sugestões de compra
+type FiltroReposicao struct {
+	JanelaDias    int // Dias de histórico de vendas e saídas considerados no consumo
+	CoberturaDias int
+	PrazoPadrao   int // Prazo de entrega para medicamentos sem fornecedor
+	FornecedorID  int // Restringe às sugestões desse fornecedor
+	Todos         bool
+}
+
+// SugestaoReposicao é a situação de estoque de um medicamento e a quantidade sugerida para compra
+type SugestaoReposicao struct {
+	MedicamentoID      string   `json:"medicamento_id"`
+	Nome               string   `json:"nome"`
+	Estoque            int      `json:"estoque"`
+	EmPedido           int      `json:"em_pedido"` // Pendente em pedidos já enviados
+	ConsumoPeriodo     int      `json:"consumo_periodo"`
+	ConsumoDiario      float64  `json:"consumo_diario"`
+	CoberturaAtualDias *float64 `json:"cobertura_atual_dias"` // Nulo quando não houve consumo
+	FornecedorID       int      `json:"fornecedor_id,omitempty"`
+	FornecedorNome     string   `json:"fornecedor_nome,omitempty"`
+	PrazoEntregaDias   int      `json:"prazo_entrega_dias"`
+	EstoqueMinimo      int      `json:"estoque_minimo"`
+	PontoPedido        int      `json:"ponto_pedido"`
+	EstoqueMaximo      int      `json:"estoque_maximo"`
+	QuantidadeSugerida int      `json:"quantidade_sugerida"`
+	CustoUnitario      float64  `json:"custo_unitario"` // Custo da última entrada com custo informado
+	ValorEstimado      float64  `json:"valor_estimado"`
+}
+
+// dadosReposicao é uma linha de selecionar_dados_reposicao
+type dadosReposicao struct {
+	MedicamentoID string
+	Nome          string
+	Estoque       int
+	EstoqueMinimo sql.NullInt64
+	EstoqueMaximo sql.NullInt64
+	PontoPedido   sql.NullInt64
+	FornecedorID  sql.NullInt64
+	EmPedido      int
+	Consumo       int
+	UltimoCusto   sql.NullFloat64
+}
+
+// GetParametrosReposicao retorna os limites de estoque do medicamento. Sem cadastro, todos os campos vêm nulos.
+func (b *Banco) GetParametrosReposicao(medicamentoID string) (*ParametrosReposicao, error) {
+	if b.GetMedicamento(medicamentoID) == nil {
+		return nil, ErrMedicamentoNaoEncontrado
+	}
+	return b.selecionarParametrosReposicao(b.db, medicamentoID)
+}
+
+func (b *Banco) selecionarParametrosReposicao(db execer, medicamentoID string) (*ParametrosReposicao, error) {
+	query := b.queries.GetQuery(qSelecionarParametrosReposicao)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_parametros_reposicao' não encontrada")
+	}
+
+	p := &ParametrosReposicao{MedicamentoID: medicamentoID}
+	var minimo, maximo, ponto, fornecedor sql.NullInt64
+	var atualizadoEm sql.NullTime
+	err := db.QueryRow(query, medicamentoID).Scan(&p.MedicamentoID, &minimo, &maximo, &ponto, &fornecedor, &atualizadoEm)
+	if err == sql.ErrNoRows {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.EstoqueMinimo = intNulo(minimo)
+	p.EstoqueMaximo = intNulo(maximo)
+	p.PontoPedido = intNulo(ponto)
+	p.FornecedorID = intNulo(fornecedor)
+	p.AtualizadoEm = atualizadoEm.Time
+	return p, nil
+}
+
+// SalvarParametrosReposicao grava os limites de estoque do medicamento, substituindo os anteriores.
+func (b *Banco) SalvarParametrosReposicao(p ParametrosReposicao, usuarioID int) (*ParametrosReposicao, error) {
+	if err := validarParametrosReposicao(p); err != nil {
+		return nil, err
+	}
+	if b.GetMedicamento(p.MedicamentoID) == nil {
+		return nil, ErrMedicamentoNaoEncontrado
+	}
+	if p.FornecedorID != nil {
+		fornecedor, err := b.GetFornecedor(*p.FornecedorID)
+		if err != nil {
+			return nil, err
+		}
+		if fornecedor == nil {
+			return nil, ErrFornecedorNaoEncontrado
+		}
+	}
+
+	query := b.queries.GetQuery(qSalvarParametrosReposicao)
+	if query == "" {
+		return nil, errors.New("query 'salvar_parametros_reposicao' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	anterior, err := b.selecionarParametrosReposicao(tx, p.MedicamentoID)
+	if err != nil {
+		return nil, err
+	}
+	p.AtualizadoEm = b.Agora()
+	_, err = tx.Exec(query, p.MedicamentoID, p.EstoqueMinimo, p.EstoqueMaximo, p.PontoPedido, p.FornecedorID, p.AtualizadoEm)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao salvar parâmetros de reposição: %w", err)
+	}
+	if err := b.registrarAuditoria(tx, usuarioID, AcaoAtualizar, "parametros_reposicao", p.MedicamentoID, anterior, p); err != nil {
+		return nil, err
+	}
+	return &p, tx.Commit()
+}
+
+func validarParametrosReposicao(p ParametrosReposicao) error {
+	for nome, valor := range map[string]*int{"estoque_minimo": p.EstoqueMinimo, "estoque_maximo": p.EstoqueMaximo, "ponto_pedido": p.PontoPedido} {
+		if valor != nil && *valor < 0 {
+			return fmt.Errorf("%w: %s não pode ser negativo", ErrParametrosReposicaoInvalidos, nome)
+		}
+	}
+	if p.EstoqueMaximo != nil {
+		if p.EstoqueMinimo != nil && *p.EstoqueMaximo < *p.EstoqueMinimo {
+			return fmt.Errorf("%w: o estoque máximo deve ser maior ou igual ao mínimo", ErrParametrosReposicaoInvalidos)
+		}
+		if p.PontoPedido != nil && *p.EstoqueMaximo < *p.PontoPedido {
+			return fmt.Errorf("%w: o estoque máximo deve ser maior ou igual ao ponto de pedido", ErrParametrosReposicaoInvalidos)
+		}
+	}
+	return nil
+}
+
+// SugerirReposicao calcula o consumo médio diário de cada medicamento (vendas não canceladas, sem as
+// unidades devolvidas, e saídas avulsas dentro da janela) e sugere quanto comprar para voltar ao estoque
+// máximo quando o estoque mais o que já está em pedido atinge o ponto de pedido.
+func (b *Banco) SugerirReposicao(filtro FiltroReposicao) ([]SugestaoReposicao, error) {
+	filtro = normalizarFiltroReposicao(filtro)
+	query := b.queries.GetQuery(qSelecionarDadosReposicao)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_dados_reposicao' não encontrada")
+	}
+
+	fornecedores, err := b.ListarFornecedores(true)
+	if err != nil {
+		return nil, err
+	}
+	porID := make(map[int]Fornecedor, len(fornecedores))
+	for _, f := range fornecedores {
+		porID[f.ID] = f
+	}
+
+	inicio := time.Now().AddDate(0, 0, -filtro.JanelaDias)
+	rows, err := b.db.Query(query, inicio, inicio)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao calcular consumo dos medicamentos: %w", err)
+	}
+	defer rows.Close()
+
+	var sugestoes []SugestaoReposicao
+	for rows.Next() {
+		var d dadosReposicao
+		err := rows.Scan(&d.MedicamentoID, &d.Nome, &d.Estoque, &d.EstoqueMinimo, &d.EstoqueMaximo, &d.PontoPedido,
+			&d.FornecedorID, &d.EmPedido, &d.Consumo, &d.UltimoCusto)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler dados de reposição: %w", err)
+		}
+		if filtro.FornecedorID != 0 && int(d.FornecedorID.Int64) != filtro.FornecedorID {
+			continue
+		}
+
+		var fornecedor *Fornecedor
+		if f, ok := porID[int(d.FornecedorID.Int64)]; ok {
+			fornecedor = &f
+		}
+		s := calcularSugestaoReposicao(d, fornecedor, filtro)
+		if s.QuantidadeSugerida > 0 || filtro.Todos {
+			sugestoes = append(sugestoes, s)
+		}
+	}
+	return sugestoes, rows.Err()
+}
+
+// normalizarFiltroReposicao aplica os valores padrão e os limites da janela e da cobertura.
+func normalizarFiltroReposicao(f FiltroReposicao) FiltroReposicao {
+	if f.JanelaDias <= 0 {
+		f.JanelaDias = JanelaConsumoPadraoDias
+	}
+	if f.JanelaDias > limiteDiasReposicao {
+		f.JanelaDias = limiteDiasReposicao
+	}
+	if f.CoberturaDias <= 0 {
+		f.CoberturaDias = CoberturaPadraoDias
+	}
+	if f.CoberturaDias > limiteDiasReposicao {
+		f.CoberturaDias = limiteDiasReposicao
+	}
+	if f.PrazoPadrao <= 0 {
+		f.PrazoPadrao = PrazoEntregaPadraoDias
+	}
+	return f
+}
+
+// calcularSugestaoReposicao aplica as regras de reposição a um medicamento:
+//   - ponto de pedido = consumo diário × prazo de entrega + estoque mínimo;
+//   - estoque máximo = consumo diário × (prazo + cobertura) + estoque mínimo;
+//   - quantidade sugerida = estoque máximo − (estoque + em pedido), quando esse saldo chega ao ponto de pedido.
+//
+// Os valores cadastrados em parametros_reposicao têm prioridade sobre os calculados.
+func calcularSugestaoReposicao(d dadosReposicao, fornecedor *Fornecedor, filtro FiltroReposicao) SugestaoReposicao {
+	s := SugestaoReposicao{
+		MedicamentoID:    d.MedicamentoID,
+		Nome:             d.Nome,
+		Estoque:          d.Estoque,
+		EmPedido:         d.EmPedido,
+		ConsumoPeriodo:   d.Consumo,
+		ConsumoDiario:    float64(d.Consumo) / float64(filtro.JanelaDias),
+		PrazoEntregaDias: filtro.PrazoPadrao,
+		EstoqueMinimo:    int(d.EstoqueMinimo.Int64),
+		CustoUnitario:    d.UltimoCusto.Float64,
+	}
+	if fornecedor != nil {
+		s.FornecedorID, s.FornecedorNome = fornecedor.ID, fornecedor.Nome
+		s.PrazoEntregaDias = fornecedor.PrazoEntregaDias
+	}
+	if s.ConsumoDiario > 0 {
+		cobertura := math.Round(float64(d.Estoque)/s.ConsumoDiario*10) / 10
+		s.CoberturaAtualDias = &cobertura
+	}
+
+	s.PontoPedido = int(math.Ceil(s.ConsumoDiario*float64(s.PrazoEntregaDias))) + s.EstoqueMinimo
+	if d.PontoPedido.Valid {
+		s.PontoPedido = int(d.PontoPedido.Int64)
+	}
+	s.EstoqueMaximo = int(math.Ceil(s.ConsumoDiario*float64(s.PrazoEntregaDias+filtro.CoberturaDias))) + s.EstoqueMinimo
+	if d.EstoqueMaximo.Valid {
+		s.EstoqueMaximo = int(d.EstoqueMaximo.Int64)
+	}
+	if s.EstoqueMaximo < s.PontoPedido {
+		s.EstoqueMaximo = s.PontoPedido
+	}
+
+	posicao := d.Estoque + d.EmPedido
+	if posicao <= s.PontoPedido && s.EstoqueMaximo > posicao {
+		s.QuantidadeSugerida = s.EstoqueMaximo - posicao
+	}
+	s.ConsumoDiario = math.Round(s.ConsumoDiario*100) / 100
+	s.ValorEstimado = arredondar(float64(s.QuantidadeSugerida) * s.CustoUnitario)
+	return s
+}
+
+// CriarPedidoSugerido cria um pedido em rascunho para o fornecedor com as quantidades sugeridas.
+// Se medicamentoIDs for informado, só esses medicamentos entram no pedido.
+func (b *Banco) CriarPedidoSugerido(fornecedorID int, filtro FiltroReposicao, medicamentoIDs []string, usuarioID int) (*PedidoCompra, error) {
+	filtro = normalizarFiltroReposicao(filtro)
+	filtro.FornecedorID = fornecedorID
+	filtro.Todos = false
+	sugestoes, err := b.SugerirReposicao(filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	selecionados := make(map[string]bool, len(medicamentoIDs))
+	for _, id := range medicamentoIDs {
+		selecionados[id] = true
+	}
+	req := PedidoCompraRequest{
+		FornecedorID: fornecedorID,
+		Observacao:   fmt.Sprintf("Sugestão de reposição (consumo de %d dias, cobertura de %d dias)", filtro.JanelaDias, filtro.CoberturaDias),
+	}
+	for _, s := range sugestoes {
+		if len(selecionados) > 0 && !selecionados[s.MedicamentoID] {
+			continue
+		}
+		req.Itens = append(req.Itens, ItemPedidoCompraRequest{MedicamentoID: s.MedicamentoID, Quantidade: s.QuantidadeSugerida, CustoUnitario: s.CustoUnitario})
+	}
+	if len(req.Itens) == 0 {
+		return nil, fmt.Errorf("%w: nenhum medicamento do fornecedor precisa de reposição", ErrPedidoCompraInvalido)
+	}
+	return b.CriarPedidoCompra(req, usuarioID)
+}
+
+func intNulo(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
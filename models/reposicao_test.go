@@ -0,0 +1,60 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcularSugestaoReposicao(t *testing.T) {
+	filtro := normalizarFiltroReposicao(FiltroReposicao{})
+	fornecedor := &Fornecedor{ID: 4, Nome: "Distribuidora", PrazoEntregaDias: 5}
+
+	// 90 unidades em 30 dias = 3 por dia; ponto de pedido 3×5 + 10 = 25, máximo 3×(5+30) + 10 = 115
+	d := dadosReposicao{
+		MedicamentoID: "1",
+		Estoque:       20,
+		EmPedido:      4,
+		Consumo:       90,
+		EstoqueMinimo: sql.NullInt64{Int64: 10, Valid: true},
+		UltimoCusto:   sql.NullFloat64{Float64: 2.5, Valid: true},
+	}
+	s := calcularSugestaoReposicao(d, fornecedor, filtro)
+	assert.Equal(t, 3.0, s.ConsumoDiario)
+	assert.Equal(t, 5, s.PrazoEntregaDias)
+	assert.Equal(t, 25, s.PontoPedido)
+	assert.Equal(t, 115, s.EstoqueMaximo)
+	assert.Equal(t, 91, s.QuantidadeSugerida) // 115 − (20 + 4)
+	assert.Equal(t, 227.5, s.ValorEstimado)
+	if assert.NotNil(t, s.CoberturaAtualDias) {
+		assert.Equal(t, 6.7, *s.CoberturaAtualDias)
+	}
+
+	// Acima do ponto de pedido não há sugestão
+	d.Estoque = 30
+	s = calcularSugestaoReposicao(d, fornecedor, filtro)
+	assert.Equal(t, 0, s.QuantidadeSugerida)
+
+	// Valores cadastrados têm prioridade sobre os calculados
+	d.PontoPedido = sql.NullInt64{Int64: 40, Valid: true}
+	d.EstoqueMaximo = sql.NullInt64{Int64: 60, Valid: true}
+	s = calcularSugestaoReposicao(d, fornecedor, filtro)
+	assert.Equal(t, 40, s.PontoPedido)
+	assert.Equal(t, 60, s.EstoqueMaximo)
+	assert.Equal(t, 26, s.QuantidadeSugerida)
+}
+
+func TestCalcularSugestaoReposicaoSemConsumo(t *testing.T) {
+	filtro := normalizarFiltroReposicao(FiltroReposicao{})
+
+	// Sem vendas e sem mínimo cadastrado, não há o que repor
+	s := calcularSugestaoReposicao(dadosReposicao{MedicamentoID: "2"}, nil, filtro)
+	assert.Equal(t, 0, s.QuantidadeSugerida)
+	assert.Nil(t, s.CoberturaAtualDias)
+	assert.Equal(t, PrazoEntregaPadraoDias, s.PrazoEntregaDias)
+
+	// Com mínimo cadastrado, repõe até o mínimo mesmo sem consumo
+	s = calcularSugestaoReposicao(dadosReposicao{MedicamentoID: "2", Estoque: 1, EstoqueMinimo: sql.NullInt64{Int64: 6, Valid: true}}, nil, filtro)
+	assert.Equal(t, 5, s.QuantidadeSugerida)
+}
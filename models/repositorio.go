@@ -0,0 +1,116 @@
+package models
+
+import "medicontrol/sqlutils"
+
+// Os repositórios isolam o acesso às tabelas centrais (medicamentos, categorias, movimentações e vendas)
+// do banco em uso. Todos os métodos recebem o Executor, para funcionarem tanto na conexão quanto
+// dentro de uma transação aberta pelas regras de negócio.
+
+// RepositorioMedicamentos persiste o cadastro de medicamentos
+type RepositorioMedicamentos interface {
+	Listar(db Executor) ([]Medicamento, error)
+	// BuscarPorID retorna nil, sem erro, quando o medicamento não existe
+	BuscarPorID(db Executor, id string) (*Medicamento, error)
+	// BuscarPorCodigoANVISA retorna nil, sem erro, quando o medicamento não existe
+	BuscarPorCodigoANVISA(db Executor, codigo string) (*Medicamento, error)
+	// BuscarPorCodigoBarras recebe o GTIN já normalizado e retorna nil, sem erro, quando ele não está cadastrado
+	BuscarPorCodigoBarras(db Executor, gtin string) (*Medicamento, error)
+	// Pesquisar aplica a busca e os filtros, ordena e pagina. O termo é procurado no índice de busca
+	// ou, sem ele, com LIKE no nome, no fabricante e no código ANVISA, sem diferenciar maiúsculas
+	Pesquisar(db Executor, filtro FiltroMedicamentos) (*PaginaMedicamentos, error)
+	// Inserir e Atualizar gravam também os códigos de barras e a ligação com os princípios ativos, já
+	// cadastrados; na atualização, eles e a lista de controle só quando não são nil. Um código de outro
+	// medicamento é ErrCodigoBarrasEmUso. Atualizar não grava o estoque nem a validade, que vêm dos lotes.
+	Inserir(db Executor, med *Medicamento) error
+	Atualizar(db Executor, med *Medicamento) error
+	Excluir(db Executor, id string) error
+	// ListarAlternativas lista os medicamentos em estoque, de referência, genéricos ou similares
+	// intercambiáveis, com a mesma composição do informado, do mais barato ao mais caro
+	ListarAlternativas(db Executor, id string) ([]Medicamento, error)
+	// AtualizarEstoque grava a quantidade em estoque e o custo médio do medicamento
+	AtualizarEstoque(db Executor, id string, quantidade int, custoMedio float64) error
+	// ListarBaixoEstoque lista os medicamentos abaixo do estoque mínimo ou, sem mínimo cadastrado, do limite
+	ListarBaixoEstoque(db Executor, limite int) ([]Medicamento, error)
+}
+
+// RepositorioCategorias persiste as categorias de medicamentos
+type RepositorioCategorias interface {
+	Listar(db Executor) ([]Categoria, error)
+	// BuscarPorNome retorna nil, sem erro, quando a categoria não existe
+	BuscarPorNome(db Executor, nome string) (*Categoria, error)
+	Inserir(db Executor, cat *Categoria) error
+}
+
+// MovimentacaoListada é uma movimentação com o nome e o tipo do medicamento
+type MovimentacaoListada struct {
+	Movimentacao
+	NomeMedicamento string
+	TipoMedicamento string
+}
+
+// RepositorioMovimentacoes persiste as entradas e saídas de estoque
+type RepositorioMovimentacoes interface {
+	Inserir(db Executor, mov *Movimentacao) error
+	Listar(db Executor) ([]MovimentacaoListada, error)
+}
+
+// ItemVendaGravado é um item de venda como gravado em venda_items
+type ItemVendaGravado struct {
+	VendaID       int64
+	MedicamentoID string
+	Quantidade    int
+	PrecoUnitario float64
+	Desconto      float64
+	ValorTotal    float64
+	// Custo médio do medicamento no momento da venda; zero quando desconhecido
+	CustoUnitario float64
+}
+
+// RepositorioVendas persiste as vendas e seus itens
+type RepositorioVendas interface {
+	// Inserir cria o cabeçalho da venda e retorna o ID gerado
+	Inserir(db Executor, userID int, caixaID int64) (int64, error)
+	// InserirItem grava o item e retorna o ID gerado
+	InserirItem(db Executor, item ItemVendaGravado) (int64, error)
+	// AplicarDescontoItem soma o desconto ao item e grava seu novo valor total
+	AplicarDescontoItem(db Executor, itemID int64, desconto, valorTotal float64) error
+	GravarTotais(db Executor, resumo *ResumoVenda) error
+	// Status retorna ErrVendaNaoEncontrada quando a venda não existe
+	Status(db Executor, vendaID int64) (string, error)
+	AtualizarStatus(db Executor, vendaID int64, status, motivo string) error
+	// Buscar retorna a venda com seus itens, sem os pagamentos, ou nil se ela não existir
+	Buscar(db Executor, vendaID int64) (*VendaDetalhe, error)
+	Listar(db Executor) ([]VendaInfo, error)
+	Pesquisar(db Executor, filtro FiltroVendas) (*PaginaVendas, error)
+	// TotalUnidadesVendidas soma as unidades vendidas, sem vendas canceladas nem itens devolvidos
+	TotalUnidadesVendidas(db Executor) (int, error)
+}
+
+// Repositorios reúne os repositórios de um banco de dados
+type Repositorios struct {
+	Medicamentos  RepositorioMedicamentos
+	Categorias    RepositorioCategorias
+	Movimentacoes RepositorioMovimentacoes
+	Vendas        RepositorioVendas
+}
+
+// NovosRepositoriosSQLite cria os repositórios para o SQLite com as queries informadas
+func NovosRepositoriosSQLite(queries *sqlutils.Queries) Repositorios {
+	return novosRepositoriosSQL(dialetoSQLite{}, queries)
+}
+
+// NovosRepositoriosPostgres cria os repositórios para o PostgreSQL com as queries informadas
+func NovosRepositoriosPostgres(queries *sqlutils.Queries) Repositorios {
+	return novosRepositoriosSQL(dialetoPostgres{}, queries)
+}
+
+// novosRepositoriosSQL monta os repositórios sobre database/sql; as queries de cada banco
+// vêm de sql/ e das variações em sql/<dialeto>/, e o que não cabe num arquivo fica no dialeto.
+func novosRepositoriosSQL(d dialetoSQL, queries *sqlutils.Queries) Repositorios {
+	return Repositorios{
+		Medicamentos:  medicamentosSQL{queries: queries, busca: d.indiceBusca()},
+		Categorias:    categoriasSQL{queries: queries},
+		Movimentacoes: movimentacoesSQL{queries: queries},
+		Vendas:        vendasSQL{queries: queries, dialeto: d},
+	}
+}
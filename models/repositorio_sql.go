@@ -0,0 +1,775 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"medicontrol/sqlutils"
+)
+
+// queryObrigatoria carrega uma query do dialeto em uso ou falha se ela não existir.
+func queryObrigatoria(queries *sqlutils.Queries, nome string) (string, error) {
+	query := queries.GetQuery(nome)
+	if query == "" {
+		return "", fmt.Errorf("query '%s' não encontrada", nome)
+	}
+	return query, nil
+}
+
+// medicamentosSQL implementa RepositorioMedicamentos sobre database/sql. Com um índice de busca,
+// ele é atualizado junto com o cadastro; sem índice, a busca compara o termo com LIKE.
+type medicamentosSQL struct {
+	queries *sqlutils.Queries
+	busca   indiceBusca
+}
+
+func (r medicamentosSQL) Listar(db Executor) ([]Medicamento, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarTodosMedicamentos)
+	if err != nil {
+		return nil, err
+	}
+	medicamentos, err := listarMedicamentos(db, query)
+	if err != nil {
+		return nil, err
+	}
+	return medicamentos, preencherRelacionados(db, medicamentos, false)
+}
+
+// listarMedicamentos lê as linhas das queries no formato de selecionar_todos_medicamentos.
+func listarMedicamentos(db Executor, query string, args ...interface{}) ([]Medicamento, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var medicamentos []Medicamento
+	for rows.Next() {
+		med, err := scanMedicamento(rows)
+		if err != nil {
+			log.Printf("Erro ao escanear linha do medicamento: %v", err)
+			continue
+		}
+		medicamentos = append(medicamentos, *med)
+	}
+	return medicamentos, rows.Err()
+}
+
+func (r medicamentosSQL) BuscarPorID(db Executor, id string) (*Medicamento, error) {
+	return buscarUmMedicamento(db, r.queries, qSelecionarMedicamentoPorId, id)
+}
+
+func (r medicamentosSQL) BuscarPorCodigoANVISA(db Executor, codigo string) (*Medicamento, error) {
+	return buscarUmMedicamento(db, r.queries, qSelecionarMedicamentoPorCodigoAnvisa, codigo)
+}
+
+func (r medicamentosSQL) BuscarPorCodigoBarras(db Executor, gtin string) (*Medicamento, error) {
+	return buscarUmMedicamento(db, r.queries, qSelecionarMedicamentoPorCodigoBarras, gtin)
+}
+
+func buscarUmMedicamento(db Executor, queries *sqlutils.Queries, nomeQuery string, arg interface{}) (*Medicamento, error) {
+	query, err := queryObrigatoria(queries, nomeQuery)
+	if err != nil {
+		return nil, err
+	}
+	med, err := scanMedicamento(db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lista := []Medicamento{*med}
+	if err := preencherRelacionados(db, lista, true); err != nil {
+		return nil, err
+	}
+	return &lista[0], nil
+}
+
+// preencherRelacionados lê os códigos de barras e os princípios ativos dos medicamentos
+func preencherRelacionados(db Executor, medicamentos []Medicamento, somenteListados bool) error {
+	if err := preencherCodigosBarras(db, medicamentos, somenteListados); err != nil {
+		return err
+	}
+	return preencherPrincipiosAtivos(db, medicamentos, somenteListados)
+}
+
+// filtroListados restringe a consulta aos medicamentos listados, quando somenteListados é verdadeiro
+func filtroListados(coluna string, medicamentos []Medicamento, somenteListados bool) (string, []interface{}) {
+	if !somenteListados {
+		return "", nil
+	}
+	args := make([]interface{}, 0, len(medicamentos))
+	for _, med := range medicamentos {
+		args = append(args, med.ID)
+	}
+	return " WHERE " + coluna + " IN (?" + strings.Repeat(", ?", len(medicamentos)-1) + ")", args
+}
+
+// preencherCodigosBarras lê numa só consulta os códigos de barras dos medicamentos listados;
+// com somenteListados falso, lê os de todos, para listagens do cadastro inteiro.
+func preencherCodigosBarras(db Executor, medicamentos []Medicamento, somenteListados bool) error {
+	if len(medicamentos) == 0 {
+		return nil
+	}
+	where, args := filtroListados("medicamento_id", medicamentos, somenteListados)
+	rows, err := db.Query("SELECT medicamento_id, gtin FROM medicamento_codigos_barras"+where+" ORDER BY gtin", args...)
+	if err != nil {
+		return fmt.Errorf("erro ao ler os códigos de barras: %w", err)
+	}
+	defer rows.Close()
+
+	codigos := map[string][]string{}
+	for rows.Next() {
+		var id, gtin string
+		if err := rows.Scan(&id, &gtin); err != nil {
+			return err
+		}
+		codigos[id] = append(codigos[id], gtin)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range medicamentos {
+		medicamentos[i].CodigosBarras = codigos[medicamentos[i].ID]
+		if medicamentos[i].CodigosBarras == nil {
+			medicamentos[i].CodigosBarras = []string{}
+		}
+	}
+	return nil
+}
+
+// preencherPrincipiosAtivos lê numa só consulta a composição dos medicamentos, como preencherCodigosBarras
+func preencherPrincipiosAtivos(db Executor, medicamentos []Medicamento, somenteListados bool) error {
+	if len(medicamentos) == 0 {
+		return nil
+	}
+	where, args := filtroListados("mp.medicamento_id", medicamentos, somenteListados)
+	rows, err := db.Query(`SELECT mp.medicamento_id, p.id, p.nome, p.concentracao, p.forma
+		FROM medicamento_principios mp
+		JOIN principios_ativos p ON p.id = mp.principio_id`+where+" ORDER BY p.chave", args...)
+	if err != nil {
+		return fmt.Errorf("erro ao ler os princípios ativos: %w", err)
+	}
+	defer rows.Close()
+
+	principios := map[string][]PrincipioAtivo{}
+	for rows.Next() {
+		var id string
+		var p PrincipioAtivo
+		if err := rows.Scan(&id, &p.ID, &p.Nome, &p.Concentracao, &p.Forma); err != nil {
+			return err
+		}
+		principios[id] = append(principios[id], p)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range medicamentos {
+		medicamentos[i].PrincipiosAtivos = principios[medicamentos[i].ID]
+		if medicamentos[i].PrincipiosAtivos == nil {
+			medicamentos[i].PrincipiosAtivos = []PrincipioAtivo{}
+		}
+	}
+	return nil
+}
+
+// salvarPrincipiosAtivos substitui a composição do medicamento pelos princípios, já cadastrados
+func (r medicamentosSQL) salvarPrincipiosAtivos(db Executor, id string, principios []PrincipioAtivo) error {
+	query, err := queryObrigatoria(r.queries, qExcluirPrincipiosMedicamento)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(query, id); err != nil {
+		return err
+	}
+	query, err = queryObrigatoria(r.queries, qInserirMedicamentoPrincipio)
+	if err != nil {
+		return err
+	}
+	for _, p := range principios {
+		if _, err := db.Exec(query, id, p.ID); err != nil {
+			return fmt.Errorf("erro ao gravar o princípio ativo %s: %w", p.Nome, err)
+		}
+	}
+	return nil
+}
+
+// salvarCodigosBarras substitui os códigos de barras do medicamento pelos informados, já normalizados
+func (r medicamentosSQL) salvarCodigosBarras(db Executor, id string, codigos []string) error {
+	for _, gtin := range codigos {
+		dono, err := r.BuscarPorCodigoBarras(db, gtin)
+		if err != nil {
+			return err
+		}
+		if dono != nil && dono.ID != id {
+			return fmt.Errorf("%w: %s (%s)", ErrCodigoBarrasEmUso, gtin, dono.Nome)
+		}
+	}
+
+	query, err := queryObrigatoria(r.queries, qExcluirCodigosBarrasMedicamento)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(query, id); err != nil {
+		return err
+	}
+	query, err = queryObrigatoria(r.queries, qInserirCodigoBarras)
+	if err != nil {
+		return err
+	}
+	for _, gtin := range codigos {
+		if _, err := db.Exec(query, gtin, id); err != nil {
+			return fmt.Errorf("erro ao gravar o código de barras %s: %w", gtin, err)
+		}
+	}
+	return nil
+}
+
+// colunasOrdenacaoMedicamentos traduz os campos de ordenação da listagem
+var colunasOrdenacaoMedicamentos = map[string]string{
+	OrdenarPorNome:       "m.Nome",
+	OrdenarPorPreco:      "COALESCE(m.Preco, 0)",
+	OrdenarPorQuantidade: "m.Quantidade",
+	OrdenarPorValidade:   "m.Validade",
+	OrdenarPorRelevancia: "busca.relevancia",
+}
+
+func (r medicamentosSQL) Pesquisar(db Executor, filtro FiltroMedicamentos) (*PaginaMedicamentos, error) {
+	if filtro.Ordenacao == "" {
+		filtro.Ordenacao = OrdenarPorNome
+		if filtro.Termo != "" {
+			filtro.Ordenacao = OrdenarPorRelevancia
+		}
+	}
+	coluna, ok := colunasOrdenacaoMedicamentos[filtro.Ordenacao]
+	if !ok {
+		return nil, ErrOrdenacaoInvalida
+	}
+
+	var args []interface{}
+	filtros := `
+		FROM medicamentos m`
+	palavras := palavrasBusca(filtro.Termo)
+	relevancia := false
+	if r.busca != nil && len(palavras) > 0 {
+		vocabulario, err := r.busca.palavras(db)
+		if err != nil {
+			return nil, err
+		}
+		subconsulta, argsBusca := r.busca.subconsulta(expandirBusca(palavras, vocabulario))
+		filtros += " JOIN (" + subconsulta + ") busca ON busca.medicamento_id = m.ID"
+		args = append(args, argsBusca...)
+		relevancia = true
+	}
+	filtros += `
+		LEFT JOIN categorias c ON m.CategoriaID = c.ID
+		WHERE 1 = 1`
+
+	if filtro.Termo != "" && r.busca == nil {
+		// LOWER dos dois lados, para que o banco compare com a mesma regra de maiúsculas
+		filtros += " AND (LOWER(m.Nome) LIKE LOWER(?) OR LOWER(COALESCE(m.Fabricante, '')) LIKE LOWER(?) OR COALESCE(m.CodigoANVISA, '') LIKE ?)"
+		padrao := "%" + filtro.Termo + "%"
+		args = append(args, padrao, padrao, padrao)
+	}
+	if filtro.CategoriaID != "" {
+		filtros += " AND m.CategoriaID = ?"
+		args = append(args, filtro.CategoriaID)
+	}
+	if filtro.Fabricante != "" {
+		filtros += " AND LOWER(m.Fabricante) = LOWER(?)"
+		args = append(args, filtro.Fabricante)
+	}
+	if filtro.Tipo != "" {
+		filtros += " AND LOWER(m.Tipo) = LOWER(?)"
+		args = append(args, filtro.Tipo)
+	}
+	if filtro.QuantidadeMin != nil {
+		filtros += " AND m.Quantidade >= ?"
+		args = append(args, *filtro.QuantidadeMin)
+	}
+	if filtro.QuantidadeMax != nil {
+		filtros += " AND m.Quantidade <= ?"
+		args = append(args, *filtro.QuantidadeMax)
+	}
+	// A validade é gravada como texto YYYY-MM-DD, então a comparação é de texto
+	if !filtro.ValidadeDe.IsZero() || !filtro.ValidadeAte.IsZero() {
+		filtros += " AND COALESCE(m.Validade, '') <> ''"
+	}
+	if !filtro.ValidadeDe.IsZero() {
+		filtros += " AND m.Validade >= ?"
+		args = append(args, filtro.ValidadeDe.Format("2006-01-02"))
+	}
+	if !filtro.ValidadeAte.IsZero() {
+		filtros += " AND m.Validade < ?"
+		args = append(args, filtro.ValidadeAte.Format("2006-01-02"))
+	}
+
+	pagina := &PaginaMedicamentos{Pagina: filtro.Pagina, PorPagina: filtro.PorPagina}
+	if err := db.QueryRow("SELECT COUNT(*)"+filtros, args...).Scan(&pagina.Total); err != nil {
+		return nil, fmt.Errorf("erro ao contar medicamentos: %w", err)
+	}
+
+	ordem := " ORDER BY "
+	decrescente := filtro.Decrescente
+	switch {
+	case filtro.Ordenacao == OrdenarPorValidade:
+		// Medicamentos sem validade ficam no fim, em qualquer direção
+		ordem += "CASE WHEN COALESCE(m.Validade, '') = '' THEN 1 ELSE 0 END, "
+	case filtro.Ordenacao == OrdenarPorRelevancia && relevancia:
+		// Os mais relevantes primeiro; a ordem decrescente os põe no fim
+		decrescente = !decrescente
+	case filtro.Ordenacao == OrdenarPorRelevancia:
+		// Sem busca no índice, não há relevância para ordenar
+		coluna = colunasOrdenacaoMedicamentos[OrdenarPorNome]
+	}
+	ordem += coluna
+	if decrescente {
+		ordem += " DESC"
+	}
+	// O nome e o ID desempatam, para que as páginas não repitam nem pulem medicamentos
+	ordem += ", m.Nome, m.ID"
+
+	query := `SELECT m.ID, m.Nome, COALESCE(m.Fabricante, ''), COALESCE(m.Tipo, ''), COALESCE(m.CodigoANVISA, ''), m.Quantidade,
+		       COALESCE(m.Validade, ''), m.CriadoEm, m.Preco, c.ID, c.Nome, COALESCE(m.ListaControle, ''), COALESCE(m.CustoMedio, 0),
+		       COALESCE(m.CategoriaRegulatoria, '')` +
+		filtros + ordem
+	if filtro.PorPagina > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filtro.PorPagina, (filtro.Pagina-1)*filtro.PorPagina)
+	}
+
+	var err error
+	pagina.Medicamentos, err = listarMedicamentos(db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := preencherRelacionados(db, pagina.Medicamentos, filtro.PorPagina > 0); err != nil {
+		return nil, err
+	}
+	return pagina, nil
+}
+
+func (r medicamentosSQL) Inserir(db Executor, med *Medicamento) error {
+	query, err := queryObrigatoria(r.queries, qInserirMedicamento)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(query, med.ID, med.Nome, med.Fabricante, med.Tipo, med.CodigoANVISA, med.Quantidade, med.Validade,
+		med.Preco, med.CriadoEm, med.CategoriaID, med.listaControle(), arredondarCusto(med.CustoMedio), med.CategoriaRegulatoria); err != nil {
+		return err
+	}
+	if err := r.salvarCodigosBarras(db, med.ID, med.CodigosBarras); err != nil {
+		return err
+	}
+	if err := r.salvarPrincipiosAtivos(db, med.ID, med.PrincipiosAtivos); err != nil {
+		return err
+	}
+	return indexarMedicamento(db, r.busca, med.ID)
+}
+
+func (r medicamentosSQL) Atualizar(db Executor, med *Medicamento) error {
+	query, err := queryObrigatoria(r.queries, qAtualizarMedicamento)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(query, med.Nome, med.Fabricante, med.Tipo, med.CodigoANVISA,
+		med.Preco, med.CategoriaID, med.ListaControle, med.CategoriaRegulatoria, med.ID); err != nil {
+		return err
+	}
+	if med.CodigosBarras != nil {
+		if err := r.salvarCodigosBarras(db, med.ID, med.CodigosBarras); err != nil {
+			return err
+		}
+	}
+	if med.PrincipiosAtivos != nil {
+		if err := r.salvarPrincipiosAtivos(db, med.ID, med.PrincipiosAtivos); err != nil {
+			return err
+		}
+	}
+	return indexarMedicamento(db, r.busca, med.ID)
+}
+
+func (r medicamentosSQL) Excluir(db Executor, id string) error {
+	if err := r.salvarCodigosBarras(db, id, nil); err != nil {
+		return err
+	}
+	if err := r.salvarPrincipiosAtivos(db, id, nil); err != nil {
+		return err
+	}
+	query, err := queryObrigatoria(r.queries, qDeletarMedicamento)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(query, id); err != nil {
+		return err
+	}
+	return indexarMedicamento(db, r.busca, id)
+}
+
+func (r medicamentosSQL) ListarAlternativas(db Executor, id string) ([]Medicamento, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarAlternativasMedicamento)
+	if err != nil {
+		return nil, err
+	}
+	alternativas, err := listarMedicamentos(db, query, id, id, id)
+	if err != nil {
+		return nil, err
+	}
+	return alternativas, preencherRelacionados(db, alternativas, true)
+}
+
+func (r medicamentosSQL) AtualizarEstoque(db Executor, id string, quantidade int, custoMedio float64) error {
+	query, err := queryObrigatoria(r.queries, qAtualizarEstoqueCustoMedicamento)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(query, quantidade, custoMedio, id)
+	return err
+}
+
+func (r medicamentosSQL) ListarBaixoEstoque(db Executor, limite int) ([]Medicamento, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarMedicamentosBaixoEstoque)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(query, limite)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var medicamentos []Medicamento
+	for rows.Next() {
+		var med Medicamento
+		if err := rows.Scan(&med.ID, &med.Nome, &med.Fabricante, &med.Quantidade); err != nil {
+			log.Printf("Erro ao escanear medicamento com baixo estoque: %v", err)
+			continue
+		}
+		medicamentos = append(medicamentos, med)
+	}
+	return medicamentos, rows.Err()
+}
+
+// categoriasSQL implementa RepositorioCategorias sobre database/sql
+type categoriasSQL struct {
+	queries *sqlutils.Queries
+}
+
+func (r categoriasSQL) Listar(db Executor) ([]Categoria, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarTodasCategorias)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categorias []Categoria
+	for rows.Next() {
+		var cat Categoria
+		if err := rows.Scan(&cat.ID, &cat.Nome); err != nil {
+			log.Printf("Erro ao escanear linha da categoria: %v", err)
+			continue
+		}
+		categorias = append(categorias, cat)
+	}
+	return categorias, rows.Err()
+}
+
+func (r categoriasSQL) BuscarPorNome(db Executor, nome string) (*Categoria, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarCategoriaPorNome)
+	if err != nil {
+		return nil, err
+	}
+	var cat Categoria
+	err = db.QueryRow(query, nome).Scan(&cat.ID, &cat.Nome)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+func (r categoriasSQL) Inserir(db Executor, cat *Categoria) error {
+	query, err := queryObrigatoria(r.queries, qInserirCategoria)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(query, cat.ID, cat.Nome)
+	return err
+}
+
+// movimentacoesSQL implementa RepositorioMovimentacoes sobre database/sql
+type movimentacoesSQL struct {
+	queries *sqlutils.Queries
+}
+
+func (r movimentacoesSQL) Inserir(db Executor, mov *Movimentacao) error {
+	query, err := queryObrigatoria(r.queries, qInserirMovimentacao)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(query, mov.ID, mov.MedicamentoID, mov.Tipo, mov.Quantidade, mov.Data, mov.Observacao, mov.UsuarioID,
+		mov.NotaFiscal, mov.CNPJFornecedor, sql.NullInt64{Int64: mov.VendaID, Valid: mov.VendaID != 0},
+		sql.NullFloat64{Float64: mov.CustoUnitario, Valid: mov.CustoUnitario > 0})
+	return err
+}
+
+func (r movimentacoesSQL) Listar(db Executor) ([]MovimentacaoListada, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarTodasMovimentacoes)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movimentacoes []MovimentacaoListada
+	for rows.Next() {
+		var mov MovimentacaoListada
+		if err := rows.Scan(&mov.ID, &mov.MedicamentoID, &mov.Tipo, &mov.Quantidade, &mov.Data, &mov.Observacao,
+			&mov.NomeMedicamento, &mov.TipoMedicamento); err != nil {
+			log.Printf("Erro ao escanear movimentação: %v", err)
+			continue
+		}
+		movimentacoes = append(movimentacoes, mov)
+	}
+	return movimentacoes, rows.Err()
+}
+
+// vendasSQL implementa RepositorioVendas sobre database/sql
+type vendasSQL struct {
+	queries *sqlutils.Queries
+	dialeto dialetoSQL
+}
+
+func (r vendasSQL) Inserir(db Executor, userID int, caixaID int64) (int64, error) {
+	query, err := queryObrigatoria(r.queries, qInserirVenda)
+	if err != nil {
+		return 0, err
+	}
+	return r.dialeto.inserirComID(db, query, userID, caixaID)
+}
+
+func (r vendasSQL) InserirItem(db Executor, item ItemVendaGravado) (int64, error) {
+	query, err := queryObrigatoria(r.queries, qInserirVendaItem)
+	if err != nil {
+		return 0, err
+	}
+	custo := sql.NullFloat64{Float64: item.CustoUnitario, Valid: item.CustoUnitario > 0}
+	return r.dialeto.inserirComID(db, query, item.VendaID, item.MedicamentoID, item.Quantidade, item.PrecoUnitario,
+		item.Desconto, item.ValorTotal, custo)
+}
+
+func (r vendasSQL) AplicarDescontoItem(db Executor, itemID int64, desconto, valorTotal float64) error {
+	query, err := queryObrigatoria(r.queries, qAtualizarValorItemVenda)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(query, desconto, valorTotal, itemID)
+	return err
+}
+
+func (r vendasSQL) GravarTotais(db Executor, resumo *ResumoVenda) error {
+	query, err := queryObrigatoria(r.queries, qAtualizarTotaisVenda)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(query, resumo.Subtotal, resumo.Desconto, resumo.Total, resumo.ValorRecebido, resumo.Troco, resumo.VendaID)
+	return err
+}
+
+func (r vendasSQL) Status(db Executor, vendaID int64) (string, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarStatusVenda)
+	if err != nil {
+		return "", err
+	}
+	var status string
+	err = db.QueryRow(query, vendaID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrVendaNaoEncontrada
+	}
+	return status, err
+}
+
+func (r vendasSQL) AtualizarStatus(db Executor, vendaID int64, status, motivo string) error {
+	query, err := queryObrigatoria(r.queries, qAtualizarStatusVenda)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(query, status, motivo, vendaID)
+	return err
+}
+
+func (r vendasSQL) Buscar(db Executor, vendaID int64) (*VendaDetalhe, error) {
+	query, err := queryObrigatoria(r.queries, qSelecionarVendaPorId)
+	if err != nil {
+		return nil, err
+	}
+
+	var venda VendaDetalhe
+	var motivo sql.NullString
+	var subtotal, total, recebido sql.NullFloat64
+	err = db.QueryRow(query, vendaID).Scan(&venda.ID, &venda.Data, &venda.UserID, &venda.Username, &venda.Status, &motivo,
+		&subtotal, &venda.Desconto, &total, &recebido, &venda.Troco)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	venda.MotivoEstorno = motivo.String
+	venda.Subtotal = subtotal.Float64
+	venda.TotalCobrado = total.Float64
+	venda.ValorRecebido = recebido.Float64
+
+	queryItens, err := queryObrigatoria(r.queries, qSelecionarItensVendaDetalhe)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(queryItens, vendaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	venda.Itens = []ItemVendaDetalhe{}
+	for rows.Next() {
+		var item ItemVendaDetalhe
+		var nome sql.NullString
+		var valorTotal sql.NullFloat64
+		if err := rows.Scan(&item.ID, &item.MedicamentoID, &nome, &item.Quantidade, &item.QuantidadeDevolvida, &item.PrecoUnitario,
+			&item.Desconto, &valorTotal); err != nil {
+			return nil, fmt.Errorf("erro ao escanear item da venda: %w", err)
+		}
+		item.Nome = nome.String
+		// Vendas anteriores aos descontos não têm o valor do item gravado
+		if !valorTotal.Valid {
+			valorTotal.Float64 = float64(item.Quantidade) * item.PrecoUnitario
+		}
+		if item.Quantidade > 0 {
+			item.Subtotal = arredondar(valorTotal.Float64 * float64(item.Quantidade-item.QuantidadeDevolvida) / float64(item.Quantidade))
+		}
+		venda.QuantidadeItens++
+		venda.TotalVenda += item.Subtotal
+		venda.Itens = append(venda.Itens, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	venda.TotalVenda = arredondar(venda.TotalVenda)
+	return &venda, nil
+}
+
+func (r vendasSQL) Listar(db Executor) ([]VendaInfo, error) {
+	query, err := queryObrigatoria(r.queries, qListarVendas)
+	if err != nil {
+		return nil, err
+	}
+	return listarVendas(db, query)
+}
+
+// listarVendas lê as linhas no formato de ListarVendas.
+func listarVendas(db Executor, query string, args ...interface{}) ([]VendaInfo, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar vendas: %w", err)
+	}
+	defer rows.Close()
+
+	var vendas []VendaInfo
+	for rows.Next() {
+		var v VendaInfo
+		if err := rows.Scan(&v.ID, &v.Data, &v.UserID, &v.Username, &v.Status, &v.QuantidadeItens, &v.TotalVenda); err != nil {
+			return nil, fmt.Errorf("erro ao scanear linha da venda: %w", err)
+		}
+		vendas = append(vendas, v)
+	}
+	return vendas, rows.Err()
+}
+
+// Pesquisar agrupa as vendas numa subconsulta para filtrar pelo total sem depender de HAVING sobre
+// apelidos, que o PostgreSQL não aceita.
+func (r vendasSQL) Pesquisar(db Executor, filtro FiltroVendas) (*PaginaVendas, error) {
+	agrupadas := `
+		SELECT v.id, v.data, v.user_id, COALESCE(u.username, '') AS username, v.status, COUNT(vi.id) AS quantidade_itens,
+		       COALESCE(SUM(COALESCE(vi.valor_total, vi.quantidade * vi.preco_unitario) * (vi.quantidade - vi.quantidade_devolvida) / vi.quantidade), 0) AS total_venda
+		FROM vendas v
+		LEFT JOIN usuarios u ON u.id = v.user_id
+		LEFT JOIN venda_items vi ON vi.venda_id = v.id
+		WHERE 1 = 1`
+
+	var args []interface{}
+	if !filtro.De.IsZero() {
+		agrupadas += " AND v.data >= ?"
+		args = append(args, filtro.De)
+	}
+	if !filtro.Ate.IsZero() {
+		agrupadas += " AND v.data < ?"
+		args = append(args, filtro.Ate)
+	}
+	if filtro.UsuarioID != 0 {
+		agrupadas += " AND v.user_id = ?"
+		args = append(args, filtro.UsuarioID)
+	}
+	if filtro.Status != "" {
+		agrupadas += " AND v.status = ?"
+		args = append(args, filtro.Status)
+	}
+	if filtro.MedicamentoID != "" {
+		agrupadas += " AND EXISTS (SELECT 1 FROM venda_items f WHERE f.venda_id = v.id AND f.medicamento_id = ?)"
+		args = append(args, filtro.MedicamentoID)
+	}
+	agrupadas += " GROUP BY v.id, v.data, v.user_id, u.username, v.status"
+
+	query := "SELECT id, data, user_id, username, status, quantidade_itens, total_venda FROM (" + agrupadas + ") AS t WHERE 1 = 1"
+	if filtro.TotalMin != nil {
+		query += " AND total_venda >= ?"
+		args = append(args, *filtro.TotalMin)
+	}
+	if filtro.TotalMax != nil {
+		query += " AND total_venda <= ?"
+		args = append(args, *filtro.TotalMax)
+	}
+
+	pagina := &PaginaVendas{Pagina: filtro.Pagina, PorPagina: filtro.PorPagina}
+	if err := db.QueryRow("SELECT COUNT(*) FROM ("+query+") AS c", args...).Scan(&pagina.Total); err != nil {
+		return nil, fmt.Errorf("erro ao contar vendas: %w", err)
+	}
+
+	query += " ORDER BY data DESC, id DESC"
+	if filtro.PorPagina > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filtro.PorPagina, (filtro.Pagina-1)*filtro.PorPagina)
+	}
+
+	var err error
+	pagina.Vendas, err = listarVendas(db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pagina, nil
+}
+
+func (r vendasSQL) TotalUnidadesVendidas(db Executor) (int, error) {
+	query, err := queryObrigatoria(r.queries, qContarTotalVendas)
+	if err != nil {
+		return 0, err
+	}
+	// SUM é nulo quando não há vendas
+	var total sql.NullInt64
+	if err := db.QueryRow(query).Scan(&total); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return int(total.Int64), nil
+}
@@ -0,0 +1,524 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Namespace das mensagens do SNGPC (Sistema Nacional de Gerenciamento de Produtos Controlados)
+const sngpcNamespace = "urn:sngpc-schema"
+
+// Códigos do SNGPC usados na exportação
+const (
+	sngpcUsoHumano             = "1"
+	sngpcUnidadeCaixa          = "1"
+	sngpcOperacaoCompra        = "1"
+	sngpcConselhoCRM           = "CRM"
+	sngpcReceitaControle2Vias  = "1"
+	sngpcNotificacaoReceitaB   = "2"
+	sngpcNotificacaoEspecial   = "3"
+	sngpcNotificacaoReceitaA   = "4"
+	sngpcPerdaVencimento       = "3"
+	sngpcPerdaNoProcesso       = "5"
+	sngpcFormatoData           = "2006-01-02"
+	sngpcTamanhoRegistroMS     = 13
+	sngpcTamanhoMaximoLote     = 20
+	sngpcTamanhoMaximoNotaFisc = 9
+)
+
+var (
+	reSomenteDigitos = regexp.MustCompile(`^[0-9]+$`)
+	reNaoDigitos     = regexp.MustCompile(`[^0-9]`)
+	reUF             = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// EmitenteSNGPC identifica o estabelecimento e o responsável técnico que transmitem o arquivo
+type EmitenteSNGPC struct {
+	CNPJ           string
+	CPFTransmissor string
+}
+
+// ErrSNGPCInvalido indica que o arquivo gerado não atende ao leiaute do SNGPC
+var ErrSNGPCInvalido = errors.New("arquivo SNGPC inválido")
+
+// ErroValidacaoSNGPC reúne os problemas encontrados na validação do arquivo
+type ErroValidacaoSNGPC struct {
+	Problemas []string
+}
+
+func (e *ErroValidacaoSNGPC) Error() string {
+	return fmt.Sprintf("%s: %s", ErrSNGPCInvalido, strings.Join(e.Problemas, "; "))
+}
+
+func (e *ErroValidacaoSNGPC) Unwrap() error { return ErrSNGPCInvalido }
+
+// MensagemSNGPC é o arquivo de movimentação (entradas, dispensações e perdas) do período
+type MensagemSNGPC struct {
+	XMLName   xml.Name         `xml:"mensagemSNGPC"`
+	Xmlns     string           `xml:"xmlns,attr"`
+	Cabecalho CabecalhoSNGPC   `xml:"cabecalho"`
+	Corpo     CorpoMensagemSNG `xml:"corpo"`
+}
+
+// MensagemSNGPCInventario é o arquivo de inventário dos controlados em estoque
+type MensagemSNGPCInventario struct {
+	XMLName   xml.Name       `xml:"mensagemSNGPCInventario"`
+	Xmlns     string         `xml:"xmlns,attr"`
+	Cabecalho CabecalhoSNGPC `xml:"cabecalho"`
+	Corpo     struct {
+		Medicamentos struct {
+			Entradas []MedicamentoSNGPC `xml:"entradaMedicamentos>medicamentoEntrada"`
+		} `xml:"medicamentos"`
+	} `xml:"corpo"`
+}
+
+// CabecalhoSNGPC identifica emitente e período do arquivo
+type CabecalhoSNGPC struct {
+	CNPJEmissor    string `xml:"cnpjEmissor"`
+	CPFTransmissor string `xml:"cpfTransmissor"`
+	DataInicio     string `xml:"dataInicio"`
+	DataFim        string `xml:"dataFim"`
+}
+
+// CorpoMensagemSNG agrupa as entradas e saídas de medicamentos do período
+type CorpoMensagemSNG struct {
+	Medicamentos struct {
+		Entradas []EntradaSNGPC `xml:"entradaMedicamentos"`
+		Vendas   []VendaSNGPC   `xml:"saidaMedicamentoVendaAoConsumidor"`
+		Perdas   []PerdaSNGPC   `xml:"saidaMedicamentoPerda"`
+	} `xml:"medicamentos"`
+}
+
+// EntradaSNGPC é a entrada de um controlado por nota fiscal de compra
+type EntradaSNGPC struct {
+	NotaFiscal struct {
+		Numero      string `xml:"numeroNotaFiscal"`
+		Operacao    string `xml:"tipoOperacaoNotaFiscal"`
+		Data        string `xml:"dataNotaFiscal"`
+		CNPJOrigem  string `xml:"cnpjOrigem"`
+		CNPJDestino string `xml:"cnpjDestino"`
+	} `xml:"notaFiscalEntradaMedicamento"`
+	Medicamento      MedicamentoSNGPC `xml:"medicamentoEntrada"`
+	DataRecebimento  string           `xml:"dataRecebimentoMedicamento"`
+	referenciaOrigem string
+}
+
+// VendaSNGPC é a dispensação de um controlado ao consumidor, com os dados da receita
+type VendaSNGPC struct {
+	TipoReceituario   string `xml:"tipoReceituarioMedicamento"`
+	NumeroNotificacao string `xml:"numeroNotificacaoMedicamento"`
+	DataPrescricao    string `xml:"dataPrescricaoMedicamento"`
+	Prescritor        struct {
+		Nome     string `xml:"nomePrescritor"`
+		Registro string `xml:"numeroRegistroProfissional"`
+		Conselho string `xml:"conselhoProfissional"`
+		UF       string `xml:"UFConselho"`
+	} `xml:"prescritorMedicamento"`
+	Uso       string `xml:"usoMedicamento"`
+	Comprador struct {
+		Nome      string `xml:"nomeComprador"`
+		Documento string `xml:"numeroDocumento"`
+	} `xml:"compradorMedicamento"`
+	Medicamentos     []MedicamentoSNGPC `xml:"medicamentoVenda"`
+	DataVenda        string             `xml:"dataVendaMedicamento"`
+	referenciaOrigem string
+}
+
+// PerdaSNGPC é a saída de um controlado que não foi vendido: descarte de lote vencido ou falta no inventário
+type PerdaSNGPC struct {
+	Motivo           string           `xml:"motivoPerdaMedicamento"`
+	Medicamento      MedicamentoSNGPC `xml:"medicamentoPerda"`
+	DataPerda        string           `xml:"dataPerdaMedicamento"`
+	referenciaOrigem string
+}
+
+// MedicamentoSNGPC identifica o medicamento, o lote e a quantidade movimentada
+type MedicamentoSNGPC struct {
+	RegistroMS string `xml:"registroMSMedicamento"`
+	Lote       string `xml:"numeroLoteMedicamento"`
+	Quantidade int    `xml:"quantidadeMedicamento"`
+	Unidade    string `xml:"unidadeMedidaMedicamento"`
+}
+
+// tipoReceituarioSNGPC relaciona a lista da Portaria 344/98 ao tipo de receituário exigido
+func tipoReceituarioSNGPC(lista string) string {
+	switch {
+	case strings.HasPrefix(lista, "A"):
+		return sngpcNotificacaoReceitaA
+	case strings.HasPrefix(lista, "B"):
+		return sngpcNotificacaoReceitaB
+	case lista == "C2" || lista == "C3":
+		return sngpcNotificacaoEspecial
+	}
+	return sngpcReceitaControle2Vias
+}
+
+// somenteDigitos remove pontuação de documentos (CNPJ, CPF, registro MS)
+func somenteDigitos(s string) string {
+	return reNaoDigitos.ReplaceAllString(s, "")
+}
+
+func novoCabecalhoSNGPC(emitente EmitenteSNGPC, de, ate time.Time) CabecalhoSNGPC {
+	return CabecalhoSNGPC{
+		CNPJEmissor:    somenteDigitos(emitente.CNPJ),
+		CPFTransmissor: somenteDigitos(emitente.CPFTransmissor),
+		DataInicio:     de.Format(sngpcFormatoData),
+		DataFim:        ate.AddDate(0, 0, -1).Format(sngpcFormatoData),
+	}
+}
+
+// GerarSNGPCMovimentacao gera o XML do SNGPC com as entradas, vendas e perdas de controlados no período [de, ate).
+// As vendas saem com o que foi dispensado até o fim do período: devoluções posteriores não alteram um período
+// já transmitido. O documento gerado é validado contra o leiaute do SNGPC; períodos com dados obrigatórios
+// faltando (receitas incompletas, entradas sem nota fiscal) retornam *ErroValidacaoSNGPC.
+func (b *Banco) GerarSNGPCMovimentacao(emitente EmitenteSNGPC, de, ate time.Time) ([]byte, error) {
+	msg := MensagemSNGPC{Xmlns: sngpcNamespace, Cabecalho: novoCabecalhoSNGPC(emitente, de, ate)}
+
+	entradas, err := b.entradasControladasSNGPC(emitente, de, ate)
+	if err != nil {
+		return nil, err
+	}
+	vendas, err := b.vendasControladasSNGPC(de, ate)
+	if err != nil {
+		return nil, err
+	}
+	perdas, err := b.perdasControladasSNGPC(de, ate)
+	if err != nil {
+		return nil, err
+	}
+	msg.Corpo.Medicamentos.Entradas = entradas
+	msg.Corpo.Medicamentos.Vendas = vendas
+	msg.Corpo.Medicamentos.Perdas = perdas
+
+	// Problemas que só podem ser apontados com a referência do registro de origem
+	problemas := validarCabecalhoSNGPC(msg.Cabecalho)
+	for _, e := range entradas {
+		problemas = append(problemas, validarEntradaSNGPC(e, e.referenciaOrigem)...)
+	}
+	for _, v := range vendas {
+		problemas = append(problemas, validarVendaSNGPC(v, v.referenciaOrigem)...)
+	}
+	for _, p := range perdas {
+		problemas = append(problemas, validarMedicamentoSNGPC(p.Medicamento, p.referenciaOrigem)...)
+	}
+	if len(problemas) > 0 {
+		return nil, &ErroValidacaoSNGPC{Problemas: problemas}
+	}
+
+	data, err := marshalSNGPC(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidarXMLSNGPC(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GerarSNGPCInventario gera o XML de inventário com o saldo atual, por lote, dos medicamentos controlados.
+func (b *Banco) GerarSNGPCInventario(emitente EmitenteSNGPC, dataInventario time.Time) ([]byte, error) {
+	inicio := time.Date(dataInventario.Year(), dataInventario.Month(), dataInventario.Day(), 0, 0, 0, 0, time.Local)
+	msg := MensagemSNGPCInventario{Xmlns: sngpcNamespace, Cabecalho: novoCabecalhoSNGPC(emitente, inicio, inicio.AddDate(0, 0, 1))}
+
+	query := b.queries.GetQuery(qSelecionarInventarioControlados)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_inventario_controlados' não encontrada")
+	}
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	problemas := validarCabecalhoSNGPC(msg.Cabecalho)
+	for rows.Next() {
+		var nome string
+		var m MedicamentoSNGPC
+		if err := rows.Scan(&nome, &m.RegistroMS, &m.Lote, &m.Quantidade); err != nil {
+			return nil, fmt.Errorf("erro ao escanear inventário de controlados: %w", err)
+		}
+		m.RegistroMS = somenteDigitos(m.RegistroMS)
+		m.Unidade = sngpcUnidadeCaixa
+		problemas = append(problemas, validarMedicamentoSNGPC(m, "inventário de '"+nome+"'")...)
+		msg.Corpo.Medicamentos.Entradas = append(msg.Corpo.Medicamentos.Entradas, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(problemas) > 0 {
+		return nil, &ErroValidacaoSNGPC{Problemas: problemas}
+	}
+
+	data, err := marshalSNGPC(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidarXMLSNGPC(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func marshalSNGPC(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gerar XML do SNGPC: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// entradasControladasSNGPC lista, por lote, as entradas de controlados no período.
+func (b *Banco) entradasControladasSNGPC(emitente EmitenteSNGPC, de, ate time.Time) ([]EntradaSNGPC, error) {
+	query := b.queries.GetQuery(qSelecionarEntradasControlados)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_entradas_controlados' não encontrada")
+	}
+	rows, err := b.db.Query(query, de, ate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entradas []EntradaSNGPC
+	for rows.Next() {
+		var e EntradaSNGPC
+		var movID, nome string
+		var data time.Time
+		var notaFiscal, cnpjFornecedor sql.NullString
+		if err := rows.Scan(&movID, &data, &nome, &e.Medicamento.RegistroMS, &e.Medicamento.Lote, &e.Medicamento.Quantidade,
+			&notaFiscal, &cnpjFornecedor); err != nil {
+			return nil, fmt.Errorf("erro ao escanear entrada de controlado: %w", err)
+		}
+		e.NotaFiscal.Numero = notaFiscal.String
+		e.NotaFiscal.Operacao = sngpcOperacaoCompra
+		e.NotaFiscal.Data = data.Format(sngpcFormatoData)
+		e.NotaFiscal.CNPJOrigem = somenteDigitos(cnpjFornecedor.String)
+		e.NotaFiscal.CNPJDestino = somenteDigitos(emitente.CNPJ)
+		e.Medicamento.RegistroMS = somenteDigitos(e.Medicamento.RegistroMS)
+		e.Medicamento.Unidade = sngpcUnidadeCaixa
+		e.DataRecebimento = data.Format(sngpcFormatoData)
+		e.referenciaOrigem = fmt.Sprintf("entrada %s de '%s'", movID, nome)
+		entradas = append(entradas, e)
+	}
+	return entradas, rows.Err()
+}
+
+// vendasControladasSNGPC lista as vendas de controlados no período, uma por item, com a receita retida.
+// As quantidades descontam só as devoluções feitas até o fim do período, para que o arquivo de um período
+// já transmitido não mude quando um item é devolvido depois. Vendas canceladas depois do período continuam nele.
+func (b *Banco) vendasControladasSNGPC(de, ate time.Time) ([]VendaSNGPC, error) {
+	query := b.queries.GetQuery(qSelecionarVendasControladosSngpc)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_vendas_controlados_sngpc' não encontrada")
+	}
+	rows, err := b.db.Query(query, ate, ate, de, ate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vendas []VendaSNGPC
+	porItem := make(map[int64]int) // venda_item_id -> índice em vendas
+	for rows.Next() {
+		var vendaID, itemID int64
+		var data time.Time
+		var nome, lista, registroMS string
+		var lote sql.NullString
+		var quantidadeLote, quantidadeItem int
+		var numero, dataReceita, prescritorNome, crm, uf, pacienteNome, pacienteDoc sql.NullString
+		if err := rows.Scan(&vendaID, &itemID, &data, &nome, &lista, &registroMS, &quantidadeItem, &lote, &quantidadeLote,
+			&numero, &dataReceita, &prescritorNome, &crm, &uf, &pacienteNome, &pacienteDoc); err != nil {
+			return nil, fmt.Errorf("erro ao escanear venda de controlado: %w", err)
+		}
+		// Itens totalmente devolvidos e lotes estornados dentro do período não foram dispensados
+		if quantidadeItem <= 0 || (lote.Valid && quantidadeLote <= 0) {
+			continue
+		}
+
+		idx, ok := porItem[itemID]
+		if !ok {
+			var v VendaSNGPC
+			v.TipoReceituario = tipoReceituarioSNGPC(lista)
+			v.NumeroNotificacao = numero.String
+			v.DataPrescricao = dataReceita.String
+			if d, err := parseData(dataReceita.String); err == nil {
+				v.DataPrescricao = d.Format(sngpcFormatoData)
+			}
+			v.Prescritor.Nome = prescritorNome.String
+			v.Prescritor.Registro = crm.String
+			v.Prescritor.Conselho = sngpcConselhoCRM
+			v.Prescritor.UF = strings.ToUpper(uf.String)
+			v.Uso = sngpcUsoHumano
+			v.Comprador.Nome = pacienteNome.String
+			v.Comprador.Documento = somenteDigitos(pacienteDoc.String)
+			v.DataVenda = data.Format(sngpcFormatoData)
+			v.referenciaOrigem = fmt.Sprintf("venda %d, item '%s'", vendaID, nome)
+			vendas = append(vendas, v)
+			idx = len(vendas) - 1
+			porItem[itemID] = idx
+		}
+
+		// Itens vendidos sem lote associado aparecem com o lote em branco e são apontados na validação
+		quantidade := quantidadeLote
+		if !lote.Valid {
+			quantidade = quantidadeItem
+		}
+		vendas[idx].Medicamentos = append(vendas[idx].Medicamentos, MedicamentoSNGPC{
+			RegistroMS: somenteDigitos(registroMS),
+			Lote:       lote.String,
+			Quantidade: quantidade,
+			Unidade:    sngpcUnidadeCaixa,
+		})
+	}
+	return vendas, rows.Err()
+}
+
+// perdasControladasSNGPC lista, por lote, as saídas de controlados no período que não foram vendas:
+// saídas avulsas, como o descarte de um lote vencido, e as faltas encontradas no inventário.
+func (b *Banco) perdasControladasSNGPC(de, ate time.Time) ([]PerdaSNGPC, error) {
+	query, err := b.queryObrigatoria(qSelecionarPerdasControlados)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := b.db.Query(query, de, ate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perdas []PerdaSNGPC
+	for rows.Next() {
+		var p PerdaSNGPC
+		var movID, tipo, nome string
+		var data time.Time
+		var validade sql.NullString
+		if err := rows.Scan(&movID, &data, &tipo, &nome, &p.Medicamento.RegistroMS, &p.Medicamento.Lote, &validade,
+			&p.Medicamento.Quantidade); err != nil {
+			return nil, fmt.Errorf("erro ao escanear saída de controlado: %w", err)
+		}
+		// Lotes que já tinham vencido saem por vencimento; o resto, inclusive as faltas do inventário,
+		// como perda no processo
+		p.Motivo = sngpcPerdaNoProcesso
+		if v, err := parseData(validade.String); err == nil && v.Before(data) {
+			p.Motivo = sngpcPerdaVencimento
+		}
+		p.Medicamento.RegistroMS = somenteDigitos(p.Medicamento.RegistroMS)
+		p.Medicamento.Unidade = sngpcUnidadeCaixa
+		p.DataPerda = data.Format(sngpcFormatoData)
+		p.referenciaOrigem = fmt.Sprintf("%s %s de '%s'", tipo, movID, nome)
+		perdas = append(perdas, p)
+	}
+	return perdas, rows.Err()
+}
+
+// ValidarXMLSNGPC confere um arquivo do SNGPC (movimentação ou inventário) contra o leiaute publicado
+// pela ANVISA (sngpc.xsd): elementos obrigatórios e sua ordem, tamanhos, formatos e códigos aceitos.
+// Regras que o esquema não expressa, como o período do cabeçalho, são conferidas em seguida.
+func ValidarXMLSNGPC(data []byte) error {
+	if problemas := esquemaSNGPC.validar(data); len(problemas) > 0 {
+		return &ErroValidacaoSNGPC{Problemas: problemas}
+	}
+	var msg struct {
+		Cabecalho CabecalhoSNGPC `xml:"cabecalho"`
+	}
+	if err := xml.Unmarshal(data, &msg); err != nil {
+		return &ErroValidacaoSNGPC{Problemas: []string{"XML malformado: " + err.Error()}}
+	}
+	if problemas := validarCabecalhoSNGPC(msg.Cabecalho); len(problemas) > 0 {
+		return &ErroValidacaoSNGPC{Problemas: problemas}
+	}
+	return nil
+}
+
+func validarCabecalhoSNGPC(c CabecalhoSNGPC) []string {
+	var problemas []string
+	if len(c.CNPJEmissor) != 14 || !reSomenteDigitos.MatchString(c.CNPJEmissor) {
+		problemas = append(problemas, "cabecalho: cnpjEmissor deve ter 14 dígitos (configure SNGPC_CNPJ)")
+	}
+	if len(c.CPFTransmissor) != 11 || !reSomenteDigitos.MatchString(c.CPFTransmissor) {
+		problemas = append(problemas, "cabecalho: cpfTransmissor deve ter 11 dígitos (configure SNGPC_CPF_TRANSMISSOR)")
+	}
+	inicio, errInicio := time.Parse(sngpcFormatoData, c.DataInicio)
+	fim, errFim := time.Parse(sngpcFormatoData, c.DataFim)
+	if errInicio != nil || errFim != nil {
+		problemas = append(problemas, "cabecalho: dataInicio e dataFim devem estar no formato AAAA-MM-DD")
+	} else if fim.Before(inicio) {
+		problemas = append(problemas, "cabecalho: dataFim anterior a dataInicio")
+	}
+	return problemas
+}
+
+func validarMedicamentoSNGPC(m MedicamentoSNGPC, ref string) []string {
+	var problemas []string
+	if len(m.RegistroMS) != sngpcTamanhoRegistroMS || !reSomenteDigitos.MatchString(m.RegistroMS) {
+		problemas = append(problemas, fmt.Sprintf("%s: registro MS '%s' deve ter %d dígitos", ref, m.RegistroMS, sngpcTamanhoRegistroMS))
+	}
+	if m.Lote == "" || len(m.Lote) > sngpcTamanhoMaximoLote {
+		problemas = append(problemas, fmt.Sprintf("%s: número do lote ausente ou com mais de %d caracteres", ref, sngpcTamanhoMaximoLote))
+	}
+	if m.Quantidade <= 0 {
+		problemas = append(problemas, fmt.Sprintf("%s: quantidade deve ser maior que zero", ref))
+	}
+	if m.Unidade == "" {
+		problemas = append(problemas, fmt.Sprintf("%s: unidade de medida ausente", ref))
+	}
+	return problemas
+}
+
+func validarEntradaSNGPC(e EntradaSNGPC, ref string) []string {
+	var problemas []string
+	nf := e.NotaFiscal
+	if nf.Numero == "" || len(nf.Numero) > sngpcTamanhoMaximoNotaFisc || !reSomenteDigitos.MatchString(nf.Numero) {
+		problemas = append(problemas, fmt.Sprintf("%s: número da nota fiscal ausente ou inválido", ref))
+	}
+	if len(nf.CNPJOrigem) != 14 {
+		problemas = append(problemas, fmt.Sprintf("%s: CNPJ do fornecedor ausente ou inválido", ref))
+	}
+	if len(nf.CNPJDestino) != 14 {
+		problemas = append(problemas, fmt.Sprintf("%s: CNPJ de destino ausente ou inválido", ref))
+	}
+	if _, err := time.Parse(sngpcFormatoData, nf.Data); err != nil {
+		problemas = append(problemas, fmt.Sprintf("%s: data da nota fiscal inválida", ref))
+	}
+	if _, err := time.Parse(sngpcFormatoData, e.DataRecebimento); err != nil {
+		problemas = append(problemas, fmt.Sprintf("%s: data de recebimento inválida", ref))
+	}
+	return append(problemas, validarMedicamentoSNGPC(e.Medicamento, ref)...)
+}
+
+func validarVendaSNGPC(v VendaSNGPC, ref string) []string {
+	var problemas []string
+	if v.NumeroNotificacao == "" {
+		problemas = append(problemas, fmt.Sprintf("%s: número da receita/notificação ausente", ref))
+	}
+	if _, err := time.Parse(sngpcFormatoData, v.DataPrescricao); err != nil {
+		problemas = append(problemas, fmt.Sprintf("%s: data da prescrição ausente ou inválida", ref))
+	}
+	if v.Prescritor.Nome == "" {
+		problemas = append(problemas, fmt.Sprintf("%s: nome do prescritor ausente", ref))
+	}
+	if v.Prescritor.Registro == "" {
+		problemas = append(problemas, fmt.Sprintf("%s: registro profissional (CRM) do prescritor ausente", ref))
+	}
+	if !reUF.MatchString(v.Prescritor.UF) {
+		problemas = append(problemas, fmt.Sprintf("%s: UF do conselho do prescritor ausente ou inválida", ref))
+	}
+	if v.Comprador.Documento == "" {
+		problemas = append(problemas, fmt.Sprintf("%s: documento do paciente ausente", ref))
+	}
+	if _, err := time.Parse(sngpcFormatoData, v.DataVenda); err != nil {
+		problemas = append(problemas, fmt.Sprintf("%s: data da venda inválida", ref))
+	}
+	if len(v.Medicamentos) == 0 {
+		problemas = append(problemas, fmt.Sprintf("%s: nenhum medicamento informado", ref))
+	}
+	for _, m := range v.Medicamentos {
+		problemas = append(problemas, validarMedicamentoSNGPC(m, ref)...)
+	}
+	return problemas
+}
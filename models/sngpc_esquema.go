@@ -0,0 +1,310 @@
+package models
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// sngpcXSD é o leiaute do SNGPC contra o qual os arquivos gerados são validados
+//
+//go:embed sngpc.xsd
+var sngpcXSD []byte
+
+var esquemaSNGPC = mustCarregarEsquemaXSD(sngpcXSD)
+
+// esquemaXSD é o subconjunto do XML Schema usado no leiaute do SNGPC: elementos globais, tipos
+// complexos com sequência de elementos e tipos simples restritos por padrão, tamanho ou enumeração.
+type esquemaXSD struct {
+	namespace string
+	elementos map[string]string
+	complexos map[string][]particulaXSD
+	simples   map[string]tipoSimplesXSD
+}
+
+// particulaXSD é um elemento de uma sequência, com o número de ocorrências permitido (max -1 = unbounded)
+type particulaXSD struct {
+	nome, tipo string
+	min, max   int
+}
+
+type tipoSimplesXSD struct {
+	base                            string
+	padroes                         []*regexp.Regexp
+	tamanho, tamanhoMin, tamanhoMax int // -1 quando a faceta não é informada
+	valores                         []string
+}
+
+type elementoXSD struct {
+	Nome      string `xml:"name,attr"`
+	Tipo      string `xml:"type,attr"`
+	MinOccurs string `xml:"minOccurs,attr"`
+	MaxOccurs string `xml:"maxOccurs,attr"`
+}
+
+type facetaXSD struct {
+	Valor string `xml:"value,attr"`
+}
+
+type documentoXSD struct {
+	TargetNamespace string        `xml:"targetNamespace,attr"`
+	Elementos       []elementoXSD `xml:"element"`
+	Complexos       []struct {
+		Nome      string        `xml:"name,attr"`
+		Sequencia []elementoXSD `xml:"sequence>element"`
+	} `xml:"complexType"`
+	Simples []struct {
+		Nome      string `xml:"name,attr"`
+		Restricao struct {
+			Base        string      `xml:"base,attr"`
+			Padroes     []facetaXSD `xml:"pattern"`
+			Tamanho     *facetaXSD  `xml:"length"`
+			TamanhoMin  *facetaXSD  `xml:"minLength"`
+			TamanhoMax  *facetaXSD  `xml:"maxLength"`
+			Enumeracoes []facetaXSD `xml:"enumeration"`
+		} `xml:"restriction"`
+	} `xml:"simpleType"`
+}
+
+// mustCarregarEsquemaXSD lê o esquema embutido; um esquema inválido é erro de programação.
+func mustCarregarEsquemaXSD(data []byte) *esquemaXSD {
+	e, err := carregarEsquemaXSD(data)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func carregarEsquemaXSD(data []byte) (*esquemaXSD, error) {
+	var doc documentoXSD
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("esquema XSD inválido: %w", err)
+	}
+	e := &esquemaXSD{
+		namespace: doc.TargetNamespace,
+		elementos: make(map[string]string),
+		complexos: make(map[string][]particulaXSD),
+		simples:   make(map[string]tipoSimplesXSD),
+	}
+	for _, el := range doc.Elementos {
+		e.elementos[el.Nome] = tipoLocalXSD(el.Tipo)
+	}
+	for _, c := range doc.Complexos {
+		var sequencia []particulaXSD
+		for _, el := range c.Sequencia {
+			p := particulaXSD{nome: el.Nome, tipo: tipoLocalXSD(el.Tipo), min: 1, max: 1}
+			if el.MinOccurs != "" {
+				p.min, _ = strconv.Atoi(el.MinOccurs)
+			}
+			switch el.MaxOccurs {
+			case "":
+			case "unbounded":
+				p.max = -1
+			default:
+				p.max, _ = strconv.Atoi(el.MaxOccurs)
+			}
+			sequencia = append(sequencia, p)
+		}
+		e.complexos[c.Nome] = sequencia
+	}
+	for _, s := range doc.Simples {
+		r := s.Restricao
+		t := tipoSimplesXSD{base: tipoLocalXSD(r.Base)}
+		for _, p := range r.Padroes {
+			// Os padrões do XML Schema valem para o valor inteiro
+			re, err := regexp.Compile("^(?:" + p.Valor + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("esquema XSD inválido: padrão do tipo %s: %w", s.Nome, err)
+			}
+			t.padroes = append(t.padroes, re)
+		}
+		t.tamanho, t.tamanhoMin, t.tamanhoMax = valorFacetaXSD(r.Tamanho), valorFacetaXSD(r.TamanhoMin), valorFacetaXSD(r.TamanhoMax)
+		for _, v := range r.Enumeracoes {
+			t.valores = append(t.valores, v.Valor)
+		}
+		e.simples[s.Nome] = t
+	}
+	return e, nil
+}
+
+// valorFacetaXSD retorna o valor numérico da faceta, ou -1 se ela não foi informada.
+func valorFacetaXSD(f *facetaXSD) int {
+	if f == nil {
+		return -1
+	}
+	v, err := strconv.Atoi(f.Valor)
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// tipoLocalXSD remove o prefixo do namespace do esquema ("tns:tCNPJ" vira "tCNPJ");
+// os tipos do XML Schema mantêm o prefixo "xs:".
+func tipoLocalXSD(tipo string) string {
+	if strings.HasPrefix(tipo, "xs:") {
+		return tipo
+	}
+	if i := strings.Index(tipo, ":"); i >= 0 {
+		return tipo[i+1:]
+	}
+	return tipo
+}
+
+// noXML é um elemento do documento validado
+type noXML struct {
+	nome   xml.Name
+	filhos []*noXML
+	texto  strings.Builder
+}
+
+func lerDocumentoXML(data []byte) (*noXML, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var raiz *noXML
+	var pilha []*noXML
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			no := &noXML{nome: t.Name}
+			if len(pilha) > 0 {
+				pai := pilha[len(pilha)-1]
+				pai.filhos = append(pai.filhos, no)
+			} else if raiz == nil {
+				raiz = no
+			}
+			pilha = append(pilha, no)
+		case xml.EndElement:
+			pilha = pilha[:len(pilha)-1]
+		case xml.CharData:
+			if len(pilha) > 0 {
+				pilha[len(pilha)-1].texto.Write(t)
+			}
+		}
+	}
+	if raiz == nil {
+		return nil, errors.New("documento vazio")
+	}
+	return raiz, nil
+}
+
+// validar confere o documento contra o esquema e retorna os problemas, com o caminho de cada elemento.
+func (e *esquemaXSD) validar(data []byte) []string {
+	raiz, err := lerDocumentoXML(data)
+	if err != nil {
+		return []string{"XML malformado: " + err.Error()}
+	}
+	if raiz.nome.Space != e.namespace {
+		return []string{fmt.Sprintf("namespace '%s' inválido, esperado '%s'", raiz.nome.Space, e.namespace)}
+	}
+	tipo, ok := e.elementos[raiz.nome.Local]
+	if !ok {
+		return []string{fmt.Sprintf("elemento raiz '%s' desconhecido", raiz.nome.Local)}
+	}
+	var problemas []string
+	e.validarNo(raiz, tipo, "/"+raiz.nome.Local, &problemas)
+	return problemas
+}
+
+func (e *esquemaXSD) validarNo(no *noXML, tipo, caminho string, problemas *[]string) {
+	sequencia, complexo := e.complexos[tipo]
+	if !complexo {
+		if len(no.filhos) > 0 {
+			*problemas = append(*problemas, fmt.Sprintf("%s: elemento '%s' não previsto", caminho, no.filhos[0].nome.Local))
+			return
+		}
+		if problema := e.validarValor(strings.TrimSpace(no.texto.String()), tipo); problema != "" {
+			*problemas = append(*problemas, caminho+": "+problema)
+		}
+		return
+	}
+	if strings.TrimSpace(no.texto.String()) != "" {
+		*problemas = append(*problemas, caminho+": texto fora dos elementos")
+	}
+
+	i := 0
+	for _, p := range sequencia {
+		ocorrencias := 0
+		for i < len(no.filhos) && no.filhos[i].nome.Local == p.nome && (p.max < 0 || ocorrencias < p.max) {
+			filho := no.filhos[i]
+			ocorrencias++
+			i++
+			caminhoFilho := caminho + "/" + p.nome
+			if p.max != 1 {
+				caminhoFilho += fmt.Sprintf("[%d]", ocorrencias)
+			}
+			if filho.nome.Space != e.namespace {
+				*problemas = append(*problemas, fmt.Sprintf("%s: namespace '%s' inválido", caminhoFilho, filho.nome.Space))
+				continue
+			}
+			e.validarNo(filho, p.tipo, caminhoFilho, problemas)
+		}
+		if ocorrencias < p.min {
+			*problemas = append(*problemas, fmt.Sprintf("%s: elemento obrigatório '%s' ausente", caminho, p.nome))
+		}
+	}
+	// O primeiro elemento que sobra está fora de ordem ou não existe no leiaute
+	if i < len(no.filhos) {
+		*problemas = append(*problemas, fmt.Sprintf("%s: elemento '%s' não previsto nesta posição", caminho, no.filhos[i].nome.Local))
+	}
+}
+
+// validarValor confere o conteúdo de um elemento simples; retorna vazio se o valor é válido.
+func (e *esquemaXSD) validarValor(valor, tipo string) string {
+	t, ok := e.simples[tipo]
+	if !ok {
+		t = tipoSimplesXSD{base: tipo, tamanho: -1, tamanhoMin: -1, tamanhoMax: -1}
+	}
+	switch t.base {
+	case "xs:string":
+	case "xs:date":
+		if _, err := time.Parse(sngpcFormatoData, valor); err != nil {
+			return fmt.Sprintf("data '%s' deve estar no formato AAAA-MM-DD", valor)
+		}
+	case "xs:positiveInteger":
+		if n, err := strconv.ParseUint(valor, 10, 64); err != nil || n == 0 {
+			return fmt.Sprintf("'%s' deve ser um inteiro maior que zero", valor)
+		}
+	default:
+		return fmt.Sprintf("tipo '%s' não suportado pelo esquema", tipo)
+	}
+
+	tamanho := utf8.RuneCountInString(valor)
+	if t.tamanho >= 0 && tamanho != t.tamanho {
+		return fmt.Sprintf("'%s' deve ter %d caracteres", valor, t.tamanho)
+	}
+	if t.tamanhoMin >= 0 && tamanho < t.tamanhoMin {
+		return fmt.Sprintf("'%s' deve ter ao menos %d caractere(s)", valor, t.tamanhoMin)
+	}
+	if t.tamanhoMax >= 0 && tamanho > t.tamanhoMax {
+		return fmt.Sprintf("'%s' deve ter no máximo %d caracteres", valor, t.tamanhoMax)
+	}
+	for _, re := range t.padroes {
+		if !re.MatchString(valor) {
+			return fmt.Sprintf("'%s' não segue o padrão %s do tipo %s", valor, strings.TrimSuffix(strings.TrimPrefix(re.String(), "^(?:"), ")$"), tipo)
+		}
+	}
+	if len(t.valores) > 0 {
+		for _, v := range t.valores {
+			if v == valor {
+				return ""
+			}
+		}
+		return fmt.Sprintf("'%s' não é um dos valores aceitos (%s)", valor, strings.Join(t.valores, ", "))
+	}
+	return ""
+}
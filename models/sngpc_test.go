@@ -0,0 +1,178 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var emitenteTeste = EmitenteSNGPC{CNPJ: "12.345.678/0001-95", CPFTransmissor: "123.456.789-09"}
+
+func TestGerarSNGPCMovimentacao(t *testing.T) {
+	t.Parallel()
+	// As vendas são gravadas com a hora do banco; o relógio só move as movimentações de estoque
+	agora := time.Now()
+	b := abrirBancoTesteCom(t, ConfigBanco{Driver: DriverSQLite, Caminho: ":memory:", Relogio: func() time.Time { return agora }})
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	hoje := time.Date(agora.Year(), agora.Month(), agora.Day(), 0, 0, 0, 0, time.Local)
+	amanha := hoje.AddDate(0, 0, 1)
+
+	b1 := "B1"
+	med := &Medicamento{ID: "1", Nome: "Clonazepam 2mg", CodigoANVISA: "1.2345.6789.012-3", ListaControle: &b1, Preco: 20}
+	if !assert.NoError(t, b.AddMedicamento(med, 0)) {
+		return
+	}
+	if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoEntrada, Quantidade: 10, Lote: "L1",
+		Validade: "2030-06-30", NotaFiscal: "4521", CNPJFornecedor: "98.765.432/0001-10"})) {
+		return
+	}
+	if _, err := b.AbrirCaixa(1, 0); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+	resumo, err := b.RegistrarVenda(RegistrarVendaRequest{
+		Itens: []ItemVendaRequest{{MedicamentoID: 1, Quantidade: 3, Receita: &Receita{
+			NumeroReceita:     "B1-778899",
+			DataReceita:       agora.Format("2006-01-02"),
+			PrescritorNome:    "Dra. Marta Lima",
+			PrescritorCRM:     "12345",
+			PrescritorUF:      "SP",
+			PacienteNome:      "João Souza",
+			PacienteDocumento: "123.456.789-09",
+		}}},
+		Pagamentos: []PagamentoVenda{{Forma: FormaDinheiro, Valor: 60}},
+	}, 1, RoleFarmaceutico)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Descarte de uma unidade e falta de outra no inventário: saídas que não são vendas
+	if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoSaida, Quantidade: 1, Lote: "L1", Observacao: "embalagem violada"})) {
+		return
+	}
+	inv, err := b.AbrirInventario("", "", 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+	contada := 5
+	if _, err := b.RegistrarContagensInventario(inv.ID, []ContagemInventarioRequest{{MedicamentoID: "1", NumeroLote: "L1", Quantidade: &contada}}, 1); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := b.AprovarInventario(inv.ID, "Contagem mensal", 1); !assert.NoError(t, err) {
+		return
+	}
+
+	xmlHoje, err := b.GerarSNGPCMovimentacao(emitenteTeste, hoje, amanha)
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc := string(xmlHoje)
+	assert.Equal(t, 1, strings.Count(doc, "<entradaMedicamentos>"))
+	assert.Contains(t, doc, "<numeroNotaFiscal>4521</numeroNotaFiscal>")
+	assert.Equal(t, 1, strings.Count(doc, "<saidaMedicamentoVendaAoConsumidor>"))
+	assert.Contains(t, doc, "<quantidadeMedicamento>3</quantidadeMedicamento>")
+	assert.Equal(t, 2, strings.Count(doc, "<saidaMedicamentoPerda>"))
+	assert.Equal(t, 2, strings.Count(doc, "<motivoPerdaMedicamento>"+sngpcPerdaNoProcesso+"</motivoPerdaMedicamento>"))
+	assert.NoError(t, ValidarXMLSNGPC(xmlHoje))
+
+	// O período anterior não tem nada de hoje
+	xmlOntem, err := b.GerarSNGPCMovimentacao(emitenteTeste, hoje.AddDate(0, 0, -1), hoje)
+	if assert.NoError(t, err) {
+		assert.NotContains(t, string(xmlOntem), "Medicamento")
+	}
+
+	// Devolução dois dias depois: o arquivo de hoje, já transmitido, não muda
+	agora = agora.AddDate(0, 0, 2)
+	venda, err := b.GetVenda(resumo.VendaID)
+	if !assert.NoError(t, err) || !assert.Len(t, venda.Itens, 1) {
+		return
+	}
+	if _, err := b.DevolverItensVenda(resumo.VendaID, DevolucaoVendaRequest{Itens: []ItemDevolucaoRequest{{VendaItemID: venda.Itens[0].ID, Quantidade: 1}}}, 1); !assert.NoError(t, err) {
+		return
+	}
+	xmlDepois, err := b.GerarSNGPCMovimentacao(emitenteTeste, hoje, amanha)
+	if assert.NoError(t, err) {
+		assert.Equal(t, doc, string(xmlDepois))
+	}
+	// Num período que inclui a devolução, a venda sai com o que ficou com o cliente
+	xmlTresDias, err := b.GerarSNGPCMovimentacao(emitenteTeste, hoje, hoje.AddDate(0, 0, 3))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(xmlTresDias), "<quantidadeMedicamento>2</quantidadeMedicamento>")
+		assert.NotContains(t, string(xmlTresDias), "<quantidadeMedicamento>3</quantidadeMedicamento>")
+	}
+}
+
+func TestValidarXMLSNGPC(t *testing.T) {
+	valido := `<?xml version="1.0" encoding="UTF-8"?>
+<mensagemSNGPC xmlns="urn:sngpc-schema">
+  <cabecalho>
+    <cnpjEmissor>12345678000195</cnpjEmissor>
+    <cpfTransmissor>12345678909</cpfTransmissor>
+    <dataInicio>2025-03-01</dataInicio>
+    <dataFim>2025-03-31</dataFim>
+  </cabecalho>
+  <corpo>
+    <medicamentos>
+      <saidaMedicamentoVendaAoConsumidor>
+        <tipoReceituarioMedicamento>2</tipoReceituarioMedicamento>
+        <numeroNotificacaoMedicamento>B1-778899</numeroNotificacaoMedicamento>
+        <dataPrescricaoMedicamento>2025-03-10</dataPrescricaoMedicamento>
+        <prescritorMedicamento>
+          <nomePrescritor>Dra. Marta Lima</nomePrescritor>
+          <numeroRegistroProfissional>12345</numeroRegistroProfissional>
+          <conselhoProfissional>CRM</conselhoProfissional>
+          <UFConselho>SP</UFConselho>
+        </prescritorMedicamento>
+        <usoMedicamento>1</usoMedicamento>
+        <compradorMedicamento>
+          <nomeComprador>João Souza</nomeComprador>
+          <numeroDocumento>12345678909</numeroDocumento>
+        </compradorMedicamento>
+        <medicamentoVenda>
+          <registroMSMedicamento>1234567890123</registroMSMedicamento>
+          <numeroLoteMedicamento>L1</numeroLoteMedicamento>
+          <quantidadeMedicamento>3</quantidadeMedicamento>
+          <unidadeMedidaMedicamento>1</unidadeMedidaMedicamento>
+        </medicamentoVenda>
+        <dataVendaMedicamento>2025-03-12</dataVendaMedicamento>
+      </saidaMedicamentoVendaAoConsumidor>
+      <saidaMedicamentoPerda>
+        <motivoPerdaMedicamento>3</motivoPerdaMedicamento>
+        <medicamentoPerda>
+          <registroMSMedicamento>1234567890123</registroMSMedicamento>
+          <numeroLoteMedicamento>L0</numeroLoteMedicamento>
+          <quantidadeMedicamento>2</quantidadeMedicamento>
+          <unidadeMedidaMedicamento>1</unidadeMedidaMedicamento>
+        </medicamentoPerda>
+        <dataPerdaMedicamento>2025-03-15</dataPerdaMedicamento>
+      </saidaMedicamentoPerda>
+    </medicamentos>
+  </corpo>
+</mensagemSNGPC>`
+	assert.NoError(t, ValidarXMLSNGPC([]byte(valido)))
+
+	for problema, trocar := range map[string][2]string{
+		"UFConselho: 'Sao' não segue o padrão":             {"<UFConselho>SP<", "<UFConselho>Sao<"},
+		"registroMSMedicamento: '123' não segue o padrão":  {"<registroMSMedicamento>1234567890123</registroMSMedicamento>\n          <numeroLoteMedicamento>L1", "<registroMSMedicamento>123</registroMSMedicamento>\n          <numeroLoteMedicamento>L1"},
+		"quantidadeMedicamento: '0' deve ser um inteiro":   {"<quantidadeMedicamento>3<", "<quantidadeMedicamento>0<"},
+		"motivoPerdaMedicamento: '9' não é um dos valores": {"<motivoPerdaMedicamento>3<", "<motivoPerdaMedicamento>9<"},
+		"dataVendaMedicamento: data '12/03/2025'":          {"2025-03-12", "12/03/2025"},
+		"elemento obrigatório 'nomePrescritor' ausente":    {"<nomePrescritor>Dra. Marta Lima</nomePrescritor>", ""},
+		// Fora de ordem: a perda vem depois das vendas
+		"elemento 'saidaMedicamentoVendaAoConsumidor' não previsto nesta posição": {"<medicamentos>\n      <saidaMedicamentoVendaAoConsumidor>", "<medicamentos>\n      <saidaMedicamentoPerda/><saidaMedicamentoVendaAoConsumidor>"},
+		"elemento 'observacao' não previsto":                                      {"<usoMedicamento>1</usoMedicamento>", "<usoMedicamento>1</usoMedicamento><observacao>x</observacao>"},
+		"namespace 'urn:outro' inválido":                                          {`xmlns="urn:sngpc-schema"`, `xmlns="urn:outro"`},
+		"dataFim anterior a dataInicio":                                           {"<dataFim>2025-03-31<", "<dataFim>2025-02-28<"},
+		"XML malformado":                                                          {"</corpo>", ""},
+	} {
+		if !assert.Contains(t, valido, trocar[0], problema) {
+			continue
+		}
+		err := ValidarXMLSNGPC([]byte(strings.Replace(valido, trocar[0], trocar[1], 1)))
+		assert.ErrorIs(t, err, ErrSNGPCInvalido, problema)
+		assert.ErrorContains(t, err, problema)
+	}
+}
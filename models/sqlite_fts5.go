@@ -0,0 +1,6 @@
+//go:build sqlite_fts5
+
+package models
+
+// fts5Compilado indica que o SQLite foi compilado com FTS5 (build tag sqlite_fts5 do go-sqlite3)
+const fts5Compilado = true
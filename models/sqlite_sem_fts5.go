@@ -0,0 +1,7 @@
+//go:build !sqlite_fts5
+
+package models
+
+// fts5Compilado indica que o SQLite foi compilado com FTS5 (build tag sqlite_fts5 do go-sqlite3).
+// Sem ele, a busca de medicamentos volta a comparar o termo com LIKE.
+const fts5Compilado = false
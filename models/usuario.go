@@ -0,0 +1,382 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Papéis de usuário aceitos pelo sistema
+const (
+	RoleAdmin        = "admin"
+	RoleFarmaceutico = "farmaceutico"
+	RoleAtendente    = "atendente"
+)
+
+const (
+	maxTentativasLogin = 5
+	tempoBloqueio      = 15 * time.Minute
+)
+
+// ErrCredenciaisInvalidas é retornado quando usuário ou senha não conferem
+var ErrCredenciaisInvalidas = errors.New("credenciais inválidas")
+
+// ErrUsuarioBloqueado é retornado quando o usuário excedeu o número de tentativas de login
+var ErrUsuarioBloqueado = errors.New("usuário bloqueado temporariamente por excesso de tentativas")
+
+// ErrSessaoInvalida é retornado quando o usuário do token não existe mais ou foi desativado
+var ErrSessaoInvalida = errors.New("sessão inválida: usuário inexistente ou desativado")
+
+// Usuario representa um usuário do sistema
+type Usuario struct {
+	ID              int        `json:"id"`
+	Username        string     `json:"username"`
+	Nome            string     `json:"nome"`
+	PasswordHash    string     `json:"-"`
+	Role            string     `json:"role"`
+	Ativo           bool       `json:"ativo"`
+	TentativasLogin int        `json:"-"`
+	UltimaTentativa *time.Time `json:"-"`
+	UltimoLogin     *time.Time `json:"ultimo_login,omitempty"`
+	CriadoEm        time.Time  `json:"criado_em"`
+}
+
+// UsuarioRequest é o que a API recebe para criar ou atualizar um usuário
+type UsuarioRequest struct {
+	Username string `json:"username"`
+	Nome     string `json:"nome"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	Ativo    *bool  `json:"ativo"`
+}
+
+// RoleValida informa se o papel informado é um dos papéis aceitos.
+func RoleValida(role string) bool {
+	switch role {
+	case RoleAdmin, RoleFarmaceutico, RoleAtendente:
+		return true
+	}
+	return false
+}
+
+// garantirAdminPadrao cria o usuário 'admin' caso ainda não exista nenhum usuário cadastrado.
+func (b *Banco) garantirAdminPadrao() error {
+	query, err := b.queryObrigatoria(qContarUsuarios)
+	if err != nil {
+		return err
+	}
+	var total int
+	if err := b.db.QueryRow(query).Scan(&total); err != nil {
+		return err
+	}
+	if total > 0 {
+		return nil
+	}
+
+	// Senha: senha123 (deve ser alterada após o primeiro acesso)
+	hash, err := bcrypt.GenerateFromPassword([]byte("senha123"), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("erro ao gerar hash da senha do admin: %w", err)
+	}
+	admin := &Usuario{Username: "admin", Nome: "Administrador", PasswordHash: string(hash), Role: RoleAdmin, Ativo: true}
+	if err := b.inserirUsuario(b.db, admin); err != nil {
+		return err
+	}
+	log.Println("Aviso: usuário 'admin' criado com a senha padrão. Altere-a o quanto antes.")
+	return nil
+}
+
+// validarSenha verifica se a senha atende aos requisitos mínimos
+func validarSenha(password string) error {
+	if len(password) < 8 {
+		return errors.New("a senha deve ter pelo menos 8 caracteres")
+	}
+
+	var (
+		hasUpper   bool
+		hasLower   bool
+		hasNumber  bool
+		hasSpecial bool
+	)
+
+	for _, char := range password {
+		switch {
+		case char >= 'A' && char <= 'Z':
+			hasUpper = true
+		case char >= 'a' && char <= 'z':
+			hasLower = true
+		case char >= '0' && char <= '9':
+			hasNumber = true
+		case char >= '!' && char <= '/' || char >= ':' && char <= '@':
+			hasSpecial = true
+		}
+	}
+
+	if !hasUpper || !hasLower || !hasNumber || !hasSpecial {
+		return errors.New("a senha deve conter pelo menos uma letra maiúscula, uma minúscula, um número e um caractere especial")
+	}
+
+	return nil
+}
+
+// CriarUsuario valida os dados e cadastra um novo usuário em nome de autorID.
+func (b *Banco) CriarUsuario(req UsuarioRequest, autorID int) (*Usuario, error) {
+	if req.Username == "" || req.Password == "" {
+		return nil, errors.New("usuário e senha são obrigatórios")
+	}
+	if !RoleValida(req.Role) {
+		return nil, fmt.Errorf("papel '%s' inválido", req.Role)
+	}
+	if err := validarSenha(req.Password); err != nil {
+		return nil, err
+	}
+
+	existente, err := b.GetUsuarioByUsername(req.Username)
+	if err != nil {
+		return nil, err
+	}
+	if existente != nil {
+		return nil, errors.New("usuário já existe")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	usuario := &Usuario{
+		Username:     req.Username,
+		Nome:         req.Nome,
+		PasswordHash: string(hash),
+		Role:         req.Role,
+		Ativo:        true,
+	}
+	if req.Ativo != nil {
+		usuario.Ativo = *req.Ativo
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := b.inserirUsuario(tx, usuario); err != nil {
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, autorID, AcaoCriar, "usuario", strconv.Itoa(usuario.ID), nil, usuario); err != nil {
+		return nil, err
+	}
+	return usuario, tx.Commit()
+}
+
+// inserirUsuario grava o usuário e preenche seu ID.
+func (b *Banco) inserirUsuario(db execer, u *Usuario) error {
+	query := b.queries.GetQuery(qInserirUsuario)
+	if query == "" {
+		return errors.New("query 'inserir_usuario' não encontrada")
+	}
+	u.CriadoEm = b.Agora()
+	id, err := b.inserirComID(db, query, u.Username, u.Nome, u.PasswordHash, u.Role, u.Ativo, u.CriadoEm)
+	if err != nil {
+		log.Printf("Erro ao inserir usuário '%s': %v", u.Username, err)
+		return err
+	}
+	u.ID = int(id)
+	return nil
+}
+
+// AtualizarUsuario altera nome, papel, situação e, se informada, a senha de um usuário.
+func (b *Banco) AtualizarUsuario(id int, req UsuarioRequest, autorID int) (*Usuario, error) {
+	usuario, err := b.GetUsuario(id)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil {
+		return nil, errors.New("usuário não encontrado")
+	}
+	anterior := *usuario
+
+	if req.Nome != "" {
+		usuario.Nome = req.Nome
+	}
+	if req.Role != "" {
+		if !RoleValida(req.Role) {
+			return nil, fmt.Errorf("papel '%s' inválido", req.Role)
+		}
+		usuario.Role = req.Role
+	}
+	if req.Ativo != nil {
+		usuario.Ativo = *req.Ativo
+	}
+	if req.Password != "" {
+		if err := validarSenha(req.Password); err != nil {
+			return nil, err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		usuario.PasswordHash = string(hash)
+		usuario.TentativasLogin = 0
+	}
+
+	query := b.queries.GetQuery(qAtualizarUsuario)
+	if query == "" {
+		return nil, errors.New("query 'atualizar_usuario' não encontrada")
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(query, usuario.Nome, usuario.PasswordHash, usuario.Role, usuario.Ativo, usuario.TentativasLogin, usuario.ID)
+	if err != nil {
+		log.Printf("Erro ao atualizar usuário %d: %v", id, err)
+		return nil, err
+	}
+	if err := b.registrarAuditoria(tx, autorID, AcaoAtualizar, "usuario", strconv.Itoa(id), anterior, usuario); err != nil {
+		return nil, err
+	}
+	return usuario, tx.Commit()
+}
+
+// DesativarUsuario impede o acesso de um usuário sem apagar seu histórico de vendas e movimentações.
+func (b *Banco) DesativarUsuario(id int, autorID int) error {
+	ativo := false
+	_, err := b.AtualizarUsuario(id, UsuarioRequest{Ativo: &ativo}, autorID)
+	return err
+}
+
+// GetUsuario retorna um usuário pelo ID, ou nil se não existir.
+func (b *Banco) GetUsuario(id int) (*Usuario, error) {
+	return b.selecionarUsuario(qSelecionarUsuarioPorId, id)
+}
+
+// UsuarioDaSessao confere, a cada requisição, se o usuário do token continua ativo e retorna o
+// cadastro atual, para que a desativação e a troca de papel valham antes de o token expirar.
+func (b *Banco) UsuarioDaSessao(id int) (*Usuario, error) {
+	usuario, err := b.GetUsuario(id)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil || !usuario.Ativo {
+		return nil, ErrSessaoInvalida
+	}
+	return usuario, nil
+}
+
+// GetUsuarioByUsername retorna um usuário pelo nome de login, ou nil se não existir.
+func (b *Banco) GetUsuarioByUsername(username string) (*Usuario, error) {
+	return b.selecionarUsuario(qSelecionarUsuarioPorUsername, username)
+}
+
+func (b *Banco) selecionarUsuario(nomeQuery string, arg interface{}) (*Usuario, error) {
+	query := b.queries.GetQuery(nomeQuery)
+	if query == "" {
+		return nil, fmt.Errorf("query '%s' não encontrada", nomeQuery)
+	}
+
+	u, err := scanUsuario(b.db.QueryRow(query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil // Não é um erro, apenas não encontrou
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func scanUsuario(row scanner) (*Usuario, error) {
+	var u Usuario
+	var nome sql.NullString
+	var ultimaTentativa, ultimoLogin sql.NullTime
+	err := row.Scan(&u.ID, &u.Username, &nome, &u.PasswordHash, &u.Role, &u.Ativo,
+		&u.TentativasLogin, &ultimaTentativa, &ultimoLogin, &u.CriadoEm)
+	if err != nil {
+		return nil, err
+	}
+	u.Nome = nome.String
+	if ultimaTentativa.Valid {
+		u.UltimaTentativa = &ultimaTentativa.Time
+	}
+	if ultimoLogin.Valid {
+		u.UltimoLogin = &ultimoLogin.Time
+	}
+	return &u, nil
+}
+
+// ListarUsuarios retorna todos os usuários cadastrados.
+func (b *Banco) ListarUsuarios() ([]Usuario, error) {
+	query := b.queries.GetQuery(qSelecionarTodosUsuarios)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_todos_usuarios' não encontrada")
+	}
+
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usuarios []Usuario
+	for rows.Next() {
+		u, err := scanUsuario(rows)
+		if err != nil {
+			log.Printf("Erro ao escanear linha do usuário: %v", err)
+			continue
+		}
+		usuarios = append(usuarios, *u)
+	}
+	return usuarios, rows.Err()
+}
+
+// AutenticarUsuario confere as credenciais, aplicando o bloqueio após tentativas repetidas.
+func (b *Banco) AutenticarUsuario(username, password string) (*Usuario, error) {
+	usuario, err := b.GetUsuarioByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if usuario == nil || !usuario.Ativo {
+		return nil, ErrCredenciaisInvalidas
+	}
+
+	// Verifica se a conta está bloqueada
+	agora := b.Agora()
+	if usuario.TentativasLogin >= maxTentativasLogin && usuario.UltimaTentativa != nil {
+		if agora.Sub(*usuario.UltimaTentativa) < tempoBloqueio {
+			return nil, ErrUsuarioBloqueado
+		}
+		// Reseta as tentativas após o período de bloqueio
+		usuario.TentativasLogin = 0
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(usuario.PasswordHash), []byte(password)); err != nil {
+		// Incrementa tentativas de login falhas
+		query := b.queries.GetQuery(qRegistrarFalhaLoginUsuario)
+		if query != "" {
+			if _, errUpd := b.db.Exec(query, usuario.TentativasLogin+1, agora, usuario.ID); errUpd != nil {
+				log.Printf("Erro ao registrar tentativa de login do usuário '%s': %v", username, errUpd)
+			}
+		}
+		return nil, ErrCredenciaisInvalidas
+	}
+
+	// Login bem-sucedido: reseta contadores e atualiza último login
+	query := b.queries.GetQuery(qRegistrarLoginUsuario)
+	if query != "" {
+		if _, err := b.db.Exec(query, agora, usuario.ID); err != nil {
+			log.Printf("Erro ao registrar login do usuário '%s': %v", username, err)
+		}
+	}
+	usuario.TentativasLogin = 0
+	usuario.UltimoLogin = &agora
+	return usuario, nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidarSenha(t *testing.T) {
+	assert.NoError(t, validarSenha("Balcao@2026"))
+	for _, fraca := range []string{"Ab@1", "balcao@2026", "BALCAO@2026", "Balcao@dois", "Balcao2026"} {
+		assert.Error(t, validarSenha(fraca), fraca)
+	}
+}
+
+func TestAutenticarUsuarioComBloqueio(t *testing.T) {
+	t.Parallel()
+	agora := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	b := abrirBancoTesteCom(t, ConfigBanco{Driver: DriverSQLite, Caminho: ":memory:", Relogio: func() time.Time { return agora }})
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+
+	_, err := b.CriarUsuario(UsuarioRequest{Username: "ana", Nome: "Ana", Password: "Balcao@2026", Role: "gerente"}, 0)
+	assert.Error(t, err, "papel inválido")
+	_, err = b.CriarUsuario(UsuarioRequest{Username: "ana", Nome: "Ana", Password: "fraca", Role: RoleAtendente}, 0)
+	assert.Error(t, err, "senha fraca")
+	ana, err := b.CriarUsuario(UsuarioRequest{Username: "ana", Nome: "Ana", Password: "Balcao@2026", Role: RoleAtendente}, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	usuario, err := b.AutenticarUsuario("ana", "Balcao@2026")
+	if assert.NoError(t, err) {
+		assert.Equal(t, RoleAtendente, usuario.Role)
+	}
+
+	// Cinco senhas erradas bloqueiam o login, mesmo com a senha certa, por 15 minutos
+	for i := 0; i < maxTentativasLogin; i++ {
+		_, err := b.AutenticarUsuario("ana", "errada")
+		assert.ErrorIs(t, err, ErrCredenciaisInvalidas)
+	}
+	_, err = b.AutenticarUsuario("ana", "Balcao@2026")
+	assert.ErrorIs(t, err, ErrUsuarioBloqueado)
+	agora = agora.Add(tempoBloqueio + time.Minute)
+	_, err = b.AutenticarUsuario("ana", "Balcao@2026")
+	assert.NoError(t, err)
+
+	_, err = b.AutenticarUsuario("ninguem", "Balcao@2026")
+	assert.ErrorIs(t, err, ErrCredenciaisInvalidas)
+	if !assert.NoError(t, b.DesativarUsuario(ana.ID, 0)) {
+		return
+	}
+	_, err = b.AutenticarUsuario("ana", "Balcao@2026")
+	assert.ErrorIs(t, err, ErrCredenciaisInvalidas)
+}
+
+func TestUsuarioDaSessao(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	carlos, err := b.CriarUsuario(UsuarioRequest{Username: "carlos", Nome: "Carlos", Password: "Farmacia@2026", Role: RoleFarmaceutico}, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	usuario, err := b.UsuarioDaSessao(carlos.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, RoleFarmaceutico, usuario.Role)
+	}
+
+	// A troca de papel vale na requisição seguinte, sem esperar o token expirar
+	if _, err := b.AtualizarUsuario(carlos.ID, UsuarioRequest{Role: RoleAtendente}, 0); !assert.NoError(t, err) {
+		return
+	}
+	usuario, err = b.UsuarioDaSessao(carlos.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, RoleAtendente, usuario.Role)
+	}
+
+	if !assert.NoError(t, b.DesativarUsuario(carlos.ID, 0)) {
+		return
+	}
+	_, err = b.UsuarioDaSessao(carlos.ID)
+	assert.ErrorIs(t, err, ErrSessaoInvalida)
+	_, err = b.UsuarioDaSessao(999)
+	assert.ErrorIs(t, err, ErrSessaoInvalida)
+}
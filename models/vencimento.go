@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// ItemVencimento representa um lote com saldo que já venceu ou está perto de vencer
+type ItemVencimento struct {
+	MedicamentoID  string  `json:"medicamento_id"`
+	Nome           string  `json:"nome"`
+	Fabricante     string  `json:"fabricante"`
+	LoteID         string  `json:"lote_id"`
+	NumeroLote     string  `json:"numero_lote"`
+	Validade       string  `json:"validade"`
+	DiasParaVencer int     `json:"dias_para_vencer"` // Negativo quando já vencido
+	Quantidade     int     `json:"quantidade"`
+	Preco          float64 `json:"preco"`
+	ValorEstoque   float64 `json:"valor_estoque"` // Quantidade * Preco
+}
+
+// RelatorioVencimento agrupa os lotes vencidos e a vencer dentro do prazo consultado
+type RelatorioVencimento struct {
+	DataReferencia string           `json:"data_referencia"`
+	Dias           int              `json:"dias"`
+	Vencidos       []ItemVencimento `json:"vencidos"`
+	AVencer        []ItemVencimento `json:"a_vencer"`
+	ValorVencido   float64          `json:"valor_vencido"`
+	ValorAVencer   float64          `json:"valor_a_vencer"`
+}
+
+// GetRelatorioVencimento lista os lotes com saldo vencidos ou que vencem nos próximos 'dias' dias.
+func (b *Banco) GetRelatorioVencimento(dias int, referencia time.Time) (*RelatorioVencimento, error) {
+	query := b.queries.GetQuery(qSelecionarLotesComSaldo)
+	if query == "" {
+		return nil, errors.New("query 'selecionar_lotes_com_saldo' não encontrada")
+	}
+
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hoje := time.Date(referencia.Year(), referencia.Month(), referencia.Day(), 0, 0, 0, 0, time.UTC)
+	limite := hoje.AddDate(0, 0, dias)
+
+	relatorio := &RelatorioVencimento{
+		DataReferencia: hoje.Format("2006-01-02"),
+		Dias:           dias,
+		Vencidos:       []ItemVencimento{},
+		AVencer:        []ItemVencimento{},
+	}
+
+	for rows.Next() {
+		var item ItemVencimento
+		if err := rows.Scan(&item.MedicamentoID, &item.Nome, &item.Fabricante, &item.Preco,
+			&item.LoteID, &item.NumeroLote, &item.Validade, &item.Quantidade); err != nil {
+			return nil, fmt.Errorf("erro ao escanear lote para relatório de vencimento: %w", err)
+		}
+
+		validade, err := parseData(item.Validade)
+		if err != nil {
+			log.Printf("Aviso: lote %s do medicamento '%s' ignorado no relatório de vencimento: %v", item.NumeroLote, item.Nome, err)
+			continue
+		}
+		if validade.After(limite) {
+			continue
+		}
+
+		item.DiasParaVencer = int(validade.Sub(hoje).Hours() / 24)
+		item.ValorEstoque = float64(item.Quantidade) * item.Preco
+
+		if validade.Before(hoje) {
+			relatorio.Vencidos = append(relatorio.Vencidos, item)
+			relatorio.ValorVencido += item.ValorEstoque
+		} else {
+			relatorio.AVencer = append(relatorio.AVencer, item)
+			relatorio.ValorAVencer += item.ValorEstoque
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Os que vencem (ou venceram) primeiro aparecem no topo
+	for _, lista := range [][]ItemVencimento{relatorio.Vencidos, relatorio.AVencer} {
+		sort.SliceStable(lista, func(i, j int) bool { return lista[i].DiasParaVencer < lista[j].DiasParaVencer })
+	}
+
+	return relatorio, nil
+}
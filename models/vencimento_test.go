@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRelatorioVencimento(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Amoxicilina 500mg", Preco: 2}, 0)) {
+		return
+	}
+	for _, lote := range []struct {
+		numero, validade string
+		quantidade       int
+	}{
+		{"VENCIDO", "2025-02-28", 5},
+		{"MES", "03/2025", 10}, // Vale até 31/03
+		{"ABRIL", "2025-04-05", 4},
+		{"LONGE", "2026-01-31", 20},
+	} {
+		if !assert.NoError(t, b.RegistrarMovimentacao(Movimentacao{MedicamentoID: "1", Tipo: TipoEntrada, Quantidade: lote.quantidade,
+			Lote: lote.numero, Validade: lote.validade})) {
+			return
+		}
+	}
+
+	relatorio, err := b.GetRelatorioVencimento(30, time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "2025-03-10", relatorio.DataReferencia)
+	if assert.Len(t, relatorio.Vencidos, 1) {
+		assert.Equal(t, "VENCIDO", relatorio.Vencidos[0].NumeroLote)
+		assert.Equal(t, -10, relatorio.Vencidos[0].DiasParaVencer)
+	}
+	var aVencer []string
+	for _, item := range relatorio.AVencer {
+		aVencer = append(aVencer, item.NumeroLote)
+	}
+	assert.Equal(t, []string{"MES", "ABRIL"}, aVencer, "do que vence primeiro para o último, dentro da janela")
+	assert.Equal(t, 10.0, relatorio.ValorVencido)
+	assert.Equal(t, 28.0, relatorio.ValorAVencer)
+}
@@ -1,11 +1,10 @@
 package models
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"log"
-	"medicontrol/sqlutils"
+	"strconv"
 	"time"
 )
 
@@ -27,113 +26,328 @@ type VendaItem struct {
 
 // RegistrarVendaRequest é o que a API recebe para criar uma venda
 type RegistrarVendaRequest struct {
-	Itens []struct {
-		MedicamentoID int `json:"medicamento_id"`
-		Quantidade    int `json:"quantidade"`
-	} `json:"itens"`
+	Itens []ItemVendaRequest `json:"itens"`
+	// Desconto sobre o total, em percentual, aplicado depois dos descontos dos itens
+	DescontoPercentual float64          `json:"desconto_percentual"`
+	Pagamentos         []PagamentoVenda `json:"pagamentos"`
+	// Resposta aos alertas de interação medicamentosa entre os itens
+	Interacoes *ConfirmacaoInteracoes `json:"interacoes,omitempty"`
 }
 
+// ItemVendaRequest é um item da requisição de venda
+type ItemVendaRequest struct {
+	MedicamentoID      int     `json:"medicamento_id"`
+	Quantidade         int     `json:"quantidade"`
+	DescontoPercentual float64 `json:"desconto_percentual"`
+	// Obrigatória para medicamentos controlados (Portaria 344/98)
+	Receita *Receita `json:"receita,omitempty"`
+	// Lote lido do código de barras da embalagem; sem lote, a baixa segue o FEFO
+	Lote string `json:"lote,omitempty"`
+}
+
+var (
+	// ErrReceitaObrigatoria indica a venda de um controlado sem receita válida
+	ErrReceitaObrigatoria = errors.New("receita obrigatória para medicamento controlado")
+	// ErrQuantidadeInvalida indica um item de venda com quantidade zero ou negativa
+	ErrQuantidadeInvalida = errors.New("a quantidade do item deve ser maior que zero")
+)
+
 // VendaInfo é a struct para os dados de resumo da lista de vendas
 type VendaInfo struct {
 	ID              int       `json:"id"`
 	Data            time.Time `json:"data"`
 	UserID          int       `json:"user_id"`
+	Username        string    `json:"username"`
+	Status          string    `json:"status"`
 	QuantidadeItens int       `json:"quantidade_itens"`
 	TotalVenda      float64   `json:"total_venda"`
 }
 
 // ListarVendas busca um resumo de todas as vendas no banco de dados.
-func ListarVendas() ([]VendaInfo, error) {
-	query := sqlutils.GetQuery("ListarVendas")
-	if query == "" {
-		return nil, errors.New("query ListarVendas não encontrada")
-	}
+func (b *Banco) ListarVendas() ([]VendaInfo, error) {
+	return b.repos.Vendas.Listar(b.db)
+}
+
+// FiltroVendas define os filtros e a página da listagem de vendas
+type FiltroVendas struct {
+	De            time.Time
+	Ate           time.Time // exclusivo
+	UsuarioID     int
+	MedicamentoID string
+	Status        string
+	TotalMin      *float64
+	TotalMax      *float64
+	Pagina        int
+	PorPagina     int
+}
+
+// PaginaVendas é uma página da listagem de vendas
+type PaginaVendas struct {
+	Vendas    []VendaInfo `json:"vendas"`
+	Total     int         `json:"total"`
+	Pagina    int         `json:"pagina"`
+	PorPagina int         `json:"por_pagina"`
+}
+
+// VendaDetalhe é o cabeçalho da venda com seus itens e pagamentos.
+// TotalVenda desconta as devoluções; TotalCobrado é o valor fechado no momento da venda.
+type VendaDetalhe struct {
+	VendaInfo
+	MotivoEstorno string             `json:"motivo_estorno,omitempty"`
+	Subtotal      float64            `json:"subtotal"`
+	Desconto      float64            `json:"desconto"`
+	TotalCobrado  float64            `json:"total_cobrado"`
+	ValorRecebido float64            `json:"valor_recebido"`
+	Troco         float64            `json:"troco"`
+	Itens         []ItemVendaDetalhe `json:"itens"`
+	Pagamentos    []PagamentoVenda   `json:"pagamentos"`
+	Interacoes    []InteracaoVenda   `json:"interacoes,omitempty"`
+}
+
+// ItemVendaDetalhe é uma linha da venda; o subtotal já tem os descontos e desconta as unidades devolvidas
+type ItemVendaDetalhe struct {
+	ID                  int64   `json:"id"`
+	MedicamentoID       string  `json:"medicamento_id"`
+	Nome                string  `json:"nome"`
+	Quantidade          int     `json:"quantidade"`
+	QuantidadeDevolvida int     `json:"quantidade_devolvida"`
+	PrecoUnitario       float64 `json:"preco_unitario"`
+	Desconto            float64 `json:"desconto"`
+	Subtotal            float64 `json:"subtotal"`
+}
+
+// BuscarVendas lista as vendas que atendem ao filtro, da mais recente para a mais antiga, paginadas.
+func (b *Banco) BuscarVendas(filtro FiltroVendas) (*PaginaVendas, error) {
+	return b.repos.Vendas.Pesquisar(b.db, filtro)
+}
 
-	rows, err := sqlDB.Query(query)
+// GetVenda retorna a venda com seus itens e pagamentos, ou nil se ela não existir.
+func (b *Banco) GetVenda(id int64) (*VendaDetalhe, error) {
+	venda, err := b.repos.Vendas.Buscar(b.db, id)
+	if err != nil || venda == nil {
+		return venda, err
+	}
+	venda.Pagamentos, err = b.pagamentosVenda(b.db, id)
+	if err != nil {
+		return nil, err
+	}
+	venda.Interacoes, err = b.interacoesVenda(id)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao executar a query para listar vendas: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
+	return venda, nil
+}
 
-	var vendas []VendaInfo
-	for rows.Next() {
-		var v VendaInfo
-		// Corresponde à ordem SELECT da query ListarVendas
-		if err := rows.Scan(&v.ID, &v.Data, &v.UserID, &v.QuantidadeItens, &v.TotalVenda); err != nil {
-			return nil, fmt.Errorf("erro ao scanear linha da venda: %w", err)
+// RegistrarVenda processa uma nova venda em nome do usuário informado, atualizando o estoque e registrando os itens.
+// Os descontos são limitados pelo papel (role) do usuário e os pagamentos devem cobrir o total; o troco só sai do dinheiro.
+// A venda fica vinculada ao caixa aberto do usuário; sem caixa aberto retorna ErrCaixaFechado.
+// Interações medicamentosas entre os itens precisam da ciência do atendente e, as graves, da liberação
+// de um farmacêutico; sem elas retorna um *ErroInteracoes com os alertas.
+func (b *Banco) RegistrarVenda(req RegistrarVendaRequest, userID int, role string) (*ResumoVenda, error) {
+	// Uma quantidade negativa devolveria estoque aos lotes e baixaria o total, escapando do limite de desconto
+	for _, item := range req.Itens {
+		if item.Quantidade <= 0 {
+			return nil, fmt.Errorf("%w: medicamento %d com quantidade %d", ErrQuantidadeInvalida, item.MedicamentoID, item.Quantidade)
 		}
-		vendas = append(vendas, v)
+	}
+	if err := validarDesconto(req.DescontoPercentual, role, "total da venda"); err != nil {
+		return nil, err
 	}
 
-	return vendas, nil
-}
+	// Conferidas antes da transação, porque a liberação autentica o farmacêutico
+	interacoes, err := b.VerificarInteracoes(req.Itens)
+	if err != nil {
+		return nil, err
+	}
+	liberadoPor, err := b.confirmarInteracoes(interacoes, req.Interacoes, userID, role)
+	if err != nil {
+		return nil, err
+	}
 
-// RegistrarVenda processa uma nova venda, atualizando o estoque e registrando os itens.
-func RegistrarVenda(req RegistrarVendaRequest) (int64, error) {
-	tx, err := sqlDB.Begin()
+	tx, err := b.db.Begin()
 	if err != nil {
-		return 0, fmt.Errorf("erro ao iniciar transação: %w", err)
+		return nil, fmt.Errorf("erro ao iniciar transação: %w", err)
 	}
 	defer tx.Rollback() // Rollback é uma proteção; só tem efeito se Commit não for chamado.
+	// A venda é o caminho mais frequente do sistema: usar as queries já preparadas
+	exec := b.comPreparadas(tx)
 
-	// 1. Inserir na tabela 'vendas' para gerar um ID de venda.
-	queryInsertVenda := sqlutils.GetQuery("InserirVenda")
-	if queryInsertVenda == "" {
-		return 0, errors.New("query InserirVenda não encontrada")
-	}
-	res, err := tx.Exec(queryInsertVenda, 1) // Provisório: UserID fixo como 1
+	caixaID, err := b.caixaAbertoID(exec, userID)
 	if err != nil {
-		return 0, fmt.Errorf("erro ao inserir na tabela de vendas: %w", err)
+		return nil, err
 	}
-	vendaID, err := res.LastInsertId()
+
+	// 1. Inserir na tabela 'vendas' para gerar um ID de venda.
+	vendaID, err := b.repos.Vendas.Inserir(exec, userID, caixaID)
 	if err != nil {
-		return 0, fmt.Errorf("erro ao obter o ID da última venda inserida: %w", err)
+		return nil, fmt.Errorf("erro ao inserir na tabela de vendas: %w", err)
 	}
 
-	// Carregar queries necessárias
-	queryInsertVendaItem := sqlutils.GetQuery("InserirVendaItem")
-	queryGetMedicamento := sqlutils.GetQuery("ObterMedicamentoCompleto")
-	queryUpdateEstoque := sqlutils.GetQuery("AtualizarEstoqueMedicamento")
+	// Itens como gravados, para a trilha de auditoria
+	var itensAuditoria []map[string]interface{}
+	// IDs e valores líquidos dos itens, para ratear o desconto da venda
+	var itensIDs []int64
+	var valoresItens []float64
+	resumo := &ResumoVenda{VendaID: vendaID, CaixaID: caixaID, Interacoes: interacoes.Alertas}
+	if req.Interacoes != nil {
+		if err := b.registrarInteracoesVenda(exec, vendaID, interacoes.Alertas, userID, liberadoPor, req.Interacoes.Justificativa); err != nil {
+			return nil, err
+		}
+	}
 
 	// 2. Iterar sobre cada item da requisição.
 	for _, itemReq := range req.Itens {
-		var med Medicamento
-		var categoriaNome sql.NullString
-		var preco sql.NullFloat64
-
 		// Buscar dados atuais do medicamento dentro da transação para garantir consistência.
-		err := tx.QueryRow(queryGetMedicamento, itemReq.MedicamentoID).Scan(
-			&med.ID, &med.Nome, &med.Fabricante, &med.CodigoANVISA, &med.Quantidade,
-			&med.Validade, &med.CriadoEm, &med.Categoria.ID, &categoriaNome, &preco,
-		)
+		med, err := b.repos.Medicamentos.BuscarPorID(exec, strconv.Itoa(itemReq.MedicamentoID))
 		if err != nil {
-			return 0, fmt.Errorf("medicamento com ID %d não encontrado na transação: %w", itemReq.MedicamentoID, err)
+			return nil, fmt.Errorf("erro ao buscar o medicamento com ID %d: %w", itemReq.MedicamentoID, err)
+		}
+		if med == nil {
+			return nil, fmt.Errorf("medicamento com ID %d não encontrado na transação: %w", itemReq.MedicamentoID, ErrMedicamentoNaoEncontrado)
 		}
-		if preco.Valid {
-			med.Preco = preco.Float64
+
+		// O preço cobrado não pode passar do PMC da tabela CMED importada.
+		if err := b.validarPrecoPMC(exec, med.CodigoANVISA, med.Preco); err != nil {
+			return nil, fmt.Errorf("medicamento '%s': %w", med.Nome, err)
 		}
 
 		// Validar estoque.
 		if med.Quantidade < itemReq.Quantidade {
-			return 0, fmt.Errorf("estoque insuficiente para o medicamento '%s'", med.Nome)
+			return nil, fmt.Errorf("estoque insuficiente para o medicamento '%s'", med.Nome)
+		}
+
+		// Medicamentos controlados só podem ser vendidos com a receita retida.
+		if med.Controlado {
+			if err := validarReceita(itemReq.Receita, b.Agora()); err != nil {
+				return nil, fmt.Errorf("%w: medicamento '%s' (lista %s): %v", ErrReceitaObrigatoria, med.Nome, med.listaControle(), err)
+			}
+		}
+
+		// Calcular o valor do item com o desconto concedido.
+		if err := validarDesconto(itemReq.DescontoPercentual, role, "'"+med.Nome+"'"); err != nil {
+			return nil, err
+		}
+		// O limite vale para o desconto que o item recebe de fato, somado ao da venda
+		if err := validarDesconto(descontoCombinado(itemReq.DescontoPercentual, req.DescontoPercentual), role, "'"+med.Nome+"' com o desconto da venda"); err != nil {
+			return nil, err
 		}
+		bruto := arredondar(float64(itemReq.Quantidade) * med.Preco)
+		descontoItem := arredondar(bruto * itemReq.DescontoPercentual / 100)
+		valorItem := arredondar(bruto - descontoItem)
 
-		// Inserir o item na tabela 'venda_items'.
-		_, err = tx.Exec(queryInsertVendaItem, vendaID, itemReq.MedicamentoID, itemReq.Quantidade, med.Preco)
+		// Inserir o item na tabela 'venda_items', congelando o custo médio do momento da venda.
+		vendaItemID, err := b.repos.Vendas.InserirItem(exec, ItemVendaGravado{
+			VendaID:       vendaID,
+			MedicamentoID: med.ID,
+			Quantidade:    itemReq.Quantidade,
+			PrecoUnitario: med.Preco,
+			Desconto:      descontoItem,
+			ValorTotal:    valorItem,
+			CustoUnitario: med.CustoMedio,
+		})
 		if err != nil {
-			return 0, fmt.Errorf("erro ao inserir o item de venda '%s': %w", med.Nome, err)
+			return nil, fmt.Errorf("erro ao inserir o item de venda '%s': %w", med.Nome, err)
+		}
+
+		// Baixar do lote vendido ou, sem ele, dos que vencem primeiro (FEFO) e registrar a origem do item.
+		var lotes []LoteConsumido
+		if itemReq.Lote != "" {
+			lotes, err = b.consumirLote(exec, med.ID, itemReq.Lote, itemReq.Quantidade)
+		} else {
+			lotes, err = b.consumirLotesFEFO(exec, med.ID, itemReq.Quantidade)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao baixar lotes do medicamento '%s': %w", med.Nome, err)
+		}
+		if err := b.registrarLotesVendaItem(exec, vendaItemID, lotes); err != nil {
+			return nil, err
+		}
+
+		var receita *Receita
+		if med.Controlado {
+			receita = itemReq.Receita
+			receita.VendaID = vendaID
+			receita.VendaItemID = vendaItemID
+			receita.MedicamentoID = med.ID
+			if err := b.inserirReceita(exec, receita); err != nil {
+				return nil, err
+			}
 		}
 
 		// Atualizar o estoque do medicamento.
 		novoEstoque := med.Quantidade - itemReq.Quantidade
-		_, err = tx.Exec(queryUpdateEstoque, novoEstoque, itemReq.MedicamentoID)
-		if err != nil {
-			return 0, fmt.Errorf("erro ao atualizar o estoque do medicamento '%s': %w", med.Nome, err)
+		if err := b.repos.Medicamentos.AtualizarEstoque(exec, med.ID, novoEstoque, med.CustoMedio); err != nil {
+			return nil, fmt.Errorf("erro ao atualizar o estoque do medicamento '%s': %w", med.Nome, err)
 		}
+		if err := b.atualizarValidadeMedicamento(exec, med.ID); err != nil {
+			return nil, fmt.Errorf("erro ao atualizar a validade do medicamento '%s': %w", med.Nome, err)
+		}
+
+		resumo.Subtotal += bruto
+		resumo.Desconto += descontoItem
+		itensIDs = append(itensIDs, vendaItemID)
+		valoresItens = append(valoresItens, valorItem)
+
+		itensAuditoria = append(itensAuditoria, map[string]interface{}{
+			"venda_item_id":    vendaItemID,
+			"medicamento_id":   med.ID,
+			"nome":             med.Nome,
+			"quantidade":       itemReq.Quantidade,
+			"preco_unitario":   med.Preco,
+			"desconto":         descontoItem,
+			"custo_unitario":   med.CustoMedio,
+			"estoque_anterior": med.Quantidade,
+			"estoque_novo":     novoEstoque,
+			"lotes":            lotes,
+			"receita":          receita,
+		})
+
+		log.Printf("Item vendido: %s | Quantidade: %d | Preço Unitário: %.2f | Desconto: %.2f", med.Nome, itemReq.Quantidade, med.Preco, descontoItem)
+	}
 
-		log.Printf("Item vendido: %s | Quantidade: %d | Preço Unitário: %.2f", med.Nome, itemReq.Quantidade, med.Preco)
+	// 3. Aplicar o desconto sobre o total, rateado entre os itens para que devoluções estornem o valor pago.
+	var somaItens float64
+	for _, v := range valoresItens {
+		somaItens += v
+	}
+	descontoVenda := arredondar(somaItens * req.DescontoPercentual / 100)
+	if descontoVenda > 0 {
+		for i, rateio := range ratear(valoresItens, descontoVenda) {
+			if err := b.repos.Vendas.AplicarDescontoItem(exec, itensIDs[i], rateio, arredondar(valoresItens[i]-rateio)); err != nil {
+				return nil, fmt.Errorf("erro ao aplicar desconto no item %d: %w", itensIDs[i], err)
+			}
+		}
+	}
+	resumo.Subtotal = arredondar(resumo.Subtotal)
+	resumo.Desconto = arredondar(resumo.Desconto + descontoVenda)
+	resumo.Total = arredondar(somaItens - descontoVenda)
+
+	// 4. Conferir os pagamentos e gravar os totais da venda.
+	resumo.ValorRecebido, resumo.Troco, err = calcularPagamentos(req.Pagamentos, resumo.Total)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.inserirPagamentosVenda(exec, vendaID, req.Pagamentos); err != nil {
+		return nil, err
+	}
+	if err := b.repos.Vendas.GravarTotais(exec, resumo); err != nil {
+		return nil, fmt.Errorf("erro ao gravar os totais da venda: %w", err)
+	}
+
+	depois := map[string]interface{}{
+		"venda_id":            vendaID,
+		"user_id":             userID,
+		"itens":               itensAuditoria,
+		"desconto_percentual": req.DescontoPercentual,
+		"pagamentos":          req.Pagamentos,
+		"interacoes":          req.Interacoes,
+		"liberado_por":        liberadoPor,
+		"resumo":              resumo,
+	}
+	if err := b.registrarAuditoria(exec, userID, AcaoCriar, "venda", strconv.FormatInt(vendaID, 10), nil, depois); err != nil {
+		return nil, err
 	}
 
 	// Se todos os itens foram processados sem erro, comitar a transação.
-	return vendaID, tx.Commit()
+	return resumo, tx.Commit()
 }
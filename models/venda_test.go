@@ -0,0 +1,181 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRatear(t *testing.T) {
+	assert.Equal(t, []float64{3.33, 3.33, 3.34}, ratear([]float64{10, 10, 10}, 10))
+	assert.Equal(t, []float64{0, 0}, ratear([]float64{10, 20}, 0))
+}
+
+func TestDescontoCombinado(t *testing.T) {
+	assert.Equal(t, 9.75, descontoCombinado(5, 5))
+	assert.Equal(t, 5.0, descontoCombinado(5, 0))
+	assert.Equal(t, 5.0, descontoCombinado(0, 5))
+	assert.Equal(t, 14.5, descontoCombinado(5, 10))
+}
+
+func TestCalcularPagamentos(t *testing.T) {
+	pagamentos := []PagamentoVenda{{Forma: " PIX ", Valor: 20}, {Forma: FormaDinheiro, Valor: 50}}
+	recebido, troco, err := calcularPagamentos(pagamentos, 62.5)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 70.0, recebido)
+		assert.Equal(t, 7.5, troco)
+		assert.Equal(t, FormaPix, pagamentos[0].Forma)
+	}
+
+	for _, invalidos := range [][]PagamentoVenda{
+		nil,
+		{{Forma: "cheque", Valor: 70}},
+		{{Forma: FormaDinheiro, Valor: 0}},
+		{{Forma: FormaDinheiro, Valor: 60}},
+		// Só o dinheiro gera troco
+		{{Forma: FormaCartaoCredito, Valor: 70}},
+	} {
+		_, _, err := calcularPagamentos(invalidos, 62.5)
+		assert.ErrorIs(t, err, ErrPagamentoInvalido, invalidos)
+	}
+}
+
+func TestRegistrarVenda(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	if !assert.NoError(t, b.AddMedicamento(&Medicamento{ID: "1", Nome: "Dipirona 500mg", Quantidade: 10, Preco: 10}, 0)) {
+		return
+	}
+
+	item := func(quantidade int, desconto float64) []ItemVendaRequest {
+		return []ItemVendaRequest{{MedicamentoID: 1, Quantidade: quantidade, DescontoPercentual: desconto}}
+	}
+	dinheiro := func(valor float64) []PagamentoVenda {
+		return []PagamentoVenda{{Forma: FormaDinheiro, Valor: valor}}
+	}
+
+	_, err := b.RegistrarVenda(RegistrarVendaRequest{Itens: item(1, 0), Pagamentos: dinheiro(10)}, 1, RoleAtendente)
+	assert.ErrorIs(t, err, ErrCaixaFechado)
+	if _, err := b.AbrirCaixa(1, 0); err != nil {
+		t.Fatalf("erro ao abrir caixa: %v", err)
+	}
+
+	// Quantidades zero ou negativas não passam, nem para o administrador
+	for _, quantidade := range []int{0, -3} {
+		_, err := b.RegistrarVenda(RegistrarVendaRequest{
+			Itens:      append(item(5, 0), item(quantidade, 0)...),
+			Pagamentos: dinheiro(50),
+		}, 1, RoleAdmin)
+		assert.ErrorIs(t, err, ErrQuantidadeInvalida)
+	}
+
+	// O atendente concede até 5%, no item e no total
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{Itens: item(2, 6), Pagamentos: dinheiro(20)}, 1, RoleAtendente)
+	assert.ErrorIs(t, err, ErrDescontoAcimaDoLimite)
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{Itens: item(2, 0), DescontoPercentual: 10, Pagamentos: dinheiro(20)}, 1, RoleAtendente)
+	assert.ErrorIs(t, err, ErrDescontoAcimaDoLimite)
+	// Os dois descontos se acumulam no item: 5% + 5% dão 9,75% e 5% + 12% dão 16,4%
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{Itens: item(2, 5), DescontoPercentual: 5, Pagamentos: dinheiro(20)}, 1, RoleAtendente)
+	assert.ErrorIs(t, err, ErrDescontoAcimaDoLimite)
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{Itens: item(2, 5), DescontoPercentual: 12, Pagamentos: dinheiro(20)}, 1, RoleFarmaceutico)
+	assert.ErrorIs(t, err, ErrDescontoAcimaDoLimite)
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{Itens: item(2, -5), Pagamentos: dinheiro(25)}, 1, RoleAdmin)
+	assert.ErrorIs(t, err, ErrDescontoAcimaDoLimite)
+	_, err = b.RegistrarVenda(RegistrarVendaRequest{Itens: item(11, 0), Pagamentos: dinheiro(110)}, 1, RoleAdmin)
+	assert.ErrorContains(t, err, "estoque insuficiente")
+	assert.Equal(t, 10, b.GetMedicamento("1").Quantidade, "as vendas recusadas não baixam o estoque")
+
+	resumo, err := b.RegistrarVenda(RegistrarVendaRequest{
+		Itens:              item(3, 5),
+		DescontoPercentual: 10,
+		Pagamentos:         []PagamentoVenda{{Forma: FormaPix, Valor: 10}, {Forma: FormaDinheiro, Valor: 20}},
+	}, 1, RoleFarmaceutico)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// 30,00 - 5% no item = 28,50; - 10% no total = 25,65
+	assert.Equal(t, 30.0, resumo.Subtotal)
+	assert.Equal(t, 4.35, resumo.Desconto)
+	assert.Equal(t, 25.65, resumo.Total)
+	assert.Equal(t, 4.35, resumo.Troco)
+	assert.Equal(t, 7, b.GetMedicamento("1").Quantidade)
+
+	venda, err := b.GetVenda(resumo.VendaID)
+	if assert.NoError(t, err) && assert.NotNil(t, venda) {
+		assert.Equal(t, 25.65, venda.TotalVenda)
+		assert.Len(t, venda.Pagamentos, 2)
+		if assert.Len(t, venda.Itens, 1) {
+			assert.Equal(t, 3, venda.Itens[0].Quantidade)
+			assert.Equal(t, 25.65, venda.Itens[0].Subtotal)
+		}
+	}
+}
+
+func TestBuscarVendas(t *testing.T) {
+	t.Parallel()
+	b := abrirBancoTeste(t)
+	if _, err := b.Migrar(0); err != nil {
+		t.Fatalf("erro ao migrar banco de teste: %v", err)
+	}
+	for _, med := range []*Medicamento{{ID: "1", Nome: "Dipirona 500mg", Quantidade: 50, Preco: 10}, {ID: "2", Nome: "Losartana 50mg", Quantidade: 50, Preco: 25}} {
+		if !assert.NoError(t, b.AddMedicamento(med, 0)) {
+			return
+		}
+	}
+	vender := func(usuarioID, medicamentoID, quantidade int, valor float64) int64 {
+		resumo, err := b.RegistrarVenda(RegistrarVendaRequest{
+			Itens:      []ItemVendaRequest{{MedicamentoID: medicamentoID, Quantidade: quantidade}},
+			Pagamentos: []PagamentoVenda{{Forma: FormaDinheiro, Valor: valor}},
+		}, usuarioID, RoleAtendente)
+		if err != nil {
+			t.Fatalf("erro ao registrar venda: %v", err)
+		}
+		return resumo.VendaID
+	}
+	for _, usuarioID := range []int{1, 2} {
+		if _, err := b.AbrirCaixa(usuarioID, 0); err != nil {
+			t.Fatalf("erro ao abrir caixa: %v", err)
+		}
+	}
+	primeira := vender(1, 1, 2, 20)
+	vender(1, 2, 1, 25)
+	terceira := vender(2, 1, 5, 50)
+	cancelada := vender(2, 2, 4, 100)
+	if _, err := b.CancelarVenda(cancelada, "", 2); !assert.NoError(t, err) {
+		return
+	}
+
+	ids := func(filtro FiltroVendas) []int {
+		pagina, err := b.BuscarVendas(filtro)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		var ids []int
+		for _, v := range pagina.Vendas {
+			ids = append(ids, v.ID)
+		}
+		return ids
+	}
+	todas := ids(FiltroVendas{})
+	assert.Len(t, todas, 4)
+	assert.Equal(t, []int{int(terceira), int(primeira)}, ids(FiltroVendas{MedicamentoID: "1"}), "da mais recente para a mais antiga")
+	assert.Equal(t, []int{int(cancelada), int(terceira)}, ids(FiltroVendas{UsuarioID: 2}))
+	assert.Equal(t, []int{int(cancelada)}, ids(FiltroVendas{Status: StatusVendaCancelada}))
+
+	// O total desconta o que foi devolvido: a venda cancelada vale zero
+	minimo, maximo := 20.0, 30.0
+	assert.Len(t, ids(FiltroVendas{TotalMin: &minimo, TotalMax: &maximo}), 2)
+	zero := 0.0
+	assert.Equal(t, []int{int(cancelada)}, ids(FiltroVendas{TotalMax: &zero}))
+
+	pagina, err := b.BuscarVendas(FiltroVendas{Pagina: 2, PorPagina: 3})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, pagina.Total)
+		if assert.Len(t, pagina.Vendas, 1) {
+			assert.Equal(t, int(primeira), pagina.Vendas[0].ID)
+		}
+	}
+}
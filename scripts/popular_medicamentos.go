@@ -44,7 +44,7 @@ func main() {
 
 	rand.Seed(time.Now().UnixNano())
 
-	if err := models.InitDB(); err != nil {
+	if err := models.InitDB(models.ConfigBanco{}); err != nil {
 		log.Fatalf("Erro ao inicializar banco de dados: %v", err)
 	}
 
@@ -9,11 +9,12 @@ import (
 
 func main() {
 	log.Println("Testando serviço da ANVISA...")
+	anvisa := services.NovoClienteAnvisa()
 
 	// Teste 1: Buscar medicamento existente
 	codigo := "1234567890123"
 	log.Printf("Buscando medicamento com código %s...\n", codigo)
-	dados, err := services.BuscarDadosAnvisa(codigo)
+	dados, err := anvisa.BuscarDadosAnvisa(codigo)
 	if err != nil {
 		log.Fatal("Erro ao buscar dados:", err)
 	}
@@ -23,7 +24,7 @@ func main() {
 	// Teste 2: Buscar medicamento inexistente
 	codigoInexistente := "0000000000000"
 	log.Printf("Tentando buscar medicamento inexistente %s...\n", codigoInexistente)
-	_, err = services.BuscarDadosAnvisa(codigoInexistente)
+	_, err = anvisa.BuscarDadosAnvisa(codigoInexistente)
 	if err != nil {
 		fmt.Printf("Erro esperado: %v\n\n", err)
 	}
@@ -31,7 +32,7 @@ func main() {
 	// Teste 3: Testar cache
 	log.Println("Testando cache - buscando mesmo medicamento novamente...")
 	start := time.Now()
-	dados, err = services.BuscarDadosAnvisa(codigo)
+	dados, err = anvisa.BuscarDadosAnvisa(codigo)
 	if err != nil {
 		log.Fatal("Erro ao buscar dados do cache:", err)
 	}
@@ -10,7 +10,7 @@ func main() {
 	log.Println("Iniciando teste do banco de dados...")
 
 	// Inicializar o banco de dados
-	if err := models.InitDB(); err != nil {
+	if err := models.InitDB(models.ConfigBanco{}); err != nil {
 		log.Fatal("Erro ao inicializar banco de dados:", err)
 	}
 
@@ -31,22 +31,33 @@ type DadosAnvisa struct {
 	Fabricante string
 	Registro   string // Código de registro ANVISA
 	Classe     string // Classe Terapêutica
+	Substancia string // Princípios ativos, separados por "+" ou ";" quando há mais de um
 	// Status (ATIVO/INATIVO) - a API externa pode não fornecer isso diretamente
 }
 
 const apiBaseURL = "https://apiflaskmedicamentos.herokuapp.com/medicamentos"
 
+// ClienteAnvisa consulta a API pública de medicamentos registrados na ANVISA.
+type ClienteAnvisa struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NovoClienteAnvisa cria um cliente para a API pública, com tempo limite de 20 segundos por consulta.
+func NovoClienteAnvisa() *ClienteAnvisa {
+	return &ClienteAnvisa{BaseURL: apiBaseURL, HTTP: &http.Client{Timeout: 20 * time.Second}}
+}
+
 // BuscarDadosAnvisa busca informações de um medicamento pelo código de registro usando a API pública.
-func BuscarDadosAnvisa(codigoRegistro string) (*DadosAnvisa, error) {
+func (c *ClienteAnvisa) BuscarDadosAnvisa(codigoRegistro string) (*DadosAnvisa, error) {
 	if codigoRegistro == "" {
 		return nil, errors.New("código de registro não pode ser vazio")
 	}
 
 	// A API permite buscar por ?registro=CODIGO
-	url := fmt.Sprintf("%s?registro=%s", apiBaseURL, codigoRegistro)
+	url := fmt.Sprintf("%s?registro=%s", c.BaseURL, codigoRegistro)
 	log.Printf("Consultando API externa: %s", url)
 
-	httpClient := &http.Client{Timeout: 20 * time.Second}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		log.Printf("Erro ao criar requisição HTTP: %v", err)
@@ -55,7 +66,7 @@ func BuscarDadosAnvisa(codigoRegistro string) (*DadosAnvisa, error) {
 	// Algumas APIs podem requerer um User-Agent
 	req.Header.Set("User-Agent", "MediControlApp/1.0")
 
-	resp, err := httpClient.Do(req)
+	resp, err := c.HTTP.Do(req)
 	if err != nil {
 		log.Printf("Erro ao fazer requisição para API ANVISA: %v", err)
 		return nil, fmt.Errorf("erro na comunicação com a API: %w", err)
@@ -104,6 +115,7 @@ func BuscarDadosAnvisa(codigoRegistro string) (*DadosAnvisa, error) {
 		Fabricante: apiMed.Laboratorio,
 		Registro:   apiMed.Registro, // Garantir que este é o código ANVISA
 		Classe:     apiMed.ClasseTerapeutica,
+		Substancia: apiMed.Substancia,
 	}
 
 	// Se o campo Registro da API estiver vazio, mas recebemos o produto, preenchemos com o código buscado.
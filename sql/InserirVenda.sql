@@ -0,0 +1 @@
+INSERT INTO vendas (user_id, data, caixa_id) VALUES (?, CURRENT_TIMESTAMP, ?);
@@ -0,0 +1,2 @@
+INSERT INTO venda_items (venda_id, medicamento_id, quantidade, preco_unitario, desconto, valor_total, custo_unitario)
+VALUES (?, ?, ?, ?, ?, ?, ?);
@@ -0,0 +1,7 @@
+SELECT v.id, v.data, v.user_id, COALESCE(u.username, ''), v.status, COUNT(vi.id),
+       COALESCE(SUM(COALESCE(vi.valor_total, vi.quantidade * vi.preco_unitario) * (vi.quantidade - vi.quantidade_devolvida) / vi.quantidade), 0)
+FROM vendas v
+LEFT JOIN usuarios u ON u.id = v.user_id
+LEFT JOIN venda_items vi ON vi.venda_id = v.id
+GROUP BY v.id, v.data, v.user_id, u.username, v.status
+ORDER BY v.data DESC;
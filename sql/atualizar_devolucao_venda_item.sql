@@ -0,0 +1 @@
+UPDATE venda_items SET quantidade_devolvida = quantidade_devolvida + ? WHERE id = ?;
@@ -0,0 +1 @@
+UPDATE venda_item_lotes SET quantidade_devolvida = quantidade_devolvida + ? WHERE venda_item_id = ? AND lote_id = ?;
@@ -0,0 +1 @@
+UPDATE medicamentos SET Quantidade = ?, CustoMedio = ? WHERE ID = ?;
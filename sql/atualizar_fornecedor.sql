@@ -0,0 +1,3 @@
+UPDATE fornecedores
+SET nome = ?, cnpj = ?, contato = ?, telefone = ?, email = ?, prazo_entrega_dias = ?, ativo = ?
+WHERE id = ?;
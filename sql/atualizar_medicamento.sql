@@ -0,0 +1,3 @@
+UPDATE medicamentos
+SET Nome = ?, Fabricante = ?, Tipo = ?, CodigoANVISA = ?, Preco = ?, CategoriaID = ?, ListaControle = COALESCE(?, ListaControle), CategoriaRegulatoria = ?
+WHERE ID = ?;
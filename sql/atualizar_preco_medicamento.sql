@@ -0,0 +1 @@
+UPDATE medicamentos SET Preco = ? WHERE ID = ?;
@@ -0,0 +1 @@
+UPDATE lotes SET quantidade = ? WHERE id = ?;
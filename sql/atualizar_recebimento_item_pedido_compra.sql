@@ -0,0 +1,3 @@
+UPDATE pedido_compra_itens
+SET quantidade_recebida = quantidade_recebida + ?
+WHERE id = ?;
@@ -0,0 +1 @@
+UPDATE agendamentos_preco SET status = ?, motivo = ?, aplicado_em = ? WHERE id = ?;
@@ -0,0 +1,3 @@
+UPDATE pedidos_compra
+SET status = ?, enviado_em = ?, previsao_entrega = ?, recebido_em = ?
+WHERE id = ?;
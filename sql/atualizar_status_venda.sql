@@ -0,0 +1 @@
+UPDATE vendas SET status = ?, motivo_estorno = ? WHERE id = ?;
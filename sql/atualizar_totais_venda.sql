@@ -0,0 +1 @@
+UPDATE vendas SET subtotal = ?, desconto = ?, total = ?, valor_recebido = ?, troco = ? WHERE id = ?;
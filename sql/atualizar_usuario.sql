@@ -0,0 +1,3 @@
+UPDATE usuarios
+SET nome = ?, password_hash = ?, role = ?, ativo = ?, tentativas_login = ?
+WHERE id = ?;
@@ -0,0 +1 @@
+UPDATE medicamentos SET Validade = ? WHERE ID = ?;
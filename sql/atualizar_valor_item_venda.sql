@@ -0,0 +1 @@
+UPDATE venda_items SET desconto = desconto + ?, valor_total = ? WHERE id = ?;
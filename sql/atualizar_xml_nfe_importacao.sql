@@ -0,0 +1,3 @@
+UPDATE nfe_importacoes
+SET xml = ?, usuario_id = ?, criado_em = ?
+WHERE id = ?;
@@ -0,0 +1,3 @@
+UPDATE nfe_importacoes
+SET status = ?, confirmado_em = ?, confirmado_por = ?
+WHERE id = ?;
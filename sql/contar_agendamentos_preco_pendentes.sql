@@ -0,0 +1,3 @@
+SELECT COUNT(*)
+FROM agendamentos_preco
+WHERE medicamento_id = ? AND vigencia = ? AND status = 'pendente';
@@ -0,0 +1,4 @@
+SELECT SUM(vi.quantidade - vi.quantidade_devolvida)
+FROM venda_items vi
+JOIN vendas v ON v.id = vi.venda_id
+WHERE v.status <> 'cancelada';
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    versao INTEGER PRIMARY KEY,
+    nome TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    aplicada_em DATETIME NOT NULL
+);
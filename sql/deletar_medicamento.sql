@@ -0,0 +1 @@
+DELETE FROM medicamentos WHERE ID = ?;
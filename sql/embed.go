@@ -0,0 +1,10 @@
+// Package sql embute no binário as queries da aplicação, as variações de cada dialeto e as
+// migrações de esquema, para que o servidor não dependa da pasta 'sql' no diretório de trabalho.
+package sql
+
+import "embed"
+
+// Arquivos é a pasta 'sql', com as subpastas 'migrations' e 'postgres'.
+//
+//go:embed *.sql migrations postgres
+var Arquivos embed.FS
@@ -0,0 +1,3 @@
+UPDATE inventarios
+SET status = ?, encerrado_por = ?, encerrado_em = ?, motivo_ajuste = ?
+WHERE id = ?;
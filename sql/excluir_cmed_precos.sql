@@ -0,0 +1 @@
+DELETE FROM cmed_precos;
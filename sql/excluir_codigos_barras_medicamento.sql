@@ -0,0 +1 @@
+DELETE FROM medicamento_codigos_barras WHERE medicamento_id = ?;
@@ -0,0 +1 @@
+DELETE FROM interacoes_medicamentosas;
@@ -0,0 +1 @@
+DELETE FROM pedido_compra_itens WHERE pedido_id = ?;
@@ -0,0 +1 @@
+DELETE FROM medicamento_principios WHERE medicamento_id = ?;
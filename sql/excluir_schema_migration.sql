@@ -0,0 +1 @@
+DELETE FROM schema_migrations WHERE versao = ?;
@@ -0,0 +1 @@
+UPDATE caixas SET status = ?, fechado_em = ?, fechado_por = ?, observacao = ? WHERE id = ?;
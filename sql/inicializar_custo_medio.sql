@@ -0,0 +1,11 @@
+UPDATE medicamentos
+SET CustoMedio = (
+    SELECT ROUND(SUM(l.quantidade * l.custo_unitario) / SUM(l.quantidade), 4)
+    FROM lotes l
+    WHERE l.medicamento_id = medicamentos.ID AND l.quantidade > 0 AND l.custo_unitario > 0
+)
+WHERE COALESCE(CustoMedio, 0) = 0
+  AND EXISTS (
+    SELECT 1 FROM lotes l
+    WHERE l.medicamento_id = medicamentos.ID AND l.quantidade > 0 AND l.custo_unitario > 0
+);
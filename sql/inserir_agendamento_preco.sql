@@ -0,0 +1,2 @@
+INSERT INTO agendamentos_preco (medicamento_id, preco, vigencia, status, usuario_id, criado_em)
+VALUES (?, ?, ?, ?, ?, ?);
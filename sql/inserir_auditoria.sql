@@ -0,0 +1,2 @@
+INSERT INTO auditoria (usuario_id, data, acao, entidade, entidade_id, antes, depois)
+VALUES (?, ?, ?, ?, ?, ?, ?);
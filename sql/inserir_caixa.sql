@@ -0,0 +1,2 @@
+INSERT INTO caixas (usuario_id, status, aberto_em, valor_abertura)
+VALUES (?, ?, ?, ?);
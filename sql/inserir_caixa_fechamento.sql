@@ -0,0 +1,2 @@
+INSERT INTO caixa_fechamentos (caixa_id, forma, esperado, contado, diferenca)
+VALUES (?, ?, ?, ?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO caixa_movimentos (caixa_id, tipo, valor, motivo, venda_id, usuario_id, data, forma)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
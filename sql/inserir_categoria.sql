@@ -0,0 +1 @@
+INSERT INTO categorias (ID, Nome) VALUES (?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO cmed_precos (registro, ean, produto, apresentacao, laboratorio, pmc, aliquota, importado_em)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
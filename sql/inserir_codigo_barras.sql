@@ -0,0 +1 @@
+INSERT INTO medicamento_codigos_barras (gtin, medicamento_id) VALUES (?, ?);
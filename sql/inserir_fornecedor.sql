@@ -0,0 +1,2 @@
+INSERT INTO fornecedores (nome, cnpj, contato, telefone, email, prazo_entrega_dias, ativo, criado_em)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
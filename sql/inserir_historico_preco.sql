@@ -0,0 +1,2 @@
+INSERT INTO historico_precos (medicamento_id, preco_anterior, preco_novo, origem, agendamento_id, usuario_id, alterado_em)
+VALUES (?, ?, ?, ?, ?, ?, ?);
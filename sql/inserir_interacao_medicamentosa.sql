@@ -0,0 +1,3 @@
+INSERT INTO interacoes_medicamentosas (substancia_a, substancia_b, gravidade, descricao, conduta)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (substancia_a, substancia_b) DO UPDATE SET gravidade = excluded.gravidade, descricao = excluded.descricao, conduta = excluded.conduta;
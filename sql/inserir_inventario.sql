@@ -0,0 +1,2 @@
+INSERT INTO inventarios (categoria_id, status, observacao, aberto_por, aberto_em)
+VALUES (?, ?, ?, ?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO inventario_ajustes (inventario_id, medicamento_id, numero_lote, tipo, quantidade, movimentacao_id)
+VALUES (?, ?, ?, ?, ?, ?);
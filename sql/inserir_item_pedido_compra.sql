@@ -0,0 +1,2 @@
+INSERT INTO pedido_compra_itens (pedido_id, medicamento_id, quantidade, custo_unitario)
+VALUES (?, ?, ?, ?);
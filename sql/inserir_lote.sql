@@ -0,0 +1,2 @@
+INSERT INTO lotes (id, medicamento_id, numero_lote, validade, quantidade, fornecedor, data_entrada, custo_unitario)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
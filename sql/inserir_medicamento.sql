@@ -0,0 +1,2 @@
+INSERT INTO medicamentos (ID, Nome, Fabricante, Tipo, CodigoANVISA, Quantidade, Validade, Preco, CriadoEm, CategoriaID, ListaControle, CustoMedio, CategoriaRegulatoria)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
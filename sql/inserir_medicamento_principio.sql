@@ -0,0 +1 @@
+INSERT INTO medicamento_principios (medicamento_id, principio_id) VALUES (?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO movimentacoes (ID, MedicamentoID, Tipo, Quantidade, Data, Observacao, UsuarioID, NotaFiscal, CNPJFornecedor, VendaID, CustoUnitario)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
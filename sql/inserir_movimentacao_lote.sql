@@ -0,0 +1,2 @@
+INSERT INTO movimentacao_lotes (movimentacao_id, lote_id, quantidade)
+VALUES (?, ?, ?);
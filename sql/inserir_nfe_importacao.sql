@@ -0,0 +1,2 @@
+INSERT INTO nfe_importacoes (chave, numero, serie, emitente_cnpj, emitente_nome, xml, status, usuario_id, criado_em)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO venda_pagamentos (venda_id, forma, valor, autorizacao)
+VALUES (?, ?, ?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO pedidos_compra (fornecedor_id, status, usuario_id, criado_em, observacao)
+VALUES (?, ?, ?, ?, ?);
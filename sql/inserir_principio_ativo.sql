@@ -0,0 +1 @@
+INSERT INTO principios_ativos (id, nome, concentracao, forma, chave) VALUES (?, ?, ?, ?, ?);
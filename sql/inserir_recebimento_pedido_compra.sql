@@ -0,0 +1,2 @@
+INSERT INTO pedido_compra_recebimentos (pedido_id, item_id, movimentacao_id, quantidade, custo_unitario, numero_lote, validade, nota_fiscal, usuario_id, data)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
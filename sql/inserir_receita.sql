@@ -0,0 +1,3 @@
+INSERT INTO receitas (id, venda_id, venda_item_id, medicamento_id, numero_receita, data_receita,
+                      prescritor_nome, prescritor_crm, prescritor_uf, paciente_nome, paciente_documento, criado_em)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
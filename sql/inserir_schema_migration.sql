@@ -0,0 +1 @@
+INSERT INTO schema_migrations (versao, nome, checksum, aplicada_em) VALUES (?, ?, ?, ?);
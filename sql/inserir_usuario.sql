@@ -0,0 +1,2 @@
+INSERT INTO usuarios (username, nome, password_hash, role, ativo, criado_em)
+VALUES (?, ?, ?, ?, ?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO venda_interacoes (venda_id, medicamento_a_id, medicamento_b_id, substancia_a, substancia_b, gravidade, ciente_user_id, liberado_por_user_id, justificativa, criado_em)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
@@ -0,0 +1,2 @@
+INSERT INTO venda_item_lotes (venda_item_id, lote_id, quantidade)
+VALUES (?, ?, ?);
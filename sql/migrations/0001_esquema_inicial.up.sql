@@ -0,0 +1,346 @@
+-- Esquema inicial do MediControl: todas as tabelas existentes antes do controle de versão do esquema.
+-- Usa IF NOT EXISTS para que bancos criados pelas versões anteriores possam ser adotados sem perda de dados.
+
+CREATE TABLE IF NOT EXISTS categorias (
+    ID TEXT PRIMARY KEY,
+    Nome TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS medicamentos (
+    ID TEXT PRIMARY KEY,
+    Nome TEXT NOT NULL,
+    Fabricante TEXT,
+    Tipo TEXT,
+    CodigoANVISA TEXT,
+    Quantidade INTEGER NOT NULL DEFAULT 0,
+    Validade TEXT,
+    Preco REAL DEFAULT 0.0,
+    CriadoEm DATETIME,
+    CategoriaID TEXT,
+    ListaControle TEXT,
+    CustoMedio REAL NOT NULL DEFAULT 0,
+    FOREIGN KEY (CategoriaID) REFERENCES categorias(ID)
+);
+
+CREATE TABLE IF NOT EXISTS movimentacoes (
+    ID TEXT PRIMARY KEY,
+    MedicamentoID TEXT NOT NULL,
+    Tipo TEXT NOT NULL,
+    Quantidade INTEGER NOT NULL,
+    Data DATETIME NOT NULL,
+    Observacao TEXT,
+    UsuarioID INTEGER,
+    NotaFiscal TEXT,
+    CNPJFornecedor TEXT,
+    VendaID INTEGER,
+    CustoUnitario REAL,
+    FOREIGN KEY (MedicamentoID) REFERENCES medicamentos(ID)
+);
+
+CREATE TABLE IF NOT EXISTS vendas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER,
+    data DATETIME NOT NULL,
+    status TEXT NOT NULL DEFAULT 'ativa',
+    motivo_estorno TEXT,
+    subtotal REAL,
+    desconto REAL NOT NULL DEFAULT 0,
+    total REAL,
+    valor_recebido REAL,
+    troco REAL NOT NULL DEFAULT 0,
+    caixa_id INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS venda_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    venda_id INTEGER NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    preco_unitario REAL NOT NULL,
+    quantidade_devolvida INTEGER NOT NULL DEFAULT 0,
+    desconto REAL NOT NULL DEFAULT 0,
+    valor_total REAL,
+    custo_unitario REAL,
+    FOREIGN KEY (venda_id) REFERENCES vendas(id),
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID)
+);
+
+CREATE TABLE IF NOT EXISTS receitas (
+    id TEXT PRIMARY KEY,
+    venda_id INTEGER NOT NULL,
+    venda_item_id INTEGER NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    numero_receita TEXT NOT NULL,
+    data_receita TEXT NOT NULL,
+    prescritor_nome TEXT,
+    prescritor_crm TEXT NOT NULL,
+    prescritor_uf TEXT,
+    paciente_nome TEXT,
+    paciente_documento TEXT NOT NULL,
+    criado_em DATETIME NOT NULL,
+    FOREIGN KEY (venda_id) REFERENCES vendas(id),
+    FOREIGN KEY (venda_item_id) REFERENCES venda_items(id)
+);
+
+CREATE TABLE IF NOT EXISTS lotes (
+    id TEXT PRIMARY KEY,
+    medicamento_id TEXT NOT NULL,
+    numero_lote TEXT NOT NULL,
+    validade TEXT,
+    quantidade INTEGER NOT NULL DEFAULT 0,
+    fornecedor TEXT,
+    data_entrada DATETIME NOT NULL,
+    custo_unitario REAL,
+    UNIQUE (medicamento_id, numero_lote),
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS movimentacao_lotes (
+    movimentacao_id TEXT NOT NULL,
+    lote_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    PRIMARY KEY (movimentacao_id, lote_id),
+    FOREIGN KEY (lote_id) REFERENCES lotes(id)
+);
+
+CREATE TABLE IF NOT EXISTS venda_item_lotes (
+    venda_item_id INTEGER NOT NULL,
+    lote_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    quantidade_devolvida INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (venda_item_id, lote_id),
+    FOREIGN KEY (venda_item_id) REFERENCES venda_items(id),
+    FOREIGN KEY (lote_id) REFERENCES lotes(id)
+);
+
+CREATE TABLE IF NOT EXISTS auditoria (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    usuario_id INTEGER NOT NULL,
+    data DATETIME NOT NULL,
+    acao TEXT NOT NULL,
+    entidade TEXT NOT NULL,
+    entidade_id TEXT NOT NULL,
+    antes TEXT,
+    depois TEXT
+);
+
+CREATE TABLE IF NOT EXISTS usuarios (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL UNIQUE,
+    nome TEXT,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL,
+    ativo BOOLEAN NOT NULL DEFAULT 1,
+    tentativas_login INTEGER NOT NULL DEFAULT 0,
+    ultima_tentativa DATETIME,
+    ultimo_login DATETIME,
+    criado_em DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS caixas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    usuario_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'aberto',
+    aberto_em DATETIME NOT NULL,
+    valor_abertura REAL NOT NULL DEFAULT 0,
+    fechado_em DATETIME,
+    fechado_por INTEGER,
+    observacao TEXT,
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id)
+);
+
+CREATE TABLE IF NOT EXISTS caixa_movimentos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    caixa_id INTEGER NOT NULL,
+    tipo TEXT NOT NULL,
+    valor REAL NOT NULL,
+    motivo TEXT,
+    venda_id INTEGER,
+    usuario_id INTEGER NOT NULL,
+    data DATETIME NOT NULL,
+    FOREIGN KEY (caixa_id) REFERENCES caixas(id)
+);
+
+CREATE TABLE IF NOT EXISTS caixa_fechamentos (
+    caixa_id INTEGER NOT NULL,
+    forma TEXT NOT NULL,
+    esperado REAL NOT NULL,
+    contado REAL NOT NULL,
+    diferenca REAL NOT NULL,
+    PRIMARY KEY (caixa_id, forma),
+    FOREIGN KEY (caixa_id) REFERENCES caixas(id)
+);
+
+CREATE TABLE IF NOT EXISTS venda_pagamentos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    venda_id INTEGER NOT NULL,
+    forma TEXT NOT NULL,
+    valor REAL NOT NULL,
+    autorizacao TEXT,
+    FOREIGN KEY (venda_id) REFERENCES vendas(id)
+);
+
+CREATE TABLE IF NOT EXISTS fornecedores (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nome TEXT NOT NULL,
+    cnpj TEXT NOT NULL UNIQUE,
+    contato TEXT,
+    telefone TEXT,
+    email TEXT,
+    prazo_entrega_dias INTEGER NOT NULL DEFAULT 0,
+    ativo BOOLEAN NOT NULL DEFAULT 1,
+    criado_em DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pedidos_compra (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fornecedor_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'rascunho',
+    usuario_id INTEGER NOT NULL,
+    criado_em DATETIME NOT NULL,
+    enviado_em DATETIME,
+    previsao_entrega TEXT,
+    recebido_em DATETIME,
+    observacao TEXT,
+    FOREIGN KEY (fornecedor_id) REFERENCES fornecedores(id),
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id)
+);
+
+CREATE TABLE IF NOT EXISTS pedido_compra_itens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pedido_id INTEGER NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    quantidade_recebida INTEGER NOT NULL DEFAULT 0,
+    custo_unitario REAL NOT NULL DEFAULT 0,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos_compra(id) ON DELETE CASCADE,
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID)
+);
+
+CREATE TABLE IF NOT EXISTS pedido_compra_recebimentos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    pedido_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    movimentacao_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    custo_unitario REAL NOT NULL DEFAULT 0,
+    numero_lote TEXT,
+    validade TEXT,
+    nota_fiscal TEXT,
+    usuario_id INTEGER NOT NULL,
+    data DATETIME NOT NULL,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos_compra(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES pedido_compra_itens(id),
+    FOREIGN KEY (movimentacao_id) REFERENCES movimentacoes(ID)
+);
+
+CREATE TABLE IF NOT EXISTS nfe_importacoes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chave TEXT NOT NULL UNIQUE,
+    numero TEXT NOT NULL,
+    serie TEXT,
+    emitente_cnpj TEXT NOT NULL,
+    emitente_nome TEXT,
+    xml TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pendente',
+    usuario_id INTEGER NOT NULL,
+    criado_em DATETIME NOT NULL,
+    confirmado_em DATETIME,
+    confirmado_por INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS nfe_produtos_fornecedor (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    cnpj_fornecedor TEXT NOT NULL,
+    codigo_produto TEXT NOT NULL,
+    ean TEXT,
+    medicamento_id TEXT NOT NULL,
+    atualizado_em DATETIME NOT NULL,
+    UNIQUE (cnpj_fornecedor, codigo_produto),
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS parametros_reposicao (
+    medicamento_id TEXT PRIMARY KEY,
+    estoque_minimo INTEGER,
+    estoque_maximo INTEGER,
+    ponto_pedido INTEGER,
+    fornecedor_id INTEGER,
+    atualizado_em DATETIME NOT NULL,
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID) ON DELETE CASCADE,
+    FOREIGN KEY (fornecedor_id) REFERENCES fornecedores(id)
+);
+
+CREATE TABLE IF NOT EXISTS agendamentos_preco (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medicamento_id TEXT NOT NULL,
+    preco REAL NOT NULL,
+    vigencia TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pendente',
+    motivo TEXT,
+    usuario_id INTEGER NOT NULL,
+    criado_em DATETIME NOT NULL,
+    aplicado_em DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS historico_precos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    medicamento_id TEXT NOT NULL,
+    preco_anterior REAL NOT NULL,
+    preco_novo REAL NOT NULL,
+    origem TEXT NOT NULL,
+    agendamento_id INTEGER,
+    usuario_id INTEGER NOT NULL,
+    alterado_em DATETIME NOT NULL,
+    FOREIGN KEY (agendamento_id) REFERENCES agendamentos_preco(id)
+);
+
+CREATE TABLE IF NOT EXISTS cmed_precos (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    registro TEXT NOT NULL,
+    ean TEXT,
+    produto TEXT NOT NULL,
+    apresentacao TEXT,
+    laboratorio TEXT,
+    pmc REAL NOT NULL,
+    aliquota TEXT NOT NULL,
+    importado_em DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_cmed_precos_registro ON cmed_precos (registro);
+
+CREATE TABLE IF NOT EXISTS inventarios (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    categoria_id TEXT,
+    status TEXT NOT NULL DEFAULT 'aberto',
+    observacao TEXT,
+    aberto_por INTEGER NOT NULL,
+    aberto_em DATETIME NOT NULL,
+    encerrado_por INTEGER,
+    encerrado_em DATETIME,
+    motivo_ajuste TEXT
+);
+
+CREATE TABLE IF NOT EXISTS inventario_contagens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    inventario_id INTEGER NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    numero_lote TEXT NOT NULL DEFAULT '',
+    validade TEXT,
+    quantidade INTEGER NOT NULL,
+    usuario_id INTEGER NOT NULL,
+    contado_em DATETIME NOT NULL,
+    UNIQUE (inventario_id, medicamento_id, numero_lote, usuario_id),
+    FOREIGN KEY (inventario_id) REFERENCES inventarios(id)
+);
+
+CREATE TABLE IF NOT EXISTS inventario_ajustes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    inventario_id INTEGER NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    numero_lote TEXT NOT NULL DEFAULT '',
+    tipo TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    movimentacao_id TEXT,
+    FOREIGN KEY (inventario_id) REFERENCES inventarios(id),
+    FOREIGN KEY (movimentacao_id) REFERENCES movimentacoes(id)
+);
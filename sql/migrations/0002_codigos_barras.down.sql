@@ -0,0 +1 @@
+DROP TABLE IF EXISTS medicamento_codigos_barras;
@@ -0,0 +1,23 @@
+-- Princípios ativos, cada um numa concentração e forma farmacêutica, e a composição dos medicamentos.
+-- Medicamentos com a mesma composição são equivalentes; a categoria regulatória (referência, genérico
+-- ou similar intercambiável) diz se um pode substituir o outro na dispensação.
+CREATE TABLE IF NOT EXISTS principios_ativos (
+    id TEXT PRIMARY KEY,
+    nome TEXT NOT NULL,
+    concentracao TEXT NOT NULL DEFAULT '',
+    forma TEXT NOT NULL DEFAULT '',
+    -- Nome, concentração e forma normalizados, para não cadastrar o mesmo princípio duas vezes
+    chave TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS medicamento_principios (
+    medicamento_id TEXT NOT NULL,
+    principio_id TEXT NOT NULL,
+    PRIMARY KEY (medicamento_id, principio_id),
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID),
+    FOREIGN KEY (principio_id) REFERENCES principios_ativos(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_medicamento_principios_principio ON medicamento_principios (principio_id);
+
+ALTER TABLE medicamentos ADD COLUMN CategoriaRegulatoria TEXT;
@@ -0,0 +1,29 @@
+-- Interações entre princípios ativos, carregadas de uma planilha mantida pela farmácia. Cada par é
+-- gravado uma vez, com as substâncias normalizadas e em ordem (substancia_a < substancia_b).
+CREATE TABLE IF NOT EXISTS interacoes_medicamentosas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    substancia_a TEXT NOT NULL,
+    substancia_b TEXT NOT NULL,
+    gravidade TEXT NOT NULL,
+    descricao TEXT NOT NULL DEFAULT '',
+    conduta TEXT NOT NULL DEFAULT '',
+    UNIQUE (substancia_a, substancia_b)
+);
+
+-- Alertas de interação de cada venda, com quem ficou ciente e, nos graves, o farmacêutico que liberou
+CREATE TABLE IF NOT EXISTS venda_interacoes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    venda_id INTEGER NOT NULL,
+    medicamento_a_id TEXT NOT NULL,
+    medicamento_b_id TEXT NOT NULL,
+    substancia_a TEXT NOT NULL,
+    substancia_b TEXT NOT NULL,
+    gravidade TEXT NOT NULL,
+    ciente_user_id INTEGER NOT NULL,
+    liberado_por_user_id INTEGER,
+    justificativa TEXT NOT NULL DEFAULT '',
+    criado_em DATETIME NOT NULL,
+    FOREIGN KEY (venda_id) REFERENCES vendas(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_venda_interacoes_venda ON venda_interacoes (venda_id);
@@ -0,0 +1 @@
+ALTER TABLE caixa_movimentos DROP COLUMN forma;
@@ -0,0 +1,29 @@
+-- Remove todas as tabelas do esquema inicial, das dependentes para as referenciadas.
+DROP TABLE IF EXISTS inventario_ajustes;
+DROP TABLE IF EXISTS inventario_contagens;
+DROP TABLE IF EXISTS inventarios;
+DROP TABLE IF EXISTS cmed_precos;
+DROP TABLE IF EXISTS historico_precos;
+DROP TABLE IF EXISTS agendamentos_preco;
+DROP TABLE IF EXISTS parametros_reposicao;
+DROP TABLE IF EXISTS nfe_produtos_fornecedor;
+DROP TABLE IF EXISTS nfe_importacoes;
+DROP TABLE IF EXISTS pedido_compra_recebimentos;
+DROP TABLE IF EXISTS pedido_compra_itens;
+DROP TABLE IF EXISTS pedidos_compra;
+DROP TABLE IF EXISTS fornecedores;
+DROP TABLE IF EXISTS venda_pagamentos;
+DROP TABLE IF EXISTS caixa_fechamentos;
+DROP TABLE IF EXISTS caixa_movimentos;
+DROP TABLE IF EXISTS caixas;
+DROP TABLE IF EXISTS usuarios;
+DROP TABLE IF EXISTS auditoria;
+DROP TABLE IF EXISTS venda_item_lotes;
+DROP TABLE IF EXISTS movimentacao_lotes;
+DROP TABLE IF EXISTS lotes;
+DROP TABLE IF EXISTS receitas;
+DROP TABLE IF EXISTS venda_items;
+DROP TABLE IF EXISTS vendas;
+DROP TABLE IF EXISTS movimentacoes;
+DROP TABLE IF EXISTS medicamentos;
+DROP TABLE IF EXISTS categorias;
@@ -0,0 +1,344 @@
+-- Esquema inicial do MediControl no PostgreSQL, equivalente a sql/migrations/0001_esquema_inicial.up.sql.
+
+CREATE TABLE IF NOT EXISTS categorias (
+    ID TEXT PRIMARY KEY,
+    Nome TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS medicamentos (
+    ID TEXT PRIMARY KEY,
+    Nome TEXT NOT NULL,
+    Fabricante TEXT,
+    Tipo TEXT,
+    CodigoANVISA TEXT,
+    Quantidade INTEGER NOT NULL DEFAULT 0,
+    Validade TEXT,
+    Preco DOUBLE PRECISION DEFAULT 0.0,
+    CriadoEm TIMESTAMPTZ,
+    CategoriaID TEXT,
+    ListaControle TEXT,
+    CustoMedio DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS movimentacoes (
+    ID TEXT PRIMARY KEY,
+    MedicamentoID TEXT NOT NULL,
+    Tipo TEXT NOT NULL,
+    Quantidade INTEGER NOT NULL,
+    Data TIMESTAMPTZ NOT NULL,
+    Observacao TEXT,
+    UsuarioID BIGINT,
+    NotaFiscal TEXT,
+    CNPJFornecedor TEXT,
+    VendaID BIGINT,
+    CustoUnitario DOUBLE PRECISION,
+    FOREIGN KEY (MedicamentoID) REFERENCES medicamentos(ID)
+);
+
+CREATE TABLE IF NOT EXISTS vendas (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    data TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL DEFAULT 'ativa',
+    motivo_estorno TEXT,
+    subtotal DOUBLE PRECISION,
+    desconto DOUBLE PRECISION NOT NULL DEFAULT 0,
+    total DOUBLE PRECISION,
+    valor_recebido DOUBLE PRECISION,
+    troco DOUBLE PRECISION NOT NULL DEFAULT 0,
+    caixa_id BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS venda_items (
+    id BIGSERIAL PRIMARY KEY,
+    venda_id BIGINT NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    preco_unitario DOUBLE PRECISION NOT NULL,
+    quantidade_devolvida INTEGER NOT NULL DEFAULT 0,
+    desconto DOUBLE PRECISION NOT NULL DEFAULT 0,
+    valor_total DOUBLE PRECISION,
+    custo_unitario DOUBLE PRECISION,
+    FOREIGN KEY (venda_id) REFERENCES vendas(id),
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID)
+);
+
+CREATE TABLE IF NOT EXISTS receitas (
+    id TEXT PRIMARY KEY,
+    venda_id BIGINT NOT NULL,
+    venda_item_id BIGINT NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    numero_receita TEXT NOT NULL,
+    data_receita TEXT NOT NULL,
+    prescritor_nome TEXT,
+    prescritor_crm TEXT NOT NULL,
+    prescritor_uf TEXT,
+    paciente_nome TEXT,
+    paciente_documento TEXT NOT NULL,
+    criado_em TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (venda_id) REFERENCES vendas(id),
+    FOREIGN KEY (venda_item_id) REFERENCES venda_items(id)
+);
+
+CREATE TABLE IF NOT EXISTS lotes (
+    id TEXT PRIMARY KEY,
+    medicamento_id TEXT NOT NULL,
+    numero_lote TEXT NOT NULL,
+    validade TEXT,
+    quantidade INTEGER NOT NULL DEFAULT 0,
+    fornecedor TEXT,
+    data_entrada TIMESTAMPTZ NOT NULL,
+    custo_unitario DOUBLE PRECISION,
+    UNIQUE (medicamento_id, numero_lote),
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS movimentacao_lotes (
+    movimentacao_id TEXT NOT NULL,
+    lote_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    PRIMARY KEY (movimentacao_id, lote_id),
+    FOREIGN KEY (lote_id) REFERENCES lotes(id)
+);
+
+CREATE TABLE IF NOT EXISTS venda_item_lotes (
+    venda_item_id BIGINT NOT NULL,
+    lote_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    quantidade_devolvida INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (venda_item_id, lote_id),
+    FOREIGN KEY (venda_item_id) REFERENCES venda_items(id),
+    FOREIGN KEY (lote_id) REFERENCES lotes(id)
+);
+
+CREATE TABLE IF NOT EXISTS auditoria (
+    id BIGSERIAL PRIMARY KEY,
+    usuario_id BIGINT NOT NULL,
+    data TIMESTAMPTZ NOT NULL,
+    acao TEXT NOT NULL,
+    entidade TEXT NOT NULL,
+    entidade_id TEXT NOT NULL,
+    antes TEXT,
+    depois TEXT
+);
+
+CREATE TABLE IF NOT EXISTS usuarios (
+    id BIGSERIAL PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    nome TEXT,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL,
+    ativo BOOLEAN NOT NULL DEFAULT TRUE,
+    tentativas_login INTEGER NOT NULL DEFAULT 0,
+    ultima_tentativa TIMESTAMPTZ,
+    ultimo_login TIMESTAMPTZ,
+    criado_em TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS caixas (
+    id BIGSERIAL PRIMARY KEY,
+    usuario_id BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'aberto',
+    aberto_em TIMESTAMPTZ NOT NULL,
+    valor_abertura DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fechado_em TIMESTAMPTZ,
+    fechado_por BIGINT,
+    observacao TEXT,
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id)
+);
+
+CREATE TABLE IF NOT EXISTS caixa_movimentos (
+    id BIGSERIAL PRIMARY KEY,
+    caixa_id BIGINT NOT NULL,
+    tipo TEXT NOT NULL,
+    valor DOUBLE PRECISION NOT NULL,
+    motivo TEXT,
+    venda_id BIGINT,
+    usuario_id BIGINT NOT NULL,
+    data TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (caixa_id) REFERENCES caixas(id)
+);
+
+CREATE TABLE IF NOT EXISTS caixa_fechamentos (
+    caixa_id BIGINT NOT NULL,
+    forma TEXT NOT NULL,
+    esperado DOUBLE PRECISION NOT NULL,
+    contado DOUBLE PRECISION NOT NULL,
+    diferenca DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (caixa_id, forma),
+    FOREIGN KEY (caixa_id) REFERENCES caixas(id)
+);
+
+CREATE TABLE IF NOT EXISTS venda_pagamentos (
+    id BIGSERIAL PRIMARY KEY,
+    venda_id BIGINT NOT NULL,
+    forma TEXT NOT NULL,
+    valor DOUBLE PRECISION NOT NULL,
+    autorizacao TEXT,
+    FOREIGN KEY (venda_id) REFERENCES vendas(id)
+);
+
+CREATE TABLE IF NOT EXISTS fornecedores (
+    id BIGSERIAL PRIMARY KEY,
+    nome TEXT NOT NULL,
+    cnpj TEXT NOT NULL UNIQUE,
+    contato TEXT,
+    telefone TEXT,
+    email TEXT,
+    prazo_entrega_dias INTEGER NOT NULL DEFAULT 0,
+    ativo BOOLEAN NOT NULL DEFAULT TRUE,
+    criado_em TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pedidos_compra (
+    id BIGSERIAL PRIMARY KEY,
+    fornecedor_id BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'rascunho',
+    usuario_id BIGINT NOT NULL,
+    criado_em TIMESTAMPTZ NOT NULL,
+    enviado_em TIMESTAMPTZ,
+    previsao_entrega TEXT,
+    recebido_em TIMESTAMPTZ,
+    observacao TEXT,
+    FOREIGN KEY (fornecedor_id) REFERENCES fornecedores(id),
+    FOREIGN KEY (usuario_id) REFERENCES usuarios(id)
+);
+
+CREATE TABLE IF NOT EXISTS pedido_compra_itens (
+    id BIGSERIAL PRIMARY KEY,
+    pedido_id BIGINT NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    quantidade_recebida INTEGER NOT NULL DEFAULT 0,
+    custo_unitario DOUBLE PRECISION NOT NULL DEFAULT 0,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos_compra(id) ON DELETE CASCADE,
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID)
+);
+
+CREATE TABLE IF NOT EXISTS pedido_compra_recebimentos (
+    id BIGSERIAL PRIMARY KEY,
+    pedido_id BIGINT NOT NULL,
+    item_id BIGINT NOT NULL,
+    movimentacao_id TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    custo_unitario DOUBLE PRECISION NOT NULL DEFAULT 0,
+    numero_lote TEXT,
+    validade TEXT,
+    nota_fiscal TEXT,
+    usuario_id BIGINT NOT NULL,
+    data TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos_compra(id) ON DELETE CASCADE,
+    FOREIGN KEY (item_id) REFERENCES pedido_compra_itens(id),
+    FOREIGN KEY (movimentacao_id) REFERENCES movimentacoes(ID)
+);
+
+CREATE TABLE IF NOT EXISTS nfe_importacoes (
+    id BIGSERIAL PRIMARY KEY,
+    chave TEXT NOT NULL UNIQUE,
+    numero TEXT NOT NULL,
+    serie TEXT,
+    emitente_cnpj TEXT NOT NULL,
+    emitente_nome TEXT,
+    xml TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pendente',
+    usuario_id BIGINT NOT NULL,
+    criado_em TIMESTAMPTZ NOT NULL,
+    confirmado_em TIMESTAMPTZ,
+    confirmado_por BIGINT
+);
+
+CREATE TABLE IF NOT EXISTS nfe_produtos_fornecedor (
+    id BIGSERIAL PRIMARY KEY,
+    cnpj_fornecedor TEXT NOT NULL,
+    codigo_produto TEXT NOT NULL,
+    ean TEXT,
+    medicamento_id TEXT NOT NULL,
+    atualizado_em TIMESTAMPTZ NOT NULL,
+    UNIQUE (cnpj_fornecedor, codigo_produto),
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS parametros_reposicao (
+    medicamento_id TEXT PRIMARY KEY,
+    estoque_minimo INTEGER,
+    estoque_maximo INTEGER,
+    ponto_pedido INTEGER,
+    fornecedor_id BIGINT,
+    atualizado_em TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID) ON DELETE CASCADE,
+    FOREIGN KEY (fornecedor_id) REFERENCES fornecedores(id)
+);
+
+CREATE TABLE IF NOT EXISTS agendamentos_preco (
+    id BIGSERIAL PRIMARY KEY,
+    medicamento_id TEXT NOT NULL,
+    preco DOUBLE PRECISION NOT NULL,
+    vigencia TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pendente',
+    motivo TEXT,
+    usuario_id BIGINT NOT NULL,
+    criado_em TIMESTAMPTZ NOT NULL,
+    aplicado_em TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS historico_precos (
+    id BIGSERIAL PRIMARY KEY,
+    medicamento_id TEXT NOT NULL,
+    preco_anterior DOUBLE PRECISION NOT NULL,
+    preco_novo DOUBLE PRECISION NOT NULL,
+    origem TEXT NOT NULL,
+    agendamento_id BIGINT,
+    usuario_id BIGINT NOT NULL,
+    alterado_em TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (agendamento_id) REFERENCES agendamentos_preco(id)
+);
+
+CREATE TABLE IF NOT EXISTS cmed_precos (
+    id BIGSERIAL PRIMARY KEY,
+    registro TEXT NOT NULL,
+    ean TEXT,
+    produto TEXT NOT NULL,
+    apresentacao TEXT,
+    laboratorio TEXT,
+    pmc DOUBLE PRECISION NOT NULL,
+    aliquota TEXT NOT NULL,
+    importado_em TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_cmed_precos_registro ON cmed_precos (registro);
+
+CREATE TABLE IF NOT EXISTS inventarios (
+    id BIGSERIAL PRIMARY KEY,
+    categoria_id TEXT,
+    status TEXT NOT NULL DEFAULT 'aberto',
+    observacao TEXT,
+    aberto_por BIGINT NOT NULL,
+    aberto_em TIMESTAMPTZ NOT NULL,
+    encerrado_por BIGINT,
+    encerrado_em TIMESTAMPTZ,
+    motivo_ajuste TEXT
+);
+
+CREATE TABLE IF NOT EXISTS inventario_contagens (
+    id BIGSERIAL PRIMARY KEY,
+    inventario_id BIGINT NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    numero_lote TEXT NOT NULL DEFAULT '',
+    validade TEXT,
+    quantidade INTEGER NOT NULL,
+    usuario_id BIGINT NOT NULL,
+    contado_em TIMESTAMPTZ NOT NULL,
+    UNIQUE (inventario_id, medicamento_id, numero_lote, usuario_id),
+    FOREIGN KEY (inventario_id) REFERENCES inventarios(id)
+);
+
+CREATE TABLE IF NOT EXISTS inventario_ajustes (
+    id BIGSERIAL PRIMARY KEY,
+    inventario_id BIGINT NOT NULL,
+    medicamento_id TEXT NOT NULL,
+    numero_lote TEXT NOT NULL DEFAULT '',
+    tipo TEXT NOT NULL,
+    quantidade INTEGER NOT NULL,
+    movimentacao_id TEXT,
+    FOREIGN KEY (inventario_id) REFERENCES inventarios(id),
+    FOREIGN KEY (movimentacao_id) REFERENCES movimentacoes(id)
+);
@@ -0,0 +1,10 @@
+-- Códigos de barras (GTIN) dos medicamentos. Um medicamento pode ter vários (embalagens diferentes,
+-- trocas de fabricante), mas cada GTIN identifica um só medicamento. Gravado com 13 dígitos (EAN-13)
+-- ou, nas embalagens de agrupamento, com 14 (GTIN-14).
+CREATE TABLE IF NOT EXISTS medicamento_codigos_barras (
+    gtin TEXT PRIMARY KEY,
+    medicamento_id TEXT NOT NULL,
+    FOREIGN KEY (medicamento_id) REFERENCES medicamentos(ID)
+);
+
+CREATE INDEX IF NOT EXISTS idx_medicamento_codigos_barras_medicamento ON medicamento_codigos_barras (medicamento_id);
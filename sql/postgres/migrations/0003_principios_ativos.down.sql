@@ -0,0 +1,3 @@
+ALTER TABLE medicamentos DROP COLUMN CategoriaRegulatoria;
+DROP TABLE IF EXISTS medicamento_principios;
+DROP TABLE IF EXISTS principios_ativos;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS venda_interacoes;
+DROP TABLE IF EXISTS interacoes_medicamentosas;
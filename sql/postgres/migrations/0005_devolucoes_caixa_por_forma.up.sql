@@ -0,0 +1,5 @@
+-- As devoluções ao cliente saem pela forma em que a venda foi paga. As lançadas antes desta migração
+-- eram todas descontadas do dinheiro.
+ALTER TABLE caixa_movimentos ADD COLUMN forma TEXT;
+
+UPDATE caixa_movimentos SET forma = 'dinheiro' WHERE tipo = 'devolucao';
@@ -0,0 +1,22 @@
+SELECT v.id, vi.id, v.data, m.Nome, m.ListaControle, m.CodigoANVISA,
+       vi.quantidade - COALESCE((SELECT SUM(mv.Quantidade)
+                                 FROM movimentacoes mv
+                                 WHERE mv.VendaID = v.id AND mv.MedicamentoID = vi.medicamento_id
+                                   AND mv.Tipo = 'entrada' AND mv.Data < ?), 0),
+       l.numero_lote,
+       COALESCE(vil.quantidade - COALESCE((SELECT SUM(ml.quantidade)
+                                           FROM movimentacoes mv
+                                           JOIN movimentacao_lotes ml ON ml.movimentacao_id = mv.ID
+                                           WHERE mv.VendaID = v.id AND mv.MedicamentoID = vi.medicamento_id
+                                             AND mv.Tipo = 'entrada' AND ml.lote_id = vil.lote_id AND mv.Data < ?), 0), 0),
+       r.numero_receita, r.data_receita, r.prescritor_nome, r.prescritor_crm, r.prescritor_uf,
+       r.paciente_nome, r.paciente_documento
+FROM venda_items vi
+JOIN vendas v ON v.id = vi.venda_id
+JOIN medicamentos m ON m.ID = vi.medicamento_id
+LEFT JOIN venda_item_lotes vil ON vil.venda_item_id = vi.id
+LEFT JOIN lotes l ON l.id = vil.lote_id
+LEFT JOIN receitas r ON r.venda_item_id = vi.id
+WHERE m.ListaControle IS NOT NULL AND m.ListaControle <> ''
+  AND v.data >= ? AND v.data < ?
+ORDER BY v.data, vi.id;
@@ -0,0 +1 @@
+UPDATE usuarios SET tentativas_login = ?, ultima_tentativa = ? WHERE id = ?;
@@ -0,0 +1 @@
+UPDATE usuarios SET tentativas_login = 0, ultima_tentativa = NULL, ultimo_login = ? WHERE id = ?;
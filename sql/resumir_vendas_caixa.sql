@@ -0,0 +1,3 @@
+SELECT COUNT(*), COALESCE(SUM(total), 0), COALESCE(SUM(troco), 0)
+FROM vendas
+WHERE caixa_id = ?;
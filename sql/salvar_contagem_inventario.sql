@@ -0,0 +1,6 @@
+INSERT INTO inventario_contagens (inventario_id, medicamento_id, numero_lote, validade, quantidade, usuario_id, contado_em)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (inventario_id, medicamento_id, numero_lote, usuario_id) DO UPDATE SET
+    validade = excluded.validade,
+    quantidade = excluded.quantidade,
+    contado_em = excluded.contado_em;
@@ -0,0 +1,8 @@
+INSERT INTO parametros_reposicao (medicamento_id, estoque_minimo, estoque_maximo, ponto_pedido, fornecedor_id, atualizado_em)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (medicamento_id) DO UPDATE SET
+    estoque_minimo = excluded.estoque_minimo,
+    estoque_maximo = excluded.estoque_maximo,
+    ponto_pedido = excluded.ponto_pedido,
+    fornecedor_id = excluded.fornecedor_id,
+    atualizado_em = excluded.atualizado_em;
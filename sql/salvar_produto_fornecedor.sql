@@ -0,0 +1,4 @@
+INSERT INTO nfe_produtos_fornecedor (cnpj_fornecedor, codigo_produto, ean, medicamento_id, atualizado_em)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (cnpj_fornecedor, codigo_produto)
+DO UPDATE SET ean = excluded.ean, medicamento_id = excluded.medicamento_id, atualizado_em = excluded.atualizado_em;
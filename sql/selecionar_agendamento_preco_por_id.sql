@@ -0,0 +1,4 @@
+SELECT a.id, a.medicamento_id, COALESCE(m.Nome, ''), a.preco, a.vigencia, a.status, a.motivo, a.usuario_id, a.criado_em, a.aplicado_em
+FROM agendamentos_preco a
+LEFT JOIN medicamentos m ON m.ID = a.medicamento_id
+WHERE a.id = ?;
@@ -0,0 +1,14 @@
+SELECT m.ID, m.Nome, COALESCE(m.Fabricante, ''), COALESCE(m.Tipo, ''), COALESCE(m.CodigoANVISA, ''), m.Quantidade, COALESCE(m.Validade, ''), m.CriadoEm, m.Preco, c.ID, c.Nome, COALESCE(m.ListaControle, ''), COALESCE(m.CustoMedio, 0), COALESCE(m.CategoriaRegulatoria, '')
+FROM medicamentos m
+LEFT JOIN categorias c ON m.CategoriaID = c.ID
+WHERE m.ID <> ?
+  AND m.Quantidade > 0
+  AND m.CategoriaRegulatoria IN ('referencia', 'generico', 'similar')
+  AND (SELECT COUNT(*) FROM medicamento_principios mp WHERE mp.medicamento_id = m.ID) =
+      (SELECT COUNT(*) FROM medicamento_principios mp WHERE mp.medicamento_id = ?)
+  AND NOT EXISTS (
+      SELECT 1 FROM medicamento_principios mp
+      WHERE mp.medicamento_id = m.ID
+        AND mp.principio_id NOT IN (SELECT principio_id FROM medicamento_principios WHERE medicamento_id = ?)
+  )
+ORDER BY COALESCE(m.Preco, 0), m.Nome, m.ID;
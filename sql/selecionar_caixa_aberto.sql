@@ -0,0 +1 @@
+SELECT id FROM caixas WHERE usuario_id = ? AND status = ? ORDER BY id DESC LIMIT 1;
@@ -0,0 +1 @@
+SELECT forma, contado FROM caixa_fechamentos WHERE caixa_id = ?;
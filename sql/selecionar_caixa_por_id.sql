@@ -0,0 +1,4 @@
+SELECT c.id, c.usuario_id, COALESCE(u.username, ''), c.status, c.aberto_em, c.valor_abertura, c.fechado_em, c.observacao
+FROM caixas c
+LEFT JOIN usuarios u ON u.id = c.usuario_id
+WHERE c.id = ?;
@@ -0,0 +1,4 @@
+SELECT v.caixa_id, c.status, v.troco
+FROM vendas v
+LEFT JOIN caixas c ON c.id = v.caixa_id
+WHERE v.id = ?;
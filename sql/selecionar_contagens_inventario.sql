@@ -0,0 +1,4 @@
+SELECT id, inventario_id, medicamento_id, numero_lote, COALESCE(validade, ''), quantidade, usuario_id, contado_em
+FROM inventario_contagens
+WHERE inventario_id = ?
+ORDER BY medicamento_id, numero_lote, usuario_id;
@@ -0,0 +1,36 @@
+SELECT m.ID, m.Nome, m.Quantidade,
+       r.estoque_minimo, r.estoque_maximo, r.ponto_pedido,
+       COALESCE(r.fornecedor_id, (
+           SELECT p.fornecedor_id
+           FROM pedido_compra_itens i
+           JOIN pedidos_compra p ON p.id = i.pedido_id
+           WHERE i.medicamento_id = m.ID
+           ORDER BY p.criado_em DESC, p.id DESC
+           LIMIT 1
+       )) AS fornecedor_id,
+       (
+           SELECT COALESCE(SUM(i.quantidade - i.quantidade_recebida), 0)
+           FROM pedido_compra_itens i
+           JOIN pedidos_compra p ON p.id = i.pedido_id
+           WHERE i.medicamento_id = m.ID AND p.status IN ('enviado', 'parcialmente recebido')
+       ) AS em_pedido,
+       (
+           SELECT COALESCE(SUM(vi.quantidade - vi.quantidade_devolvida), 0)
+           FROM venda_items vi
+           JOIN vendas v ON v.id = vi.venda_id
+           WHERE vi.medicamento_id = m.ID AND v.status <> 'cancelada' AND v.data >= ?
+       ) + (
+           SELECT COALESCE(SUM(mv.Quantidade), 0)
+           FROM movimentacoes mv
+           WHERE mv.MedicamentoID = m.ID AND mv.Tipo IN ('saida', 'ajuste_saida') AND mv.Data >= ?
+       ) AS consumo,
+       (
+           SELECT l.custo_unitario
+           FROM lotes l
+           WHERE l.medicamento_id = m.ID AND l.custo_unitario > 0
+           ORDER BY l.data_entrada DESC
+           LIMIT 1
+       ) AS ultimo_custo
+FROM medicamentos m
+LEFT JOIN parametros_reposicao r ON r.medicamento_id = m.ID
+ORDER BY m.Nome;
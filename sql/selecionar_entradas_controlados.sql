@@ -0,0 +1,10 @@
+SELECT mv.ID, mv.Data, m.Nome, m.CodigoANVISA, l.numero_lote, ml.quantidade, mv.NotaFiscal, mv.CNPJFornecedor
+FROM movimentacoes mv
+JOIN medicamentos m ON m.ID = mv.MedicamentoID
+JOIN movimentacao_lotes ml ON ml.movimentacao_id = mv.ID
+JOIN lotes l ON l.id = ml.lote_id
+WHERE mv.Tipo = 'entrada'
+  AND mv.VendaID IS NULL
+  AND m.ListaControle IS NOT NULL AND m.ListaControle <> ''
+  AND datetime(mv.Data) >= datetime(?) AND datetime(mv.Data) < datetime(?)
+ORDER BY mv.Data, m.Nome;
@@ -0,0 +1,3 @@
+SELECT id, nome, cnpj, contato, telefone, email, prazo_entrega_dias, ativo, criado_em
+FROM fornecedores
+WHERE cnpj = ?;
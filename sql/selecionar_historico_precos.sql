@@ -0,0 +1,4 @@
+SELECT id, medicamento_id, preco_anterior, preco_novo, origem, agendamento_id, usuario_id, alterado_em
+FROM historico_precos
+WHERE medicamento_id = ?
+ORDER BY alterado_em DESC, id DESC;
@@ -0,0 +1,3 @@
+SELECT id, substancia_a, substancia_b, gravidade, descricao, conduta
+FROM interacoes_medicamentosas
+ORDER BY substancia_a, substancia_b;
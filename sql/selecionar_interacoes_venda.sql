@@ -0,0 +1,9 @@
+SELECT vi.medicamento_a_id, COALESCE(ma.Nome, ''), vi.medicamento_b_id, COALESCE(mb.Nome, ''), vi.substancia_a, vi.substancia_b, vi.gravidade,
+       vi.ciente_user_id, COALESCE(uc.username, ''), vi.liberado_por_user_id, COALESCE(ul.username, ''), vi.justificativa, vi.criado_em
+FROM venda_interacoes vi
+LEFT JOIN medicamentos ma ON ma.ID = vi.medicamento_a_id
+LEFT JOIN medicamentos mb ON mb.ID = vi.medicamento_b_id
+LEFT JOIN usuarios uc ON uc.id = vi.ciente_user_id
+LEFT JOIN usuarios ul ON ul.id = vi.liberado_por_user_id
+WHERE vi.venda_id = ?
+ORDER BY vi.id;
@@ -0,0 +1,4 @@
+SELECT medicamento_id, numero_lote, tipo, quantidade, COALESCE(movimentacao_id, '')
+FROM inventario_ajustes
+WHERE inventario_id = ?
+ORDER BY id;
@@ -0,0 +1,6 @@
+SELECT m.Nome, m.CodigoANVISA, l.numero_lote, l.quantidade
+FROM lotes l
+JOIN medicamentos m ON m.ID = l.medicamento_id
+WHERE m.ListaControle IS NOT NULL AND m.ListaControle <> ''
+  AND l.quantidade > 0
+ORDER BY m.Nome, l.numero_lote;
@@ -0,0 +1,5 @@
+SELECT i.id, COALESCE(i.categoria_id, ''), COALESCE(c.nome, ''), i.status, COALESCE(i.observacao, ''), i.aberto_por, i.aberto_em,
+       COALESCE(i.encerrado_por, 0), i.encerrado_em, COALESCE(i.motivo_ajuste, '')
+FROM inventarios i
+LEFT JOIN categorias c ON c.id = i.categoria_id
+WHERE i.id = ?;
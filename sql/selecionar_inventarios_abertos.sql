@@ -0,0 +1,3 @@
+SELECT id, COALESCE(categoria_id, '')
+FROM inventarios
+WHERE status = 'aberto';
@@ -0,0 +1,5 @@
+SELECT i.id, i.medicamento_id, COALESCE(m.Nome, ''), i.quantidade, i.quantidade_recebida, i.custo_unitario
+FROM pedido_compra_itens i
+LEFT JOIN medicamentos m ON m.ID = i.medicamento_id
+WHERE i.pedido_id = ?
+ORDER BY i.id;
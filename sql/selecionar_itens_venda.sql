@@ -0,0 +1,6 @@
+SELECT vi.id, vi.medicamento_id, m.Nome, vi.quantidade, vi.quantidade_devolvida,
+       COALESCE(vi.valor_total, vi.quantidade * vi.preco_unitario), COALESCE(vi.custo_unitario, 0)
+FROM venda_items vi
+LEFT JOIN medicamentos m ON m.ID = vi.medicamento_id
+WHERE vi.venda_id = ?
+ORDER BY vi.id;
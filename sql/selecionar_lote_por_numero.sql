@@ -0,0 +1,3 @@
+SELECT id, quantidade
+FROM lotes
+WHERE medicamento_id = ? AND numero_lote = ?;
@@ -0,0 +1,5 @@
+SELECT m.ID, m.Nome, COALESCE(m.Fabricante, ''), COALESCE(m.Preco, 0),
+       l.id, l.numero_lote, COALESCE(l.validade, ''), l.quantidade
+FROM lotes l
+JOIN medicamentos m ON m.ID = l.medicamento_id
+WHERE l.quantidade > 0;
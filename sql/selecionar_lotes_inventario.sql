@@ -0,0 +1,4 @@
+SELECT l.id, l.medicamento_id, l.numero_lote, l.validade, l.quantidade, l.fornecedor, l.data_entrada, COALESCE(l.custo_unitario, 0)
+FROM lotes l
+JOIN medicamentos m ON m.ID = l.medicamento_id
+WHERE l.quantidade > 0 AND (? = '' OR m.CategoriaID = ?);
@@ -0,0 +1,3 @@
+SELECT id, medicamento_id, numero_lote, validade, quantidade, fornecedor, data_entrada, COALESCE(custo_unitario, 0)
+FROM lotes
+WHERE medicamento_id = ?;
@@ -0,0 +1,5 @@
+SELECT vil.lote_id, l.numero_lote, l.validade, vil.quantidade, vil.quantidade_devolvida
+FROM venda_item_lotes vil
+JOIN lotes l ON l.id = vil.lote_id
+WHERE vil.venda_item_id = ?
+ORDER BY l.validade, l.numero_lote;
@@ -0,0 +1,5 @@
+SELECT m.ID, m.Nome, COALESCE(m.Fabricante, ''), COALESCE(m.Tipo, ''), COALESCE(m.CodigoANVISA, ''), m.Quantidade, COALESCE(m.Validade, ''), m.CriadoEm, m.Preco, c.ID, c.Nome, COALESCE(m.ListaControle, ''), COALESCE(m.CustoMedio, 0), COALESCE(m.CategoriaRegulatoria, '')
+FROM medicamento_codigos_barras cb
+JOIN medicamentos m ON m.ID = cb.medicamento_id
+LEFT JOIN categorias c ON m.CategoriaID = c.ID
+WHERE cb.gtin = ?;
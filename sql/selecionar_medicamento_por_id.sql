@@ -0,0 +1,4 @@
+SELECT m.ID, m.Nome, COALESCE(m.Fabricante, ''), COALESCE(m.Tipo, ''), COALESCE(m.CodigoANVISA, ''), m.Quantidade, COALESCE(m.Validade, ''), m.CriadoEm, m.Preco, c.ID, c.Nome, COALESCE(m.ListaControle, ''), COALESCE(m.CustoMedio, 0), COALESCE(m.CategoriaRegulatoria, '')
+FROM medicamentos m
+LEFT JOIN categorias c ON m.CategoriaID = c.ID
+WHERE m.ID = ?;
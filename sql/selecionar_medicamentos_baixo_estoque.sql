@@ -0,0 +1,5 @@
+SELECT m.ID, m.Nome, COALESCE(m.Fabricante, ''), m.Quantidade
+FROM medicamentos m
+LEFT JOIN parametros_reposicao r ON r.medicamento_id = m.ID
+WHERE m.Quantidade < COALESCE(r.estoque_minimo, ?)
+ORDER BY m.Quantidade ASC;
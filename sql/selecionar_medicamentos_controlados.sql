@@ -0,0 +1,4 @@
+SELECT ID, Nome, ListaControle, Quantidade
+FROM medicamentos
+WHERE ListaControle IS NOT NULL AND ListaControle <> ''
+ORDER BY ListaControle, Nome;
@@ -0,0 +1,4 @@
+SELECT ID, Nome, Quantidade, COALESCE(Validade, ''), COALESCE(CustoMedio, 0)
+FROM medicamentos
+WHERE ? = '' OR CategoriaID = ?
+ORDER BY Nome;
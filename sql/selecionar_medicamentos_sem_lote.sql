@@ -0,0 +1,4 @@
+SELECT m.ID, m.Quantidade, m.Validade
+FROM medicamentos m
+WHERE m.Quantidade > 0
+  AND NOT EXISTS (SELECT 1 FROM lotes l WHERE l.medicamento_id = m.ID);
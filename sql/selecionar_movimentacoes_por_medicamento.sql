@@ -0,0 +1,4 @@
+SELECT ID, Tipo, Quantidade, Data, Observacao
+FROM movimentacoes
+WHERE MedicamentoID = ?
+ORDER BY Data;
@@ -0,0 +1,4 @@
+SELECT id, caixa_id, tipo, valor, motivo, venda_id, usuario_id, data, forma
+FROM caixa_movimentos
+WHERE caixa_id = ?
+ORDER BY data, id;
@@ -0,0 +1,3 @@
+SELECT id, chave, status, xml, usuario_id, criado_em, confirmado_em
+FROM nfe_importacoes
+WHERE chave = ?;
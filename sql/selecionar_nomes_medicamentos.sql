@@ -0,0 +1,2 @@
+SELECT ID, Nome
+FROM medicamentos;
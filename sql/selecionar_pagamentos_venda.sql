@@ -0,0 +1,4 @@
+SELECT id, forma, valor, autorizacao
+FROM venda_pagamentos
+WHERE venda_id = ?
+ORDER BY id;
@@ -0,0 +1,3 @@
+SELECT medicamento_id, estoque_minimo, estoque_maximo, ponto_pedido, fornecedor_id, atualizado_em
+FROM parametros_reposicao
+WHERE medicamento_id = ?;
@@ -0,0 +1,5 @@
+SELECT p.id, p.fornecedor_id, f.nome, f.cnpj, p.status, p.usuario_id, p.criado_em, p.enviado_em,
+       p.previsao_entrega, p.recebido_em, p.observacao
+FROM pedidos_compra p
+JOIN fornecedores f ON f.id = p.fornecedor_id
+WHERE p.id = ?;
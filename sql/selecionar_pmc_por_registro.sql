@@ -0,0 +1,3 @@
+SELECT MIN(pmc)
+FROM cmed_precos
+WHERE registro = ?;
@@ -0,0 +1,3 @@
+SELECT id, nome, concentracao, forma
+FROM principios_ativos
+WHERE chave = ?;
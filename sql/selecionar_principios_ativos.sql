@@ -0,0 +1,4 @@
+SELECT id, nome, concentracao, forma
+FROM principios_ativos
+WHERE chave LIKE ?
+ORDER BY chave;
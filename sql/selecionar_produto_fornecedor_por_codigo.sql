@@ -0,0 +1,4 @@
+SELECT p.medicamento_id
+FROM nfe_produtos_fornecedor p
+JOIN medicamentos m ON m.ID = p.medicamento_id
+WHERE p.cnpj_fornecedor = ? AND p.codigo_produto = ?;
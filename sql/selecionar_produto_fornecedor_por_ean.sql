@@ -0,0 +1,6 @@
+SELECT p.medicamento_id
+FROM nfe_produtos_fornecedor p
+JOIN medicamentos m ON m.ID = p.medicamento_id
+WHERE p.ean = ?
+ORDER BY p.atualizado_em DESC
+LIMIT 1;
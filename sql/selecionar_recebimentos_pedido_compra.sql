@@ -0,0 +1,5 @@
+SELECT id, item_id, movimentacao_id, quantidade, custo_unitario, COALESCE(numero_lote, ''), COALESCE(validade, ''),
+       COALESCE(nota_fiscal, ''), usuario_id, data
+FROM pedido_compra_recebimentos
+WHERE pedido_id = ?
+ORDER BY data, id;
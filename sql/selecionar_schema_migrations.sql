@@ -0,0 +1 @@
+SELECT versao, nome, checksum, aplicada_em FROM schema_migrations ORDER BY versao;
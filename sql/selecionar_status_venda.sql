@@ -0,0 +1 @@
+SELECT status FROM vendas WHERE id = ?;
@@ -0,0 +1 @@
+SELECT ID, Nome FROM categorias ORDER BY Nome;
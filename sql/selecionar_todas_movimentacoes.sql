@@ -0,0 +1,4 @@
+SELECT mv.ID, mv.MedicamentoID, mv.Tipo, mv.Quantidade, mv.Data, COALESCE(mv.Observacao, ''), COALESCE(m.Nome, ''), COALESCE(m.Tipo, '')
+FROM movimentacoes mv
+LEFT JOIN medicamentos m ON m.ID = mv.MedicamentoID
+ORDER BY mv.Data DESC;
@@ -0,0 +1,3 @@
+SELECT id, username, nome, password_hash, role, ativo, tentativas_login, ultima_tentativa, ultimo_login, criado_em
+FROM usuarios
+WHERE id = ?;
@@ -0,0 +1,5 @@
+SELECT v.id, v.data, v.user_id, COALESCE(u.username, ''), v.status, v.motivo_estorno,
+       v.subtotal, v.desconto, v.total, v.valor_recebido, v.troco
+FROM vendas v
+LEFT JOIN usuarios u ON u.id = v.user_id
+WHERE v.id = ?;
@@ -0,0 +1,6 @@
+SELECT v.id, v.data, vi.quantidade, r.numero_receita, r.prescritor_crm
+FROM venda_items vi
+JOIN vendas v ON v.id = vi.venda_id
+LEFT JOIN receitas r ON r.venda_item_id = vi.id
+WHERE vi.medicamento_id = ?
+ORDER BY v.data;
@@ -0,0 +1,9 @@
+SELECT m.ID, m.Nome, COALESCE(m.CodigoANVISA, ''), m.Preco, p.pmc, p.produto, COALESCE(p.apresentacao, '')
+FROM medicamentos m
+JOIN (
+    SELECT registro, MIN(pmc) AS pmc, MIN(produto) AS produto, MIN(apresentacao) AS apresentacao
+    FROM cmed_precos
+    GROUP BY registro
+) p ON p.registro = REPLACE(REPLACE(REPLACE(m.CodigoANVISA, '.', ''), '-', ''), ' ', '')
+WHERE m.Preco > p.pmc
+ORDER BY (m.Preco - p.pmc) DESC;
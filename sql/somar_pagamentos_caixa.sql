@@ -0,0 +1,5 @@
+SELECT p.forma, SUM(p.valor)
+FROM venda_pagamentos p
+JOIN vendas v ON v.id = p.venda_id
+WHERE v.caixa_id = ?
+GROUP BY p.forma;
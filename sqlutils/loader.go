@@ -1,57 +1,150 @@
 package sqlutils
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
 	"log"
-	"os"
-	"path/filepath"
+	"path"
+	"sort"
 	"strings"
-	"sync"
+
+	arquivossql "medicontrol/sql"
 )
 
-var (
-	queries = make(map[string]string)
-	once    sync.Once
+// Dialetos de SQL suportados. As queries da pasta 'sql' valem para todos; a subpasta com o nome
+// de um dialeto (por exemplo 'sql/postgres') traz as versões que mudam nele e as suas migrações.
+const (
+	DialetoSQLite   = "sqlite"
+	DialetoPostgres = "postgres"
 )
 
-// LoadSQLFiles carrega todas as queries SQL da pasta 'sql' para a memória.
-// Esta função é projetada para ser chamada uma vez durante a inicialização da aplicação.
-func LoadSQLFiles(sqlDir string) error {
-	once.Do(func() {
-		err := filepath.WalkDir(sqlDir, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if !d.IsDir() && strings.HasSuffix(d.Name(), ".sql") {
-				content, readErr := os.ReadFile(path)
-				if readErr != nil {
-					log.Printf("Erro ao ler o arquivo SQL %s: %v", path, readErr)
-					return readErr // ou continue, dependendo da política de erro desejada
-				}
-				queryName := strings.TrimSuffix(d.Name(), ".sql")
-				queries[queryName] = string(content)
-				log.Printf("Carregada query: %s", queryName)
-			}
-			return nil
-		})
+// Queries guarda as queries e as migrações carregadas da pasta 'sql'. Um valor é imutável depois de
+// carregado, então pode ser compartilhado por vários bancos abertos ao mesmo tempo.
+type Queries struct {
+	queries    map[string]string
+	overrides  map[string]map[string]string
+	migrations map[string][]Migration
+	dialeto    string
+}
+
+// LoadEmbeddedSQL carrega as queries embutidas no binário a partir da pasta 'sql'.
+func LoadEmbeddedSQL() (*Queries, error) {
+	return LoadSQLFiles(arquivossql.Arquivos)
+}
+
+// LoadSQLFiles carrega todas as queries SQL da raiz de fsys para a memória, junto com as
+// migrações de esquema da subpasta 'migrations' e as variações de cada dialeto.
+// O resultado usa o dialeto SQLite; veja UsarDialeto.
+func LoadSQLFiles(fsys fs.FS) (*Queries, error) {
+	q := &Queries{
+		queries:    make(map[string]string),
+		overrides:  make(map[string]map[string]string),
+		migrations: make(map[string][]Migration),
+		dialeto:    DialetoSQLite,
+	}
+
+	ignorar := map[string]bool{
+		MigrationsDir:   true,
+		DialetoPostgres: true,
+	}
+	err := fs.WalkDir(fsys, ".", func(caminho string, d fs.DirEntry, err error) error {
 		if err != nil {
-			log.Fatalf("Erro fatal ao carregar arquivos SQL da pasta %s: %v", sqlDir, err)
-			// Em um cenário de produção, você pode querer retornar o erro em vez de Fatalf
-			// para permitir um tratamento de erro mais granular pelo chamador.
+			return err
+		}
+		// As migrações e os dialetos são carregados à parte
+		if d.IsDir() && ignorar[caminho] {
+			return fs.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".sql") {
+			queryName, content, readErr := lerQuery(fsys, caminho)
+			if readErr != nil {
+				return readErr
+			}
+			q.queries[queryName] = content
+			log.Printf("Carregada query: %s", queryName)
 		}
+		return nil
 	})
-	if len(queries) == 0 {
-		log.Printf("Aviso: Nenhum arquivo .sql encontrado em %s ou a pasta não existe.", sqlDir)
-		// Poderia ser um erro se arquivos SQL são esperados.
-		// return fmt.Errorf("nenhum arquivo .sql encontrado em %s", sqlDir)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar arquivos SQL: %w", err)
 	}
-	return nil
+	if len(q.queries) == 0 {
+		return nil, errors.New("nenhum arquivo .sql encontrado")
+	}
+	if q.migrations[DialetoSQLite], err = LoadMigrations(fsys, MigrationsDir); err != nil {
+		return nil, err
+	}
+
+	if q.overrides[DialetoPostgres], err = q.carregarVariacoes(fsys, DialetoPostgres); err != nil {
+		return nil, err
+	}
+	if q.migrations[DialetoPostgres], err = LoadMigrations(fsys, path.Join(DialetoPostgres, MigrationsDir)); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func lerQuery(fsys fs.FS, caminho string) (string, string, error) {
+	content, err := fs.ReadFile(fsys, caminho)
+	if err != nil {
+		log.Printf("Erro ao ler o arquivo SQL %s: %v", caminho, err)
+		return "", "", err
+	}
+	return strings.TrimSuffix(path.Base(caminho), ".sql"), string(content), nil
 }
 
-// GetQuery retorna uma query SQL carregada pelo nome.
+// carregarVariacoes lê as queries de um dialeto, que substituem as de mesmo nome da pasta principal.
+func (q *Queries) carregarVariacoes(fsys fs.FS, dir string) (map[string]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler a pasta %s: %w", dir, err)
+	}
+
+	variacoes := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		queryName, content, err := lerQuery(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := q.queries[queryName]; !ok {
+			return nil, fmt.Errorf("a query %s de %s não existe na pasta principal", queryName, dir)
+		}
+		variacoes[queryName] = content
+	}
+	return variacoes, nil
+}
+
+// UsarDialeto retorna as mesmas queries com as variações e as migrações do banco informado.
+// O valor original não é alterado.
+func (q *Queries) UsarDialeto(nome string) (*Queries, error) {
+	switch nome {
+	case DialetoSQLite, DialetoPostgres:
+		copia := *q
+		copia.dialeto = nome
+		return &copia, nil
+	}
+	return nil, fmt.Errorf("dialeto SQL desconhecido: %s", nome)
+}
+
+// Dialeto retorna o dialeto SQL em uso.
+func (q *Queries) Dialeto() string {
+	return q.dialeto
+}
+
+// GetQuery retorna uma query SQL carregada pelo nome, na variação do dialeto em uso se houver.
 // Retorna uma string vazia se a query não for encontrada.
-func GetQuery(name string) string {
-	query, ok := queries[name]
+func (q *Queries) GetQuery(name string) string {
+	if query, ok := q.overrides[q.dialeto][name]; ok {
+		return query
+	}
+	query, ok := q.queries[name]
 	if !ok {
 		log.Printf("Aviso: Query SQL '%s' não encontrada.", name)
 		return "" // Ou retorne um erro
@@ -59,3 +152,27 @@ func GetQuery(name string) string {
 
 	return query
 }
+
+// GetMigrations retorna as migrações do dialeto em uso, em ordem crescente de versão.
+func (q *Queries) GetMigrations() []Migration {
+	return q.migrations[q.dialeto]
+}
+
+// Validar confere se todas as queries informadas existem, na pasta principal ou nas variações do
+// dialeto em uso, e lista as ausentes no erro.
+func (q *Queries) Validar(nomes []string) error {
+	var ausentes []string
+	for _, nome := range nomes {
+		if _, ok := q.overrides[q.dialeto][nome]; ok {
+			continue
+		}
+		if _, ok := q.queries[nome]; !ok {
+			ausentes = append(ausentes, nome)
+		}
+	}
+	if len(ausentes) > 0 {
+		sort.Strings(ausentes)
+		return fmt.Errorf("queries não encontradas na pasta 'sql' (dialeto %s): %s", q.dialeto, strings.Join(ausentes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package sqlutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// MigrationsDir é a subpasta de 'sql' com as migrações de esquema
+const MigrationsDir = "migrations"
+
+// nomeMigracao reconhece arquivos como 0001_esquema_inicial.up.sql e 0001_esquema_inicial.down.sql
+var nomeMigracao = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration é uma alteração numerada do esquema, com o SQL para aplicá-la (Up) e para desfazê-la (Down).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifica o conteúdo do SQL de aplicação, para detectar migrações alteradas depois de aplicadas.
+func (m Migration) Checksum() string {
+	soma := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(soma[:])
+}
+
+// LoadMigrations lê as migrações da pasta informada de fsys. Cada versão precisa dos arquivos .up.sql e
+// .down.sql com o mesmo nome; arquivos fora do padrão, versões repetidas ou incompletas são erro.
+// Uma pasta inexistente resulta em nenhuma migração.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler a pasta de migrações %s: %w", dir, err)
+	}
+
+	porVersao := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		partes := nomeMigracao.FindStringSubmatch(entry.Name())
+		if partes == nil {
+			return nil, fmt.Errorf("arquivo de migração com nome inválido: %s (esperado NNNN_nome.up.sql ou NNNN_nome.down.sql)", entry.Name())
+		}
+		versao, _ := strconv.Atoi(partes[1])
+		if versao == 0 {
+			return nil, fmt.Errorf("migração %s: a versão deve ser maior que zero", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler a migração %s: %w", entry.Name(), err)
+		}
+
+		m, ok := porVersao[versao]
+		if !ok {
+			m = &Migration{Version: versao, Name: partes[2]}
+			porVersao[versao] = m
+		} else if m.Name != partes[2] {
+			return nil, fmt.Errorf("versão %d repetida nas migrações %s e %s", versao, m.Name, partes[2])
+		}
+		if partes[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	lista := make([]Migration, 0, len(porVersao))
+	for _, m := range porVersao {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migração %04d_%s incompleta: informe os arquivos .up.sql e .down.sql", m.Version, m.Name)
+		}
+		lista = append(lista, *m)
+	}
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Version < lista[j].Version })
+	return lista, nil
+}
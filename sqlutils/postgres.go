@@ -0,0 +1,104 @@
+package sqlutils
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// DriverPostgres é o nome do driver database/sql para PostgreSQL. Ele usa o pgx e converte os
+// marcadores '?' das queries para '$1', '$2', ..., para que as mesmas queries sirvam aos dois bancos.
+const DriverPostgres = "medicontrol-postgres"
+
+func init() {
+	sql.Register(DriverPostgres, driverPostgres{base: stdlib.GetDefaultDriver()})
+}
+
+// conexaoPgx são as interfaces implementadas pela conexão do pgx que o database/sql usa
+type conexaoPgx interface {
+	driver.Conn
+	driver.ConnPrepareContext
+	driver.ConnBeginTx
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.NamedValueChecker
+	driver.SessionResetter
+}
+
+type driverPostgres struct {
+	base driver.Driver
+}
+
+func (d driverPostgres) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.base.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return conexaoPostgres{conn.(conexaoPgx)}, nil
+}
+
+// conexaoPostgres repassa tudo ao pgx, trocando os marcadores das queries
+type conexaoPostgres struct {
+	conexaoPgx
+}
+
+func (c conexaoPostgres) Prepare(query string) (driver.Stmt, error) {
+	return c.conexaoPgx.Prepare(Rebind(query))
+}
+
+func (c conexaoPostgres) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.conexaoPgx.PrepareContext(ctx, Rebind(query))
+}
+
+func (c conexaoPostgres) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.conexaoPgx.ExecContext(ctx, Rebind(query), args)
+}
+
+func (c conexaoPostgres) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.conexaoPgx.QueryContext(ctx, Rebind(query), args)
+}
+
+// Rebind troca os marcadores '?' da query por '$1', '$2', ..., ignorando os que estão dentro de
+// textos entre aspas, identificadores entre aspas duplas e comentários de linha.
+func Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			fim := strings.IndexByte(query[i+1:], ch)
+			if fim < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+fim+2])
+			i += fim + 1
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			fim := strings.IndexByte(query[i:], '\n')
+			if fim < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+fim+1])
+			i += fim
+		case ch == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
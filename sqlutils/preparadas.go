@@ -0,0 +1,82 @@
+package sqlutils
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Registro reúne os nomes das queries usadas por um pacote. Os nomes são declarados em variáveis
+// do pacote, então a lista já está completa quando o primeiro banco é aberto.
+type Registro struct {
+	nomes []string
+}
+
+// Query registra o nome de uma query e o devolve, para ser usado com GetQuery.
+func (r *Registro) Query(nome string) string {
+	r.nomes = append(r.nomes, nome)
+	return nome
+}
+
+// Nomes retorna as queries registradas, na ordem em que foram declaradas.
+func (r *Registro) Nomes() []string {
+	return append([]string(nil), r.nomes...)
+}
+
+// Preparadas guarda as queries já preparadas num banco, indexadas pelo texto da query, para que
+// sejam analisadas pelo banco uma só vez e reaproveitadas a cada execução.
+type Preparadas struct {
+	porTexto map[string]*sql.Stmt
+}
+
+// Preparar prepara no banco cada uma das queries informadas, na variação do dialeto em uso. Além de
+// guardá-las para reuso, isso confere a sintaxe e as tabelas e colunas que elas citam, então precisa
+// ser chamado depois das migrações. Os erros de todas as queries são reunidos num só.
+func Preparar(db *sql.DB, q *Queries, nomes []string) (*Preparadas, error) {
+	p := &Preparadas{porTexto: make(map[string]*sql.Stmt, len(nomes))}
+	var erros []error
+	for _, nome := range nomes {
+		texto := q.GetQuery(nome)
+		if texto == "" {
+			erros = append(erros, fmt.Errorf("query '%s' não encontrada", nome))
+			continue
+		}
+		if _, ok := p.porTexto[texto]; ok {
+			continue
+		}
+		stmt, err := db.Prepare(texto)
+		if err != nil {
+			erros = append(erros, fmt.Errorf("query '%s': %w", nome, err))
+			continue
+		}
+		p.porTexto[texto] = stmt
+	}
+	if len(erros) > 0 {
+		p.Fechar()
+		return nil, fmt.Errorf("erro ao preparar as queries (dialeto %s): %w", q.Dialeto(), errors.Join(erros...))
+	}
+	return p, nil
+}
+
+// Stmt retorna a query preparada com o texto informado, ou nil se ela não foi preparada.
+func (p *Preparadas) Stmt(query string) *sql.Stmt {
+	if p == nil {
+		return nil
+	}
+	return p.porTexto[query]
+}
+
+// Fechar libera as queries preparadas.
+func (p *Preparadas) Fechar() error {
+	if p == nil {
+		return nil
+	}
+	var erros []error
+	for _, stmt := range p.porTexto {
+		if err := stmt.Close(); err != nil {
+			erros = append(erros, err)
+		}
+	}
+	p.porTexto = nil
+	return errors.Join(erros...)
+}
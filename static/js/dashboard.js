@@ -9,6 +9,14 @@ const headers = {
     'Authorization': `Bearer ${localStorage.getItem('token')}`
 };
 
+// Rótulos dos tipos de movimentação; os ajustes vêm da aprovação do inventário
+const rotulosMovimentacao = {
+    entrada: 'Entrada',
+    saida: 'Saída',
+    ajuste_entrada: 'Ajuste de inventário (entrada)',
+    ajuste_saida: 'Ajuste de inventário (saída)'
+};
+
 // Funções de utilidade
 function showPage(pageId) {
     document.querySelectorAll('.page').forEach(page => page.classList.remove('active'));
@@ -44,15 +52,32 @@ function showSuccess(message) {
     alert(message); // Podemos melhorar isso depois com um componente de toast
 }
 
+// Mostrar o total e os botões de página abaixo da tabela de medicamentos
+function renderPaginacaoMedicamentos(resultado, searchTerm) {
+    const container = document.getElementById('medicamentosPaginacao');
+    if (!container) return;
+
+    const totalPaginas = Math.max(1, Math.ceil(resultado.total / resultado.por_pagina));
+    container.innerHTML = `
+        <button type="button" class="secondary-btn" data-pagina="${resultado.pagina - 1}" ${resultado.anterior ? '' : 'disabled'}>Anterior</button>
+        <span>Página ${resultado.pagina} de ${totalPaginas} (${resultado.total} medicamentos)</span>
+        <button type="button" class="secondary-btn" data-pagina="${resultado.pagina + 1}" ${resultado.proxima ? '' : 'disabled'}>Próxima</button>
+    `;
+    container.querySelectorAll('button[data-pagina]').forEach(botao => {
+        botao.addEventListener('click', () => loadMedicamentos(searchTerm, Number(botao.dataset.pagina)));
+    });
+}
+
 // Carregar medicamentos
-async function loadMedicamentos(searchTerm = '') {
+async function loadMedicamentos(searchTerm = '', pagina = 1) {
     try {
         console.log('Iniciando carregamento de medicamentos...');
         
-        let url = '/api/medicamentos';
+        const params = new URLSearchParams({ pagina, por_pagina: 100 });
         if (searchTerm) {
-            url += `?search=${encodeURIComponent(searchTerm)}`;
+            params.set('search', searchTerm);
         }
+        const url = `/api/medicamentos?${params}`;
         
         const response = await fetch(url, { 
             method: 'GET',
@@ -68,8 +93,10 @@ async function loadMedicamentos(searchTerm = '') {
             throw new Error(`HTTP error! status: ${response.status}`);
         }
         
-        const medicamentos = await response.json();
-        console.log('Medicamentos carregados:', medicamentos);
+        const resultado = await response.json();
+        const medicamentos = resultado.medicamentos;
+        console.log('Medicamentos carregados:', resultado);
+        renderPaginacaoMedicamentos(resultado, searchTerm);
         
         const tbody = document.querySelector('#medicamentosTable tbody');
         if (!tbody) {
@@ -107,6 +134,7 @@ async function loadMedicamentos(searchTerm = '') {
                     <td>R$ ${precoFormatado}</td>
                     <td>
                         <button onclick="editMedicamento('${med.id}')" class="secondary-btn">Editar</button>
+                        <button onclick="mostrarAlternativas('${med.id}')" class="secondary-btn">Alternativas</button>
                         <button onclick="deleteMedicamento('${med.id}')" class="error-btn">Excluir</button>
                     </td>
                 </tr>
@@ -201,7 +229,7 @@ async function loadMovimentacoes() {
                     <tr>
                         <td>${dataFormatada}</td>
                         <td>${mov.nome_medicamento || 'Medicamento não encontrado'}</td>
-                        <td>${mov.tipo === 'entrada' ? 'Entrada' : 'Saída'}</td>
+                        <td>${rotulosMovimentacao[mov.tipo] || mov.tipo}</td>
                         <td>${mov.quantidade}</td>
                         <td>${mov.observacao || '-'}</td>
                     </tr>
@@ -258,6 +286,47 @@ async function loadRelatorios() {
             listaBaixa.innerHTML = '<li>Nenhum medicamento com baixo estoque.</li>';
         }
 
+        // Carregar lotes vencidos e a vencer nos próximos 30 dias
+        const responseVencimento = await fetch('/api/relatorios/vencimento?dias=30', { headers });
+        if (responseVencimento.ok) {
+            const vencimento = await responseVencimento.json();
+            const valorTotal = (vencimento.valor_vencido || 0) + (vencimento.valor_a_vencer || 0);
+            document.getElementById('valorVencimento').textContent =
+                `Em risco: R$ ${valorTotal.toFixed(2).replace('.', ',')} (vencido: R$ ${(vencimento.valor_vencido || 0).toFixed(2).replace('.', ',')})`;
+
+            const listaVencimento = document.getElementById('medicamentosVencimento');
+            listaVencimento.innerHTML = '';
+            const itens = [...(vencimento.vencidos || []), ...(vencimento.a_vencer || [])];
+            if (itens.length > 0) {
+                itens.forEach(item => {
+                    const li = document.createElement('li');
+                    const situacao = item.dias_para_vencer < 0 ? 'VENCIDO' : `vence em ${item.dias_para_vencer} dia(s)`;
+                    li.textContent = `${item.nome} - Lote ${item.numero_lote} (${item.validade}) - ${item.quantidade} un. - ${situacao}`;
+                    listaVencimento.appendChild(li);
+                });
+            } else {
+                listaVencimento.innerHTML = '<li>Nenhum lote vencido ou a vencer.</li>';
+            }
+        }
+
+        // Carregar as últimas vendas
+        const responseRecentes = await fetch('/api/vendas?por_pagina=5', { headers });
+        if (responseRecentes.ok) {
+            const resultado = await responseRecentes.json();
+            const listaRecentes = document.getElementById('vendasRecentes');
+            listaRecentes.innerHTML = '';
+            if (resultado.vendas.length > 0) {
+                resultado.vendas.forEach(venda => {
+                    const li = document.createElement('li');
+                    const data = new Date(venda.data).toLocaleString('pt-BR');
+                    li.textContent = `#${venda.id} - ${data} - ${venda.quantidade_itens} item(ns) - R$ ${venda.total_venda.toFixed(2).replace('.', ',')} (${venda.status})`;
+                    listaRecentes.appendChild(li);
+                });
+            } else {
+                listaRecentes.innerHTML = '<li>Nenhuma venda registrada.</li>';
+            }
+        }
+
         // Carregar total de vendas (manter a lógica existente e melhorá-la depois)
         const responseVendas = await fetch('/api/relatorios/vendas', { headers });
         if (responseVendas.ok) {
@@ -291,9 +360,20 @@ document.getElementById('logoutBtn').addEventListener('click', () => {
 });
 
 document.getElementById('addMedicamentoBtn').addEventListener('click', () => {
+    bloquearEstoqueMedicamento(false);
     openModal('medicamentoModal');
 });
 
+// Na edição, o estoque e a validade não são alterados pelo cadastro: os dois vêm dos lotes, que são
+// corrigidos por movimentações ou pelo inventário
+function bloquearEstoqueMedicamento(editando) {
+    ['quantidade', 'validade'].forEach(campo => {
+        const input = document.getElementById(campo);
+        input.disabled = editando;
+        input.title = editando ? 'Corrija o estoque e os lotes por uma movimentação ou pelo inventário' : '';
+    });
+}
+
 document.getElementById('addMovimentacaoBtn').addEventListener('click', () => {
     openModal('movimentacaoModal');
 });
@@ -313,15 +393,20 @@ async function handleMedicamentoSubmit(e) {
         fabricante: document.getElementById('fabricante').value,
         tipo: document.getElementById('tipo').value,
         codigo_anvisa: document.getElementById('codigo_anvisa').value,
-        quantidade: parseInt(document.getElementById('quantidade').value, 10),
-        validade: document.getElementById('validade').value,
         preco: parseFloat(document.getElementById('preco').value) || 0.0,
-        categoria_id: document.getElementById('categoriaId').value
+        categoria_id: document.getElementById('categoriaId').value,
+        codigos_barras: document.getElementById('codigosBarras').value.split(/[\s,;]+/).filter(c => c !== ''),
+        categoria_regulatoria: document.getElementById('categoriaRegulatoria').value,
+        lista_controle: document.getElementById('listaControle').value,
+        principios_ativos: lerPrincipiosAtivos(document.getElementById('principiosAtivos').value)
     };
     
-    // Adiciona o ID ao corpo apenas se estiver editando
+    // Adiciona o ID ao corpo apenas se estiver editando; o estoque e a validade só são informados na criação
     if(isEditing) {
         body.id = medicamentoId;
+    } else {
+        body.quantidade = parseInt(document.getElementById('quantidade').value, 10);
+        body.validade = document.getElementById('validade').value;
     }
 
 
@@ -341,6 +426,7 @@ async function handleMedicamentoSubmit(e) {
         closeModal('medicamentoModal');
         document.getElementById('medicamentoForm').reset();
         document.getElementById('medicamentoId').value = ''; // Limpa o campo oculto
+        bloquearEstoqueMedicamento(false);
         loadMedicamentos();
 
     } catch (error) {
@@ -367,6 +453,39 @@ document.getElementById('buscarAnvisa').addEventListener('click', async () => {
     }
 });
 
+// Leitura do código de barras na movimentação: o leitor termina com Enter, e o DataMatrix
+// da embalagem traz também o lote e a validade da entrada
+document.getElementById('codigoBarrasMovimentacao').addEventListener('keydown', async (e) => {
+    if (e.key !== 'Enter') return;
+    e.preventDefault();
+    const leitura = e.target.value.trim();
+    if (!leitura) return;
+
+    try {
+        const response = await fetch(`/api/medicamentos/barcode/${encodeURIComponent(leitura)}`, { headers });
+        const resultado = await response.json();
+        if (!response.ok) {
+            throw new Error(resultado.error || 'Código de barras não encontrado');
+        }
+        // A lista só tem a página carregada de medicamentos
+        const select = document.getElementById('medicamentoSelect');
+        if (![...select.options].some(o => o.value === resultado.medicamento.id)) {
+            select.add(new Option(resultado.medicamento.nome, resultado.medicamento.id));
+        }
+        select.value = resultado.medicamento.id;
+        if (resultado.lote) {
+            document.getElementById('loteMovimentacao').value = resultado.lote;
+        }
+        if (resultado.validade) {
+            document.getElementById('validadeMovimentacao').value = resultado.validade;
+        }
+        e.target.value = '';
+        document.getElementById('quantidadeMovimentacao').focus();
+    } catch (error) {
+        showError(`Erro: ${error.message}`);
+    }
+});
+
 // Formulário de nova movimentação
 document.getElementById('movimentacaoForm').addEventListener('submit', async (e) => {
     e.preventDefault();
@@ -375,7 +494,9 @@ document.getElementById('movimentacaoForm').addEventListener('submit', async (e)
         medicamento_id: document.getElementById('medicamentoSelect').value,
         tipo: document.getElementById('tipoMovimentacao').value,
         quantidade: parseInt(document.getElementById('quantidadeMovimentacao').value),
-        observacao: document.getElementById('observacao').value
+        observacao: document.getElementById('observacao').value,
+        lote: document.getElementById('loteMovimentacao').value.trim(),
+        validade: document.getElementById('validadeMovimentacao').value
     };
 
     try {
@@ -400,7 +521,7 @@ document.getElementById('movimentacaoForm').addEventListener('submit', async (e)
 // Função para buscar medicamentos
 async function searchMedicamentos(searchTerm) {
     try {
-        const response = await fetch(`/api/medicamentos?search=${encodeURIComponent(searchTerm)}`, { 
+        const response = await fetch(`/api/medicamentos?search=${encodeURIComponent(searchTerm)}&por_pagina=100`, { 
             method: 'GET',
             headers: {
                 'Content-Type': 'application/json',
@@ -412,7 +533,9 @@ async function searchMedicamentos(searchTerm) {
             throw new Error(`HTTP error! status: ${response.status}`);
         }
         
-        const medicamentos = await response.json();
+        const resultado = await response.json();
+        const medicamentos = resultado.medicamentos;
+        renderPaginacaoMedicamentos(resultado, searchTerm);
         const tbody = document.querySelector('#medicamentosTable tbody');
         tbody.innerHTML = '';
         
@@ -434,6 +557,7 @@ async function searchMedicamentos(searchTerm) {
                     <td>${med.quantidade || 0}</td>
                     <td>
                         <button onclick="editMedicamento('${med.id}')" class="secondary-btn">Editar</button>
+                        <button onclick="mostrarAlternativas('${med.id}')" class="secondary-btn">Alternativas</button>
                         <button onclick="deleteMedicamento('${med.id}')" class="error-btn">Excluir</button>
                     </td>
                 </tr>
@@ -532,6 +656,36 @@ async function deleteMedicamento(id) {
     }
 }
 
+// Lê a composição do formulário: um princípio por linha, como "nome | concentração | forma"
+function lerPrincipiosAtivos(texto) {
+    return texto.split('\n')
+        .map(linha => linha.split('|').map(parte => parte.trim()))
+        .filter(partes => partes[0] !== '')
+        .map(([nome, concentracao = '', forma = '']) => ({ nome, concentracao, forma }));
+}
+
+// Mostra os medicamentos em estoque que podem substituir o informado, do mais barato ao mais caro
+async function mostrarAlternativas(id) {
+    try {
+        const response = await fetch(`/api/medicamentos/${id}/alternativas`, { headers });
+        const dados = await response.json();
+        if (!response.ok) {
+            throw new Error(dados.error || 'Erro ao buscar alternativas');
+        }
+        if (dados.alternativas.length === 0) {
+            showSuccess(`Nenhuma alternativa em estoque para ${dados.medicamento.nome}.`);
+            return;
+        }
+        const categorias = { referencia: 'referência', generico: 'genérico', similar: 'similar' };
+        const linhas = dados.alternativas.map(alt =>
+            `${alt.nome} (${categorias[alt.categoria_regulatoria]}, ${alt.fabricante || '-'}): ` +
+            `R$ ${(alt.preco || 0).toFixed(2).replace('.', ',')}, ${alt.quantidade} em estoque`);
+        showSuccess(`Alternativas para ${dados.medicamento.nome}:\n\n${linhas.join('\n')}`);
+    } catch (error) {
+        showError(error.message);
+    }
+}
+
 // Editar medicamento
 // Nota: Esta função depende que exista um endpoint GET /api/medicamentos/:id
 // e que o modal de formulário tenha os IDs corretos.
@@ -554,8 +708,15 @@ async function editMedicamento(id) {
         document.getElementById('tipo').value = med.tipo || '';
         document.getElementById('codigo_anvisa').value = med.codigo_anvisa || '';
         document.getElementById('quantidade').value = med.quantidade || 0;
+        bloquearEstoqueMedicamento(true);
         document.getElementById('validade').value = med.validade || '';
         document.getElementById('preco').value = (med.preco || 0).toFixed(2);
+        document.getElementById('codigosBarras').value = (med.codigos_barras || []).join(', ');
+        document.getElementById('categoriaRegulatoria').value = med.categoria_regulatoria || '';
+        document.getElementById('listaControle').value = med.lista_controle || '';
+        document.getElementById('principiosAtivos').value = (med.principios_ativos || [])
+            .map(p => [p.nome, p.concentracao, p.forma].join(' | '))
+            .join('\n');
         
         const categoriaSelect = document.getElementById('categoriaId');
         if (categoriaSelect) {
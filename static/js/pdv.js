@@ -47,9 +47,154 @@ document.addEventListener('DOMContentLoaded', () => {
         const totalVendaSpan = document.getElementById('totalVenda');
         const finalizarVendaBtn = document.getElementById('finalizarVendaBtn');
         
+        const descontoVendaInput = document.getElementById('descontoVenda');
+        const formaPagamentoSelect = document.getElementById('formaPagamento');
+        const valorRecebidoInput = document.getElementById('valorRecebido');
+        const trocoVendaSpan = document.getElementById('trocoVenda');
+        const vendasRecentesList = document.getElementById('vendasRecentesPdv');
+        const vendaDetalheContainer = document.getElementById('vendaDetalhePdv');
+        
         let carrinho = [];
         let searchTimeout;
 
+        // --- VENDAS RECENTES ---
+        function formatarValor(valor) {
+            return (valor || 0).toFixed(2).replace('.', ',');
+        }
+
+        async function carregarVendasRecentes() {
+            try {
+                const response = await fetch('/api/vendas?por_pagina=10', { headers });
+                if (!response.ok) throw new Error('Erro ao carregar vendas recentes.');
+                const resultado = await response.json();
+
+                vendasRecentesList.innerHTML = '';
+                if (resultado.vendas.length === 0) {
+                    vendasRecentesList.innerHTML = '<li>Nenhuma venda registrada.</li>';
+                    return;
+                }
+
+                resultado.vendas.forEach(venda => {
+                    const li = document.createElement('li');
+                    const data = new Date(venda.data).toLocaleString('pt-BR');
+                    li.textContent = `#${venda.id} - ${data} - R$ ${formatarValor(venda.total_venda)} (${venda.status})`;
+                    li.onclick = () => mostrarVenda(venda.id);
+                    vendasRecentesList.appendChild(li);
+                });
+            } catch (error) {
+                console.error(error);
+            }
+        }
+
+        async function mostrarVenda(id) {
+            try {
+                const response = await fetch(`/api/vendas/${id}`, { headers });
+                if (!response.ok) throw new Error('Erro ao carregar a venda.');
+                const venda = await response.json();
+
+                const linhas = venda.itens.map(item => `
+                    <tr>
+                        <td>${item.nome}</td>
+                        <td>${item.quantidade - item.quantidade_devolvida}${item.quantidade_devolvida > 0 ? ` (${item.quantidade_devolvida} devolv.)` : ''}</td>
+                        <td>R$ ${formatarValor(item.preco_unitario)}</td>
+                        <td>R$ ${formatarValor(item.subtotal)}</td>
+                    </tr>
+                `).join('');
+
+                vendaDetalheContainer.innerHTML = `
+                    <p><strong>Venda #${venda.id}</strong> - ${venda.username || 'usuário ' + venda.user_id} - ${venda.status}</p>
+                    <table>
+                        <thead><tr><th>Medicamento</th><th>Qtd.</th><th>Unitário</th><th>Subtotal</th></tr></thead>
+                        <tbody>${linhas}</tbody>
+                    </table>
+                    <p><strong>Total: R$ ${formatarValor(venda.total_venda)}</strong></p>
+                    ${(venda.interacoes || []).map(i => `
+                        <p>Interação ${i.gravidade}: ${i.medicamento_a_nome} + ${i.medicamento_b_nome}, ciente ${i.ciente_username}${i.liberado_por_username ? `, liberada por ${i.liberado_por_username}: ${i.justificativa}` : ''}</p>
+                    `).join('')}
+                `;
+            } catch (error) {
+                showError(error.message);
+            }
+        }
+
+        carregarVendasRecentes();
+
+        // --- CAIXA ---
+        const statusCaixaSpan = document.getElementById('statusCaixa');
+        const abrirCaixaBtn = document.getElementById('abrirCaixaBtn');
+        const fecharCaixaBtn = document.getElementById('fecharCaixaBtn');
+        let caixaAtual = null;
+
+        async function carregarCaixa() {
+            try {
+                const response = await fetch('/api/caixa/atual', { headers });
+                caixaAtual = response.ok ? await response.json() : null;
+            } catch (error) {
+                console.error(error);
+                caixaAtual = null;
+            }
+
+            if (caixaAtual) {
+                const dinheiro = caixaAtual.formas.find(f => f.forma === 'dinheiro');
+                statusCaixaSpan.textContent = `Caixa #${caixaAtual.id} aberto - ${caixaAtual.quantidade_vendas} venda(s) - dinheiro esperado R$ ${formatarValor(dinheiro ? dinheiro.esperado : 0)}`;
+            } else {
+                statusCaixaSpan.textContent = 'Caixa fechado';
+            }
+            abrirCaixaBtn.style.display = caixaAtual ? 'none' : '';
+            fecharCaixaBtn.style.display = caixaAtual ? '' : 'none';
+        }
+
+        abrirCaixaBtn.addEventListener('click', async () => {
+            const valor = parseFloat((prompt('Fundo de troco (R$):', '0') || '').replace(',', '.'));
+            if (isNaN(valor)) return;
+            try {
+                const response = await fetch('/api/caixa/abertura', {
+                    method: 'POST',
+                    headers: headers,
+                    body: JSON.stringify({ valor_abertura: valor })
+                });
+                if (!response.ok) {
+                    const errorData = await response.json();
+                    throw new Error(errorData.error || 'Não foi possível abrir o caixa.');
+                }
+                showSuccess('Caixa aberto com sucesso!');
+                carregarCaixa();
+            } catch (error) {
+                showError(error.message);
+            }
+        });
+
+        // No fechamento só o dinheiro é contado; as demais formas são conferidas pelo valor esperado
+        fecharCaixaBtn.addEventListener('click', async () => {
+            if (!caixaAtual) return;
+            const valor = parseFloat((prompt('Dinheiro contado na gaveta (R$):') || '').replace(',', '.'));
+            if (isNaN(valor)) return;
+
+            const contagem = {};
+            caixaAtual.formas.forEach(f => { contagem[f.forma] = f.esperado; });
+            contagem.dinheiro = valor;
+
+            try {
+                const response = await fetch('/api/caixa/fechamento', {
+                    method: 'POST',
+                    headers: headers,
+                    body: JSON.stringify({ contagem })
+                });
+                if (!response.ok) {
+                    const errorData = await response.json();
+                    throw new Error(errorData.error || 'Não foi possível fechar o caixa.');
+                }
+                const resumo = await response.json();
+                const dinheiro = resumo.formas.find(f => f.forma === 'dinheiro');
+                showSuccess(`Caixa fechado. Diferença no dinheiro: R$ ${formatarValor(dinheiro.diferenca)}`);
+                carregarCaixa();
+            } catch (error) {
+                showError(error.message);
+            }
+        });
+
+        carregarCaixa();
+
         // --- LÓGICA DE BUSCA ---
         searchInput.addEventListener('input', () => {
             clearTimeout(searchTimeout);
@@ -64,9 +209,9 @@ document.addEventListener('DOMContentLoaded', () => {
 
         async function searchMedicamentos(term) {
             try {
-                const response = await fetch(`/api/medicamentos?search=${encodeURIComponent(term)}`, { headers });
+                const response = await fetch(`/api/medicamentos?search=${encodeURIComponent(term)}&por_pagina=10`, { headers });
                 if (!response.ok) throw new Error('Erro ao buscar medicamentos.');
-                const medicamentos = await response.json() || [];
+                const { medicamentos = [] } = await response.json();
                 
                 suggestionsContainer.innerHTML = '';
                 if (medicamentos.length === 0) {
@@ -79,7 +224,7 @@ document.addEventListener('DOMContentLoaded', () => {
                     div.className = 'suggestion-item';
                     div.innerHTML = `
                         <strong>${med.nome} (${med.fabricante})</strong><br>
-                        <small>Estoque: ${med.quantidade} | Preço: R$ ${med.Preco.toFixed(2)}</small>
+                        <small>Estoque: ${med.quantidade} | Preço: R$ ${med.preco.toFixed(2)}</small>
                     `;
                     div.onclick = () => adicionarAoCarrinho(med);
                     suggestionsContainer.appendChild(div);
@@ -92,6 +237,34 @@ document.addEventListener('DOMContentLoaded', () => {
             }
         }
         
+        // Leitores de código de barras digitam o código e terminam com Enter
+        function pareceCodigoBarras(texto) {
+            return /^\d{8,14}$/.test(texto) || /^\d{16,}/.test(texto) || texto.startsWith('(01)')
+                || texto.startsWith(']') || texto.includes('\x1d');
+        }
+
+        searchInput.addEventListener('keydown', (e) => {
+            const leitura = searchInput.value.trim();
+            if (e.key !== 'Enter' || !pareceCodigoBarras(leitura)) return;
+            e.preventDefault();
+            clearTimeout(searchTimeout);
+            lerCodigoBarras(leitura);
+        });
+
+        async function lerCodigoBarras(leitura) {
+            try {
+                const response = await fetch(`/api/medicamentos/barcode/${encodeURIComponent(leitura)}`, { headers });
+                const resultado = await response.json();
+                if (!response.ok) {
+                    throw new Error(resultado.error || 'Código de barras não encontrado.');
+                }
+                adicionarAoCarrinho(resultado.medicamento, resultado);
+            } catch (error) {
+                searchInput.value = '';
+                showError(error.message);
+            }
+        }
+
         document.addEventListener('click', (e) => {
             if (!suggestionsContainer.contains(e.target) && e.target !== searchInput) {
                 suggestionsContainer.style.display = 'none';
@@ -99,7 +272,9 @@ document.addEventListener('DOMContentLoaded', () => {
         });
 
         // --- LÓGICA DO CARRINHO ---
-        function adicionarAoCarrinho(medicamento) {
+        // A leitura do DataMatrix traz o lote da embalagem, que é o baixado na venda;
+        // sem ela, ou com um lote sem saldo, a baixa segue o vencimento mais próximo
+        function adicionarAoCarrinho(medicamento, leitura) {
             searchInput.value = '';
             suggestionsContainer.style.display = 'none';
 
@@ -108,9 +283,24 @@ document.addEventListener('DOMContentLoaded', () => {
                 return;
             }
 
-            const itemExistente = carrinho.find(item => item.id === medicamento.id);
+            let lote = '';
+            let estoque = medicamento.quantidade;
+            if (leitura && leitura.lote) {
+                if (leitura.validade && leitura.validade < new Date().toISOString().slice(0, 10)) {
+                    showError(`O lote ${leitura.lote} está vencido (${leitura.validade}).`);
+                    return;
+                }
+                if (leitura.saldo_lote > 0) {
+                    lote = leitura.lote;
+                    estoque = leitura.saldo_lote;
+                } else {
+                    showError(`Lote ${leitura.lote} sem saldo no estoque; a baixa seguirá o vencimento mais próximo.`);
+                }
+            }
+
+            const itemExistente = carrinho.find(item => item.id === medicamento.id && item.lote === lote);
             if (itemExistente) {
-                if(itemExistente.quantidade < medicamento.quantidade) {
+                if(itemExistente.quantidade < itemExistente.estoque) {
                     itemExistente.quantidade++;
                 } else {
                     showError('Quantidade máxima em estoque atingida para este item.');
@@ -119,9 +309,10 @@ document.addEventListener('DOMContentLoaded', () => {
                 carrinho.push({
                     id: medicamento.id,
                     nome: medicamento.nome,
-                    preco: medicamento.Preco,
+                    preco: medicamento.preco,
                     quantidade: 1,
-                    estoque: medicamento.quantidade
+                    estoque: estoque,
+                    lote: lote
                 });
             }
             renderizarCarrinho();
@@ -129,7 +320,6 @@ document.addEventListener('DOMContentLoaded', () => {
 
         function renderizarCarrinho() {
             carrinhoItemsContainer.innerHTML = '';
-            let total = 0;
 
             if (carrinho.length === 0) {
                 carrinhoItemsContainer.innerHTML = '<p>O carrinho está vazio.</p>';
@@ -138,34 +328,53 @@ document.addEventListener('DOMContentLoaded', () => {
                 return;
             }
 
-            carrinho.forEach(item => {
+            carrinho.forEach((item, indice) => {
                 const itemTotal = item.preco * item.quantidade;
-                total += itemTotal;
 
                 const itemDiv = document.createElement('div');
                 itemDiv.className = 'carrinho-item';
                 itemDiv.innerHTML = `
                     <div class="carrinho-item-info">
-                        <strong>${item.nome}</strong>
+                        <strong>${item.nome}</strong>${item.lote ? ` <small>Lote ${item.lote}</small>` : ''}
                         <span>R$ ${item.preco.toFixed(2).replace('.', ',')} x ${item.quantidade} = R$ ${itemTotal.toFixed(2).replace('.', ',')}</span>
                     </div>
                     <div class="carrinho-item-actions">
-                        <input type="number" value="${item.quantidade}" min="1" max="${item.estoque}" data-id="${item.id}" class="quantidade-input">
-                        <button class="remover-item-btn" data-id="${item.id}" title="Remover Item">&times;</button>
+                        <input type="number" value="${item.quantidade}" min="1" max="${item.estoque}" data-indice="${indice}" class="quantidade-input">
+                        <button class="remover-item-btn" data-indice="${indice}" title="Remover Item">&times;</button>
                     </div>
                 `;
                 carrinhoItemsContainer.appendChild(itemDiv);
             });
 
-            totalVendaSpan.textContent = total.toFixed(2).replace('.', ',');
+            totalVendaSpan.textContent = formatarValor(calcularTotal());
+            atualizarTroco();
             finalizarVendaBtn.disabled = false;
         }
+
+        // --- PAGAMENTO ---
+        // O total segue o mesmo arredondamento do servidor: desconto da venda sobre a soma dos itens
+        function calcularTotal() {
+            const soma = carrinho.reduce((acc, item) => acc + Math.round(item.preco * item.quantidade * 100) / 100, 0);
+            const percentual = parseFloat(descontoVendaInput.value) || 0;
+            const desconto = Math.round(soma * percentual) / 100;
+            return Math.round((soma - desconto) * 100) / 100;
+        }
+
+        function atualizarTroco() {
+            const total = calcularTotal();
+            const recebido = parseFloat(valorRecebidoInput.value);
+            const troco = formaPagamentoSelect.value === 'dinheiro' && !isNaN(recebido) ? Math.max(recebido - total, 0) : 0;
+            trocoVendaSpan.textContent = formatarValor(troco);
+        }
+
+        descontoVendaInput.addEventListener('input', () => renderizarCarrinho());
+        formaPagamentoSelect.addEventListener('change', atualizarTroco);
+        valorRecebidoInput.addEventListener('input', atualizarTroco);
         
         carrinhoItemsContainer.addEventListener('input', (e) => {
             if (e.target.classList.contains('quantidade-input')) {
-                const id = parseInt(e.target.dataset.id, 10);
                 let novaQuantidade = parseInt(e.target.value, 10);
-                const item = carrinho.find(i => i.id === id);
+                const item = carrinho[parseInt(e.target.dataset.indice, 10)];
 
                 if (item) {
                      if (isNaN(novaQuantidade) || novaQuantidade < 1) {
@@ -185,27 +394,79 @@ document.addEventListener('DOMContentLoaded', () => {
 
         carrinhoItemsContainer.addEventListener('click', (e) => {
             if (e.target.closest('.remover-item-btn')) {
-                const id = parseInt(e.target.closest('.remover-item-btn').dataset.id, 10);
-                carrinho = carrinho.filter(i => i.id !== id);
+                const indice = parseInt(e.target.closest('.remover-item-btn').dataset.indice, 10);
+                carrinho.splice(indice, 1);
                 renderizarCarrinho();
             }
         });
 
+        // --- INTERAÇÕES MEDICAMENTOSAS ---
+        const nomesGravidade = { leve: 'LEVE', moderada: 'MODERADA', grave: 'GRAVE', contraindicada: 'CONTRAINDICADA' };
+
+        // Confere os itens antes de registrar a venda. Retorna a confirmação para enviar com a venda,
+        // undefined quando não há interações, ou null quando o atendente desiste.
+        async function confirmarInteracoes(vendaData) {
+            const response = await fetch('/api/vendas/interacoes', {
+                method: 'POST',
+                headers: headers,
+                body: JSON.stringify(vendaData)
+            });
+            const verificacao = await response.json();
+            if (!response.ok) {
+                throw new Error(verificacao.error || 'Não foi possível verificar as interações.');
+            }
+            if (!verificacao.exige_ciente) return undefined;
+
+            const alertas = verificacao.alertas.map(a =>
+                `[${nomesGravidade[a.gravidade]}] ${a.medicamento_a_nome} + ${a.medicamento_b_nome}: ${a.descricao || a.substancia_a + ' + ' + a.substancia_b}` +
+                (a.conduta ? `\n    Conduta: ${a.conduta}` : '')).join('\n\n');
+            if (!confirm(`Interações medicamentosas entre os itens:\n\n${alertas}\n\nConfirma que orientou o cliente?`)) {
+                return null;
+            }
+            const confirmacao = { ciente: true };
+            if (!verificacao.exige_farmaceutico) return confirmacao;
+
+            confirmacao.justificativa = prompt('Interação grave: informe a justificativa da liberação pelo farmacêutico.');
+            if (!confirmacao.justificativa) return null;
+            // O farmacêutico logado libera a própria venda; o atendente informa o usuário do farmacêutico
+            const username = prompt('Usuário do farmacêutico que libera a venda (deixe em branco se for você):');
+            if (username === null) return null;
+            if (username.trim() !== '') {
+                const password = prompt(`Senha de ${username}:`);
+                if (password === null) return null;
+                confirmacao.liberacao = { username: username.trim(), password };
+            }
+            return confirmacao;
+        }
+
         // --- FINALIZAR VENDA ---
         finalizarVendaBtn.addEventListener('click', async () => {
             if (carrinho.length === 0) return;
 
+            // Sem valor informado, considera-se recebido o valor exato do total
+            const total = calcularTotal();
+            const recebido = parseFloat(valorRecebidoInput.value);
             const vendaData = {
                 itens: carrinho.map(item => ({
                     medicamento_id: item.id,
-                    quantidade: item.quantidade
-                }))
+                    quantidade: item.quantidade,
+                    lote: item.lote || undefined
+                })),
+                desconto_percentual: parseFloat(descontoVendaInput.value) || 0,
+                pagamentos: [{
+                    forma: formaPagamentoSelect.value,
+                    valor: formaPagamentoSelect.value === 'dinheiro' && !isNaN(recebido) ? recebido : total
+                }]
             };
 
             try {
                 finalizarVendaBtn.disabled = true;
                 finalizarVendaBtn.textContent = 'Processando...';
 
+                const interacoes = await confirmarInteracoes(vendaData);
+                if (interacoes === null) return;
+                vendaData.interacoes = interacoes;
+
                 const response = await fetch('/api/vendas', {
                     method: 'POST',
                     headers: headers,
@@ -217,9 +478,16 @@ document.addEventListener('DOMContentLoaded', () => {
                     throw new Error(errorData.error || 'Não foi possível concluir a venda.');
                 }
                 
-                showSuccess('Venda realizada com sucesso!');
+                const resumo = await response.json();
+                showSuccess(resumo.troco > 0
+                    ? `Venda realizada com sucesso! Troco: R$ ${formatarValor(resumo.troco)}`
+                    : 'Venda realizada com sucesso!');
                 carrinho = [];
+                descontoVendaInput.value = 0;
+                valorRecebidoInput.value = '';
                 renderizarCarrinho();
+                carregarVendasRecentes();
+                carregarCaixa();
                 // A função loadMedicamentos vem do dashboard.js e atualiza a lista principal
                 if(typeof loadMedicamentos === 'function'){
                     loadMedicamentos(); 
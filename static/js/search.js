@@ -85,7 +85,7 @@ class SearchMedicamentos {
         const signal = controller.signal;
         this.currentRequest = controller;
         
-        fetch(`/api/medicamentos?search=${encodeURIComponent(term)}`, {
+        fetch(`/api/medicamentos?search=${encodeURIComponent(term)}&por_pagina=10`, {
             method: 'GET',
             headers: {
                 'Content-Type': 'application/json',
@@ -98,7 +98,7 @@ class SearchMedicamentos {
             return response.json();
         })
         .then(data => {
-            this.displaySuggestions(data);
+            this.displaySuggestions(data.medicamentos);
         })
         .catch(error => {
             if (error.name !== 'AbortError') {
@@ -160,7 +160,7 @@ class SearchMedicamentos {
         this.showLoading(true);
         
         // Fazer a requisição de busca
-        fetch(`/api/medicamentos?search=${encodeURIComponent(searchTerm)}`, {
+        fetch(`/api/medicamentos?search=${encodeURIComponent(searchTerm)}&por_pagina=100`, {
             method: 'GET',
             headers: {
                 'Content-Type': 'application/json',
@@ -172,7 +172,7 @@ class SearchMedicamentos {
             return response.json();
         })
         .then(data => {
-            this.updateResults(data);
+            this.updateResults(data.medicamentos);
         })
         .catch(error => {
             console.error('Erro na busca:', error);